@@ -0,0 +1,184 @@
+// Package audit records who changed what.
+//
+// Every mutating operation writes an entry with Record using the tx of the change itself, so an
+// entry exists exactly when the change was committed. The entries carry the acting account, the
+// action, the changed resource with its state before and after the change and the request
+// metadata stored by the Request middleware. The audit_log table is append-only, a trigger
+// rejects updates and deletes. Since the entries outlive the deletion of the accounts, the
+// resources carrying personal data implement Personal and keep it out of the stored state.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+)
+
+// resource types
+const (
+	Court          = "court"
+	Season         = "season"
+	League         = "league"
+	Match          = "match"
+	Player         = "player"
+	Account        = "account"
+	Role           = "role"
+	ServiceAccount = "service_account"
+	APIKey         = "api_key"
+	InviteCode     = "invite_code"
+)
+
+// actions
+const (
+	ActionCreate         = "create"
+	ActionUpdate         = "update"
+	ActionDelete         = "delete"
+	ActionSubmitScore    = "submit_score"
+	ActionCorrectScore   = "correct_score"
+	ActionAssignLeague   = "assign_league"
+	ActionUnassignLeague = "unassign_league"
+	ActionUpdateRole     = "update_role"
+	ActionDeactivate     = "deactivate"
+	ActionReactivate     = "reactivate"
+	ActionLogout         = "logout"
+	ActionGrant          = "grant"
+	ActionRevokeGrant    = "revoke_grant"
+	ActionRevoke         = "revoke"
+	ActionApprove        = "approve"
+	ActionReject         = "reject"
+)
+
+// Entry is a single change of a resource. Before is nil for created resources and After is
+// nil for deleted ones, both are stored as json
+type Entry struct {
+	Action       string
+	ResourceType string
+	ResourceId   string
+	Before       any
+	After        any
+}
+
+type requestInfo struct {
+	ip        string
+	userAgent string
+	requestId string
+	method    string
+	path      string
+}
+
+type contextKey struct {
+	name string
+}
+
+var requestInfoCtxKey = &contextKey{"audit-request-info"}
+
+// Request stores the metadata of the request the entries recorded while serving it are tagged with
+func Request(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		info := &requestInfo{
+			ip:        ip,
+			userAgent: r.UserAgent(),
+			requestId: chimiddleware.GetReqID(r.Context()),
+			method:    r.Method,
+			path:      r.URL.Path,
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestInfoCtxKey, info)))
+	})
+}
+
+// Personal is implemented by the resources holding personal data such as names, emails or phone
+// numbers. Record stores the state returned by AuditState in place of the resource
+type Personal interface {
+	AuditState() any
+}
+
+// Appender takes the entries in place of the audit_log table, the transactions of the in-memory
+// backend implement it
+type Appender interface {
+	AppendAuditLog(entry db.AuditLog) error
+}
+
+// Record writes the entry with the acting account and the request metadata of the ctx. It has to be
+// called with the tx of the change so the entry is committed or rolled back together with it
+func Record(ctx context.Context, q db.Querier, e Entry) error {
+	before, err := marshal(e.Before)
+	if err != nil {
+		return failure.New("unable to record audit entry", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	after, err := marshal(e.After)
+	if err != nil {
+		return failure.New("unable to record audit entry", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	info, ok := ctx.Value(requestInfoCtxKey).(*requestInfo)
+	if !ok {
+		info = &requestInfo{}
+	}
+
+	if a, ok := q.(Appender); ok {
+		err = a.AppendAuditLog(db.AuditLog{
+			ActorAccountId: nullString(stringValue(ctx, middleware.AccountIdCtxKey)),
+			ActorApiKeyId:  nullString(stringValue(ctx, middleware.APIKeyIdCtxKey)),
+			Action:         e.Action,
+			ResourceType:   e.ResourceType,
+			ResourceId:     e.ResourceId,
+			Before:         before,
+			After:          after,
+			Ip:             nullString(info.ip),
+			UserAgent:      nullString(info.userAgent),
+			RequestId:      nullString(info.requestId),
+			Method:         nullString(info.method),
+			Path:           nullString(info.path),
+		})
+		if err != nil {
+			return failure.New("unable to record audit entry", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		return nil
+	}
+
+	sql := `
+		insert into audit_log (actor_account_id, actor_api_key_id, action, resource_type, resource_id, before, after, ip, user_agent, request_id, method, path)
+		values (nullif($1, '')::uuid, nullif($2, '')::uuid, $3, $4, $5, $6, $7, nullif($8, ''), nullif($9, ''), nullif($10, ''), nullif($11, ''), nullif($12, ''))
+	`
+
+	_, err = q.Exec(ctx, sql, stringValue(ctx, middleware.AccountIdCtxKey), stringValue(ctx, middleware.APIKeyIdCtxKey), e.Action, e.ResourceType, e.ResourceId, before, after, info.ip, info.userAgent, info.requestId, info.method, info.path)
+	if err != nil {
+		return failure.New("unable to record audit entry", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// marshal encodes the resource state, a missing state is stored as null
+func marshal(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if p, ok := v.(Personal); ok {
+		v = p.AuditState()
+	}
+	return json.Marshal(v)
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func stringValue(ctx context.Context, key any) string {
+	v, _ := ctx.Value(key).(string)
+	return v
+}
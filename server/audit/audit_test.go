@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/markovidakovic/gdsi/server/middleware"
+)
+
+// recorder keeps the args of the executed statements in place of the db
+type recorder struct {
+	args [][]interface{}
+}
+
+func (r *recorder) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	r.args = append(r.args, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func (r *recorder) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	panic("not used by Record")
+}
+
+func (r *recorder) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	panic("not used by Record")
+}
+
+func TestRecord(t *testing.T) {
+	q := &recorder{}
+
+	var recordErr error
+	h := chimiddleware.RequestID(Request(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.AccountIdCtxKey, "account")
+		ctx = context.WithValue(ctx, middleware.APIKeyIdCtxKey, "key")
+		recordErr = Record(ctx, q, Entry{
+			Action:       ActionUpdate,
+			ResourceType: Court,
+			ResourceId:   "court",
+			Before:       map[string]string{"name": "Center"},
+			After:        map[string]string{"name": "North"},
+		})
+	})))
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/courts/court", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("User-Agent", "gdsi-test")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if recordErr != nil {
+		t.Fatalf("unexpected error: %v", recordErr)
+	}
+	if len(q.args) != 1 {
+		t.Fatalf("expected one statement, got %d", len(q.args))
+	}
+
+	args := q.args[0]
+	expected := []interface{}{"account", "key", ActionUpdate, Court, "court", `{"name":"Center"}`, `{"name":"North"}`, "10.0.0.1", "gdsi-test"}
+	for i, want := range expected {
+		got := args[i]
+		if b, ok := got.([]byte); ok {
+			got = string(b)
+		}
+		if got != want {
+			t.Errorf("arg %d = %v; want %v", i+1, got, want)
+		}
+	}
+	if id, _ := args[9].(string); id == "" {
+		t.Errorf("expected the request id to be recorded")
+	}
+	if args[10] != http.MethodPut || args[11] != "/v1/courts/court" {
+		t.Errorf("expected the request method and path, got %v %v", args[10], args[11])
+	}
+}
+
+func TestRecordWithoutState(t *testing.T) {
+	q := &recorder{}
+
+	// outside of a request, e.g. the seed, the entry has no actor and no metadata
+	err := Record(context.Background(), q, Entry{Action: ActionCreate, ResourceType: Season, ResourceId: "season", After: struct{}{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := q.args[0]
+	if args[0] != "" || args[1] != "" {
+		t.Errorf("expected no actor, got %v %v", args[0], args[1])
+	}
+	// the missing before state is stored as null
+	if before, _ := args[5].([]byte); before != nil {
+		t.Errorf("expected a null before state, got %s", before)
+	}
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	b, err := os.ReadFile("../db/migrations/20261019170000_create_audit_log_table.sql")
+	if err != nil {
+		t.Fatalf("reading the migration: %v", err)
+	}
+	up, _, _ := strings.Cut(string(b), "-- migrate:down")
+
+	for _, trigger := range []string{"before update or delete on audit_log", "before truncate on audit_log"} {
+		if !strings.Contains(up, trigger) {
+			t.Errorf("expected the migration to reject changes with a %q trigger", trigger)
+		}
+	}
+}
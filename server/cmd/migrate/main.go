@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/db"
+)
+
+func main() {
+	cfgOpts := config.BindFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Apply or roll back the migrations embedded in the binary\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  migrate up            apply every pending migration\n")
+		fmt.Fprintf(os.Stderr, "  migrate down          roll back the last applied migration\n")
+		fmt.Fprintf(os.Stderr, "  migrate status        list the migrations and if they're applied\n")
+		fmt.Fprintf(os.Stderr, "  migrate to <version>  apply or roll back the migrations to reach the version\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+
+	// load config
+	cfg, err := config.LoadOptions(*cfgOpts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// connect db
+	conn, err := db.Connect(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx, conn)
+		if err != nil {
+			log.Fatalf("applying migrations: %v", err)
+		}
+		fmt.Printf("%d migrations applied\n", len(applied))
+	case "down":
+		version, err := db.MigrateDown(ctx, conn)
+		if err != nil {
+			log.Fatalf("rolling back migration: %v", err)
+		}
+		if version == "" {
+			fmt.Println("no migration to roll back")
+			return
+		}
+		fmt.Printf("migration %s rolled back\n", version)
+	case "status":
+		statuses, err := db.MigrationStatuses(ctx, conn)
+		if err != nil {
+			log.Fatalf("reading migration status: %v", err)
+		}
+		pending := 0
+		for _, s := range statuses {
+			mark := "[X]"
+			if !s.Applied {
+				mark = "[ ]"
+				pending++
+			}
+			fmt.Printf("%s %s_%s\n", mark, s.Version, s.Name)
+		}
+		fmt.Printf("\napplied: %d, pending: %d\n", len(statuses)-pending, pending)
+	case "to":
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		changed, err := db.MigrateTo(ctx, conn, args[1])
+		if err != nil {
+			log.Fatalf("migrating to %s: %v", args[1], err)
+		}
+		fmt.Printf("at version %s, %d migrations applied or rolled back\n", args[1], len(changed))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
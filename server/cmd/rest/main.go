@@ -2,30 +2,33 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
-	"net/http"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"github.com/markovidakovic/gdsi/server/config"
 	_ "github.com/markovidakovic/gdsi/server/docs"
 	"github.com/markovidakovic/gdsi/server/rest"
 )
 
 func main() {
-	srv, err := rest.NewServer()
+	cfgOpts := config.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	srv, err := rest.NewServer(*cfgOpts)
 	if err != nil {
 		log.Fatalf("api server failed to start -> %v", err)
 	}
 	srv.MountRouters()
 
+	// run server in a separate goroutine, it returns once shut down
+	failed := make(chan error, 1)
 	go func() {
-		// run server in a separate goroutine
-		log.Printf("api server started on port %s\n", srv.Cfg.ApiPort)
-		err = http.ListenAndServe(":"+srv.Cfg.ApiPort, srv.Rtr)
-		if err != nil {
-			log.Fatalf("api server failed to start -> %v", err)
+		if err := srv.Run(); err != nil {
+			failed <- err
 		}
 	}()
 
@@ -33,15 +36,19 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
-	// block for signal
-	<-stop
-	log.Println("termination signal received, server shutting down...")
+	// block for signal or a failed listener
+	select {
+	case <-stop:
+		slog.Info("termination signal received, server shutting down")
+	case err := <-failed:
+		slog.Error("api server failed, shutting down", "error", err)
+	}
 
 	// graceful shutdown cancellation context
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), srv.Cfg.HttpShutdownTimeout)
 	defer cancel()
 
 	if err = srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("error during api server shutdown -> %v", err)
+		slog.Error("error during api server shutdown", "error", err)
 	}
 }
@@ -31,6 +31,7 @@ func main() {
 	var accountsFile, courtsFile string
 	flag.StringVar(&accountsFile, "accounts-file", "./db/seed/accounts.json", "Path to seed file")
 	flag.StringVar(&courtsFile, "courts-file", "./db/seed/courts.json", "Path to seed file")
+	cfgOpts := config.BindFlags(flag.CommandLine)
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Seed accounts and courts into the database\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n")
@@ -41,7 +42,7 @@ func main() {
 	ctx := context.Background()
 
 	// load config
-	cfg, err := config.Load()
+	cfg, err := config.LoadOptions(*cfgOpts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -66,7 +67,7 @@ func main() {
 			log.Fatal(err)
 		}
 
-		err = seedAccounts(ctx, db, accounts)
+		err = seedAccounts(ctx, db, cfg.Passwords, accounts)
 		if err != nil {
 			log.Fatalf("seeding accounts: %v", err)
 		}
@@ -97,7 +98,7 @@ func main() {
 	}
 }
 
-func seedAccounts(ctx context.Context, db *db.Conn, data []Account) error {
+func seedAccounts(ctx context.Context, db *db.Conn, passwords *sec.PasswordHasher, data []Account) error {
 	tx, err := db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin tx: %w", err)
@@ -105,8 +106,8 @@ func seedAccounts(ctx context.Context, db *db.Conn, data []Account) error {
 	defer tx.Rollback(ctx)
 
 	accountSql := `
-		insert into account (name, email, dob, gender, phone_number, password, role)
-		values ($1, $2, $3, $4, $5, $6, $7)
+		insert into account (name, email, dob, gender, phone_number, password, role, approved_at)
+		values ($1, $2, $3, $4, $5, $6, $7, current_timestamp)
 		returning id
 	`
 
@@ -116,7 +117,7 @@ func seedAccounts(ctx context.Context, db *db.Conn, data []Account) error {
 	`
 
 	for _, v := range data {
-		enc, err := sec.EncryptPwd(v.Password)
+		enc, err := passwords.Hash(v.Password)
 		if err != nil {
 			return err
 		}
@@ -1,68 +1,253 @@
 package config
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
-	"github.com/go-chi/jwtauth/v5"
+	"github.com/markovidakovic/gdsi/server/sec"
 )
 
 type Config struct {
-	ApiPort              string
-	DbDriver             string
-	DbHost               string
-	DbName               string
-	DbPort               string
-	DbUser               string
-	DbPassword           string
-	DbSslMode            string
-	JwtSecret            string
-	JwtExpiration        string
-	JwtAccessExpiration  string
-	JwtRefreshExpiration string
-	JwtAuth              *jwtauth.JWTAuth
+	ApiPort                string
+	LogFormat              string
+	LogLevel               string
+	MetricsEnabled         bool
+	MetricsPort            string
+	TracingExporter        string
+	TracingOtlpEndpoint    string
+	TracingSampleRatio     float64
+	TracingServiceName     string
+	SwaggerEnabled         bool
+	CorsAllowedOrigins     []string
+	CorsAllowedMethods     []string
+	CorsAllowedHeaders     []string
+	CorsAllowCredentials   bool
+	CorsMaxAge             int
+	HttpReadTimeout        time.Duration
+	HttpReadHeaderTimeout  time.Duration
+	HttpWriteTimeout       time.Duration
+	HttpIdleTimeout        time.Duration
+	HttpMaxHeaderBytes     int
+	HttpShutdownTimeout    time.Duration
+	HttpDrainDelay         time.Duration
+	TlsCertFile            string
+	TlsKeyFile             string
+	DbDriver               string
+	DbHost                 string
+	DbName                 string
+	DbPort                 string
+	DbUser                 string
+	DbPassword             string
+	DbSslMode              string
+	DbMaxConns             int32
+	DbMinConns             int32
+	DbMaxConnLifetime      time.Duration
+	DbMaxConnIdleTime      time.Duration
+	DbHealthCheckPeriod    time.Duration
+	DbMigrateOnStart       bool
+	JwtSecret              string
+	JwtAccessExpiration    time.Duration
+	JwtRefreshExpiration   time.Duration
+	JwtKeyFiles            string
+	JwtSigningKid          string
+	JwtKeys                *sec.KeyRing
+	SmtpHost               string
+	SmtpPort               string
+	SmtpUser               string
+	SmtpPassword           string
+	MailFrom               string
+	MailLogBody            bool
+	TwoFactorRequiredRoles string
+	OidcIssuerUrl          string
+	OidcClientId           string
+	OidcClientSecret       string
+	OidcRedirectUrl        string
+	OidcScopes             string
+	AccountCacheTtl        time.Duration
+	RoleCacheTtl           time.Duration
+	IdempotencyKeyTtl      time.Duration
+	IdempotencyReserveTtl  time.Duration
+	RegistrationMode       string
+	Passwords              *sec.PasswordHasher
+	PasswordPolicy         *sec.PasswordPolicy
 }
 
+// registration modes
+const (
+	RegistrationOpen             = "open"
+	RegistrationInviteOnly       = "invite-only"
+	RegistrationApprovalRequired = "approval-required"
+)
+
 const defaultEnvFile = ".env"
 
-var requiredEnvVars = []string{"DB_DRIVER", "DB_HOST", "DB_NAME", "DB_PORT", "DB_USER", "DB_PASSWORD", "JWT_SECRET", "JWT_EXPIRATION", "JWT_ACCESS_EXPIRATION", "JWT_REFRESH_EXPIRATION"}
+// keys without a default, the api can't start without them
+var requiredKeys = []string{"DB_DRIVER", "DB_HOST", "DB_NAME", "DB_PORT", "DB_USER", "DB_PASSWORD"}
 
+// Load reads the config from the env files and the environment, see LoadOptions for the other layers
 func Load(envFiles ...string) (*Config, error) {
-	if len(envFiles) == 0 {
-		envFiles = append(envFiles, defaultEnvFile)
-	}
+	return LoadOptions(Options{EnvFiles: envFiles})
+}
 
-	err := loadEnv(envFiles)
+// LoadOptions reads the config through the layers of the options and validates it. Every
+// invalid or missing value is reported in the returned error, not only the first one
+func LoadOptions(opts Options) (*Config, error) {
+	src, err := newSource(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, rev := range requiredEnvVars {
-		if os.Getenv(rev) == "" {
-			return nil, fmt.Errorf("missing environment variable: %s", rev)
-		}
+	for _, key := range requiredKeys {
+		src.required(key)
+	}
+	// the shared secret only signs the tokens when no key ring is configured
+	if src.lookup("JWT_KEYS") == "" {
+		src.required("JWT_SECRET")
 	}
 
-	var cfg *Config = &Config{
-		ApiPort:              getEnvVar("API_PORT", "8080"),
-		DbDriver:             getEnvVar("DB_DRIVER", ""),
-		DbHost:               getEnvVar("DB_HOST", ""),
-		DbName:               getEnvVar("DB_NAME", ""),
-		DbPort:               getEnvVar("DB_PORT", ""),
-		DbUser:               getEnvVar("DB_USER", ""),
-		DbPassword:           getEnvVar("DB_PASSWORD", ""),
-		DbSslMode:            getEnvVar("DB_SSL_MODE", "disabled"),
-		JwtSecret:            getEnvVar("JWT_SECRET", ""),
-		JwtExpiration:        getEnvVar("JWT_EXPIRATION", ""),
-		JwtAccessExpiration:  getEnvVar("JWT_ACCESS_EXPIRATION", ""),
-		JwtRefreshExpiration: getEnvVar("JWT_REFRESH_EXPIRATION", ""),
+	cfg := &Config{
+		ApiPort:                src.string("API_PORT", "8080"),
+		LogFormat:              src.string("LOG_FORMAT", "json"),
+		LogLevel:               src.string("LOG_LEVEL", "info"),
+		MetricsEnabled:         src.bool("METRICS_ENABLED", false),
+		MetricsPort:            src.string("METRICS_PORT", ""),
+		TracingExporter:        src.oneOf("TRACING_EXPORTER", "none", "none", "stdout", "otlp"),
+		TracingOtlpEndpoint:    src.string("TRACING_OTLP_ENDPOINT", ""),
+		TracingSampleRatio:     src.float("TRACING_SAMPLE_RATIO", 1, 0, 1),
+		TracingServiceName:     src.string("TRACING_SERVICE_NAME", "gdsi-api"),
+		SwaggerEnabled:         src.bool("SWAGGER_ENABLED", true),
+		CorsAllowedOrigins:     src.list("CORS_ALLOWED_ORIGINS", ""),
+		CorsAllowedMethods:     src.list("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS"),
+		CorsAllowedHeaders:     src.list("CORS_ALLOWED_HEADERS", ""),
+		CorsAllowCredentials:   src.bool("CORS_ALLOW_CREDENTIALS", false),
+		CorsMaxAge:             int(src.int("CORS_MAX_AGE", 300, 0, 86400)),
+		HttpReadTimeout:        src.duration("HTTP_READ_TIMEOUT", 15*time.Second),
+		HttpReadHeaderTimeout:  src.duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		HttpWriteTimeout:       src.duration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		HttpIdleTimeout:        src.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+		HttpMaxHeaderBytes:     int(src.int("HTTP_MAX_HEADER_BYTES", 65536, 1, 1<<30)),
+		HttpShutdownTimeout:    src.duration("HTTP_SHUTDOWN_TIMEOUT", 20*time.Second),
+		HttpDrainDelay:         src.duration("HTTP_DRAIN_DELAY", 5*time.Second),
+		TlsCertFile:            src.string("TLS_CERT_FILE", ""),
+		TlsKeyFile:             src.string("TLS_KEY_FILE", ""),
+		DbDriver:               src.string("DB_DRIVER", ""),
+		DbHost:                 src.string("DB_HOST", ""),
+		DbName:                 src.string("DB_NAME", ""),
+		DbPort:                 src.string("DB_PORT", ""),
+		DbUser:                 src.string("DB_USER", ""),
+		DbPassword:             src.string("DB_PASSWORD", ""),
+		DbSslMode:              src.string("DB_SSL_MODE", "disabled"),
+		DbMaxConns:             int32(src.int("DB_MAX_CONNS", 10, 1, 1<<31-1)),
+		DbMinConns:             int32(src.int("DB_MIN_CONNS", 0, 0, 1<<31-1)),
+		DbMaxConnLifetime:      src.duration("DB_MAX_CONN_LIFETIME", time.Hour),
+		DbMaxConnIdleTime:      src.duration("DB_MAX_CONN_IDLE_TIME", 30*time.Minute),
+		DbHealthCheckPeriod:    src.duration("DB_HEALTH_CHECK_PERIOD", time.Minute),
+		DbMigrateOnStart:       src.bool("DB_MIGRATE_ON_START", false),
+		JwtSecret:              src.string("JWT_SECRET", ""),
+		JwtAccessExpiration:    src.duration("JWT_ACCESS_EXPIRATION", 30*time.Minute),
+		JwtRefreshExpiration:   src.duration("JWT_REFRESH_EXPIRATION", 720*time.Hour),
+		JwtKeyFiles:            src.string("JWT_KEYS", ""),
+		JwtSigningKid:          src.string("JWT_SIGNING_KID", ""),
+		SmtpHost:               src.string("SMTP_HOST", ""),
+		SmtpPort:               src.string("SMTP_PORT", "587"),
+		SmtpUser:               src.string("SMTP_USER", ""),
+		SmtpPassword:           src.string("SMTP_PASSWORD", ""),
+		MailFrom:               src.string("MAIL_FROM", "no-reply@gdsi.app"),
+		MailLogBody:            src.bool("MAIL_LOG_BODY", false),
+		TwoFactorRequiredRoles: src.string("TWO_FACTOR_REQUIRED_ROLES", "admin,developer"),
+		OidcIssuerUrl:          src.string("OIDC_ISSUER_URL", ""),
+		OidcClientId:           src.string("OIDC_CLIENT_ID", ""),
+		OidcClientSecret:       src.string("OIDC_CLIENT_SECRET", ""),
+		OidcRedirectUrl:        src.string("OIDC_REDIRECT_URL", ""),
+		OidcScopes:             src.string("OIDC_SCOPES", "openid email profile"),
+		AccountCacheTtl:        src.duration("ACCOUNT_CACHE_TTL", 30*time.Second),
+		RoleCacheTtl:           src.duration("ROLE_CACHE_TTL", time.Minute),
+		IdempotencyKeyTtl:      src.duration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		IdempotencyReserveTtl:  src.duration("IDEMPOTENCY_RESERVE_TTL", time.Minute),
+		RegistrationMode:       src.oneOf("REGISTRATION_MODE", RegistrationOpen, RegistrationOpen, RegistrationInviteOnly, RegistrationApprovalRequired),
 	}
 
-	// add jwt auth
-	cfg.JwtAuth = jwtauth.New("HS256", []byte(cfg.JwtSecret), nil)
+	if cfg.OidcIssuerUrl != "" && (cfg.OidcClientId == "" || cfg.OidcRedirectUrl == "") {
+		src.fail("missing config value: OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER_URL is set")
+	}
+	if (cfg.TlsCertFile == "") != (cfg.TlsKeyFile == "") {
+		src.fail("missing config value: TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	// the drain delay is part of the shutdown, the in-flight requests need time left after it
+	if cfg.HttpShutdownTimeout > 0 && cfg.HttpDrainDelay >= cfg.HttpShutdownTimeout {
+		src.fail("invalid config value: HTTP_DRAIN_DELAY must be shorter than HTTP_SHUTDOWN_TIMEOUT")
+	}
+	// a retry could run the request again while the first attempt is still writing its response
+	if cfg.IdempotencyKeyTtl > 0 && (cfg.IdempotencyReserveTtl == 0 || cfg.IdempotencyReserveTtl < cfg.HttpWriteTimeout) {
+		src.fail("invalid config value: IDEMPOTENCY_RESERVE_TTL must be positive and not shorter than HTTP_WRITE_TIMEOUT")
+	}
+	if cfg.DbMinConns > cfg.DbMaxConns {
+		src.fail("invalid config value: DB_MIN_CONNS must not exceed DB_MAX_CONNS")
+	}
+	if cfg.JwtAccessExpiration == 0 || cfg.JwtRefreshExpiration == 0 {
+		src.fail("invalid config value: JWT_ACCESS_EXPIRATION and JWT_REFRESH_EXPIRATION must be positive")
+	}
+	// the metrics have no auth, they're only served on a port kept off the public network
+	if cfg.MetricsEnabled && (cfg.MetricsPort == "" || cfg.MetricsPort == cfg.ApiPort) {
+		src.fail("invalid config value: METRICS_ENABLED requires a METRICS_PORT other than API_PORT")
+	}
+	// a wildcard pattern would send the credentials to the sites of anybody
+	if cfg.CorsAllowCredentials && slices.ContainsFunc(cfg.CorsAllowedOrigins, func(o string) bool { return strings.Contains(o, "*") }) {
+		src.fail("invalid config value: CORS_ALLOW_CREDENTIALS can't be used with a wildcard origin")
+	}
+
+	// new password hashes use the configured argon2id parameters, older hashes are rehashed on login
+	pwdParams := sec.DefaultPasswordParams
+	pwdParams.Memory = uint32(src.int("PASSWORD_ARGON2_MEMORY", int64(pwdParams.Memory), 1, 1<<32-1))
+	pwdParams.Iterations = uint32(src.int("PASSWORD_ARGON2_ITERATIONS", int64(pwdParams.Iterations), 1, 1<<32-1))
+	pwdParams.Parallelism = uint8(src.int("PASSWORD_ARGON2_PARALLELISM", int64(pwdParams.Parallelism), 1, 255))
+	minLength := int(src.int("PASSWORD_MIN_LENGTH", 10, 1, 1024))
+	blocklistFile := src.string("PASSWORD_BLOCKLIST_FILE", "")
 
-	log.Println("config loaded")
+	// the values are all read, the ones built from them need them valid
+	if err := src.err(); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	cfg.Passwords, err = sec.NewPasswordHasher(pwdParams)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	cfg.PasswordPolicy, err = sec.NewPasswordPolicy(minLength, blocklistFile)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// load the jwt signing keys
+	specs, err := sec.ParseKeySpecs(cfg.JwtKeyFiles)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		cfg.JwtKeys, err = sec.NewKeyRing(specs, cfg.JwtSigningKid, []byte(cfg.JwtSecret))
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	slog.Info("config loaded")
 
 	return cfg, nil
 }
+
+// TwoFactorRoles returns the roles for which two-factor authentication is mandatory
+func (c *Config) TwoFactorRoles() []string {
+	roles := []string{}
+	for _, r := range strings.Split(c.TwoFactorRequiredRoles, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
@@ -1,8 +1,17 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetEnvVar(t *testing.T) {
@@ -404,19 +413,24 @@ func TestLoadEnv(t *testing.T) {
 	})
 }
 
-func TestLoad(t *testing.T) {
-	// cleanup environment variables after each test
-	resetEnv := func() {
-		for _, v := range []string{"API_PORT", "DB_HOST", "DB_NAME", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_SSL_MODE"} {
-			os.Unsetenv(v)
-		}
+// the values every config needs, the tests build on them
+const requiredEnv = "DB_DRIVER=postgres\nDB_HOST=localhost\nDB_NAME=testdb\nDB_PORT=5432\nDB_USER=testuser\nDB_PASSWORD=testpass\nJWT_SECRET=testsecret\n"
+
+// unsetEnv clears the keys for the test, loadEnv sets the process environment
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, k := range append(append(keys, requiredKeys...), "JWT_SECRET", "JWT_KEYS", "JWT_SIGNING_KID") {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
 	}
+}
 
+func TestLoad(t *testing.T) {
 	t.Run("ValidConfigFromEnvFile", func(t *testing.T) {
-		defer resetEnv()
+		unsetEnv(t, "API_PORT", "DB_SSL_MODE", "JWT_ACCESS_EXPIRATION")
 
 		// create a temp file
-		tmpFile, err := createTempFile("API_PORT=9090\nDB_HOST=localhost\nDB_NAME=testdb\nDB_PORT=5432\nDB_USER=testuser\nDB_PASSWORD=testpass\nDB_SSL_MODE=enable")
+		tmpFile, err := createTempFile(requiredEnv + "API_PORT=9090\nDB_SSL_MODE=enable\nJWT_ACCESS_EXPIRATION=15m")
 		if err != nil {
 			t.Fatalf("error creating temp file: %v", err)
 		}
@@ -428,23 +442,17 @@ func TestLoad(t *testing.T) {
 		}
 
 		// validate the config
-		expected := &Config{
-			ApiPort:    "9090",
-			DbHost:     "localhost",
-			DbName:     "testdb",
-			DbPort:     "5432",
-			DbUser:     "testuser",
-			DbPassword: "testpass",
-			DbSslMode:  "enable",
+		if cfg.ApiPort != "9090" || cfg.DbHost != "localhost" || cfg.DbName != "testdb" || cfg.DbPort != "5432" ||
+			cfg.DbUser != "testuser" || cfg.DbPassword != "testpass" || cfg.DbSslMode != "enable" {
+			t.Errorf("unexpected config: %+v", cfg)
 		}
-
-		if *cfg != *expected {
-			t.Errorf("expected %+v, got %+v", expected, cfg)
+		if cfg.JwtAccessExpiration != 15*time.Minute || cfg.JwtRefreshExpiration != 720*time.Hour {
+			t.Errorf("unexpected jwt expirations: %v %v", cfg.JwtAccessExpiration, cfg.JwtRefreshExpiration)
 		}
 	})
 
 	t.Run("MissingRequiredEnvVars", func(t *testing.T) {
-		defer resetEnv()
+		unsetEnv(t, "API_PORT")
 
 		tempFile, err := createTempFile("API_PORT=8080\nDB_HOST=localhost")
 		if err != nil {
@@ -452,9 +460,6 @@ func TestLoad(t *testing.T) {
 		}
 		defer os.Remove(tempFile)
 
-		// adjust required env variables
-		requiredEnvVars = []string{"DB_NAME", "DB_USER"}
-
 		cfg, err := Load(tempFile)
 		if err == nil {
 			t.Fatalf("expected error due to missing variables, got nil")
@@ -462,42 +467,234 @@ func TestLoad(t *testing.T) {
 		if cfg != nil {
 			t.Errorf("expected nil config, got %+v", cfg)
 		}
+		// every missing value is reported at once
+		for _, key := range []string{"DB_NAME", "DB_USER", "JWT_SECRET"} {
+			if !strings.Contains(err.Error(), key) {
+				t.Errorf("expected %s in the error, got %v", key, err)
+			}
+		}
+		if strings.Contains(err.Error(), "DB_HOST") {
+			t.Errorf("expected DB_HOST not in the error, got %v", err)
+		}
+	})
+
+	t.Run("KeyRingWithoutSecret", func(t *testing.T) {
+		unsetEnv(t)
+
+		_, raw, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("error generating key: %v", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(raw)
+		if err != nil {
+			t.Fatalf("error marshaling key: %v", err)
+		}
+		keyFile := filepath.Join(t.TempDir(), "ed.pem")
+		err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+		if err != nil {
+			t.Fatalf("error writing key: %v", err)
+		}
+
+		// the shared secret is only required without the key ring
+		env := strings.Replace(requiredEnv, "JWT_SECRET=testsecret\n", "", 1)
+		tempFile, err := createTempFile(env + "JWT_KEYS=ed=" + keyFile + "\nJWT_SIGNING_KID=ed\n")
+		if err != nil {
+			t.Fatalf("error creating temp file: %v", err)
+		}
+		defer os.Remove(tempFile)
+
+		cfg, err := Load(tempFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.JwtKeys.SigningKid() != "ed" {
+			t.Errorf("SigningKid() = %s; want ed", cfg.JwtKeys.SigningKid())
+		}
+	})
+
+	t.Run("InvalidValuesReportedTogether", func(t *testing.T) {
+		unsetEnv(t, "DB_MAX_CONNS", "HTTP_READ_TIMEOUT", "METRICS_ENABLED")
+
+		tempFile, err := createTempFile(requiredEnv + "DB_MAX_CONNS=none\nHTTP_READ_TIMEOUT=soon\nMETRICS_ENABLED=maybe")
+		if err != nil {
+			t.Fatalf("error creating temp file: %v", err)
+		}
+		defer os.Remove(tempFile)
+
+		_, err = Load(tempFile)
+		if err == nil {
+			t.Fatalf("expected an error for the invalid values")
+		}
+		for _, key := range []string{"DB_MAX_CONNS", "HTTP_READ_TIMEOUT", "METRICS_ENABLED"} {
+			if !strings.Contains(err.Error(), key) {
+				t.Errorf("expected %s in the error, got %v", key, err)
+			}
+		}
 	})
 
 	t.Run("DefaultEnvFileFallback", func(t *testing.T) {
-		defer resetEnv()
+		unsetEnv(t, "API_PORT", "DB_SSL_MODE")
 
 		// create the default .env file
 		defaultEnvFile := ".env"
-		err := os.WriteFile(defaultEnvFile, []byte("API_PORT=8000\nDB_HOST=defaultdbhost\n"), 0644)
+		err := os.WriteFile(defaultEnvFile, []byte(requiredEnv+"API_PORT=8000\nDB_HOST=defaultdbhost\n"), 0644)
 		if err != nil {
 			t.Fatalf("error creating default .env file: %v", err)
 		}
 		defer os.Remove(defaultEnvFile)
 
-		requiredEnvVars = []string{"API_PORT", "DB_HOST"}
-
 		cfg, err := Load()
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
 		// validate the config
-		expected := &Config{
-			ApiPort:    "8000",
-			DbHost:     "defaultdbhost",
-			DbName:     "",
-			DbPort:     "",
-			DbUser:     "",
-			DbPassword: "",
-			DbSslMode:  "disabled",
-		}
-		if *cfg != *expected {
-			t.Errorf("expected %+v, got %+v", expected, cfg)
+		if cfg.ApiPort != "8000" || cfg.DbHost != "defaultdbhost" || cfg.DbSslMode != "disabled" {
+			t.Errorf("unexpected config: %+v", cfg)
 		}
 	})
 }
 
+func TestLoadValidation(t *testing.T) {
+	testCases := []struct {
+		name      string
+		overrides map[string]string
+		invalid   string
+	}{
+		{name: "MetricsOwnPort", overrides: map[string]string{"METRICS_ENABLED": "true", "METRICS_PORT": "9100"}},
+		{name: "MetricsWithoutPort", overrides: map[string]string{"METRICS_ENABLED": "true"}, invalid: "METRICS_PORT"},
+		{name: "MetricsOnApiPort", overrides: map[string]string{"METRICS_ENABLED": "true", "METRICS_PORT": "8080", "API_PORT": "8080"}, invalid: "METRICS_PORT"},
+		{name: "MetricsDisabled", overrides: map[string]string{"METRICS_ENABLED": "false"}},
+		{name: "CredentialsExactOrigins", overrides: map[string]string{"CORS_ALLOW_CREDENTIALS": "true", "CORS_ALLOWED_ORIGINS": "https://gdsi.app"}},
+		{name: "CredentialsAnyOrigin", overrides: map[string]string{"CORS_ALLOW_CREDENTIALS": "true", "CORS_ALLOWED_ORIGINS": "*"}, invalid: "CORS_ALLOW_CREDENTIALS"},
+		{name: "CredentialsWildcardOrigin", overrides: map[string]string{"CORS_ALLOW_CREDENTIALS": "true", "CORS_ALLOWED_ORIGINS": "https://gdsi.app,https://*.gdsi.app"}, invalid: "CORS_ALLOW_CREDENTIALS"},
+		{name: "ReserveShorterThanWrite", overrides: map[string]string{"IDEMPOTENCY_RESERVE_TTL": "10s", "HTTP_WRITE_TIMEOUT": "30s"}, invalid: "IDEMPOTENCY_RESERVE_TTL"},
+		{name: "ReserveWithoutKeys", overrides: map[string]string{"IDEMPOTENCY_RESERVE_TTL": "0", "IDEMPOTENCY_KEY_TTL": "0"}},
+		{name: "DrainDelayPastTimeout", overrides: map[string]string{"HTTP_DRAIN_DELAY": "30s", "HTTP_SHUTDOWN_TIMEOUT": "20s"}, invalid: "HTTP_DRAIN_DELAY"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			unsetEnv(t, "API_PORT", "METRICS_ENABLED", "METRICS_PORT", "HTTP_DRAIN_DELAY", "HTTP_SHUTDOWN_TIMEOUT", "CORS_ALLOW_CREDENTIALS", "CORS_ALLOWED_ORIGINS", "IDEMPOTENCY_RESERVE_TTL", "IDEMPOTENCY_KEY_TTL", "HTTP_WRITE_TIMEOUT")
+
+			envFile, err := createTempFile(requiredEnv)
+			if err != nil {
+				t.Fatalf("error creating temp file: %v", err)
+			}
+			defer os.Remove(envFile)
+
+			_, err = LoadOptions(Options{EnvFiles: []string{envFile}, Overrides: tc.overrides})
+			if tc.invalid == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.invalid) {
+				t.Errorf("LoadOptions() = %v; want an error for %s", err, tc.invalid)
+			}
+		})
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	unsetEnv(t, "API_PORT", "DB_MAX_CONNS", "LOG_LEVEL", "CORS_ALLOWED_ORIGINS", "JWT_SECRET_FILE")
+
+	secretFile, err := createTempFile("filesecret\n")
+	if err != nil {
+		t.Fatalf("error creating temp file: %v", err)
+	}
+	defer os.Remove(secretFile)
+
+	envFile, err := createTempFile("DB_DRIVER=postgres\nDB_HOST=localhost\nDB_NAME=testdb\nDB_PORT=5432\nDB_USER=testuser\nDB_PASSWORD=testpass\nDB_MAX_CONNS=20\nJWT_SECRET_FILE=" + secretFile)
+	if err != nil {
+		t.Fatalf("error creating temp file: %v", err)
+	}
+	defer os.Remove(envFile)
+
+	yamlFile := filepath.Join(t.TempDir(), "config.yaml")
+	yamlDoc := "api_port: 7070\nlog_level: debug\ndb:\n  max_conns: 5\ncors:\n  allowed_origins: [https://gdsi.app, https://admin.gdsi.app]\n"
+	if err := os.WriteFile(yamlFile, []byte(yamlDoc), 0644); err != nil {
+		t.Fatalf("error creating the config file: %v", err)
+	}
+
+	cfg, err := LoadOptions(Options{
+		File:      yamlFile,
+		EnvFiles:  []string{envFile},
+		Overrides: map[string]string{"LOG_LEVEL": "warn"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the file overrides the defaults
+	if cfg.ApiPort != "7070" {
+		t.Errorf("expected the api port of the file, got %s", cfg.ApiPort)
+	}
+	if len(cfg.CorsAllowedOrigins) != 2 || cfg.CorsAllowedOrigins[1] != "https://admin.gdsi.app" {
+		t.Errorf("expected the origins of the file, got %v", cfg.CorsAllowedOrigins)
+	}
+	// the environment overrides the file
+	if cfg.DbMaxConns != 20 {
+		t.Errorf("expected the max conns of the environment, got %d", cfg.DbMaxConns)
+	}
+	// the flags override the file
+	if cfg.LogLevel != "warn" {
+		t.Errorf("expected the log level of the flags, got %s", cfg.LogLevel)
+	}
+	// the secret is read from its file
+	if cfg.JwtSecret != "filesecret" {
+		t.Errorf("expected the secret of the file, got %q", cfg.JwtSecret)
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tomlFile := filepath.Join(dir, "config.toml")
+	tomlDoc := "api_port = \"7070\"\n\n[http]\nread_timeout = \"10s\"\n\n[tracing]\nsample_ratio = 0.5\n"
+	if err := os.WriteFile(tomlFile, []byte(tomlDoc), 0644); err != nil {
+		t.Fatalf("error creating the config file: %v", err)
+	}
+
+	values, err := readConfigFile(tomlFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"API_PORT": "7070", "HTTP_READ_TIMEOUT": "10s", "TRACING_SAMPLE_RATIO": "0.5"}
+	for k, v := range expected {
+		if values[k] != v {
+			t.Errorf("expected %s=%s, got %q", k, v, values[k])
+		}
+	}
+
+	jsonFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(jsonFile, []byte("{}"), 0644); err != nil {
+		t.Fatalf("error creating the config file: %v", err)
+	}
+	if _, err := readConfigFile(jsonFile); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	opts := BindFlags(fs)
+
+	err := fs.Parse([]string{"-config", "config.yaml", "-set", "log-level=debug", "-set", "API_PORT=9000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.File != "config.yaml" || opts.Overrides["LOG_LEVEL"] != "debug" || opts.Overrides["API_PORT"] != "9000" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	if err := fs.Parse([]string{"-set", "novalue"}); err == nil {
+		t.Error("expected an error for an override without a value")
+	}
+}
+
 func createTempFile(content string) (string, error) {
 	file, err := os.CreateTemp("", "env_file")
 	if err != nil {
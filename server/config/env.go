@@ -3,7 +3,7 @@ package config
 import (
 	"bufio"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"regexp"
 	"strings"
@@ -109,7 +109,7 @@ func setEnvVars(envVars map[string]string) error {
 	for k, v := range envVars {
 		// check if var already exist
 		if existing := os.Getenv(k); existing != "" {
-			log.Printf("WARNING: overwriting existing environment variable %q: %s -> %s", k, existing, v)
+			slog.Warn("overwriting existing environment variable", "key", k, "from", existing, "to", v)
 		}
 
 		// set the env var
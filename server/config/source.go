@@ -0,0 +1,277 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Options select the layers the config is read from. Every layer overrides the one before it:
+// the defaults, the config file, the env files and the environment, then the overrides
+type Options struct {
+	// yaml or toml file, CONFIG_FILE is used when it's empty
+	File string
+	// env files loaded into the environment, the default .env may be missing
+	EnvFiles []string
+	// values set on the command line, keyed like the environment variables
+	Overrides map[string]string
+}
+
+// BindFlags registers the config flags on the flag set, the options are filled once it's parsed
+func BindFlags(fs *flag.FlagSet) *Options {
+	opts := &Options{Overrides: make(map[string]string)}
+
+	fs.StringVar(&opts.File, "config", "", "Path to a yaml or toml config file")
+	fs.Func("env-file", "Path to an env file, can be repeated (default .env)", func(v string) error {
+		opts.EnvFiles = append(opts.EnvFiles, v)
+		return nil
+	})
+	fs.Func("set", "Override a config value as KEY=VALUE, can be repeated", func(v string) error {
+		key, val, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("expected KEY=VALUE, got %q", v)
+		}
+		opts.Overrides[normalizeKey(key)] = val
+		return nil
+	})
+
+	return opts
+}
+
+// keys whose value can be read from the file at <KEY>_FILE, like the docker and k8s secrets
+var secretKeys = []string{"DB_PASSWORD", "JWT_SECRET", "SMTP_PASSWORD", "OIDC_CLIENT_SECRET"}
+
+// source looks up the config values through the layers, the highest precedence first.
+// An empty value counts as unset, like it always did for the environment
+type source struct {
+	layers []func(key string) string
+	errs   []error
+}
+
+func newSource(opts Options) (*source, error) {
+	envFiles := opts.EnvFiles
+	if len(envFiles) == 0 {
+		// the environment may be set by the orchestrator instead
+		if _, err := os.Stat(defaultEnvFile); err == nil {
+			envFiles = []string{defaultEnvFile}
+		}
+	}
+	if err := loadEnv(envFiles); err != nil {
+		return nil, err
+	}
+
+	file := opts.File
+	if file == "" {
+		file = getEnvVar("CONFIG_FILE", "")
+	}
+	fileValues := map[string]string{}
+	if file != "" {
+		var err error
+		fileValues, err = readConfigFile(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &source{
+		layers: []func(key string) string{
+			func(key string) string { return opts.Overrides[key] },
+			func(key string) string { return getEnvVar(key, "") },
+			func(key string) string { return fileValues[key] },
+		},
+	}, nil
+}
+
+// readConfigFile reads the yaml or toml file into values keyed like the environment variables,
+// the nested keys are joined: db: {max_conns: 10} is DB_MAX_CONNS
+func readConfigFile(filename string) (map[string]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error loading config file %s -> %w", filename, err)
+	}
+
+	doc := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &doc)
+	case ".toml":
+		err = toml.Unmarshal(b, &doc)
+	default:
+		return nil, fmt.Errorf("error loading config file %s -> unsupported format %q, use yaml or toml", filename, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file %s -> %w", filename, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten(values, "", doc); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s -> %w", filename, err)
+	}
+
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			key := normalizeKey(k)
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			if err := flatten(values, key, child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		// lists are comma separated, like the environment variables
+		items := make([]string, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case map[string]any, []any:
+				return fmt.Errorf("%s must be a list of values", prefix)
+			}
+			items[i] = fmt.Sprint(item)
+		}
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+	case time.Time:
+		values[prefix] = v.Format(time.RFC3339)
+	default:
+		values[prefix] = fmt.Sprint(v)
+	}
+	return nil
+}
+
+func normalizeKey(key string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(strings.TrimSpace(key)))
+}
+
+// lookup returns the value of the key from the first layer setting it. The secrets fall back
+// to the content of the file at <KEY>_FILE
+func (s *source) lookup(key string) string {
+	for _, layer := range s.layers {
+		if v := layer(key); v != "" {
+			return v
+		}
+	}
+
+	if slices.Contains(secretKeys, key) {
+		if path := s.lookup(key + "_FILE"); path != "" {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				s.fail("invalid config value: %s_FILE -> %v", key, err)
+				return ""
+			}
+			return strings.TrimSpace(string(b))
+		}
+	}
+
+	return ""
+}
+
+// fail records the problem, the config reports them all at once
+func (s *source) fail(format string, args ...any) {
+	s.errs = append(s.errs, fmt.Errorf(format, args...))
+}
+
+func (s *source) err() error {
+	return errors.Join(s.errs...)
+}
+
+func (s *source) string(key, def string) string {
+	if v := s.lookup(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func (s *source) required(key string) string {
+	v := s.lookup(key)
+	if v == "" {
+		s.fail("missing config value: %s", key)
+	}
+	return v
+}
+
+func (s *source) bool(key string, def bool) bool {
+	v := s.lookup(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		s.fail("invalid config value: %s must be true or false", key)
+	}
+	return b
+}
+
+// int parses the key, it must be between min and max
+func (s *source) int(key string, def, min, max int64) int64 {
+	v := s.lookup(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < min || n > max {
+		s.fail("invalid config value: %s must be a number between %d and %d", key, min, max)
+		return def
+	}
+	return n
+}
+
+func (s *source) float(key string, def, min, max float64) float64 {
+	v := s.lookup(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < min || f > max {
+		s.fail("invalid config value: %s must be between %g and %g", key, min, max)
+		return def
+	}
+	return f
+}
+
+// duration parses the key as a go duration, a negative one is invalid
+func (s *source) duration(key string, def time.Duration) time.Duration {
+	v := s.lookup(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		s.fail("invalid config value: %s must be a duration like 30s or 1h", key)
+		return def
+	}
+	return d
+}
+
+// list splits the comma separated key, the empty items are dropped
+func (s *source) list(key, def string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s.string(key, def), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// oneOf checks the key holds one of the values
+func (s *source) oneOf(key, def string, values ...string) string {
+	v := s.string(key, def)
+	if !slices.Contains(values, v) {
+		s.fail("invalid config value: %s must be one of %s", key, strings.Join(values, ", "))
+	}
+	return v
+}
@@ -3,11 +3,16 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/metrics"
+	"github.com/markovidakovic/gdsi/server/tracing"
 )
 
 type Querier interface {
@@ -16,36 +21,112 @@ type Querier interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
+// Tx is the transaction the stores of a backend run their statements in. The postgres
+// transactions satisfy it, so do the ones of the in-memory backend
+type Tx interface {
+	Querier
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+var _ Tx = (pgx.Tx)(nil)
+
+// Conn is the connection pool shared by every store. Each query acquires a connection for its
+// duration, the transactions hold theirs until they're committed or rolled back
 type Conn struct {
-	*pgx.Conn
+	*pgxpool.Pool
 }
 
+var _ Querier = (*Conn)(nil)
+
 func Connect(c *config.Config) (*Conn, error) {
 	// construct the db connection string
 	connStr := fmt.Sprintf("%s://%s:%s@%s:%s/%s?sslmode=%s", c.DbDriver, c.DbUser, c.DbPassword, c.DbHost, c.DbPort, c.DbName, c.DbSslMode)
 
-	ctx := context.Background()
+	poolCfg, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the database config: %v", err)
+	}
+	poolCfg.MaxConns = c.DbMaxConns
+	poolCfg.MinConns = c.DbMinConns
+	poolCfg.MaxConnLifetime = c.DbMaxConnLifetime
+	poolCfg.MaxConnIdleTime = c.DbMaxConnIdleTime
+	poolCfg.HealthCheckPeriod = c.DbHealthCheckPeriod
+	poolCfg.ConnConfig.Tracer = multitracer.New(metrics.QueryTracer{}, tracing.QueryTracer{})
 
-	// connect to the db using pgx
-	conn, err := pgx.Connect(ctx, connStr)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// create the pool, the connections are opened lazily
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("could not connect to the database: %v", err)
 	}
 
-	// check if the connection is alive
-	err = conn.Ping(ctx)
+	// check if the database is reachable
+	err = pool.Ping(ctx)
 	if err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("could not ping the database: %v", err)
 	}
 
-	log.Println("database connected")
+	slog.Info("database connected", "max_conns", poolCfg.MaxConns)
 
-	return &Conn{Conn: conn}, nil
+	return &Conn{Pool: pool}, nil
 }
 
+// Disconnect closes the pool, it waits for the acquired connections to be released
 func Disconnect(ctx context.Context, conn *Conn) error {
-	if conn != nil {
-		return conn.Close(ctx)
+	if conn == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		conn.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PoolStats is a snapshot of the connection pool
+type PoolStats struct {
+	MaxConns                int32         `json:"max_conns"`
+	TotalConns              int32         `json:"total_conns"`
+	AcquiredConns           int32         `json:"acquired_conns"`
+	IdleConns               int32         `json:"idle_conns"`
+	ConstructingConns       int32         `json:"constructing_conns"`
+	AcquireCount            int64         `json:"acquire_count"`
+	EmptyAcquireCount       int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount    int64         `json:"canceled_acquire_count"`
+	AcquireDuration         time.Duration `json:"acquire_duration_ns" swaggertype:"integer"`
+	NewConnsCount           int64         `json:"new_conns_count"`
+	MaxLifetimeDestroyCount int64         `json:"max_lifetime_destroy_count"`
+	MaxIdleDestroyCount     int64         `json:"max_idle_destroy_count"`
+}
+
+// Stats returns the current pool statistics. EmptyAcquireCount counts the acquires which had to
+// wait for a connection, a growing number means the pool is too small for the load
+func (c *Conn) Stats() PoolStats {
+	s := c.Pool.Stat()
+	return PoolStats{
+		MaxConns:                s.MaxConns(),
+		TotalConns:              s.TotalConns(),
+		AcquiredConns:           s.AcquiredConns(),
+		IdleConns:               s.IdleConns(),
+		ConstructingConns:       s.ConstructingConns(),
+		AcquireCount:            s.AcquireCount(),
+		EmptyAcquireCount:       s.EmptyAcquireCount(),
+		CanceledAcquireCount:    s.CanceledAcquireCount(),
+		AcquireDuration:         s.AcquireDuration(),
+		NewConnsCount:           s.NewConnsCount(),
+		MaxLifetimeDestroyCount: s.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     s.MaxIdleDestroyCount(),
 	}
-	return nil
 }
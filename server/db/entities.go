@@ -10,12 +10,20 @@ type Account struct {
 	Id          string
 	Name        string
 	Email       string
-	Dob         time.Time
-	Gender      string
-	PhoneNumber string
+	Dob         sql.NullTime
+	Gender      sql.NullString
+	PhoneNumber sql.NullString
 	Password    string
-	Role        string
-	CreatedAt   time.Time
+	// the random password of the accounts provisioned through oidc, they never set one
+	PasswordUnusable bool
+	Role             string
+	DeactivatedAt    sql.NullTime
+	TokenVersion     int
+	DeletedAt        sql.NullTime
+	ApprovedAt       sql.NullTime   // null while the account waits for approval
+	ApprovedBy       sql.NullString // fk to account
+	InviteCodeId     sql.NullString // fk to invite_code
+	CreatedAt        time.Time
 }
 
 // db table refresh_token
@@ -30,6 +38,38 @@ type RefreshToken struct {
 	ExpiresAt  time.Time
 	LastUsedAt sql.NullTime
 	IsRevoked  bool
+	Amr        []string // authentication methods the token pair was issued for
+}
+
+// db table email_change
+type EmailChange struct {
+	Id          string
+	AccountId   string // fk to account
+	NewEmail    string
+	TokenHash   string
+	ExpiresAt   time.Time
+	ConfirmedAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+// db table account_totp
+type AccountTotp struct {
+	AccountId      string // fk to account
+	Secret         string
+	EnabledAt      sql.NullTime
+	LastUsedStep   sql.NullInt64
+	FailedAttempts int
+	LastFailedAt   sql.NullTime
+	CreatedAt      time.Time
+}
+
+// db table totp_recovery_code
+type TotpRecoveryCode struct {
+	Id        string
+	AccountId string // fk to account
+	CodeHash  string
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
 }
 
 // db table court
@@ -37,6 +77,7 @@ type Court struct {
 	Id        string
 	Name      string
 	CreatorId string // fk to account
+	Version   int    // bumped on every update
 	CreatedAt time.Time
 }
 
@@ -48,14 +89,26 @@ type Season struct {
 	StartDate   time.Time
 	EndDate     time.Time
 	CreatorId   string // fk to account
-	CreatedAt   string
+	Version     int    // bumped on every update
+	CreatedAt   time.Time
+}
+
+// db table league
+type League struct {
+	Id          string
+	Title       string
+	Description sql.NullString
+	SeasonId    string // fk to season
+	CreatorId   string // fk to account
+	Version     int    // bumped on every update
+	CreatedAt   time.Time
 }
 
 // db table player
 type Player struct {
 	Id               string
-	Height           sql.NullInt32
-	Weight           sql.NullInt32
+	Height           sql.NullFloat64
+	Weight           sql.NullFloat64
 	Handedness       sql.NullString
 	Racket           sql.NullString
 	MatchesExpected  int // amount of expected matches played from each season
@@ -63,8 +116,13 @@ type Player struct {
 	MatchesWon       int
 	MatchesScheduled int // amount of matches created
 	SeasonsPlayed    int
-	AccountId        string // fk to account
-	CurrentLeagueId  string // fk to league
+	AccountId        string         // fk to account
+	CurrentLeagueId  sql.NullString // fk to league
+	PrivacyPhone     string         // visibility of the account phone number
+	PrivacyEmail     string         // visibility of the account email
+	PrivacyDob       string         // visibility of the account date of birth
+	PrivacyPhysique  string         // visibility of the height and weight
+	Version          int            // bumped on every update
 	CreatedAt        time.Time
 }
 
@@ -80,6 +138,7 @@ type Match struct {
 	SeasonId    string // fk to season
 	LeagueId    string // fk to league
 	CreatorId   string // fk to player
+	Version     int    // bumped on every update
 	CreatedAt   time.Time
 }
 
@@ -98,3 +157,121 @@ type Standing struct {
 	PlayerId      string // fk to player
 	CreatedAt     time.Time
 }
+
+// db table account_identity
+type AccountIdentity struct {
+	Id          string
+	AccountId   string // fk to account
+	Issuer      string
+	Subject     string
+	Email       sql.NullString
+	LastLoginAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+// db table oidc_auth_request
+type OidcAuthRequest struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// db table idempotency_key
+type IdempotencyKey struct {
+	AccountId   string // fk to account
+	Key         string
+	Fingerprint string
+	Reservation string        // the request holding the key
+	StatusCode  sql.NullInt32 // null while the first request is running
+	ContentType sql.NullString
+	Etag        sql.NullString
+	Location    sql.NullString
+	Body        []byte
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// db table service_account
+type ServiceAccount struct {
+	AccountId   string // fk to account
+	Description sql.NullString
+	CreatorId   sql.NullString // fk to account
+	DisabledAt  sql.NullTime
+	CreatedAt   time.Time
+}
+
+// db table api_key
+type APIKey struct {
+	Id         string
+	AccountId  string // fk to account
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatorId  sql.NullString // fk to account
+	// token version of the account when the key was created
+	TokenVersion int
+	CreatedAt    time.Time
+}
+
+// db table role
+type Role struct {
+	Name        string // pk
+	Description sql.NullString
+	IsSystem    bool
+	CreatedAt   time.Time
+}
+
+// db table role_permission
+type RolePermission struct {
+	RoleName   string // fk to role
+	Permission string
+	CreatedAt  time.Time
+}
+
+// db table scoped_role_grant
+type ScopedRoleGrant struct {
+	Id        string         // pk
+	AccountId string         // fk to account
+	RoleName  string         // fk to role
+	SeasonId  sql.NullString // fk to season
+	LeagueId  sql.NullString // fk to league
+	GrantedBy sql.NullString // fk to account
+	CreatedAt time.Time
+}
+
+// db table audit_log
+type AuditLog struct {
+	Id             string         // pk
+	ActorAccountId sql.NullString // account id, no fk so the entries outlive the accounts
+	ActorApiKeyId  sql.NullString
+	Action         string
+	ResourceType   string
+	ResourceId     string
+	Before         []byte // jsonb
+	After          []byte // jsonb
+	Ip             sql.NullString
+	UserAgent      sql.NullString
+	RequestId      sql.NullString
+	Method         sql.NullString
+	Path           sql.NullString
+	CreatedAt      time.Time
+}
+
+// db table invite_code
+type InviteCode struct {
+	Id        string // pk
+	CodeHash  string
+	Note      sql.NullString
+	MaxUses   int
+	Uses      int
+	ExpiresAt sql.NullTime
+	RevokedAt sql.NullTime
+	CreatorId sql.NullString // fk to account
+	CreatedAt time.Time
+}
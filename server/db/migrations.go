@@ -0,0 +1,369 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are the dbmate migration files the binary is built with
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// the key of the advisory lock held while migrating, so the instances starting together
+// don't apply the same migration twice
+const migrationLockKey = 4_610_337_421
+
+// dbmate's section markers
+const (
+	markerUp   = "-- migrate:up"
+	markerDown = "-- migrate:down"
+)
+
+// Migration is a parsed migration file. The version is the timestamp prefix of the file name,
+// the same dbmate records in schema_migrations
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+	// set by the transaction:false option of a section, the statements which can't run in a
+	// transaction (like alter type ... add value) need it
+	UpNoTx   bool
+	DownNoTx bool
+}
+
+// MigrationStatus tells if the migration is applied to the db
+type MigrationStatus struct {
+	Version string
+	Name    string
+	Applied bool
+}
+
+// LoadMigrations parses the embedded migrations, ordered by version
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		b, err := fs.ReadFile(Migrations, path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, err := parseMigration(e.Name(), string(b))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// parseMigration splits the file into its up and down sections the way dbmate does
+func parseMigration(filename, content string) (Migration, error) {
+	version, name, ok := strings.Cut(strings.TrimSuffix(filename, ".sql"), "_")
+	if !ok || version == "" {
+		return Migration{}, fmt.Errorf("migration %s: the file name must be <version>_<name>.sql", filename)
+	}
+	m := Migration{Version: version, Name: name}
+
+	upAt := strings.Index(content, markerUp)
+	if upAt < 0 {
+		return Migration{}, fmt.Errorf("migration %s: missing the %q section", filename, markerUp)
+	}
+	downAt := strings.Index(content, markerDown)
+	if downAt >= 0 && downAt < upAt {
+		return Migration{}, fmt.Errorf("migration %s: the %q section must come first", filename, markerUp)
+	}
+
+	up := content[upAt:]
+	if downAt >= 0 {
+		up = content[upAt:downAt]
+		m.Down, m.DownNoTx = parseSection(content[downAt:])
+	}
+	m.Up, m.UpNoTx = parseSection(up)
+
+	return m, nil
+}
+
+// parseSection returns the statements of the section and if it opted out of the transaction
+func parseSection(section string) (string, bool) {
+	marker, body, _ := strings.Cut(section, "\n")
+	noTx := false
+	for _, opt := range strings.Fields(marker)[2:] {
+		if opt == "transaction:false" {
+			noTx = true
+		}
+	}
+	return strings.TrimSpace(body), noTx
+}
+
+// MigrationVersions returns the versions of the embedded migrations in order
+func MigrationVersions() ([]string, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, len(migrations))
+	for i, m := range migrations {
+		versions[i] = m.Version
+	}
+
+	return versions, nil
+}
+
+// PendingMigrations returns the embedded migrations the db hasn't applied yet
+func PendingMigrations(ctx context.Context, q Querier) ([]string, error) {
+	versions, err := MigrationVersions()
+	if err != nil {
+		return nil, fmt.Errorf("reading the embedded migrations -> %w", err)
+	}
+
+	applied, err := appliedMigrations(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, v := range versions {
+		if !applied[v] {
+			pending = append(pending, v)
+		}
+	}
+
+	return pending, nil
+}
+
+func appliedMigrations(ctx context.Context, q Querier) (map[string]bool, error) {
+	rows, err := q.Query(ctx, `select version from schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("reading the applied migrations -> %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("reading the applied migrations -> %w", err)
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading the applied migrations -> %w", err)
+	}
+
+	return applied, nil
+}
+
+// MigrationStatuses returns every embedded migration and if the db has applied it
+func MigrationStatuses(ctx context.Context, conn *Conn) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("reading the embedded migrations -> %w", err)
+	}
+
+	var applied map[string]bool
+	err = withMigrationLock(ctx, conn, func(c *pgxpool.Conn) error {
+		applied, err = appliedMigrations(ctx, c)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Version: m.Version, Name: m.Name, Applied: applied[m.Version]}
+	}
+
+	return statuses, nil
+}
+
+// MigrateUp applies every pending migration in order and returns the applied versions
+func MigrateUp(ctx context.Context, conn *Conn) ([]string, error) {
+	return migrate(ctx, conn, func(migrations []Migration, applied map[string]bool) ([]Migration, []Migration, error) {
+		return pendingOf(migrations, applied, ""), nil, nil
+	})
+}
+
+// MigrateDown rolls back the last applied migration and returns its version, it returns an
+// empty version if there's nothing to roll back
+func MigrateDown(ctx context.Context, conn *Conn) (string, error) {
+	rolledBack, err := migrate(ctx, conn, func(migrations []Migration, applied map[string]bool) ([]Migration, []Migration, error) {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if applied[migrations[i].Version] {
+				return nil, migrations[i : i+1], nil
+			}
+		}
+		return nil, nil, nil
+	})
+	if err != nil || len(rolledBack) == 0 {
+		return "", err
+	}
+	return rolledBack[0], nil
+}
+
+// MigrateTo brings the db to the version, applying the pending migrations up to it and rolling
+// back the applied ones after it. It returns the versions applied or rolled back
+func MigrateTo(ctx context.Context, conn *Conn, version string) ([]string, error) {
+	return migrate(ctx, conn, func(migrations []Migration, applied map[string]bool) ([]Migration, []Migration, error) {
+		found := false
+		var down []Migration
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if m.Version == version {
+				found = true
+				break
+			}
+			if applied[m.Version] {
+				down = append(down, m)
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("unknown migration version: %s", version)
+		}
+		return pendingOf(migrations, applied, version), down, nil
+	})
+}
+
+// pendingOf returns the migrations not applied yet, up to the version if it's set
+func pendingOf(migrations []Migration, applied map[string]bool, upTo string) []Migration {
+	var pending []Migration
+	for _, m := range migrations {
+		if upTo != "" && m.Version > upTo {
+			break
+		}
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+type migrationPlan func(migrations []Migration, applied map[string]bool) (up, down []Migration, err error)
+
+// migrate runs the migrations planned from the applied ones while holding the migration lock.
+// Every migration is applied with its schema_migrations row in one transaction, unless it opted
+// out of it
+func migrate(ctx context.Context, conn *Conn, plan migrationPlan) ([]string, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("reading the embedded migrations -> %w", err)
+	}
+
+	var done []string
+	err = withMigrationLock(ctx, conn, func(c *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		up, down, err := plan(migrations, applied)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range down {
+			if err := runMigration(ctx, c, m.Down, m.DownNoTx, `delete from schema_migrations where version = $1`, m.Version); err != nil {
+				return fmt.Errorf("rolling back migration %s_%s -> %w", m.Version, m.Name, err)
+			}
+			slog.InfoContext(ctx, "migration rolled back", "version", m.Version, "name", m.Name)
+			done = append(done, m.Version)
+		}
+		for _, m := range up {
+			if err := runMigration(ctx, c, m.Up, m.UpNoTx, `insert into schema_migrations (version) values ($1)`, m.Version); err != nil {
+				return fmt.Errorf("applying migration %s_%s -> %w", m.Version, m.Name, err)
+			}
+			slog.InfoContext(ctx, "migration applied", "version", m.Version, "name", m.Name)
+			done = append(done, m.Version)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// runMigration runs the statements and records the version
+func runMigration(ctx context.Context, c *pgxpool.Conn, statements string, noTx bool, record, version string) (err error) {
+	if noTx {
+		if statements != "" {
+			if _, err := c.Exec(ctx, statements); err != nil {
+				return err
+			}
+		}
+		_, err := c.Exec(ctx, record, version)
+		return err
+	}
+
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				err = errors.Join(err, rbErr)
+			}
+		}
+	}()
+
+	// without arguments the statements go through the simple protocol, so a section may hold many
+	if statements != "" {
+		if _, err = tx.Exec(ctx, statements); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(ctx, record, version); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// withMigrationLock runs fn on a single connection holding the migration lock, creating the
+// schema_migrations table dbmate would on a fresh db
+func withMigrationLock(ctx context.Context, conn *Conn, fn func(c *pgxpool.Conn) error) (err error) {
+	c, err := conn.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring a connection -> %w", err)
+	}
+	defer c.Release()
+
+	if _, err := c.Exec(ctx, `select pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("acquiring the migration lock -> %w", err)
+	}
+	defer func() {
+		// the lock is released with the session anyway, don't let a canceled ctx keep it
+		if _, unlockErr := c.Exec(context.WithoutCancel(ctx), `select pg_advisory_unlock($1)`, migrationLockKey); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("releasing the migration lock -> %w", unlockErr))
+		}
+	}()
+
+	if _, err := c.Exec(ctx, `create table if not exists schema_migrations (version varchar(128) primary key)`); err != nil {
+		return fmt.Errorf("creating schema_migrations -> %w", err)
+	}
+
+	return fn(c)
+}
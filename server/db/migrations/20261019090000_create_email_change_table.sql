@@ -0,0 +1,13 @@
+-- migrate:up
+create table email_change(
+    id uuid primary key not null default uuid_generate_v4(),
+    account_id uuid not null references account (id) on delete cascade,
+    new_email varchar(250) not null,
+    token_hash text not null,
+    expires_at timestamptz not null,
+    confirmed_at timestamptz,
+    created_at timestamptz not null default current_timestamp
+);
+
+-- migrate:down
+drop table if exists email_change;
@@ -0,0 +1,25 @@
+-- migrate:up
+create table account_totp(
+    account_id uuid primary key not null references account (id) on delete cascade,
+    secret text not null,
+    enabled_at timestamptz,
+    last_used_step bigint,
+    failed_attempts integer not null default 0,
+    last_failed_at timestamptz,
+    created_at timestamptz not null default current_timestamp
+);
+
+create table totp_recovery_code(
+    id uuid primary key not null default uuid_generate_v4(),
+    account_id uuid not null references account (id) on delete cascade,
+    code_hash text not null,
+    used_at timestamptz,
+    created_at timestamptz not null default current_timestamp
+);
+
+alter table refresh_token add column amr text[] not null default '{pwd}';
+
+-- migrate:down
+alter table refresh_token drop column if exists amr;
+drop table if exists totp_recovery_code;
+drop table if exists account_totp;
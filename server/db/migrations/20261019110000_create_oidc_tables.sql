@@ -0,0 +1,40 @@
+-- migrate:up
+create table account_identity(
+    id uuid primary key not null default uuid_generate_v4(),
+    account_id uuid not null references account (id) on delete cascade,
+    issuer text not null,
+    subject text not null,
+    email varchar(250),
+    last_login_at timestamptz,
+    created_at timestamptz not null default current_timestamp,
+    unique (issuer, subject)
+);
+
+create table oidc_auth_request(
+    state_hash text primary key not null,
+    nonce text not null,
+    code_verifier text not null,
+    expires_at timestamptz not null,
+    created_at timestamptz not null default current_timestamp
+);
+
+-- accounts provisioned through the identity provider might not have these
+alter table account alter column dob drop not null;
+alter table account alter column gender drop not null;
+alter table account alter column phone_number drop not null;
+
+-- migrate:down
+-- the accounts provisioned through the identity provider and the deleted ones lack the columns,
+-- they'd have to be removed along with their history, so the rollback is refused while they exist
+do $$
+begin
+    if exists (select 1 from account where dob is null or gender is null or phone_number is null) then
+        raise exception 'accounts without a dob, gender or phone number exist, fill them in before rolling back';
+    end if;
+end;
+$$;
+alter table account alter column phone_number set not null;
+alter table account alter column gender set not null;
+alter table account alter column dob set not null;
+drop table if exists oidc_auth_request;
+drop table if exists account_identity;
@@ -0,0 +1,32 @@
+-- migrate:up
+alter type role add value if not exists 'service';
+
+create table service_account(
+    account_id uuid primary key not null references account (id) on delete cascade,
+    description text,
+    creator_id uuid references account (id) on delete set null,
+    disabled_at timestamptz,
+    created_at timestamptz not null default current_timestamp
+);
+
+create table api_key(
+    id uuid primary key not null default uuid_generate_v4(),
+    account_id uuid not null references account (id) on delete cascade,
+    name varchar(250) not null,
+    prefix varchar(32) not null,
+    key_hash text not null unique,
+    scopes text[] not null default '{}',
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    creator_id uuid references account (id) on delete set null,
+    created_at timestamptz not null default current_timestamp
+);
+
+create index api_key_account_id_idx on api_key (account_id);
+
+-- migrate:down
+drop table if exists api_key;
+delete from account where id in (select account_id from service_account);
+drop table if exists service_account;
+-- postgres can't drop a single enum value, the 'service' role is left in place
@@ -0,0 +1,5 @@
+-- migrate:up
+alter table account add column deactivated_at timestamptz;
+
+-- migrate:down
+alter table account drop column if exists deactivated_at;
@@ -0,0 +1,6 @@
+-- migrate:up
+-- bumped whenever the issued access tokens must stop working
+alter table account add column token_version integer not null default 0;
+
+-- migrate:down
+alter table account drop column if exists token_version;
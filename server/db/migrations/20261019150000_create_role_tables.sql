@@ -0,0 +1,61 @@
+-- migrate:up
+-- the role enum is replaced by the role table so new roles don't need a migration
+alter table account alter column role drop default;
+alter table account alter column role type varchar(50) using role::text;
+drop type if exists role;
+
+create table role(
+    name varchar(50) primary key not null,
+    description text,
+    is_system boolean not null default false,
+    created_at timestamptz not null default current_timestamp
+);
+
+create table role_permission(
+    role_name varchar(50) not null references role (name) on delete cascade,
+    permission varchar(100) not null,
+    created_at timestamptz not null default current_timestamp,
+    primary key (role_name, permission)
+);
+
+-- the built-in roles, the grants match permission.defaultGrants
+insert into role (name, description, is_system) values
+    ('developer', 'Full access including the role management', true),
+    ('admin', 'Manages the league data and the accounts', true),
+    ('user', 'Player account', true),
+    ('service', 'Service account, only gets the scopes of its api keys', true);
+
+insert into role_permission (role_name, permission)
+select 'developer', unnest(array[
+    'create:court', 'update:court', 'delete:court',
+    'create:season', 'update:season', 'delete:season',
+    'create:league', 'update:league', 'delete:league',
+    'create:match', 'update:match', 'delete:match', 'submit:score',
+    'update:player', 'delete:player',
+    'manage:service_account', 'manage:account', 'manage:role'
+]);
+
+insert into role_permission (role_name, permission)
+select 'admin', unnest(array[
+    'create:court', 'update:court', 'delete:court',
+    'create:season', 'update:season', 'delete:season',
+    'create:league', 'update:league', 'delete:league',
+    'create:match', 'update:match', 'delete:match', 'submit:score',
+    'manage:service_account', 'manage:account'
+]);
+
+insert into role_permission (role_name, permission)
+select 'user', unnest(array['create:match', 'submit:score']);
+
+alter table account alter column role set default 'user';
+alter table account add constraint account_role_fkey foreign key (role) references role (name);
+
+-- migrate:down
+alter table account drop constraint if exists account_role_fkey;
+alter table account alter column role drop default;
+update account set role = 'user' where role not in ('developer', 'admin', 'user', 'service');
+drop table if exists role_permission;
+drop table if exists role;
+create type role as enum ('developer', 'admin', 'user', 'service');
+alter table account alter column role type role using role::role;
+alter table account alter column role set default 'user';
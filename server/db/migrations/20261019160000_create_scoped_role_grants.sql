@@ -0,0 +1,25 @@
+-- migrate:up
+-- roles granted to an account only within a season or a league, on top of its global role
+create table scoped_role_grant(
+    id uuid primary key not null default uuid_generate_v4(),
+    account_id uuid not null references account (id) on delete cascade,
+    role_name varchar(50) not null references role (name),
+    season_id uuid references season (id) on delete cascade,
+    league_id uuid references league (id) on delete cascade,
+    granted_by uuid references account (id) on delete set null,
+    created_at timestamptz not null default current_timestamp,
+    constraint scoped_role_grant_one_scope check ((season_id is null) <> (league_id is null))
+);
+
+create unique index scoped_role_grant_season_idx on scoped_role_grant (account_id, role_name, season_id) where season_id is not null;
+create unique index scoped_role_grant_league_idx on scoped_role_grant (account_id, role_name, league_id) where league_id is not null;
+
+insert into role (name, description, is_system) values
+    ('league_coordinator', 'Schedules matches, corrects scores and assigns players within its seasons or leagues', false);
+
+insert into role_permission (role_name, permission)
+select 'league_coordinator', unnest(array['create:match', 'update:match', 'submit:score', 'update:player']);
+
+-- migrate:down
+drop table if exists scoped_role_grant;
+delete from role where name = 'league_coordinator' and not exists (select 1 from account where role = 'league_coordinator');
@@ -0,0 +1,45 @@
+-- migrate:up
+create table audit_log(
+    id uuid primary key not null default uuid_generate_v4(),
+    actor_account_id uuid,
+    actor_api_key_id uuid,
+    action varchar(50) not null,
+    resource_type varchar(50) not null,
+    resource_id varchar(100) not null,
+    before jsonb,
+    after jsonb,
+    ip varchar(100),
+    user_agent text,
+    request_id varchar(100),
+    method varchar(10),
+    path text,
+    created_at timestamptz not null default current_timestamp
+);
+
+-- no foreign keys, the entries outlive the accounts and the resources they refer to
+create index audit_log_created_at_idx on audit_log (created_at desc);
+create index audit_log_actor_idx on audit_log (actor_account_id, created_at desc);
+create index audit_log_resource_idx on audit_log (resource_type, resource_id, created_at desc);
+
+create function audit_log_append_only() returns trigger as $$
+begin
+    raise exception 'audit_log is append-only';
+end;
+$$ language plpgsql;
+
+create trigger audit_log_append_only
+before update or delete on audit_log
+for each row execute function audit_log_append_only();
+
+create trigger audit_log_no_truncate
+before truncate on audit_log
+for each statement execute function audit_log_append_only();
+
+insert into role_permission (role_name, permission) values
+    ('developer', 'view:audit'),
+    ('admin', 'view:audit');
+
+-- migrate:down
+delete from role_permission where permission = 'view:audit';
+drop table if exists audit_log;
+drop function if exists audit_log_append_only();
@@ -0,0 +1,6 @@
+-- migrate:up
+-- deleted accounts are anonymized instead of removed, their player keeps the match history
+alter table account add column deleted_at timestamptz;
+
+-- migrate:down
+alter table account drop column if exists deleted_at;
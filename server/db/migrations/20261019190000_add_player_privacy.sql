@@ -0,0 +1,17 @@
+-- migrate:up
+create type visibility as enum ('everyone', 'league_mates', 'opponents', 'nobody');
+
+alter table player
+    add column privacy_phone_number visibility not null default 'opponents',
+    add column privacy_email visibility not null default 'league_mates',
+    add column privacy_dob visibility not null default 'nobody',
+    add column privacy_physique visibility not null default 'everyone';
+
+-- migrate:down
+alter table player
+    drop column if exists privacy_phone_number,
+    drop column if exists privacy_email,
+    drop column if exists privacy_dob,
+    drop column if exists privacy_physique;
+
+drop type if exists visibility;
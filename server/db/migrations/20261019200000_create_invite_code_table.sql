@@ -0,0 +1,36 @@
+-- migrate:up
+create table invite_code(
+    id uuid primary key not null default uuid_generate_v4(),
+    code_hash text not null unique,
+    note text,
+    max_uses int not null default 1,
+    uses int not null default 0,
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    creator_id uuid references account (id) on delete set null,
+    created_at timestamptz not null default current_timestamp,
+    constraint invite_code_max_uses check (max_uses > 0),
+    constraint invite_code_uses check (uses <= max_uses)
+);
+
+-- accounts without approved_at wait in the approval queue, the existing ones are approved
+alter table account
+    add column approved_at timestamptz,
+    add column approved_by uuid references account (id) on delete set null,
+    add column invite_code_id uuid references invite_code (id) on delete set null;
+
+update account set approved_at = created_at;
+
+insert into role_permission (role_name, permission) values
+    ('developer', 'manage:invite'),
+    ('admin', 'manage:invite');
+
+-- migrate:down
+delete from role_permission where permission = 'manage:invite';
+
+alter table account
+    drop column if exists approved_at,
+    drop column if exists approved_by,
+    drop column if exists invite_code_id;
+
+drop table if exists invite_code;
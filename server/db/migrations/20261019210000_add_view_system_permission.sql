@@ -0,0 +1,6 @@
+-- migrate:up
+insert into role_permission (role_name, permission) values
+    ('developer', 'view:system');
+
+-- migrate:down
+delete from role_permission where permission = 'view:system';
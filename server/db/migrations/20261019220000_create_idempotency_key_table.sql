@@ -0,0 +1,18 @@
+-- migrate:up
+-- the responses to the retried posts, status_code is null while the first request is running
+create table idempotency_key(
+    account_id uuid not null references account (id) on delete cascade,
+    key text not null,
+    fingerprint text not null,
+    status_code int,
+    content_type text,
+    body bytea,
+    expires_at timestamptz not null,
+    created_at timestamptz not null default current_timestamp,
+    primary key (account_id, key)
+);
+
+create index on idempotency_key (expires_at);
+
+-- migrate:down
+drop table if exists idempotency_key;
@@ -0,0 +1,35 @@
+-- migrate:up
+-- every update of a row bumps its version, the updates of the api only apply to the version they read
+create function bump_row_version() returns trigger as $$
+begin
+    new.version := old.version + 1;
+    return new;
+end;
+$$ language plpgsql;
+
+alter table court add column version int not null default 1;
+alter table season add column version int not null default 1;
+alter table league add column version int not null default 1;
+alter table player add column version int not null default 1;
+alter table match add column version int not null default 1;
+
+create trigger court_row_version before update on court for each row execute function bump_row_version();
+create trigger season_row_version before update on season for each row execute function bump_row_version();
+create trigger league_row_version before update on league for each row execute function bump_row_version();
+create trigger player_row_version before update on player for each row execute function bump_row_version();
+create trigger match_row_version before update on match for each row execute function bump_row_version();
+
+-- migrate:down
+drop trigger if exists court_row_version on court;
+drop trigger if exists season_row_version on season;
+drop trigger if exists league_row_version on league;
+drop trigger if exists player_row_version on player;
+drop trigger if exists match_row_version on match;
+
+alter table court drop column if exists version;
+alter table season drop column if exists version;
+alter table league drop column if exists version;
+alter table player drop column if exists version;
+alter table match drop column if exists version;
+
+drop function if exists bump_row_version();
@@ -0,0 +1,33 @@
+-- migrate:up
+-- the entries recorded so far kept the names, emails, phone numbers and birth dates of the
+-- accounts in their states, they're removed so they don't outlive the deletion of the accounts
+create function audit_log_scrub(resource_type varchar, state jsonb) returns jsonb as $$
+declare
+    field text;
+begin
+    if state is null or jsonb_typeof(state) <> 'object' then
+        return state;
+    end if;
+    if resource_type = 'account' then
+        return state - 'name' - 'email';
+    end if;
+    -- the players and the matches keep the ids of the accounts and the players they refer to
+    foreach field in array case resource_type when 'player' then array['account'] else array['player_one', 'player_two', 'winner'] end loop
+        if jsonb_typeof(state->field) = 'object' then
+            state := jsonb_set(state, array[field], jsonb_build_object('id', state->field->'id'));
+        end if;
+    end loop;
+    return state;
+end;
+$$ language plpgsql;
+
+alter table audit_log disable trigger audit_log_append_only;
+update audit_log
+set before = audit_log_scrub(resource_type, before), after = audit_log_scrub(resource_type, after)
+where resource_type in ('account', 'player', 'match');
+alter table audit_log enable trigger audit_log_append_only;
+
+drop function audit_log_scrub(varchar, jsonb);
+
+-- migrate:down
+-- the removed personal data can't be restored
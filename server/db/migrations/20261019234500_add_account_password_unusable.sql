@@ -0,0 +1,14 @@
+-- migrate:up
+-- the accounts provisioned through the identity provider got a random password nobody knows,
+-- until they set one their signed in session confirms the changes the password guards
+alter table account add column password_unusable boolean not null default false;
+
+-- the provisioned accounts were inserted in the same tx as their first identity
+update account set password_unusable = true
+where exists (
+    select 1 from account_identity
+    where account_identity.account_id = account.id and account_identity.created_at = account.created_at
+);
+
+-- migrate:down
+alter table account drop column if exists password_unusable;
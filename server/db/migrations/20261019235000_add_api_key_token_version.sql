@@ -0,0 +1,13 @@
+-- migrate:up
+-- a key is bound to the token version of its account, the forced logouts, password and role
+-- changes bump the version and with it revoke the keys the same way as the jwts
+alter table api_key add column token_version integer;
+
+update api_key set token_version = account.token_version
+from account
+where account.id = api_key.account_id;
+
+alter table api_key alter column token_version set not null;
+
+-- migrate:down
+alter table api_key drop column if exists token_version;
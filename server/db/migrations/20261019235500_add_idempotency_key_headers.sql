@@ -0,0 +1,6 @@
+-- migrate:up
+-- the replayed response carries the etag and the location of the created resource too
+alter table idempotency_key add column etag text, add column location text;
+
+-- migrate:down
+alter table idempotency_key drop column if exists etag, drop column if exists location;
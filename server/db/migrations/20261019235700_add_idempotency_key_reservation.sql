@@ -0,0 +1,7 @@
+-- migrate:up
+-- the request holding the key, a request whose reservation expired and was claimed by
+-- a retry can't store its response over the one of the retry
+alter table idempotency_key add column reservation text;
+
+-- migrate:down
+alter table idempotency_key drop column if exists reservation;
@@ -0,0 +1,70 @@
+package db
+
+import "testing"
+
+func TestParseMigration(t *testing.T) {
+	content := `-- migrate:up
+create table court (id uuid primary key);
+create index on court (id);
+
+-- migrate:down transaction:false
+drop table if exists court;
+`
+	m, err := parseMigration("20250110182359_create_court_table.sql", content)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if m.Version != "20250110182359" || m.Name != "create_court_table" {
+		t.Errorf("unexpected version and name: %s %s", m.Version, m.Name)
+	}
+	if m.Up != "create table court (id uuid primary key);\ncreate index on court (id);" {
+		t.Errorf("unexpected up section: %q", m.Up)
+	}
+	if m.Down != "drop table if exists court;" {
+		t.Errorf("unexpected down section: %q", m.Down)
+	}
+	if m.UpNoTx || !m.DownNoTx {
+		t.Errorf("expected only the down section out of the transaction, got up %v down %v", m.UpNoTx, m.DownNoTx)
+	}
+
+	invalid := map[string]string{
+		"court.sql":             "-- migrate:up\nselect 1;",
+		"20250110_court.sql":    "select 1;",
+		"20250111_reversed.sql": "-- migrate:down\nselect 1;\n-- migrate:up\nselect 1;",
+	}
+	for filename, content := range invalid {
+		if _, err := parseMigration(filename, content); err == nil {
+			t.Errorf("expected an error for %s", filename)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("expected the embedded migrations to parse, got %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Up == "" {
+			t.Errorf("migration %s has an empty up section", m.Version)
+		}
+		if i > 0 && migrations[i-1].Version >= m.Version {
+			t.Errorf("migrations out of order: %s before %s", migrations[i-1].Version, m.Version)
+		}
+	}
+}
+
+func TestPendingOf(t *testing.T) {
+	migrations := []Migration{{Version: "1"}, {Version: "2"}, {Version: "3"}}
+	applied := map[string]bool{"1": true}
+
+	if got := pendingOf(migrations, applied, ""); len(got) != 2 || got[0].Version != "2" {
+		t.Errorf("expected 2 and 3 pending, got %v", got)
+	}
+	if got := pendingOf(migrations, applied, "2"); len(got) != 1 || got[0].Version != "2" {
+		t.Errorf("expected only 2 pending up to 2, got %v", got)
+	}
+}
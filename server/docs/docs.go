@@ -15,41 +15,21 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/v1/auth/signup": {
-            "post": {
-                "description": "Signup a new account",
-                "consumes": [
-                    "application/json"
-                ],
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Get the public keys the gdsi access tokens can be verified with. Keys are identified by the kid token header",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
-                ],
-                "summary": "Signup",
-                "parameters": [
-                    {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.SignupRequestModel"
-                        }
-                    }
+                    "well-known"
                 ],
+                "summary": "JWKS",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.TokensResponseModel"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request",
-                        "schema": {
-                            "$ref": "#/definitions/failure.ValidationFailure"
+                            "type": "object"
                         }
                     },
                     "500": {
@@ -61,105 +41,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/auth/tokens/access": {
-            "post": {
-                "description": "Login and get a new access token",
-                "consumes": [
-                    "application/json"
-                ],
+        "/healthz": {
+            "get": {
+                "description": "Report that the process is alive, the dependencies are not checked",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
-                ],
-                "summary": "Login",
-                "parameters": [
-                    {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.LoginRequestModel"
-                        }
-                    }
+                    "health"
                 ],
+                "summary": "Liveness",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.TokensResponseModel"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request",
-                        "schema": {
-                            "$ref": "#/definitions/failure.ValidationFailure"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal server error",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
+                            "$ref": "#/definitions/health.ReportModel"
                         }
                     }
                 }
             }
         },
-        "/v1/auth/tokens/refresh": {
-            "post": {
-                "description": "Get a refreshed access token",
-                "consumes": [
-                    "application/json"
-                ],
+        "/readyz": {
+            "get": {
+                "description": "Check the dependencies of the api, each check reports its status and latency",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
-                ],
-                "summary": "Refresh token",
-                "parameters": [
-                    {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.RefreshTokenRequestModel"
-                        }
-                    }
+                    "health"
                 ],
+                "summary": "Readiness",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.TokensResponseModel"
+                            "$ref": "#/definitions/health.ReportModel"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "503": {
+                        "description": "Service unavailable",
                         "schema": {
-                            "$ref": "#/definitions/failure.Failure"
+                            "$ref": "#/definitions/health.ReportModel"
                         }
                     }
                 }
             }
         },
-        "/v1/courts": {
+        "/v1/admin/accounts": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get courts",
+                "description": "Get accounts, searchable by name or email",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "courts"
+                    "admin accounts"
                 ],
                 "summary": "Get",
                 "parameters": [
@@ -180,6 +120,24 @@ const docTemplate = `{
                         "description": "order by",
                         "name": "order_by",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name or email search",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "role filter",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "active, deactivated, pending (the approval queue) or deleted",
+                        "name": "status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -188,7 +146,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/courts.CourtModel"
+                                "$ref": "#/definitions/accounts.AccountModel"
                             }
                         }
                     },
@@ -204,6 +162,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -211,40 +175,37 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/v1/admin/accounts/{account_id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new court",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get account by id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "courts"
+                    "admin accounts"
                 ],
-                "summary": "Create",
+                "summary": "Get by id",
                 "parameters": [
                     {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/courts.CreateCourtRequestModel"
-                        }
+                        "type": "string",
+                        "description": "account id",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/courts.CourtModel"
+                            "$ref": "#/definitions/accounts.AccountModel"
                         }
                     },
                     "400": {
@@ -259,6 +220,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -268,26 +241,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/courts/{court_id}": {
-            "get": {
+        "/v1/admin/accounts/{account_id}/approve": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get court by id",
+                "description": "Approve an account waiting in the approval queue, its player can join leagues from now on",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "courts"
+                    "admin accounts"
                 ],
-                "summary": "Get by id",
+                "summary": "Approve",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "court id",
-                        "name": "court_id",
+                        "description": "account id",
+                        "name": "account_id",
                         "in": "path",
                         "required": true
                     }
@@ -296,7 +269,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/courts.CourtModel"
+                            "$ref": "#/definitions/accounts.AccountModel"
                         }
                     },
                     "400": {
@@ -311,12 +284,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "404": {
                         "description": "Not found",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -324,47 +309,37 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/v1/admin/accounts/{account_id}/deactivate": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing court",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Deactivate the account, its tokens are rejected from now on",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "courts"
+                    "admin accounts"
                 ],
-                "summary": "Update",
+                "summary": "Deactivate",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "court id",
-                        "name": "court_id",
+                        "description": "account id",
+                        "name": "account_id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/courts.UpdateCourtRequestModel"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/courts.CourtModel"
+                            "$ref": "#/definitions/accounts.AccountModel"
                         }
                     },
                     "400": {
@@ -379,6 +354,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "404": {
                         "description": "Not found",
                         "schema": {
@@ -392,33 +373,41 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/v1/admin/accounts/{account_id}/grants": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete an existing court",
+                "description": "Get the roles granted to the account within a season or a league",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "courts"
+                    "admin accounts"
                 ],
-                "summary": "Delete",
+                "summary": "Get scoped grants",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "court id",
-                        "name": "court_id",
+                        "description": "account id",
+                        "name": "account_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/accounts.ScopedGrantModel"
+                            }
+                        }
                     },
                     "400": {
                         "description": "Bad request",
@@ -432,45 +421,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal server error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
-                    }
-                }
-            }
-        },
-        "/v1/me": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get my account and player profile data",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "me"
-                ],
-                "summary": "Get",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/me.MeModel"
-                        }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Not found",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -483,13 +441,13 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update my account and player profile data",
+                "description": "Grant a role to the account only within a season or a league, e.g. a league coordinator. Exactly one of season_id or league_id is required",
                 "consumes": [
                     "application/json"
                 ],
@@ -497,25 +455,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "me"
+                    "admin accounts"
                 ],
-                "summary": "Update",
+                "summary": "Create scoped grant",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "account id",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "Request body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/me.UpdateMeRequestModel"
+                            "$ref": "#/definitions/accounts.CreateScopedGrantRequestModel"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/me.MeModel"
+                            "$ref": "#/definitions/accounts.ScopedGrantModel"
                         }
                     },
                     "400": {
@@ -530,12 +495,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "404": {
                         "description": "Not found",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -545,50 +522,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/players": {
-            "get": {
+        "/v1/admin/accounts/{account_id}/grants/{grant_id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get players",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Revoke a role granted within a season or a league",
                 "tags": [
-                    "players"
+                    "admin accounts"
                 ],
-                "summary": "Get",
+                "summary": "Delete scoped grant",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "page",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "per page",
-                        "name": "per_page",
-                        "in": "query"
+                        "type": "string",
+                        "description": "account id",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "order by",
-                        "name": "order_by",
-                        "in": "query"
+                        "description": "grant id",
+                        "name": "grant_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/players.PlayerModel"
-                            }
-                        }
+                    "204": {
+                        "description": "No content"
                     },
                     "400": {
                         "description": "Bad request",
@@ -602,6 +566,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -611,36 +587,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/players/{player_id}": {
-            "get": {
+        "/v1/admin/accounts/{account_id}/logout": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get player by id",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Force logout the account by revoking all of its access and refresh tokens",
                 "tags": [
-                    "players"
+                    "admin accounts"
                 ],
-                "summary": "Get by id",
+                "summary": "Logout",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "player id",
-                        "name": "player_id",
+                        "description": "account id",
+                        "name": "account_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/matches.MatchModel"
-                        }
+                    "204": {
+                        "description": "No content"
                     },
                     "400": {
                         "description": "Bad request",
@@ -654,6 +624,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "404": {
                         "description": "Not found",
                         "schema": {
@@ -667,47 +643,37 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/v1/admin/accounts/{account_id}/reactivate": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing player",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Reactivate a deactivated account",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "players"
+                    "admin accounts"
                 ],
-                "summary": "Update",
+                "summary": "Reactivate",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "player id",
-                        "name": "player_id",
+                        "description": "account id",
+                        "name": "account_id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/players.UpdatePlayerRequestModel"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/players.PlayerModel"
+                            "$ref": "#/definitions/accounts.AccountModel"
                         }
                     },
                     "400": {
@@ -722,6 +688,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "404": {
                         "description": "Not found",
                         "schema": {
@@ -737,49 +709,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons": {
-            "get": {
+        "/v1/admin/accounts/{account_id}/reject": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get seasons",
+                "description": "Reject an account waiting in the approval queue, the account is deactivated",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "seasons"
+                    "admin accounts"
                 ],
-                "summary": "Get",
+                "summary": "Reject",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "page",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "per page",
-                        "name": "per_page",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "order by",
-                        "name": "order_by",
-                        "in": "query"
+                        "description": "account id",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/seasons.SeasonModel"
-                            }
+                            "$ref": "#/definitions/accounts.AccountModel"
                         }
                     },
                     "400": {
@@ -794,6 +752,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -801,14 +777,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/v1/admin/accounts/{account_id}/role": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new season",
+                "description": "Change the account role, only developers can assign the developer role. The current access tokens of the account are revoked",
                 "consumes": [
                     "application/json"
                 ],
@@ -816,25 +794,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "seasons"
+                    "admin accounts"
                 ],
-                "summary": "Create",
+                "summary": "Update role",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "account id",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "Request body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/seasons.CreateSeasonRequestModel"
+                            "$ref": "#/definitions/accounts.UpdateAccountRoleRequestModel"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
+                    "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/seasons.SeasonModel"
+                            "$ref": "#/definitions/accounts.AccountModel"
                         }
                     },
                     "400": {
@@ -849,6 +834,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -858,35 +855,85 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}": {
+        "/v1/admin/audit": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get season by id",
+                "description": "Get the audit log entries, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "seasons"
+                    "admin audit"
                 ],
-                "summary": "Get by id",
+                "summary": "Get",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "per page",
+                        "name": "per_page",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
+                        "description": "order by",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "acting account id",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "action, e.g. create, update, delete, submit_score",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "resource type, e.g. season, league, match",
+                        "name": "resource_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "resource id",
+                        "name": "resource_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp, inclusive",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC3339 timestamp, exclusive",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/seasons.SeasonModel"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/auditlog.EntryModel"
+                            }
                         }
                     },
                     "400": {
@@ -901,8 +948,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -914,47 +961,37 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/v1/admin/audit/{entry_id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing season",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get an audit log entry by id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "seasons"
+                    "admin audit"
                 ],
-                "summary": "Update",
+                "summary": "Get by id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
+                        "description": "entry id",
+                        "name": "entry_id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/seasons.UpdateSeasonRequestModel"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/seasons.SeasonModel"
+                            "$ref": "#/definitions/auditlog.EntryModel"
                         }
                     },
                     "400": {
@@ -965,59 +1002,12 @@ const docTemplate = `{
                     },
                     "401": {
                         "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
-                    },
-                    "404": {
-                        "description": "Not found",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal server error",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
-                    }
-                }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Delete an existing season",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "seasons"
-                ],
-                "summary": "Delete",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No content"
-                    },
-                    "400": {
-                        "description": "Bad request",
-                        "schema": {
-                            "$ref": "#/definitions/failure.ValidationFailure"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -1037,29 +1027,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}/leagues": {
+        "/v1/admin/invites": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get leagues",
+                "description": "Get the invite codes, the codes themselves are never shown again after creation",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "leagues"
+                    "admin invites"
                 ],
                 "summary": "Get",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "page",
@@ -1077,6 +1060,12 @@ const docTemplate = `{
                         "description": "order by",
                         "name": "order_by",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "usable, used, expired or revoked",
+                        "name": "status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1085,7 +1074,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/leagues.LeagueModel"
+                                "$ref": "#/definitions/invites.InviteModel"
                             }
                         }
                     },
@@ -1101,6 +1090,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -1115,7 +1110,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new league",
+                "description": "Create an invite code for the signup. The code is only returned once",
                 "consumes": [
                     "application/json"
                 ],
@@ -1123,32 +1118,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "leagues"
+                    "admin invites"
                 ],
                 "summary": "Create",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "description": "Request body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/leagues.CreateLeagueRequestModel"
+                            "$ref": "#/definitions/invites.CreateInviteRequestModel"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "OK",
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/leagues.LeagueModel"
+                            "$ref": "#/definitions/invites.CreatedInviteModel"
                         }
                     },
                     "400": {
@@ -1163,6 +1151,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -1172,43 +1166,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}/leagues/{league_id}": {
-            "get": {
+        "/v1/admin/invites/{invite_id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get league by id",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Revoke an invite code, the accounts already created with it are kept",
                 "tags": [
-                    "leagues"
+                    "admin invites"
                 ],
-                "summary": "Get by id",
+                "summary": "Revoke",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
+                        "description": "invite id",
+                        "name": "invite_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/leagues.LeagueModel"
-                        }
+                    "204": {
+                        "description": "No content"
                     },
                     "400": {
                         "description": "Bad request",
@@ -1222,6 +1203,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "404": {
                         "description": "Not found",
                         "schema": {
@@ -1235,60 +1222,31 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/v1/admin/roles": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing league",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get the roles with their permissions",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "leagues"
-                ],
-                "summary": "Update",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/leagues.UpdateLeagueRequestModel"
-                        }
-                    }
+                    "admin roles"
                 ],
+                "summary": "Get",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/leagues.LeagueModel"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request",
-                        "schema": {
-                            "$ref": "#/definitions/failure.ValidationFailure"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/roles.RoleModel"
+                            }
                         }
                     },
                     "401": {
@@ -1297,8 +1255,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -1311,39 +1269,40 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete an existing league",
+                "description": "Create a custom role, the permissions can't exceed the requester role permissions",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "leagues"
+                    "admin roles"
                 ],
-                "summary": "Delete",
+                "summary": "Create",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/roles.CreateRoleRequestModel"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No content"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/roles.RoleModel"
+                        }
                     },
                     "400": {
                         "description": "Bad request",
@@ -1357,8 +1316,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -1372,73 +1337,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}/leagues/{league_id}/matches": {
+        "/v1/admin/roles/{role_name}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get matches",
+                "description": "Get role by name",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "matches"
+                    "admin roles"
                 ],
-                "summary": "Get",
+                "summary": "Get by name",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
+                        "description": "role name",
+                        "name": "role_name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "page",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "per page",
-                        "name": "per_page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "order by",
-                        "name": "order_by",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/matches.MatchModel"
-                            }
+                            "$ref": "#/definitions/roles.RoleModel"
                         }
                     },
-                    "400": {
-                        "description": "Bad request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/failure.ValidationFailure"
+                            "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -1451,13 +1394,13 @@ const docTemplate = `{
                     }
                 }
             },
-            "post": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new match",
+                "description": "Replace the role description and permissions. The developer and service roles can't be modified",
                 "consumes": [
                     "application/json"
                 ],
@@ -1465,21 +1408,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "matches"
+                    "admin roles"
                 ],
-                "summary": "Create",
+                "summary": "Update",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
+                        "description": "role name",
+                        "name": "role_name",
                         "in": "path",
                         "required": true
                     },
@@ -1489,15 +1425,15 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/matches.CreateMatchRequestModel"
+                            "$ref": "#/definitions/roles.UpdateRoleRequestModel"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
+                    "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/matches.MatchModel"
+                            "$ref": "#/definitions/roles.RoleModel"
                         }
                     },
                     "400": {
@@ -1512,6 +1448,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -1519,67 +1473,134 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/v1/seasons/{season_id}/leagues/{league_id}/matches/{match_id}": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get match by id",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Delete a custom role which is not assigned to any account",
                 "tags": [
-                    "matches"
+                    "admin roles"
                 ],
-                "summary": "Get by id",
+                "summary": "Delete",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
+                        "description": "role name",
+                        "name": "role_name",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
                     },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
                     },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/system/database": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "match id",
-                        "name": "match_id",
-                        "in": "path",
-                        "required": true
+                        "BearerAuth": []
                     }
                 ],
+                "description": "Get the statistics of the database connection pool of the serving instance",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin system"
+                ],
+                "summary": "Database stats",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/matches.MatchModel"
+                            "$ref": "#/definitions/db.PoolStats"
                         }
                     },
-                    "400": {
-                        "description": "Bad request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/failure.ValidationFailure"
+                            "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get my api keys",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api keys"
+                ],
+                "summary": "Get",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apikeys.APIKeyModel"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -1592,13 +1613,13 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update match",
+                "description": "Create a personal api key. The key is only returned once, the scopes can't exceed the account role permissions",
                 "consumes": [
                     "application/json"
                 ],
@@ -1606,46 +1627,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "matches"
+                    "api keys"
                 ],
-                "summary": "Update",
+                "summary": "Create",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "match id",
-                        "name": "match_id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "description": "Request body",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/matches.UpdateMatchRequestModel"
+                            "$ref": "#/definitions/apikeys.CreateAPIKeyRequestModel"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/matches.MatchModel"
+                            "$ref": "#/definitions/apikeys.CreatedAPIKeyModel"
                         }
                     },
                     "400": {
@@ -1660,14 +1660,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -1681,62 +1675,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}/leagues/{league_id}/matches/{match_id}/score": {
-            "post": {
+        "/v1/api-keys/{api_key_id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Submit a match score",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Revoke one of my api keys",
                 "tags": [
-                    "matches"
+                    "api keys"
                 ],
-                "summary": "Score",
+                "summary": "Revoke",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "match id",
-                        "name": "match_id",
+                        "description": "api key id",
+                        "name": "api_key_id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Request body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/matches.SubmitMatchScoreRequestModel"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/matches.MatchModel"
-                        }
+                    "204": {
+                        "description": "No content"
                     },
                     "400": {
                         "description": "Bad request",
@@ -1756,12 +1718,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
-                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -1771,69 +1727,73 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}/leagues/{league_id}/players": {
+        "/v1/auth/oidc/authorize": {
             "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get league players",
+                "description": "Start a login through the configured OpenID Connect provider. The client opens the authorization url and forwards the returned code and state to /v1/auth/oidc/callback",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "players"
+                    "auth"
                 ],
-                "summary": "Get",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
+                "summary": "Start OIDC login",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.OidcAuthorizationResponseModel"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "page",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "per page",
-                        "name": "per_page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "order by",
-                        "name": "order_by",
-                        "in": "query"
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
                     },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/oidc/callback": {
+            "post": {
+                "description": "Complete the OpenID Connect login with the authorization code and get a new access token. Accounts with two-factor authentication enabled get a challenge which is completed at /v1/auth/tokens/2fa",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Complete OIDC login",
+                "parameters": [
                     {
-                        "type": "boolean",
-                        "description": "match available",
-                        "name": "match_available",
-                        "in": "query"
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.OidcCallbackRequestModel"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/players.PlayerModel"
-                            }
+                            "$ref": "#/definitions/auth.TokensResponseModel"
+                        }
+                    },
+                    "202": {
+                        "description": "Two-factor authentication required",
+                        "schema": {
+                            "$ref": "#/definitions/auth.TwoFactorChallengeResponseModel"
                         }
                     },
                     "400": {
@@ -1848,6 +1808,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -1857,49 +1829,55 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}/leagues/{league_id}/players/{player_id}": {
+        "/v1/auth/registration": {
             "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
+                "description": "Get the registration mode so the signup form knows whether to ask for an invite code",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Registration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.RegistrationResponseModel"
+                        }
                     }
+                }
+            }
+        },
+        "/v1/auth/signup": {
+            "post": {
+                "description": "Signup a new account. Depending on the registration mode an invite code is required or the account waits for approval before it can join leagues",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Get league player",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "players"
+                    "auth"
                 ],
-                "summary": "Get",
+                "summary": "Signup",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "player id",
-                        "name": "player_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.SignupRequestModel"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/players.PlayerModel"
+                            "$ref": "#/definitions/auth.TokensResponseModel"
                         }
                     },
                     "400": {
@@ -1908,8 +1886,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.ValidationFailure"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/failure.Failure"
                         }
@@ -1923,48 +1901,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}/leagues/{league_id}/players/{player_id}/assign": {
+        "/v1/auth/tokens/2fa": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+                "description": "Complete the login with a totp code or a recovery code and get a new access token",
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "players"
+                    "auth"
                 ],
-                "summary": "Assign",
+                "summary": "Verify two-factor",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "player id",
-                        "name": "player_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.VerifyTwoFactorRequestModel"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/players.PlayerModel"
+                            "$ref": "#/definitions/auth.TokensResponseModel"
                         }
                     },
                     "400": {
@@ -1979,12 +1944,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/failure.Failure"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
-                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -1992,66 +1951,49 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+            }
+        },
+        "/v1/auth/tokens/access": {
+            "post": {
+                "description": "Login and get a new access token. Accounts with two-factor authentication enabled get a challenge which is completed at /v1/auth/tokens/2fa",
+                "consumes": [
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "players"
+                    "auth"
                 ],
-                "summary": "Remove",
+                "summary": "Login",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "player id",
-                        "name": "player_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.LoginRequestModel"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/players.PlayerModel"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request",
-                        "schema": {
-                            "$ref": "#/definitions/failure.ValidationFailure"
+                            "$ref": "#/definitions/auth.TokensResponseModel"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "202": {
+                        "description": "Two-factor authentication required",
                         "schema": {
-                            "$ref": "#/definitions/failure.Failure"
+                            "$ref": "#/definitions/auth.TwoFactorChallengeResponseModel"
                         }
                     },
-                    "404": {
-                        "description": "Not found",
+                    "400": {
+                        "description": "Bad request",
                         "schema": {
-                            "$ref": "#/definitions/failure.Failure"
+                            "$ref": "#/definitions/failure.ValidationFailure"
                         }
                     },
                     "500": {
@@ -2063,36 +2005,62 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/seasons/{season_id}/leagues/{league_id}/standings": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+        "/v1/auth/tokens/refresh": {
+            "post": {
+                "description": "Get a refreshed access token",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Get standings",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "standings"
+                    "auth"
                 ],
-                "summary": "Get",
+                "summary": "Refresh token",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "season id",
-                        "name": "season_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.RefreshTokenRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.TokensResponseModel"
+                        }
                     },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/courts": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "league id",
-                        "name": "league_id",
-                        "in": "path",
-                        "required": true
-                    },
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get courts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "courts"
+                ],
+                "summary": "Get",
+                "parameters": [
                     {
                         "type": "integer",
                         "description": "page",
@@ -2110,41 +2078,3636 @@ const docTemplate = `{
                         "description": "order by",
                         "name": "order_by",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the list the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/courts.CourtModel"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new court",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "courts"
+                ],
+                "summary": "Create",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/courts.CreateCourtRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/courts.CourtModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/courts/{court_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get court by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "courts"
+                ],
+                "summary": "Get by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "court id",
+                        "name": "court_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/courts.CourtModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the court"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing court",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "courts"
+                ],
+                "summary": "Update",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "court id",
+                        "name": "court_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the court the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/courts.UpdateCourtRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/courts.CourtModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the updated court"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete an existing court",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "courts"
+                ],
+                "summary": "Delete",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "court id",
+                        "name": "court_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the court the deletion is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get my account and player profile data",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Get",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.MeModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the account representation"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update my account and player profile data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Update",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag of the account the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/me.UpdateMeRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.MeModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the updated account representation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete my account. The personal data is erased and the account can't be signed in to anymore, the played matches stay in the league history",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Delete",
+                "parameters": [
+                    {
+                        "description": "Request body, the password of the accounts which have one",
+                        "name": "body",
+                        "in": "body",
+                        "required": false,
+                        "schema": {
+                            "$ref": "#/definitions/me.DeleteMeRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/2fa": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the two-factor authentication status of my account",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Get two-factor status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.TwoFactorStatusModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disable two-factor authentication. Not allowed for roles where two-factor is mandatory",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Disable two-factor",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/me.DisableTwoFactorRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/2fa/enable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Confirm the two-factor enrollment with a code from the authenticator app. The recovery codes are returned only once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Enable two-factor",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/me.TwoFactorCodeRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.RecoveryCodesResponseModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/2fa/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generate a new totp secret. The enrollment has to be confirmed with a code before it's enabled",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Enroll two-factor",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.TwoFactorEnrollmentModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/2fa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the two-factor recovery codes, the previous codes stop working",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Regenerate recovery codes",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/me.TwoFactorCodeRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.RecoveryCodesResponseModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/email": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Request an email change. A confirmation token is sent to the new email address",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Request email change",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/me.RequestEmailChangeRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/me.EmailChangeResponseModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/email/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Confirm a requested email change with the token sent to the new email address",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Confirm email change",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/me.ConfirmEmailChangeRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.MeModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download all my personal data (account, player, matches, standings, sessions) as a json archive",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Export",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.ExportModel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update my password. All other sessions get logged out and a new token pair is returned",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Update password",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/me.UpdatePasswordRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.UpdatePasswordResponseModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/me/privacy": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set who can see my phone number, email, date of birth and physique: everyone, league mates, opponents with a scheduled match or nobody",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Update privacy",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/me.UpdatePrivacyRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/me.MeModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/players": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get players. The personal data is shown according to the privacy settings of each player",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "players"
+                ],
+                "summary": "Get",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "per page",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order by",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the list the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/players.PlayerModel"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/players/{player_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get player by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "players"
+                ],
+                "summary": "Get by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "player id",
+                        "name": "player_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/players.PlayerModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the player representation, it differs by the viewer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing player",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "players"
+                ],
+                "summary": "Update",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "player id",
+                        "name": "player_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the player the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/players.UpdatePlayerRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/players.PlayerModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the updated player representation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get seasons",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seasons"
+                ],
+                "summary": "Get",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "per page",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order by",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the list the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/seasons.SeasonModel"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new season",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seasons"
+                ],
+                "summary": "Create",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/seasons.CreateSeasonRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seasons.SeasonModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get season by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seasons"
+                ],
+                "summary": "Get by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seasons.SeasonModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the season"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing season",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seasons"
+                ],
+                "summary": "Update",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the season the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/seasons.UpdateSeasonRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/seasons.SeasonModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the updated season"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete an existing season",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seasons"
+                ],
+                "summary": "Delete",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the season the deletion is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get leagues",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leagues"
+                ],
+                "summary": "Get",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "per page",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order by",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the list the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/leagues.LeagueModel"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new league",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leagues"
+                ],
+                "summary": "Create",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/leagues.CreateLeagueRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/leagues.LeagueModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues/{league_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get league by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leagues"
+                ],
+                "summary": "Get by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/leagues.LeagueModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the league"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing league",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leagues"
+                ],
+                "summary": "Update",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the league the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/leagues.UpdateLeagueRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/leagues.LeagueModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the updated league"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete an existing league",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leagues"
+                ],
+                "summary": "Delete",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the league the deletion is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues/{league_id}/matches": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get matches",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "matches"
+                ],
+                "summary": "Get",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "per page",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order by",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the list the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/matches.MatchModel"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new match. The requester is player one unless player_one_id is set, which requires the update match permission in the league",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "matches"
+                ],
+                "summary": "Create",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/matches.CreateMatchRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/matches.MatchModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues/{league_id}/matches/{match_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get match by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "matches"
+                ],
+                "summary": "Get by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "match id",
+                        "name": "match_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/matches.MatchModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the match representation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update match",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "matches"
+                ],
+                "summary": "Update",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "match id",
+                        "name": "match_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the match the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/matches.UpdateMatchRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/matches.MatchModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the updated match representation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues/{league_id}/matches/{match_id}/score": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Correct the score of a match, the player statistics and standings are recalculated",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "matches"
+                ],
+                "summary": "Correct score",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "match id",
+                        "name": "match_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the match the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/matches.SubmitMatchScoreRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/matches.MatchModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the updated match representation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Submit a match score, by one of the players or by the ones managing the league's matches",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "matches"
+                ],
+                "summary": "Score",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "match id",
+                        "name": "match_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the match the change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/matches.SubmitMatchScoreRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/matches.MatchModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the updated match representation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "412": {
+                        "description": "Changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues/{league_id}/players": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get league players",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "players"
+                ],
+                "summary": "Get",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "per page",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order by",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "match available",
+                        "name": "match_available",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the list the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/players.PlayerModel"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues/{league_id}/players/{player_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get league player",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "players"
+                ],
+                "summary": "Get",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "player id",
+                        "name": "player_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/players.PlayerModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "tag of the player representation, it differs by the viewer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues/{league_id}/players/{player_id}/assign": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "players"
+                ],
+                "summary": "Assign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "player id",
+                        "name": "player_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/players.PlayerModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "players"
+                ],
+                "summary": "Remove",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "player id",
+                        "name": "player_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/players.PlayerModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/seasons/{season_id}/leagues/{league_id}/standings": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get standings",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "standings"
+                ],
+                "summary": "Get",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "season id",
+                        "name": "season_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "league id",
+                        "name": "league_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "per page",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order by",
+                        "name": "order_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "etag of the list the client has",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/standings.StandingModel"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/service-accounts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get service accounts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service accounts"
+                ],
+                "summary": "Get",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "per page",
+                        "name": "per_page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order by",
+                        "name": "order_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/serviceaccounts.ServiceAccountModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new service account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service accounts"
+                ],
+                "summary": "Create",
+                "parameters": [
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/serviceaccounts.CreateServiceAccountRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/serviceaccounts.ServiceAccountModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/service-accounts/{service_account_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get service account by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service accounts"
+                ],
+                "summary": "Get by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "service account id",
+                        "name": "service_account_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/serviceaccounts.ServiceAccountModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disable the service account and revoke all of its api keys",
+                "tags": [
+                    "service accounts"
+                ],
+                "summary": "Disable",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "service account id",
+                        "name": "service_account_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/service-accounts/{service_account_id}/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the api keys of the service account",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service accounts"
+                ],
+                "summary": "Get api keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "service account id",
+                        "name": "service_account_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/serviceaccounts.APIKeyModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create an api key for the service account. The key is only returned once, the scopes can't exceed the creator role permissions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "service accounts"
+                ],
+                "summary": "Create api key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "service account id",
+                        "name": "service_account_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Request body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/serviceaccounts.CreateAPIKeyRequestModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/serviceaccounts.CreatedAPIKeyModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/service-accounts/{service_account_id}/api-keys/{api_key_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke an api key of the service account",
+                "tags": [
+                    "service accounts"
+                ],
+                "summary": "Revoke api key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "service account id",
+                        "name": "service_account_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "api key id",
+                        "name": "api_key_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No content"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/failure.ValidationFailure"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/failure.Failure"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "accounts.AccountModel": {
+            "type": "object",
+            "properties": {
+                "approved_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deactivated_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "player_id": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "accounts.CreateScopedGrantRequestModel": {
+            "type": "object",
+            "properties": {
+                "league_id": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "season_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "accounts.ScopedGrantModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "granted_by": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "league_id": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "season_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "accounts.UpdateAccountRoleRequestModel": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "apikeys.APIKeyModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/standings.StandingModel"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request",
-                        "schema": {
-                            "$ref": "#/definitions/failure.ValidationFailure"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal server error",
-                        "schema": {
-                            "$ref": "#/definitions/failure.Failure"
-                        }
+                }
+            }
+        },
+        "apikeys.CreateAPIKeyRequestModel": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        },
+        "apikeys.CreatedAPIKeyModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "auditlog.EntryModel": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor_account_id": {
+                    "type": "string"
+                },
+                "actor_api_key_id": {
+                    "type": "string"
+                },
+                "actor_name": {
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "resource_id": {
+                    "type": "string"
+                },
+                "resource_type": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
         "auth.LoginRequestModel": {
             "type": "object",
             "properties": {
@@ -2156,6 +5719,35 @@ const docTemplate = `{
                 }
             }
         },
+        "auth.OidcAuthorizationResponseModel": {
+            "type": "object",
+            "properties": {
+                "authorization_url": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.OidcCallbackRequestModel": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "invite_code": {
+                    "description": "only used when the login provisions a new account",
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
         "auth.RefreshTokenRequestModel": {
             "type": "object",
             "properties": {
@@ -2164,6 +5756,22 @@ const docTemplate = `{
                 }
             }
         },
+        "auth.RegistrationResponseModel": {
+            "type": "object",
+            "properties": {
+                "invite_code_required": {
+                    "type": "boolean"
+                },
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "open",
+                        "invite-only",
+                        "approval-required"
+                    ]
+                }
+            }
+        },
         "auth.SignupRequestModel": {
             "type": "object",
             "properties": {
@@ -2176,6 +5784,10 @@ const docTemplate = `{
                 "gender": {
                     "type": "string"
                 },
+                "invite_code": {
+                    "description": "required in the invite-only registration mode",
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
                 },
@@ -2198,6 +5810,31 @@ const docTemplate = `{
                 }
             }
         },
+        "auth.TwoFactorChallengeResponseModel": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.VerifyTwoFactorRequestModel": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "type": "string"
+                }
+            }
+        },
         "courts.CourtModel": {
             "type": "object",
             "properties": {
@@ -2215,64 +5852,219 @@ const docTemplate = `{
                         }
                     }
                 },
-                "id": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "courts.CreateCourtRequestModel": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "courts.UpdateCourtRequestModel": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.PoolStats": {
+            "type": "object",
+            "properties": {
+                "acquire_count": {
+                    "type": "integer"
+                },
+                "acquire_duration_ns": {
+                    "type": "integer"
+                },
+                "acquired_conns": {
+                    "type": "integer"
+                },
+                "canceled_acquire_count": {
+                    "type": "integer"
+                },
+                "constructing_conns": {
+                    "type": "integer"
+                },
+                "empty_acquire_count": {
+                    "type": "integer"
+                },
+                "idle_conns": {
+                    "type": "integer"
+                },
+                "max_conns": {
+                    "type": "integer"
+                },
+                "max_idle_destroy_count": {
+                    "type": "integer"
+                },
+                "max_lifetime_destroy_count": {
+                    "type": "integer"
+                },
+                "new_conns_count": {
+                    "type": "integer"
+                },
+                "total_conns": {
+                    "type": "integer"
+                }
+            }
+        },
+        "failure.Failure": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "failure.InvalidField": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "location": {
+                    "description": "location specifies where the field is conmming from (path, query, body)",
                     "type": "string"
                 },
-                "name": {
+                "message": {
                     "type": "string"
                 }
             }
         },
-        "courts.CreateCourtRequestModel": {
+        "failure.ValidationFailure": {
             "type": "object",
             "properties": {
-                "name": {
+                "invalid_fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/failure.InvalidField"
+                    }
+                },
+                "message": {
                     "type": "string"
                 }
             }
         },
-        "courts.UpdateCourtRequestModel": {
+        "health.CheckResultModel": {
             "type": "object",
             "properties": {
-                "name": {
+                "error": {
                     "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "ok",
+                        "fail"
+                    ]
                 }
             }
         },
-        "failure.Failure": {
+        "health.ReportModel": {
             "type": "object",
             "properties": {
-                "message": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResultModel"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "ok",
+                        "fail"
+                    ]
+                }
+            }
+        },
+        "invites.CreateInviteRequestModel": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_uses": {
+                    "type": "integer"
+                },
+                "note": {
                     "type": "string"
                 }
             }
         },
-        "failure.InvalidField": {
+        "invites.CreatedInviteModel": {
             "type": "object",
             "properties": {
-                "field": {
+                "code": {
                     "type": "string"
                 },
-                "location": {
-                    "description": "location specifies where the field is conmming from (path, query, body)",
+                "created_at": {
                     "type": "string"
                 },
-                "message": {
+                "creator_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "max_uses": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "revoked_at": {
                     "type": "string"
+                },
+                "uses": {
+                    "type": "integer"
                 }
             }
         },
-        "failure.ValidationFailure": {
+        "invites.InviteModel": {
             "type": "object",
             "properties": {
-                "invalid_fields": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/failure.InvalidField"
-                    }
+                "created_at": {
+                    "type": "string"
                 },
-                "message": {
+                "creator_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "max_uses": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "revoked_at": {
                     "type": "string"
+                },
+                "uses": {
+                    "type": "integer"
                 }
             }
         },
@@ -2318,6 +6110,9 @@ const docTemplate = `{
                 },
                 "title": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
@@ -2360,6 +6155,9 @@ const docTemplate = `{
                 "court_id": {
                     "type": "string"
                 },
+                "player_one_id": {
+                    "type": "string"
+                },
                 "player_two_id": {
                     "type": "string"
                 },
@@ -2412,6 +6210,9 @@ const docTemplate = `{
                 "season": {
                     "$ref": "#/definitions/matches.SeasonModel"
                 },
+                "version": {
+                    "type": "integer"
+                },
                 "winner": {
                     "$ref": "#/definitions/matches.PlayerModel"
                 }
@@ -2461,20 +6262,239 @@ const docTemplate = `{
                 }
             }
         },
+        "me.ConfirmEmailChangeRequestModel": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
         "me.CurrentLeagueModel": {
             "type": "object",
             "properties": {
                 "id": {
                     "type": "string"
                 },
-                "title": {
-                    "type": "string"
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.DeleteMeRequestModel": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.DisableTwoFactorRequestModel": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.EmailChangeResponseModel": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.ExportAPIKeyModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "me.ExportIdentityModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "issuer": {
+                    "type": "string"
+                },
+                "last_login_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.ExportMatchModel": {
+            "type": "object",
+            "properties": {
+                "court": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "league": {
+                    "type": "string"
+                },
+                "player_one_name": {
+                    "type": "string"
+                },
+                "player_two_name": {
+                    "type": "string"
+                },
+                "scheduled_at": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "string"
+                },
+                "season": {
+                    "type": "string"
+                },
+                "won": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "me.ExportModel": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/me.MeModel"
+                },
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/me.ExportAPIKeyModel"
+                    }
+                },
+                "exported_at": {
+                    "type": "string"
+                },
+                "identities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/me.ExportIdentityModel"
+                    }
+                },
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/me.ExportMatchModel"
+                    }
+                },
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/me.ExportSessionModel"
+                    }
+                },
+                "standings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/me.ExportStandingModel"
+                    }
+                }
+            }
+        },
+        "me.ExportSessionModel": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "is_revoked": {
+                    "type": "boolean"
+                },
+                "issued_at": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.ExportStandingModel": {
+            "type": "object",
+            "properties": {
+                "games_lost": {
+                    "type": "integer"
+                },
+                "games_won": {
+                    "type": "integer"
+                },
+                "league": {
+                    "type": "string"
+                },
+                "matches_played": {
+                    "type": "integer"
+                },
+                "matches_won": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "season": {
+                    "type": "string"
+                },
+                "sets_lost": {
+                    "type": "integer"
+                },
+                "sets_won": {
+                    "type": "integer"
                 }
             }
         },
         "me.MeModel": {
             "type": "object",
             "properties": {
+                "approved_at": {
+                    "description": "null while the account waits for approval",
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -2534,6 +6554,9 @@ const docTemplate = `{
                 "matches_won": {
                     "type": "integer"
                 },
+                "privacy": {
+                    "$ref": "#/definitions/privacy.Settings"
+                },
                 "racket": {
                     "type": "string"
                 },
@@ -2545,6 +6568,64 @@ const docTemplate = `{
                 }
             }
         },
+        "me.RecoveryCodesResponseModel": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "me.RequestEmailChangeRequestModel": {
+            "type": "object",
+            "properties": {
+                "new_email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.TwoFactorCodeRequestModel": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.TwoFactorEnrollmentModel": {
+            "type": "object",
+            "properties": {
+                "provisioning_uri": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.TwoFactorStatusModel": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "enabled_at": {
+                    "type": "string"
+                },
+                "recovery_codes_remaining": {
+                    "type": "integer"
+                },
+                "required": {
+                    "type": "boolean"
+                }
+            }
+        },
         "me.UpdateMeRequestModel": {
             "type": "object",
             "properties": {
@@ -2553,14 +6634,95 @@ const docTemplate = `{
                 }
             }
         },
+        "me.UpdatePasswordRequestModel": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                },
+                "repeated_new_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.UpdatePasswordResponseModel": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "me.UpdatePrivacyRequestModel": {
+            "type": "object",
+            "properties": {
+                "dob": {
+                    "type": "string",
+                    "enum": [
+                        "everyone",
+                        "league_mates",
+                        "opponents",
+                        "nobody"
+                    ]
+                },
+                "email": {
+                    "type": "string",
+                    "enum": [
+                        "everyone",
+                        "league_mates",
+                        "opponents",
+                        "nobody"
+                    ]
+                },
+                "phone_number": {
+                    "type": "string",
+                    "enum": [
+                        "everyone",
+                        "league_mates",
+                        "opponents",
+                        "nobody"
+                    ]
+                },
+                "physique": {
+                    "type": "string",
+                    "enum": [
+                        "everyone",
+                        "league_mates",
+                        "opponents",
+                        "nobody"
+                    ]
+                }
+            }
+        },
         "players.AccountModel": {
             "type": "object",
             "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "dob": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
+                },
+                "phone_number": {
+                    "type": "string"
                 }
             }
         },
@@ -2614,6 +6776,9 @@ const docTemplate = `{
                 "seasons_played": {
                     "type": "integer"
                 },
+                "version": {
+                    "type": "integer"
+                },
                 "weight": {
                     "type": "number"
                 }
@@ -2636,6 +6801,101 @@ const docTemplate = `{
                 }
             }
         },
+        "privacy.Settings": {
+            "type": "object",
+            "properties": {
+                "dob": {
+                    "type": "string",
+                    "enum": [
+                        "everyone",
+                        "league_mates",
+                        "opponents",
+                        "nobody"
+                    ]
+                },
+                "email": {
+                    "type": "string",
+                    "enum": [
+                        "everyone",
+                        "league_mates",
+                        "opponents",
+                        "nobody"
+                    ]
+                },
+                "phone_number": {
+                    "type": "string",
+                    "enum": [
+                        "everyone",
+                        "league_mates",
+                        "opponents",
+                        "nobody"
+                    ]
+                },
+                "physique": {
+                    "type": "string",
+                    "enum": [
+                        "everyone",
+                        "league_mates",
+                        "opponents",
+                        "nobody"
+                    ]
+                }
+            }
+        },
+        "roles.CreateRoleRequestModel": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "roles.RoleModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "is_system": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "roles.UpdateRoleRequestModel": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "seasons.CreateSeasonRequestModel": {
             "type": "object",
             "properties": {
@@ -2684,6 +6944,9 @@ const docTemplate = `{
                 },
                 "title": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
@@ -2704,6 +6967,135 @@ const docTemplate = `{
                 }
             }
         },
+        "serviceaccounts.APIKeyModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "serviceaccounts.CreateAPIKeyRequestModel": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "serviceaccounts.CreateServiceAccountRequestModel": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "serviceaccounts.CreatedAPIKeyModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "serviceaccounts.ServiceAccountCreatorModel": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "serviceaccounts.ServiceAccountModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "creator": {
+                    "$ref": "#/definitions/serviceaccounts.ServiceAccountCreatorModel"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "disabled_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
         "standings.StandingModel": {
             "type": "object",
             "properties": {
@@ -11,6 +11,8 @@ var (
 	ErrBadRequest = errors.New("bad request")
 	ErrDuplicate  = errors.New("duplicate")
 	ErrCantModify = errors.New("can't modify")
+	ErrMismatch   = errors.New("mismatch")
+	ErrStale      = errors.New("stale")
 
 	ErrUnauthorized = errors.New("not authorized")
 	ErrForbidden    = errors.New("forbidden")
@@ -0,0 +1,135 @@
+// Package health serves the liveness and readiness probes of the api.
+//
+// /healthz only tells the process is up and serving, it never checks the dependencies so an
+// outage of the db doesn't get the instances restarted. /readyz runs every check concurrently
+// and fails once they all reported if any of them failed, the orchestrator stops routing
+// traffic to the instance until it's ready again.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/response"
+	"github.com/markovidakovic/gdsi/server/router"
+)
+
+// the longest a single check may take before it's reported as failed
+const checkTimeout = 2 * time.Second
+
+// check statuses
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports the health of a dependency, a nil error means healthy
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type CheckResultModel struct {
+	Status    string  `json:"status" enums:"ok,fail"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type ReportModel struct {
+	Status string                      `json:"status" enums:"ok,fail"`
+	Checks map[string]CheckResultModel `json:"checks,omitempty"`
+}
+
+type api struct {
+	checks []Check
+}
+
+var _ router.Mounter = (*api)(nil)
+
+// New returns the probes, the readiness runs the checks
+func New(checks ...Check) *api {
+	return &api{
+		checks: checks,
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Get("/healthz", a.liveness)
+	r.Get("/readyz", a.readiness)
+}
+
+// @Summary Liveness
+// @Description Report that the process is alive, the dependencies are not checked
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.ReportModel "OK"
+// @Router /healthz [get]
+func (a *api) liveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, ReportModel{Status: StatusOk})
+}
+
+// @Summary Readiness
+// @Description Check the dependencies of the api, each check reports its status and latency
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.ReportModel "OK"
+// @Failure 503 {object} health.ReportModel "Service unavailable"
+// @Router /readyz [get]
+func (a *api) readiness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, a.run(r.Context()))
+}
+
+// run runs the checks concurrently, every check gets its own timeout
+func (a *api) run(ctx context.Context) ReportModel {
+	report := ReportModel{
+		Status: StatusOk,
+		Checks: make(map[string]CheckResultModel, len(a.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range a.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.Check(ctx)
+			result := CheckResultModel{
+				Status:    StatusOk,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[c.Name] = result
+			if err != nil {
+				report.Status = StatusFail
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+func writeReport(w http.ResponseWriter, report ReportModel) {
+	// the probes must see the current state
+	w.Header().Set("Cache-Control", "no-store")
+
+	status := http.StatusOK
+	if report.Status != StatusOk {
+		status = http.StatusServiceUnavailable
+	}
+	response.WriteSuccess(w, status, report)
+}
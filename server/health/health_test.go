@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func serve(t *testing.T, a *api, path string) (int, ReportModel) {
+	t.Helper()
+
+	r := chi.NewRouter()
+	a.Mount(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("expected the probe not to be cached, got %q", cc)
+	}
+
+	var report ReportModel
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decoding the report: %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestLiveness(t *testing.T) {
+	// the liveness never runs the checks
+	a := New(Check{Name: "database", Check: func(ctx context.Context) error {
+		return errors.New("down")
+	}})
+
+	status, report := serve(t, a, "/healthz")
+	if status != http.StatusOK || report.Status != StatusOk {
+		t.Errorf("expected 200 ok, got %d %s", status, report.Status)
+	}
+	if len(report.Checks) != 0 {
+		t.Errorf("expected no checks, got %v", report.Checks)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	ok := Check{Name: "database", Check: func(ctx context.Context) error { return nil }}
+	failing := Check{Name: "workers", Check: func(ctx context.Context) error { return errors.New("worker stopped") }}
+
+	status, report := serve(t, New(ok), "/readyz")
+	if status != http.StatusOK || report.Status != StatusOk {
+		t.Errorf("expected 200 ok, got %d %s", status, report.Status)
+	}
+	if report.Checks["database"].Status != StatusOk {
+		t.Errorf("expected the database check ok, got %v", report.Checks["database"])
+	}
+
+	status, report = serve(t, New(ok, failing), "/readyz")
+	if status != http.StatusServiceUnavailable || report.Status != StatusFail {
+		t.Errorf("expected 503 fail, got %d %s", status, report.Status)
+	}
+	if res := report.Checks["workers"]; res.Status != StatusFail || res.Error != "worker stopped" {
+		t.Errorf("expected the workers check failed with its error, got %v", res)
+	}
+	if report.Checks["database"].Status != StatusOk {
+		t.Errorf("expected the database check ok, got %v", report.Checks["database"])
+	}
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	slow := Check{Name: "database", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	status, report := serve(t, New(slow), "/readyz")
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", status)
+	}
+	if report.Checks["database"].Status != StatusFail {
+		t.Errorf("expected the timed out check failed, got %v", report.Checks["database"])
+	}
+}
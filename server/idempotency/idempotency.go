@@ -0,0 +1,167 @@
+// Package idempotency lets the clients retry the requests which aren't idempotent.
+//
+// A client sends a post with an Idempotency-Key header, a value it generates per operation and
+// reuses on every retry of that operation. The first request reserves the key for the account and
+// its response is stored once the handler is done, the retries are answered with the stored
+// response instead of running the handler again. The key is bound to the method, path and body
+// of the first request, reusing it for a different request is rejected. A stored response is kept
+// for the configured ttl, the responses with a 5xx status aren't stored so the operation can be
+// retried. Neither are the 401 and 403 responses, the retry with the fixed credentials or the
+// granted permission runs the handler. The reservation itself only lasts the shorter reserve ttl,
+// a request cut off by a crash of the instance doesn't block its key for longer than that. A
+// request which outlived its reservation doesn't store its response over the one of a retry
+// which claimed the key in the meantime.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/response"
+)
+
+const (
+	// Header carries the key of the request
+	Header = "Idempotency-Key"
+	// ReplayedHeader marks the responses replayed from an earlier request
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+const maxKeyLength = 255
+
+// storedResponse is the response stored for the key, the status code is 0 while the first
+// request is running. Besides the content type only the etag and the location headers are
+// kept, the others are set by the middlewares again
+type storedResponse struct {
+	fingerprint string
+	statusCode  int
+	contentType string
+	etag        string
+	location    string
+	body        []byte
+}
+
+// Keys replays the stored response for the posts with an Idempotency-Key header. It must come
+// after the account is resolved, the keys are scoped to the account. The response is stored for
+// the ttl, the key is reserved for the reserve ttl while the request runs. A zero ttl disables it
+func Keys(repo Repository, ttl, reserveTtl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			accountId, _ := r.Context().Value(middleware.AccountIdCtxKey).(string)
+			if r.Method != http.MethodPost || key == "" || accountId == "" || ttl == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				response.WriteFailure(w, failure.New(fmt.Sprintf("idempotency key can't be longer than %d characters", maxKeyLength), failure.ErrBadRequest))
+				return
+			}
+
+			// the body is held in memory for the fingerprint, the same limit as the handlers applies
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, middleware.MaxBodyBytes))
+			if err != nil {
+				response.WriteFailure(w, failure.New("unable to read the request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := fingerprintOf(r, body)
+
+			reservation := uuid.NewString()
+			stored, err := repo.reserve(r.Context(), accountId, key, reservation, fingerprint, time.Now().Add(reserveTtl))
+			if err != nil {
+				if f, ok := err.(*failure.Failure); ok {
+					response.WriteFailure(w, f)
+					return
+				}
+				response.WriteFailure(w, failure.New("unable to reserve idempotency key", fmt.Errorf("%w -> %v", failure.ErrInternal, err)))
+				return
+			}
+			if stored != nil {
+				replay(w, stored, fingerprint)
+				return
+			}
+
+			// the response is stored even if the client is gone, its retry gets it then
+			ctx := context.WithoutCancel(r.Context())
+			done := false
+			defer func() {
+				// the handler panicked, the key is freed for the retry
+				if !done {
+					if err := repo.release(ctx, accountId, key, reservation); err != nil {
+						slog.ErrorContext(ctx, "unable to release the idempotency key", "error", err)
+					}
+				}
+			}()
+
+			var buf bytes.Buffer
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+			next.ServeHTTP(ww, r)
+			done = true
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError || status == http.StatusUnauthorized || status == http.StatusForbidden {
+				err = repo.release(ctx, accountId, key, reservation)
+			} else {
+				err = repo.complete(ctx, accountId, key, reservation, &storedResponse{
+					statusCode:  status,
+					contentType: ww.Header().Get("Content-Type"),
+					etag:        ww.Header().Get("ETag"),
+					location:    ww.Header().Get("Location"),
+					body:        buf.Bytes(),
+				}, time.Now().Add(ttl))
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "unable to store the idempotent response", "error", err)
+			}
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, stored *storedResponse, fingerprint string) {
+	if stored.fingerprint != fingerprint {
+		response.WriteFailure(w, failure.New("idempotency key was already used for a different request", failure.ErrMismatch))
+		return
+	}
+	if stored.statusCode == 0 {
+		response.WriteFailure(w, failure.New("request with this idempotency key is still in progress", failure.ErrDuplicate))
+		return
+	}
+
+	for name, val := range map[string]string{"Content-Type": stored.contentType, "ETag": stored.etag, "Location": stored.location} {
+		if val != "" {
+			w.Header().Set(name, val)
+		}
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.statusCode)
+	_, _ = w.Write(stored.body)
+}
+
+// fingerprintOf identifies the request the key was first used for
+func fingerprintOf(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewPurger returns the task deleting the expired keys, it's run periodically by the server
+func NewPurger(repo Repository) func(ctx context.Context) error {
+	return repo.deleteExpired
+}
@@ -0,0 +1,232 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/middleware"
+)
+
+// counter answers with the number of the calls, the status is taken from the body
+type counter struct {
+	calls int
+}
+
+func (c *counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.calls++
+	status := http.StatusCreated
+	if r.ContentLength > 0 {
+		_, _ = fmt.Fscan(r.Body, &status)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, c.calls))
+	w.Header().Set("Location", fmt.Sprintf("/matches/%d", c.calls))
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"call":%d}`, c.calls)
+}
+
+func send(h http.Handler, accountId, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/matches", strings.NewReader(body))
+	req.Header.Set(Header, key)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.AccountIdCtxKey, accountId))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestKeys(t *testing.T) {
+	t.Run("RetryIsReplayed", func(t *testing.T) {
+		next := &counter{}
+		h := Keys(NewMemStore(memdb.New()), time.Hour, time.Minute)(next)
+
+		first := send(h, "alice", "k1", "")
+		retry := send(h, "alice", "k1", "")
+		if next.calls != 1 {
+			t.Fatalf("expected the handler to run once, ran %d times", next.calls)
+		}
+		if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+			t.Errorf("expected the first response, got %d %s", retry.Code, retry.Body)
+		}
+		if retry.Header().Get(ReplayedHeader) != "true" || retry.Header().Get("Content-Type") != "application/json" ||
+			retry.Header().Get("ETag") != `"1"` || retry.Header().Get("Location") != "/matches/1" {
+			t.Errorf("expected the replayed headers, got %v", retry.Header())
+		}
+		if first.Header().Get(ReplayedHeader) != "" {
+			t.Error("expected the first response not to be marked as replayed")
+		}
+	})
+
+	t.Run("DifferentRequestIsRejected", func(t *testing.T) {
+		next := &counter{}
+		h := Keys(NewMemStore(memdb.New()), time.Hour, time.Minute)(next)
+
+		send(h, "alice", "k1", "201")
+		if rec := send(h, "alice", "k1", "200"); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected 422 for the reused key, got %d", rec.Code)
+		}
+		if next.calls != 1 {
+			t.Errorf("expected the handler to run once, ran %d times", next.calls)
+		}
+	})
+
+	t.Run("KeysAreScopedToAccount", func(t *testing.T) {
+		next := &counter{}
+		h := Keys(NewMemStore(memdb.New()), time.Hour, time.Minute)(next)
+
+		send(h, "alice", "k1", "")
+		if rec := send(h, "bob", "k1", ""); rec.Header().Get(ReplayedHeader) != "" {
+			t.Error("expected the key of another account not to be replayed")
+		}
+		if next.calls != 2 {
+			t.Errorf("expected the handler to run for both accounts, ran %d times", next.calls)
+		}
+	})
+
+	t.Run("ServerErrorIsNotStored", func(t *testing.T) {
+		next := &counter{}
+		h := Keys(NewMemStore(memdb.New()), time.Hour, time.Minute)(next)
+
+		send(h, "alice", "k1", "503")
+		if rec := send(h, "alice", "k1", "503"); rec.Header().Get(ReplayedHeader) != "" {
+			t.Error("expected the failed request to run again")
+		}
+		if next.calls != 2 {
+			t.Errorf("expected the handler to run twice, ran %d times", next.calls)
+		}
+	})
+
+	t.Run("AuthFailureIsNotStored", func(t *testing.T) {
+		next := &counter{}
+		h := Keys(NewMemStore(memdb.New()), time.Hour, time.Minute)(next)
+
+		// the retry after the permission is granted runs the handler
+		send(h, "alice", "k1", "403")
+		if rec := send(h, "alice", "k1", "403"); rec.Header().Get(ReplayedHeader) != "" {
+			t.Error("expected the forbidden request to run again")
+		}
+		if next.calls != 2 {
+			t.Errorf("expected the handler to run twice, ran %d times", next.calls)
+		}
+	})
+
+	t.Run("RunningRequestConflicts", func(t *testing.T) {
+		repo := NewMemStore(memdb.New())
+		next := &counter{}
+		h := Keys(repo, time.Hour, time.Minute)(next)
+
+		req := httptest.NewRequest(http.MethodPost, "/matches", nil)
+		_, err := repo.reserve(context.Background(), "alice", "k1", "first", fingerprintOf(req, nil), time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rec := send(h, "alice", "k1", ""); rec.Code != http.StatusConflict {
+			t.Errorf("expected 409 while the first request runs, got %d", rec.Code)
+		}
+	})
+
+	t.Run("ExpiredKeyIsReused", func(t *testing.T) {
+		next := &counter{}
+		h := Keys(NewMemStore(memdb.New()), time.Nanosecond, time.Nanosecond)(next)
+
+		send(h, "alice", "k1", "")
+		time.Sleep(time.Millisecond)
+		send(h, "alice", "k1", "")
+		if next.calls != 2 {
+			t.Errorf("expected the expired key to run the handler again, ran %d times", next.calls)
+		}
+	})
+
+	// the key of a request cut off by a crash is only blocked for the reserve ttl
+	t.Run("ReservationExpiresBeforeResponse", func(t *testing.T) {
+		m := memdb.New()
+		id := memdb.IdempotencyKeyId{AccountId: "alice", Key: "k1"}
+		expiresAt := func() time.Time {
+			var at time.Time
+			_ = m.Read(nil, func(t *memdb.Tables) error {
+				at = t.IdempotencyKeys[id].ExpiresAt
+				return nil
+			})
+			return at
+		}
+
+		var reservedUntil time.Time
+		h := Keys(NewMemStore(m), time.Hour, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reservedUntil = expiresAt()
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		start := time.Now()
+		send(h, "alice", "k1", "")
+		if reservedUntil.Before(start) || reservedUntil.After(start.Add(2*time.Minute)) {
+			t.Errorf("expected the key reserved for a minute, until %v", reservedUntil)
+		}
+		if storedUntil := expiresAt(); storedUntil.Before(start.Add(59 * time.Minute)) {
+			t.Errorf("expected the response kept for the ttl, until %v", storedUntil)
+		}
+	})
+
+	t.Run("LargeBodyIsRejected", func(t *testing.T) {
+		next := &counter{}
+		h := Keys(NewMemStore(memdb.New()), time.Hour, time.Minute)(next)
+
+		if rec := send(h, "alice", "k1", strings.Repeat(" ", middleware.MaxBodyBytes+1)); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for the body over the limit, got %d", rec.Code)
+		}
+		if next.calls != 0 {
+			t.Errorf("expected the handler not to run, ran %d times", next.calls)
+		}
+	})
+
+	// the retry claimed the expired reservation, the late first response doesn't replace its own
+	t.Run("LostReservationIsNotStored", func(t *testing.T) {
+		calls := 0
+		var h http.Handler
+		h = Keys(NewMemStore(memdb.New()), time.Hour, time.Nanosecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			call := calls
+			if call == 1 {
+				time.Sleep(time.Millisecond)
+				send(h, "alice", "k1", "")
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"call":%d}`, call)
+		}))
+
+		send(h, "alice", "k1", "")
+		rec := send(h, "alice", "k1", "")
+		if calls != 2 {
+			t.Fatalf("expected the handler to run twice, ran %d times", calls)
+		}
+		if rec.Header().Get(ReplayedHeader) != "true" || rec.Body.String() != `{"call":2}` {
+			t.Errorf("expected the response of the retry, got %s", rec.Body)
+		}
+	})
+
+	t.Run("StoreErrorFails", func(t *testing.T) {
+		next := &counter{}
+		h := Keys(failingRepo{}, time.Hour, time.Minute)(next)
+
+		if rec := send(h, "alice", "k1", ""); rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected 500 when the key can't be reserved, got %d", rec.Code)
+		}
+		if next.calls != 0 {
+			t.Errorf("expected the handler not to run, ran %d times", next.calls)
+		}
+	})
+}
+
+// failingRepo fails to reserve with an error which isn't a failure
+type failingRepo struct {
+	Repository
+}
+
+func (failingRepo) reserve(ctx context.Context, accountId, key, reservation, fingerprint string, expiresAt time.Time) (*storedResponse, error) {
+	return nil, errors.New("connection reset")
+}
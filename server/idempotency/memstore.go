@@ -0,0 +1,114 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{db}
+}
+
+func (s *memStore) reserve(ctx context.Context, accountId, key, reservation, fingerprint string, expiresAt time.Time) (*storedResponse, error) {
+	id := memdb.IdempotencyKeyId{AccountId: accountId, Key: key}
+	now := s.db.Now()
+
+	var dest *storedResponse
+	err := s.db.Write(nil, func(t *memdb.Tables) error {
+		if ik, ok := t.IdempotencyKeys[id]; ok && !ik.ExpiresAt.Before(now) {
+			dest = &storedResponse{
+				fingerprint: ik.Fingerprint,
+				statusCode:  int(ik.StatusCode.Int32),
+				contentType: ik.ContentType.String,
+				etag:        ik.Etag.String,
+				location:    ik.Location.String,
+				body:        ik.Body,
+			}
+			return nil
+		}
+		t.IdempotencyKeys[id] = db.IdempotencyKey{
+			AccountId:   accountId,
+			Key:         key,
+			Reservation: reservation,
+			Fingerprint: fingerprint,
+			ExpiresAt:   expiresAt,
+			CreatedAt:   now,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to reserve idempotency key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) complete(ctx context.Context, accountId, key, reservation string, res *storedResponse, expiresAt time.Time) error {
+	id := memdb.IdempotencyKeyId{AccountId: accountId, Key: key}
+
+	var owned bool
+	err := s.db.Write(nil, func(t *memdb.Tables) error {
+		ik, ok := t.IdempotencyKeys[id]
+		owned = ok && ik.Reservation == reservation && !ik.StatusCode.Valid
+		if owned {
+			ik.StatusCode = sql.NullInt32{Int32: int32(res.statusCode), Valid: true}
+			ik.ContentType = sql.NullString{String: res.contentType, Valid: res.contentType != ""}
+			ik.Etag = sql.NullString{String: res.etag, Valid: res.etag != ""}
+			ik.Location = sql.NullString{String: res.location, Valid: res.location != ""}
+			ik.Body = slices.Clone(res.body)
+			ik.ExpiresAt = expiresAt
+			t.IdempotencyKeys[id] = ik
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to store idempotent response", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !owned {
+		return failure.New("idempotency key reservation expired before the response was stored", failure.ErrStale)
+	}
+
+	return nil
+}
+
+func (s *memStore) release(ctx context.Context, accountId, key, reservation string) error {
+	id := memdb.IdempotencyKeyId{AccountId: accountId, Key: key}
+	err := s.db.Write(nil, func(t *memdb.Tables) error {
+		if ik, ok := t.IdempotencyKeys[id]; ok && ik.Reservation == reservation && !ik.StatusCode.Valid {
+			delete(t.IdempotencyKeys, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to release idempotency key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) deleteExpired(ctx context.Context) error {
+	now := s.db.Now()
+	err := s.db.Write(nil, func(t *memdb.Tables) error {
+		maps.DeleteFunc(t.IdempotencyKeys, func(_ memdb.IdempotencyKeyId, ik db.IdempotencyKey) bool { return ik.ExpiresAt.Before(now) })
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to delete expired idempotency keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+)
+
+// Repository holds the idempotency keys, each reserved by its first request and later storing
+// the response the retries are answered with
+type Repository interface {
+	reserve(ctx context.Context, accountId, key, reservation, fingerprint string, expiresAt time.Time) (*storedResponse, error)
+	complete(ctx context.Context, accountId, key, reservation string, res *storedResponse, expiresAt time.Time) error
+	release(ctx context.Context, accountId, key, reservation string) error
+	deleteExpired(ctx context.Context) error
+}
+
+type store struct {
+	db *db.Conn
+}
+
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
+		db,
+	}
+}
+
+// reserve claims the key for the request, an expired key is claimed again. The response stored
+// for the key is returned when it's held by an earlier request, nil when the key was claimed
+func (s *store) reserve(ctx context.Context, accountId, key, reservation, fingerprint string, expiresAt time.Time) (*storedResponse, error) {
+	sql1 := `
+		insert into idempotency_key (account_id, key, reservation, fingerprint, expires_at)
+		values ($1, $2, $3, $4, $5)
+		on conflict (account_id, key) do update
+		set reservation = excluded.reservation, fingerprint = excluded.fingerprint, status_code = null, content_type = null,
+			etag = null, location = null, body = null, expires_at = excluded.expires_at, created_at = current_timestamp
+		where idempotency_key.expires_at < current_timestamp
+		returning true
+	`
+
+	var claimed bool
+	err := s.db.QueryRow(ctx, sql1, accountId, key, reservation, fingerprint, expiresAt).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, failure.New("unable to reserve idempotency key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	sql2 := `
+		select fingerprint, status_code, content_type, etag, location, body
+		from idempotency_key
+		where account_id = $1 and key = $2
+	`
+
+	var dest storedResponse
+	var statusCode sql.NullInt32
+	var contentType, etag, location sql.NullString
+	err = s.db.QueryRow(ctx, sql2, accountId, key).Scan(&dest.fingerprint, &statusCode, &contentType, &etag, &location, &dest.body)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// released by the earlier request in the meantime
+			return nil, failure.New("request with this idempotency key is still in progress", failure.ErrDuplicate)
+		}
+		return nil, failure.New("unable to find idempotency key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	dest.statusCode = int(statusCode.Int32)
+	dest.contentType = contentType.String
+	dest.etag = etag.String
+	dest.location = location.String
+
+	return &dest, nil
+}
+
+// complete stores the response of the request, it's kept until expiresAt instead of the
+// reservation. The key has to be still reserved by the request, once the reservation expired
+// a retry may have claimed it already
+func (s *store) complete(ctx context.Context, accountId, key, reservation string, res *storedResponse, expiresAt time.Time) error {
+	sql := `
+		update idempotency_key
+		set status_code = $4, content_type = nullif($5, ''), etag = nullif($6, ''), location = nullif($7, ''), body = $8, expires_at = $9
+		where account_id = $1 and key = $2 and reservation = $3 and status_code is null
+	`
+
+	ct, err := s.db.Exec(ctx, sql, accountId, key, reservation, res.statusCode, res.contentType, res.etag, res.location, res.body, expiresAt)
+	if err != nil {
+		return failure.New("unable to store idempotent response", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("idempotency key reservation expired before the response was stored", failure.ErrStale)
+	}
+
+	return nil
+}
+
+// release frees the key of a request which didn't complete, the stored responses and the
+// reservations of the other requests are kept
+func (s *store) release(ctx context.Context, accountId, key, reservation string) error {
+	sql := `delete from idempotency_key where account_id = $1 and key = $2 and reservation = $3 and status_code is null`
+
+	_, err := s.db.Exec(ctx, sql, accountId, key, reservation)
+	if err != nil {
+		return failure.New("unable to release idempotency key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) deleteExpired(ctx context.Context) error {
+	sql := `delete from idempotency_key where expires_at < current_timestamp`
+
+	_, err := s.db.Exec(ctx, sql)
+	if err != nil {
+		return failure.New("failed to delete expired idempotency keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
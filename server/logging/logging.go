@@ -0,0 +1,113 @@
+// Package logging sets up the structured logger of the api.
+//
+// Every request gets a set of fields carried in its context, the request id at first and the
+// account and player ids once the request is authenticated. The fields are attached to every
+// log line written with the request context and to the line summarizing the request, which
+// also holds the failure written by response.WriteFailure.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// output formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New returns the logger writing to w in the format, skipping the lines below the level
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q, must be debug, info, warn or error", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, must be %s or %s", format, FormatJSON, FormatText)
+	}
+
+	return slog.New(&contextHandler{Handler: h}), nil
+}
+
+type fieldsCtxKey struct{}
+
+// fields are shared by the request and the contexts derived from it, so the fields added by
+// the inner middlewares are seen by the outer ones
+type fields struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// WithFields returns the context carrying an empty set of fields, Add fills it
+func WithFields(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fieldsCtxKey{}, &fields{})
+}
+
+// Add attaches the attributes to every line logged with the context. It does nothing for
+// contexts created without WithFields
+func Add(ctx context.Context, attrs ...slog.Attr) {
+	f, ok := ctx.Value(fieldsCtxKey{}).(*fields)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	f.attrs = append(f.attrs, attrs...)
+	f.mu.Unlock()
+}
+
+func fieldsFrom(ctx context.Context) []slog.Attr {
+	f, ok := ctx.Value(fieldsCtxKey{}).(*fields)
+	if !ok {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]slog.Attr(nil), f.attrs...)
+}
+
+// contextHandler adds the context fields to the records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		r.AddAttrs(fieldsFrom(ctx)...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// LevelFor returns the level a response with the status is logged at. Server errors are
+// errors, rejected credentials and permissions are warnings and everything else is info
+func LevelFor(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := New(&bytes.Buffer{}, FormatText, "loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, FormatJSON, "warn"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, FormatJSON, "info")
+
+	ctx := WithFields(context.Background())
+	Add(ctx, slog.String("request_id", "r1"))
+	Add(ctx, slog.String("account_id", "a1"))
+	logger.InfoContext(ctx, "hello")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decoding the line: %v", err)
+	}
+	if line["request_id"] != "r1" || line["account_id"] != "a1" {
+		t.Errorf("expected the context fields on the line, got %v", line)
+	}
+
+	// a context without fields is left alone
+	Add(context.Background(), slog.String("ignored", "x"))
+}
+
+func TestLevelFor(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected slog.Level
+	}{
+		{http.StatusOK, slog.LevelInfo},
+		{http.StatusNotFound, slog.LevelInfo},
+		{http.StatusConflict, slog.LevelInfo},
+		{http.StatusUnauthorized, slog.LevelWarn},
+		{http.StatusForbidden, slog.LevelWarn},
+		{http.StatusInternalServerError, slog.LevelError},
+	}
+
+	for _, tt := range tests {
+		if got := LevelFor(tt.status); got != tt.expected {
+			t.Errorf("LevelFor(%d) = %v, expected %v", tt.status, got, tt.expected)
+		}
+	}
+}
+
+func TestRequests(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, FormatJSON, "info")
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(prev)
+
+	h := chimiddleware.RequestID(Requests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Add(r.Context(), slog.String("account_id", "a1"))
+		if !RecordFailure(w, errors.New("boom")) {
+			t.Error("expected the failure to be recorded")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/courts", nil))
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decoding the line: %v", err)
+	}
+	if line["level"] != "ERROR" || line["status"] != float64(500) || line["error"] != "boom" {
+		t.Errorf("expected an error line with the failure, got %v", line)
+	}
+	if line["account_id"] != "a1" || line["request_id"] == nil {
+		t.Errorf("expected the request and account ids, got %v", line)
+	}
+
+	if RecordFailure(httptest.NewRecorder(), errors.New("boom")) {
+		t.Error("expected no recording outside of Requests")
+	}
+}
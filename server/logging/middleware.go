@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// responseWriter records the response of the request and the failure it was written for
+type responseWriter struct {
+	chimiddleware.WrapResponseWriter
+	failure error
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.WrapResponseWriter.Unwrap()
+}
+
+// RecordFailure hands the failure of the response to the request log line. It reports false
+// if the writer doesn't belong to a request logged by Requests, the caller logs it then
+func RecordFailure(w http.ResponseWriter, err error) bool {
+	// the middlewares after Requests may have wrapped the writer again
+	for {
+		switch rw := w.(type) {
+		case *responseWriter:
+			rw.failure = err
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// Requests logs a line for every request, at the level following its status. It must come after
+// chi's RequestID middleware, the request id is attached to every line logged for the request
+func Requests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ctx := WithFields(r.Context())
+		if id := chimiddleware.GetReqID(ctx); id != "" {
+			Add(ctx, slog.String("request_id", id))
+		}
+		// the lines of a traced request can be looked up by its trace
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			Add(ctx, slog.String("trace_id", sc.TraceID().String()))
+		}
+
+		rw := &responseWriter{WrapResponseWriter: chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)}
+
+		defer func() {
+			status := rw.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int("bytes", rw.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_addr", r.RemoteAddr),
+			}
+			if rw.failure != nil {
+				attrs = append(attrs, slog.String("error", rw.failure.Error()))
+			}
+
+			slog.LogAttrs(ctx, LevelFor(status), "request", attrs...)
+		}()
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,92 @@
+// Package mail provides a minimal abstraction for sending transactional
+// emails (confirmations, notifications) to account holders.
+//
+// When no smtp host is configured the messages are only logged, which
+// keeps local development working without a mail server. The bodies carry
+// the confirmation codes, so they're left out unless MAIL_LOG_BODY is set.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/markovidakovic/gdsi/server/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender returns an smtp sender if the smtp host is configured,
+// otherwise it falls back to a sender which only logs the messages
+func NewSender(cfg *config.Config) Sender {
+	if cfg.SmtpHost == "" {
+		return &logSender{logBody: cfg.MailLogBody}
+	}
+	return &smtpSender{
+		addr:     net.JoinHostPort(cfg.SmtpHost, cfg.SmtpPort),
+		host:     cfg.SmtpHost,
+		user:     cfg.SmtpUser,
+		password: cfg.SmtpPassword,
+		from:     cfg.MailFrom,
+	}
+}
+
+type smtpSender struct {
+	addr     string
+	host     string
+	user     string
+	password string
+	from     string
+}
+
+func (s *smtpSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.user != "" {
+		auth = smtp.PlainAuth("", s.user, s.password, s.host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	err := smtp.SendMail(s.addr, auth, s.from, []string{msg.To}, []byte(b.String()))
+	if err != nil {
+		return fmt.Errorf("sending mail to %s -> %w", msg.To, err)
+	}
+
+	return nil
+}
+
+// logSender only logs the recipient and the subject, anyone reading the logs could otherwise
+// use the codes of the body. The body is logged at debug level for local development only
+type logSender struct {
+	logBody bool
+}
+
+func (s *logSender) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "mail not sent, no smtp host configured", "to", msg.To, "subject", msg.Subject)
+	if s.logBody {
+		slog.DebugContext(ctx, "unsent mail body", "to", msg.To, "body", msg.Body)
+	}
+	return nil
+}
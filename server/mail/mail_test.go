@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/markovidakovic/gdsi/server/config"
+)
+
+func TestNewSender(t *testing.T) {
+	// without an smtp host the messages are only logged
+	if _, ok := NewSender(&config.Config{}).(*logSender); !ok {
+		t.Errorf("expected the log sender without an smtp host")
+	}
+
+	s, ok := NewSender(&config.Config{SmtpHost: "smtp.gdsi.test", SmtpPort: "2525", SmtpUser: "gdsi", MailFrom: "no-reply@gdsi.test"}).(*smtpSender)
+	if !ok {
+		t.Fatalf("expected the smtp sender with an smtp host")
+	}
+	if s.addr != "smtp.gdsi.test:2525" || s.from != "no-reply@gdsi.test" {
+		t.Errorf("unexpected smtp sender %+v", s)
+	}
+}
+
+func TestSmtpSenderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := &smtpSender{addr: "127.0.0.1:1", host: "127.0.0.1"}
+	if err := s.Send(ctx, Message{To: "alice@gdsi.test"}); err == nil {
+		t.Errorf("expected the canceled send to fail")
+	}
+}
+
+func TestLogSenderRedactsBody(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	msg := Message{To: "alice@gdsi.test", Subject: "Confirm your new gdsi email address", Body: "code 1234abcd"}
+
+	if err := NewSender(&config.Config{}).Send(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "1234abcd") {
+		t.Errorf("expected the body to be left out of the logs, got %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "alice@gdsi.test") {
+		t.Errorf("expected the recipient to be logged, got %s", buf.String())
+	}
+
+	// the explicit toggle logs the body for local development
+	buf.Reset()
+	if err := NewSender(&config.Config{MailLogBody: true}).Send(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "level=DEBUG") || !strings.Contains(buf.String(), "1234abcd") {
+		t.Errorf("expected the body at debug level, got %s", buf.String())
+	}
+}
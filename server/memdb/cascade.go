@@ -0,0 +1,86 @@
+package memdb
+
+import (
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/markovidakovic/gdsi/server/db"
+)
+
+// ErrForeignKey is returned when a row is deleted while the rows of another table still
+// reference it, like the foreign keys without on delete cascade
+var ErrForeignKey = errors.New("memdb: the row is still referenced from another table")
+
+// The delete methods remove the row with the rows referencing it, following the on delete
+// rules of the migrations. They report whether the row existed
+
+func (t *Tables) DeleteCourt(courtId string) (bool, error) {
+	if _, ok := t.Courts[courtId]; !ok {
+		return false, nil
+	}
+	for _, m := range t.Matches {
+		if m.CourtId == courtId {
+			return false, ErrForeignKey
+		}
+	}
+	delete(t.Courts, courtId)
+	return true, nil
+}
+
+func (t *Tables) DeleteSeason(seasonId string) (bool, error) {
+	if _, ok := t.Seasons[seasonId]; !ok {
+		return false, nil
+	}
+	for leagueId, l := range t.Leagues {
+		if l.SeasonId == seasonId {
+			if _, err := t.DeleteLeague(leagueId); err != nil {
+				return false, err
+			}
+		}
+	}
+	maps.DeleteFunc(t.Matches, func(_ string, m db.Match) bool { return m.SeasonId == seasonId })
+	maps.DeleteFunc(t.Standings, func(_ string, s db.Standing) bool { return s.SeasonId == seasonId })
+	maps.DeleteFunc(t.ScopedRoleGrants, func(_ string, g db.ScopedRoleGrant) bool {
+		return g.SeasonId.Valid && g.SeasonId.String == seasonId
+	})
+	delete(t.Seasons, seasonId)
+	return true, nil
+}
+
+func (t *Tables) DeleteLeague(leagueId string) (bool, error) {
+	if _, ok := t.Leagues[leagueId]; !ok {
+		return false, nil
+	}
+	for _, p := range t.Players {
+		if p.CurrentLeagueId.Valid && p.CurrentLeagueId.String == leagueId {
+			return false, ErrForeignKey
+		}
+	}
+	maps.DeleteFunc(t.Matches, func(_ string, m db.Match) bool { return m.LeagueId == leagueId })
+	maps.DeleteFunc(t.Standings, func(_ string, s db.Standing) bool { return s.LeagueId == leagueId })
+	maps.DeleteFunc(t.ScopedRoleGrants, func(_ string, g db.ScopedRoleGrant) bool {
+		return g.LeagueId.Valid && g.LeagueId.String == leagueId
+	})
+	delete(t.Leagues, leagueId)
+	return true, nil
+}
+
+func (t *Tables) DeleteRole(name string) (bool, error) {
+	if _, ok := t.Roles[name]; !ok {
+		return false, nil
+	}
+	for _, a := range t.Accounts {
+		if a.Role == name {
+			return false, ErrForeignKey
+		}
+	}
+	for _, g := range t.ScopedRoleGrants {
+		if g.RoleName == name {
+			return false, ErrForeignKey
+		}
+	}
+	t.RolePermissions = slices.DeleteFunc(t.RolePermissions, func(rp db.RolePermission) bool { return rp.RoleName == name })
+	delete(t.Roles, name)
+	return true, nil
+}
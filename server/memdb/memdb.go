@@ -0,0 +1,306 @@
+// Package memdb is the in-memory backend of the stores. It holds the rows of the db tables, so
+// the api can be served and tested without postgres.
+//
+// A transaction works on its own copy of the tables and replaces the committed ones on commit.
+// The commit fails with ErrConflict if another transaction changed the tables since it began,
+// the way a serializable transaction of postgres would. Writes without a transaction are
+// committed on their own.
+package memdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/markovidakovic/gdsi/server/db"
+)
+
+// ErrConflict is returned by the commit of a transaction racing with another one
+var ErrConflict = errors.New("memdb: the tables changed since the transaction began")
+
+// Tables are the rows of the db tables keyed by their primary key, the audit log is kept in
+// insertion order. The rows are values, a change replaces the row in its table
+type Tables struct {
+	Accounts          map[string]db.Account
+	RefreshTokens     map[string]db.RefreshToken
+	EmailChanges      map[string]db.EmailChange
+	AccountTotps      map[string]db.AccountTotp // keyed by account id
+	TotpRecoveryCodes map[string]db.TotpRecoveryCode
+	AccountIdentities map[string]db.AccountIdentity
+	OidcAuthRequests  map[string]db.OidcAuthRequest // keyed by state hash
+	ServiceAccounts   map[string]db.ServiceAccount  // keyed by account id
+	APIKeys           map[string]db.APIKey
+	Roles             map[string]db.Role // keyed by name
+	RolePermissions   []db.RolePermission
+	ScopedRoleGrants  map[string]db.ScopedRoleGrant
+	InviteCodes       map[string]db.InviteCode
+	Courts            map[string]db.Court
+	Seasons           map[string]db.Season
+	Leagues           map[string]db.League
+	Players           map[string]db.Player
+	Matches           map[string]db.Match
+	Standings         map[string]db.Standing
+	AuditLogs         []db.AuditLog
+	IdempotencyKeys   map[IdempotencyKeyId]db.IdempotencyKey
+}
+
+// IdempotencyKeyId is the primary key of the idempotency keys, they're scoped to the account
+type IdempotencyKeyId struct {
+	AccountId string
+	Key       string
+}
+
+func (t *Tables) clone() *Tables {
+	return &Tables{
+		Accounts:          maps.Clone(t.Accounts),
+		RefreshTokens:     maps.Clone(t.RefreshTokens),
+		EmailChanges:      maps.Clone(t.EmailChanges),
+		AccountTotps:      maps.Clone(t.AccountTotps),
+		TotpRecoveryCodes: maps.Clone(t.TotpRecoveryCodes),
+		AccountIdentities: maps.Clone(t.AccountIdentities),
+		OidcAuthRequests:  maps.Clone(t.OidcAuthRequests),
+		ServiceAccounts:   maps.Clone(t.ServiceAccounts),
+		APIKeys:           maps.Clone(t.APIKeys),
+		Roles:             maps.Clone(t.Roles),
+		RolePermissions:   slices.Clone(t.RolePermissions),
+		ScopedRoleGrants:  maps.Clone(t.ScopedRoleGrants),
+		InviteCodes:       maps.Clone(t.InviteCodes),
+		Courts:            maps.Clone(t.Courts),
+		Seasons:           maps.Clone(t.Seasons),
+		Leagues:           maps.Clone(t.Leagues),
+		Players:           maps.Clone(t.Players),
+		Matches:           maps.Clone(t.Matches),
+		Standings:         maps.Clone(t.Standings),
+		AuditLogs:         slices.Clone(t.AuditLogs),
+		IdempotencyKeys:   maps.Clone(t.IdempotencyKeys),
+	}
+}
+
+// DB is the in-memory database shared by the stores of the backend
+type DB struct {
+	mu      sync.Mutex
+	tables  *Tables
+	version uint64
+
+	// the stores ask for the time while they hold mu
+	clockMu sync.Mutex
+	clock   time.Time
+}
+
+// New returns an empty database with the built-in roles the migrations seed
+func New() *DB {
+	t := &Tables{
+		Accounts:          map[string]db.Account{},
+		RefreshTokens:     map[string]db.RefreshToken{},
+		EmailChanges:      map[string]db.EmailChange{},
+		AccountTotps:      map[string]db.AccountTotp{},
+		TotpRecoveryCodes: map[string]db.TotpRecoveryCode{},
+		AccountIdentities: map[string]db.AccountIdentity{},
+		OidcAuthRequests:  map[string]db.OidcAuthRequest{},
+		ServiceAccounts:   map[string]db.ServiceAccount{},
+		APIKeys:           map[string]db.APIKey{},
+		Roles:             map[string]db.Role{},
+		ScopedRoleGrants:  map[string]db.ScopedRoleGrant{},
+		InviteCodes:       map[string]db.InviteCode{},
+		Courts:            map[string]db.Court{},
+		Seasons:           map[string]db.Season{},
+		Leagues:           map[string]db.League{},
+		Players:           map[string]db.Player{},
+		Matches:           map[string]db.Match{},
+		Standings:         map[string]db.Standing{},
+		IdempotencyKeys:   map[IdempotencyKeyId]db.IdempotencyKey{},
+	}
+
+	m := &DB{tables: t}
+	// the migrations are embedded, a failure can only come from a migration the seed can't read
+	if err := m.seedRoles(); err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// Now returns the current time, later than every time it returned before so the rows created
+// one after the other keep their order
+func (m *DB) Now() time.Time {
+	m.clockMu.Lock()
+	defer m.clockMu.Unlock()
+
+	now := time.Now()
+	if !now.After(m.clock) {
+		now = m.clock.Add(time.Microsecond)
+	}
+	m.clock = now
+
+	return now
+}
+
+// NewId returns a new primary key
+func NewId() string {
+	return uuid.NewString()
+}
+
+// Begin starts a transaction on a copy of the committed tables
+func (m *DB) Begin(ctx context.Context) (db.Tx, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &Tx{db: m, version: m.version, tables: m.tables.clone()}, nil
+}
+
+// Read runs fn on the tables of the tx, or on the committed ones without a tx. fn must not
+// change the tables
+func (m *DB) Read(tx db.Tx, fn func(t *Tables) error) error {
+	if tx != nil {
+		mtx, err := m.own(tx)
+		if err != nil {
+			return err
+		}
+		return fn(mtx.tables)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return fn(m.tables)
+}
+
+// Write runs fn on the tables of the tx. Without a tx the change is committed on its own, the
+// tables are left untouched if fn fails
+func (m *DB) Write(tx db.Tx, fn func(t *Tables) error) error {
+	if tx != nil {
+		mtx, err := m.own(tx)
+		if err != nil {
+			return err
+		}
+		mtx.dirty = true
+		return fn(mtx.tables)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.tables.clone()
+	if err := fn(t); err != nil {
+		return err
+	}
+	m.tables = t
+	m.version++
+
+	return nil
+}
+
+func (m *DB) own(tx db.Tx) (*Tx, error) {
+	mtx, ok := tx.(*Tx)
+	if !ok || mtx.db != m {
+		return nil, errors.New("memdb: the tx doesn't belong to the database")
+	}
+	if mtx.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return mtx, nil
+}
+
+// ErrNoSQL is returned by the statements run in a transaction of the database, the stores of
+// the backend reach its tables through Read and Write
+var ErrNoSQL = errors.New("memdb: sql statements aren't supported")
+
+// Tx is a transaction of the database. It satisfies db.Tx so the services handle it like the
+// postgres one
+type Tx struct {
+	db      *DB
+	version uint64
+	tables  *Tables
+	dirty   bool
+	closed  bool
+}
+
+func (tx *Tx) Commit(ctx context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+
+	if !tx.dirty {
+		return nil
+	}
+
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	if tx.db.version != tx.version {
+		return ErrConflict
+	}
+	tx.db.tables = tx.tables
+	tx.db.version++
+
+	return nil
+}
+
+func (tx *Tx) Rollback(ctx context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.closed = true
+
+	return nil
+}
+
+var _ db.Tx = (*Tx)(nil)
+
+func (tx *Tx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, ErrNoSQL
+}
+
+func (tx *Tx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, ErrNoSQL
+}
+
+func (tx *Tx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return noSQLRow{}
+}
+
+// noSQLRow is the row of a QueryRow, it fails to scan
+type noSQLRow struct{}
+
+func (noSQLRow) Scan(dest ...any) error {
+	return ErrNoSQL
+}
+
+// AppendAuditLog adds the entry to the audit log of the tx, it's committed with the change
+func (tx *Tx) AppendAuditLog(entry db.AuditLog) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	if entry.Id == "" {
+		entry.Id = NewId()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = tx.db.Now()
+	}
+	tx.dirty = true
+	tx.tables.AuditLogs = append(tx.tables.AuditLogs, entry)
+
+	return nil
+}
+
+// the layouts postgres commonly gets the timestamps and dates in
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseTime parses a timestamp or date sent as text, like postgres casts it. The times without
+// a zone are in utc
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("memdb: invalid input syntax for type timestamp: %q", s)
+}
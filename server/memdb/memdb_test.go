@@ -0,0 +1,186 @@
+package memdb
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+func addCourt(m *DB, tx db.Tx, name string) error {
+	return m.Write(tx, func(t *Tables) error {
+		c := db.Court{Id: NewId(), Name: name, CreatedAt: m.Now()}
+		t.Courts[c.Id] = c
+		return nil
+	})
+}
+
+func countCourts(t *testing.T, m *DB, tx db.Tx) int {
+	t.Helper()
+
+	var count int
+	err := m.Read(tx, func(t *Tables) error {
+		count = len(t.Courts)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return count
+}
+
+func TestTx(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CommitPublishes", func(t *testing.T) {
+		m := New()
+		tx, _ := m.Begin(ctx)
+		if err := addCourt(m, tx, "center"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if countCourts(t, m, nil) != 0 || countCourts(t, m, tx) != 1 {
+			t.Fatal("expected the change to be visible only in the tx before commit")
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if countCourts(t, m, nil) != 1 {
+			t.Error("expected the committed court")
+		}
+		if err := addCourt(m, tx, "side"); !errors.Is(err, pgx.ErrTxClosed) {
+			t.Errorf("expected ErrTxClosed after commit, got %v", err)
+		}
+	})
+
+	t.Run("RollbackDiscards", func(t *testing.T) {
+		m := New()
+		tx, _ := m.Begin(ctx)
+		_ = addCourt(m, tx, "center")
+		if err := tx.Rollback(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if countCourts(t, m, nil) != 0 {
+			t.Error("expected the rolled back court to be discarded")
+		}
+		if err := tx.Rollback(ctx); !errors.Is(err, pgx.ErrTxClosed) {
+			t.Errorf("expected ErrTxClosed on the second rollback, got %v", err)
+		}
+	})
+
+	t.Run("ConflictingCommitFails", func(t *testing.T) {
+		m := New()
+		first, _ := m.Begin(ctx)
+		second, _ := m.Begin(ctx)
+		_ = addCourt(m, first, "center")
+		_ = addCourt(m, second, "side")
+
+		if err := first.Commit(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := second.Commit(ctx); !errors.Is(err, ErrConflict) {
+			t.Fatalf("expected ErrConflict, got %v", err)
+		}
+		if countCourts(t, m, nil) != 1 {
+			t.Error("expected only the first commit to apply")
+		}
+	})
+
+	t.Run("ReadOnlyCommitDoesntConflict", func(t *testing.T) {
+		m := New()
+		tx, _ := m.Begin(ctx)
+		_ = countCourts(t, m, tx)
+		_ = addCourt(m, nil, "center")
+		if err := tx.Commit(ctx); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	// the stores of the backend don't run sql, a statement fails instead of panicking
+	t.Run("StatementsFail", func(t *testing.T) {
+		tx, _ := New().Begin(ctx)
+		if _, err := tx.Exec(ctx, "delete from court"); !errors.Is(err, ErrNoSQL) {
+			t.Errorf("Exec() = %v; want %v", err, ErrNoSQL)
+		}
+		if _, err := tx.Query(ctx, "select id from court"); !errors.Is(err, ErrNoSQL) {
+			t.Errorf("Query() = %v; want %v", err, ErrNoSQL)
+		}
+		var id string
+		if err := tx.QueryRow(ctx, "select id from court").Scan(&id); !errors.Is(err, ErrNoSQL) {
+			t.Errorf("QueryRow().Scan() = %v; want %v", err, ErrNoSQL)
+		}
+	})
+
+	t.Run("FailedWriteLeavesTables", func(t *testing.T) {
+		m := New()
+		err := m.Write(nil, func(t *Tables) error {
+			t.Courts["x"] = db.Court{Id: "x"}
+			return errors.New("boom")
+		})
+		if err == nil {
+			t.Fatal("expected the error of fn")
+		}
+		if countCourts(t, m, nil) != 0 {
+			t.Error("expected the failed write to be discarded")
+		}
+	})
+}
+
+func TestDeleteLeagueReferenced(t *testing.T) {
+	m := New()
+	err := m.Write(nil, func(t *Tables) error {
+		t.Leagues["l"] = db.League{Id: "l", SeasonId: "s"}
+		t.Players["p"] = db.Player{Id: "p", CurrentLeagueId: NullString(ptr("l"))}
+		_, err := t.DeleteLeague("l")
+		return err
+	})
+	if !errors.Is(err, ErrForeignKey) {
+		t.Fatalf("expected ErrForeignKey, got %v", err)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestSeedRoles(t *testing.T) {
+	m := New()
+
+	grants := map[string][]string{}
+	err := m.Read(nil, func(t *Tables) error {
+		for _, rp := range t.RolePermissions {
+			grants[rp.RoleName] = append(grants[rp.RoleName], rp.Permission)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the migrations seed the grants the permission checks fall back to
+	for role, perms := range permission.DefaultGrants() {
+		var expected []string
+		for _, p := range perms {
+			expected = append(expected, string(p))
+		}
+		slices.Sort(expected)
+		got := slices.Sorted(slices.Values(grants[role]))
+		if !slices.Equal(got, expected) {
+			t.Errorf("grants of %s = %v; want %v", role, got, expected)
+		}
+	}
+
+	roles := m.tables.Roles
+	if r, ok := roles[permission.RoleService]; !ok || !r.IsSystem {
+		t.Errorf("expected the system service role, got %+v", r)
+	}
+	if r, ok := roles["league_coordinator"]; !ok || r.IsSystem || !r.Description.Valid {
+		t.Errorf("expected the league coordinator role with its description, got %+v", r)
+	}
+	if !slices.Contains(grants["league_coordinator"], string(permission.SubmitScore)) {
+		t.Errorf("expected the league coordinator grants, got %v", grants["league_coordinator"])
+	}
+}
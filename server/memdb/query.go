@@ -0,0 +1,113 @@
+package memdb
+
+import (
+	"cmp"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+// Sort orders the rows like the order by clause of the stores: by the sorting field of orderBy
+// when it's a valid one, by def otherwise. The sort is stable so the ties keep the order of def
+func Sort[T any](rows []T, orderBy *params.OrderBy, fields map[string]func(a, b T) int, def func(a, b T) int) {
+	slices.SortStableFunc(rows, def)
+	if orderBy == nil {
+		return
+	}
+	field, ok := fields[orderBy.Field]
+	if !ok || (orderBy.Direction != "asc" && orderBy.Direction != "desc") {
+		return
+	}
+	if orderBy.Direction == "desc" {
+		slices.SortStableFunc(rows, func(a, b T) int { return field(b, a) })
+		return
+	}
+	slices.SortStableFunc(rows, field)
+}
+
+// Page returns the rows of limit and offset, a negative limit returns them all
+func Page[T any](rows []T, limit, offset int) []T {
+	if offset >= len(rows) {
+		return rows[:0]
+	}
+	rows = rows[offset:]
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// CompareTime orders the times from the oldest
+func CompareTime(a, b time.Time) int {
+	return a.Compare(b)
+}
+
+// CompareTimePtr orders the optional times from the oldest, nil sorts after every time like null
+// does in postgres
+func CompareTimePtr(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// CompareText orders the strings like the default collation does, ignoring the case
+func CompareText(a, b string) int {
+	return cmp.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// NullString is the column value of the optional string, nil is null
+func NullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+// StringPtr is the optional string of the column value, null is nil
+func StringPtr(ns sql.NullString) *string {
+	if !ns.Valid {
+		return nil
+	}
+	return &ns.String
+}
+
+// NullFloat64 is the column value of the optional number, nil is null
+func NullFloat64(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+// Float64Ptr is the optional number of the column value, null is nil
+func Float64Ptr(nf sql.NullFloat64) *float64 {
+	if !nf.Valid {
+		return nil
+	}
+	return &nf.Float64
+}
+
+// TimePtr is the optional time of the column value, null is nil
+func TimePtr(nt sql.NullTime) *time.Time {
+	if !nt.Valid {
+		return nil
+	}
+	return &nt.Time
+}
+
+// NullTime is the column value of the optional time, nil is null
+func NullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
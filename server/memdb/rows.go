@@ -0,0 +1,68 @@
+package memdb
+
+import (
+	"errors"
+
+	"github.com/markovidakovic/gdsi/server/db"
+)
+
+// ErrUnique is returned when a row repeats the value of a unique column
+var ErrUnique = errors.New("memdb: duplicate key value violates a unique constraint")
+
+// InsertAccount adds the account like the account table does: the email is unique and the role
+// has to exist
+func (t *Tables) InsertAccount(a db.Account) error {
+	if _, ok := t.AccountByEmail(a.Email); ok {
+		return ErrUnique
+	}
+	if _, ok := t.Roles[a.Role]; !ok {
+		return ErrForeignKey
+	}
+	t.Accounts[a.Id] = a
+	return nil
+}
+
+// InsertPlayer adds the player of the account, the empty privacy settings and version get the
+// column defaults
+func (t *Tables) InsertPlayer(p db.Player) error {
+	if _, ok := t.Accounts[p.AccountId]; !ok {
+		return ErrForeignKey
+	}
+	if p.PrivacyPhone == "" {
+		p.PrivacyPhone = "opponents"
+	}
+	if p.PrivacyEmail == "" {
+		p.PrivacyEmail = "league_mates"
+	}
+	if p.PrivacyDob == "" {
+		p.PrivacyDob = "nobody"
+	}
+	if p.PrivacyPhysique == "" {
+		p.PrivacyPhysique = "everyone"
+	}
+	if p.Version == 0 {
+		p.Version = 1
+	}
+	t.Players[p.Id] = p
+	return nil
+}
+
+// PlayerOf returns the player of the account, the accounts have at most one
+func (t *Tables) PlayerOf(accountId string) (db.Player, bool) {
+	for _, p := range t.Players {
+		if p.AccountId == accountId {
+			return p, true
+		}
+	}
+	return db.Player{}, false
+}
+
+// AccountByEmail returns the account with the email, the emails are unique
+func (t *Tables) AccountByEmail(email string) (db.Account, bool) {
+	for _, a := range t.Accounts {
+		if a.Email == email {
+			return a, true
+		}
+	}
+	return db.Account{}, false
+}
@@ -0,0 +1,94 @@
+package memdb
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/markovidakovic/gdsi/server/db"
+)
+
+// The roles and their grants are seeded by the migrations. The in-memory backend reads the
+// same insert statements out of them, so both backends start with the same roles
+
+var (
+	seedInsert = regexp.MustCompile(`(?is)insert\s+into\s+(role|role_permission)\s*\(([^)]*)\)\s*(.*?);`)
+	seedQuoted = regexp.MustCompile(`'((?:[^']|'')*)'`)
+	seedRole   = regexp.MustCompile(`(?i)\(\s*'((?:[^']|'')*)'\s*,\s*(?:'((?:[^']|'')*)'|null)\s*,\s*(true|false)\s*\)`)
+	seedGrant  = regexp.MustCompile(`\(\s*'((?:[^']|'')*)'\s*,\s*'((?:[^']|'')*)'\s*\)`)
+	seedUnnest = regexp.MustCompile(`(?is)^select\s+'((?:[^']|'')*)'\s*,\s*unnest\s*\(\s*array\s*\[(.*)\]\s*\)$`)
+)
+
+func (m *DB) seedRoles() error {
+	migrations, err := db.LoadMigrations()
+	if err != nil {
+		return fmt.Errorf("memdb: loading the migrations: %w", err)
+	}
+
+	for _, mig := range migrations {
+		for _, match := range seedInsert.FindAllStringSubmatch(mig.Up, -1) {
+			table, columns, rest := strings.ToLower(match[1]), strings.Join(strings.Fields(match[2]), ""), strings.TrimSpace(match[3])
+
+			switch {
+			case table == "role" && columns == "name,description,is_system":
+				err = m.seedRoleRows(rest)
+			case table == "role_permission" && columns == "role_name,permission":
+				err = m.seedGrantRows(rest)
+			default:
+				err = fmt.Errorf("unexpected columns %s", columns)
+			}
+			if err != nil {
+				return fmt.Errorf("memdb: seeding the %s rows of migration %s: %w", table, mig.Version, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// seedRoleRows inserts the roles of the values list
+func (m *DB) seedRoleRows(values string) error {
+	rows := seedRole.FindAllStringSubmatch(values, -1)
+	if len(rows) == 0 {
+		return fmt.Errorf("no rows in %q", values)
+	}
+	for _, row := range rows {
+		r := db.Role{Name: unquote(row[1]), IsSystem: strings.EqualFold(row[3], "true"), CreatedAt: m.Now()}
+		if row[2] != "" {
+			r.Description = sql.NullString{String: unquote(row[2]), Valid: true}
+		}
+		m.tables.Roles[r.Name] = r
+	}
+	return nil
+}
+
+// seedGrantRows inserts the grants of the values list or of the select of one role with the
+// unnested permission array
+func (m *DB) seedGrantRows(source string) error {
+	var grants [][2]string
+	if sel := seedUnnest.FindStringSubmatch(source); sel != nil {
+		for _, perm := range seedQuoted.FindAllStringSubmatch(sel[2], -1) {
+			grants = append(grants, [2]string{unquote(sel[1]), unquote(perm[1])})
+		}
+	} else {
+		for _, row := range seedGrant.FindAllStringSubmatch(source, -1) {
+			grants = append(grants, [2]string{unquote(row[1]), unquote(row[2])})
+		}
+	}
+	if len(grants) == 0 {
+		return fmt.Errorf("no rows in %q", source)
+	}
+
+	for _, g := range grants {
+		if _, ok := m.tables.Roles[g[0]]; !ok {
+			return fmt.Errorf("unknown role %s", g[0])
+		}
+		m.tables.RolePermissions = append(m.tables.RolePermissions, db.RolePermission{RoleName: g[0], Permission: g[1], CreatedAt: m.Now()})
+	}
+	return nil
+}
+
+func unquote(s string) string {
+	return strings.ReplaceAll(s, "''", "'")
+}
@@ -0,0 +1,264 @@
+// Package metrics holds the prometheus metrics of the api.
+//
+// The metrics are registered on their own registry, served by Handler. They cover the http
+// requests per chi route pattern, the db connection pool and the query durations, and the
+// domain activity: created matches, submitted scores, logins and the active seasons.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gdsi"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of the http requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of the http requests by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of the http requests being served.",
+	})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of the db queries by statement type and outcome.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"statement", "outcome"})
+
+	matchesCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "matches_created_total",
+		Help:      "Number of the created matches.",
+	})
+
+	scoresSubmitted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scores_submitted_total",
+		Help:      "Number of the submitted match scores, corrections excluded.",
+	})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of the login attempts by result, failed two-factor codes count as failed logins.",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		queryDuration,
+		matchesCreated, scoresSubmitted, logins,
+	)
+
+	// the results are known up front, both are exported from the start
+	logins.WithLabelValues("success")
+	logins.WithLabelValues("failure")
+}
+
+// Handler serves the metrics in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// MatchCreated counts a created match
+func MatchCreated() {
+	matchesCreated.Inc()
+}
+
+// ScoreSubmitted counts a submitted match score
+func ScoreSubmitted() {
+	scoresSubmitted.Inc()
+}
+
+// LoginSucceeded counts a login which issued the tokens
+func LoginSucceeded() {
+	logins.WithLabelValues("success").Inc()
+}
+
+// LoginFailed counts a login rejected for wrong credentials or a wrong two-factor code
+func LoginFailed() {
+	logins.WithLabelValues("failure").Inc()
+}
+
+// Requests records the http metrics of the requests. The route label is the chi route pattern,
+// so the requests of the same endpoint share the series whatever their ids
+func Requests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		// the pattern is complete once the request went through the router
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Pool is the connection pool the pool metrics are read from
+type Pool interface {
+	Stat() *pgxpool.Stat
+}
+
+// RegisterPool exports the statistics of the db connection pool
+func RegisterPool(pool Pool) {
+	registry.MustRegister(&poolCollector{pool: pool})
+}
+
+var (
+	poolMaxConns = prometheus.NewDesc(namespace+"_db_pool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolConns    = prometheus.NewDesc(namespace+"_db_pool_conns", "Number of the pool connections by state.", []string{"state"}, nil)
+	poolAcquires = prometheus.NewDesc(namespace+"_db_pool_acquires_total", "Number of the connection acquires, empty ones had to wait for a connection.", []string{"kind"}, nil)
+	poolWait     = prometheus.NewDesc(namespace+"_db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections.", nil, nil)
+	poolNewConns = prometheus.NewDesc(namespace+"_db_pool_new_conns_total", "Number of the connections opened.", nil, nil)
+)
+
+type poolCollector struct {
+	pool Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolMaxConns
+	ch <- poolConns
+	ch <- poolAcquires
+	ch <- poolWait
+	ch <- poolNewConns
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolConns, prometheus.GaugeValue, float64(s.AcquiredConns()), "acquired")
+	ch <- prometheus.MustNewConstMetric(poolConns, prometheus.GaugeValue, float64(s.IdleConns()), "idle")
+	ch <- prometheus.MustNewConstMetric(poolConns, prometheus.GaugeValue, float64(s.ConstructingConns()), "constructing")
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.AcquireCount()-s.EmptyAcquireCount()), "immediate")
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()), "empty")
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.CanceledAcquireCount()), "canceled")
+	ch <- prometheus.MustNewConstMetric(poolWait, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolNewConns, prometheus.CounterValue, float64(s.NewConnsCount()))
+}
+
+// ActiveSeasonsCounter returns the number of the seasons running right now
+type ActiveSeasonsCounter = func(ctx context.Context) (int, error)
+
+var activeSeasons = prometheus.NewDesc(namespace+"_active_seasons", "Number of the seasons running today.", nil, nil)
+
+// RegisterActiveSeasons exports the number of the active seasons, counted on every scrape
+func RegisterActiveSeasons(count ActiveSeasonsCounter) {
+	registry.MustRegister(&seasonsCollector{count: count})
+}
+
+type seasonsCollector struct {
+	count ActiveSeasonsCounter
+}
+
+func (c *seasonsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSeasons
+}
+
+func (c *seasonsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	n, err := c.count(ctx)
+	if err != nil {
+		slog.Error("counting the active seasons", "error", err)
+		ch <- prometheus.NewInvalidMetric(activeSeasons, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(activeSeasons, prometheus.GaugeValue, float64(n))
+}
+
+type queryStartCtxKey struct{}
+
+// QueryTracer records the duration of the db queries, it's set as the tracer of the pool connections
+type QueryTracer struct{}
+
+var _ pgx.QueryTracer = QueryTracer{}
+
+type queryStart struct {
+	at        time.Time
+	statement string
+}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartCtxKey{}, queryStart{at: time.Now(), statement: statementType(data.SQL)})
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartCtxKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	outcome := "success"
+	if data.Err != nil {
+		outcome = "error"
+	}
+
+	queryDuration.WithLabelValues(start.statement, outcome).Observe(time.Since(start.at).Seconds())
+}
+
+// statementType returns the leading keyword of the sql, the statements the stores don't run
+// are grouped as other to keep the label values bounded
+func statementType(sql string) string {
+	keyword := strings.TrimSpace(sql)
+	if i := strings.IndexFunc(keyword, unicode.IsSpace); i >= 0 {
+		keyword = keyword[:i]
+	}
+	keyword = strings.ToLower(keyword)
+
+	switch keyword {
+	case "select", "insert", "update", "delete", "with", "begin", "commit", "rollback":
+		return keyword
+	}
+	return "other"
+}
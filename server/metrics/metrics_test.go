@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStatementType(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected string
+	}{
+		{"select 1", "select"},
+		{"\n\t\tSELECT\n\t\t\tid\n\t\tfrom season", "select"},
+		{"\n\t\tinsert into court (name) values ($1)", "insert"},
+		{"update account\tset name = $1", "update"},
+		{"delete from refresh_token", "delete"},
+		{"with ranked as (select 1) select * from ranked", "with"},
+		{"truncate season", "other"},
+		{"", "other"},
+	}
+
+	for _, tt := range tests {
+		if got := statementType(tt.sql); got != tt.expected {
+			t.Errorf("statementType(%q) = %q, expected %q", tt.sql, got, tt.expected)
+		}
+	}
+}
+
+func TestRequests(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Requests)
+	r.Get("/v1/courts/{court_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, id := range []string{"a", "b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/courts/"+id, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/v1/courts/{court_id}", "404")); got != 2 {
+		t.Errorf("expected 2 requests on the route pattern, got %v", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", got)
+	}
+}
@@ -2,64 +2,395 @@ package middleware
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/logging"
 	"github.com/markovidakovic/gdsi/server/permission"
 	"github.com/markovidakovic/gdsi/server/response"
+	"github.com/markovidakovic/gdsi/server/sec"
+	"github.com/markovidakovic/gdsi/server/session"
 )
 
 var (
 	AccountIdCtxKey   = &contextKey{"account-id"}
 	AccountRoleCtxKey = &contextKey{"account-role"}
 	PlayerIdCtxKey    = &contextKey{"player-id"}
+	AuthMethodsCtxKey = &contextKey{"auth-methods"}
+	APIKeyIdCtxKey    = &contextKey{"api-key-id"}
+	ScopesCtxKey      = &contextKey{"scopes"}
+	scopeCtxKey       = &contextKey{"scope"}
 )
 
-// OwnershipChecker type is used so we can provide the RequireOwnershipOrPermission middleware with
-// a store function to check if the authenticated requestor created the resource
-type OwnershipChecker = func(ctx context.Context, resourceId, accountId string) (bool, error)
+// amr value of the requests authenticated with an api key
+const authMethodAPIKey = "api_key"
 
-// AttachAccountId sets the authenticated account id to the context for easier access in the handlers
-func AccountInfo(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, claims, _ := jwtauth.FromContext(r.Context())
+// APIKeyIdentity is the account an api key belongs to together with the key scopes
+type APIKeyIdentity struct {
+	KeyId     string
+	AccountId string
+	Role      string
+	PlayerId  string
+	Scopes    []permission.Permission
+}
+
+// APIKeyLookup finds the identity of a non revoked and non expired api key by its hash
+type APIKeyLookup = func(ctx context.Context, keyHash string) (*APIKeyIdentity, error)
+
+// APIKeyVerifier authenticates the requests carrying an api key in the X-API-Key header or as
+// the bearer token. It sets the same context values as AccountInfo, the jwt middlewares after
+// it let those requests through. Requests without an api key are passed on untouched
+func APIKeyVerifier(lookup APIKeyLookup) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				if bearer := jwtauth.TokenFromHeader(r); sec.IsAPIKey(bearer) {
+					key = bearer
+				}
+			}
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity, err := lookup(r.Context(), sec.HashToken(key))
+			if err != nil {
+				if f, ok := err.(*failure.Failure); ok {
+					response.WriteFailure(w, f)
+					return
+				}
+				response.WriteFailure(w, failure.New("invalid api key", fmt.Errorf("%w -> %v", failure.ErrUnauthorized, err)))
+				return
+			}
+
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, AccountIdCtxKey, identity.AccountId)
+			ctx = context.WithValue(ctx, AccountRoleCtxKey, identity.Role)
+			ctx = context.WithValue(ctx, PlayerIdCtxKey, identity.PlayerId)
+			ctx = context.WithValue(ctx, AuthMethodsCtxKey, []string{authMethodAPIKey})
+			ctx = context.WithValue(ctx, APIKeyIdCtxKey, identity.KeyId)
+			ctx = context.WithValue(ctx, ScopesCtxKey, identity.Scopes)
+
+			logging.Add(ctx, slog.String("account_id", identity.AccountId), slog.String("player_id", identity.PlayerId), slog.String("api_key_id", identity.KeyId))
 
-		// todo: uuid validation
-		accountId, ok := claims["sub"].(string)
-		if !ok {
-			response.WriteFailure(w, failure.New("account unauthorized", failure.ErrUnauthorized))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticatedByAPIKey reports if the APIKeyVerifier already authenticated the request
+func authenticatedByAPIKey(r *http.Request) bool {
+	_, ok := r.Context().Value(APIKeyIdCtxKey).(string)
+	return ok
+}
+
+// RejectAPIKey rejects the requests authenticated with an api key. The key scopes only narrow
+// down the permissions, the routes managing the account itself need a signed in session
+func RejectAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authenticatedByAPIKey(r) {
+			response.WriteFailure(w, failure.New("api keys can't access this resource", failure.ErrForbidden))
 			return
 		}
-		role, ok := claims["role"].(string)
-		if !ok {
-			response.WriteFailure(w, failure.New("account unauthorized", failure.ErrUnauthorized))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasPermission checks the role permission, narrowed down to the key scopes for api key requests.
+// Without a global permission the roles granted within the season or league of the url are checked
+func hasPermission(r *http.Request, perm permission.Permission) (bool, error) {
+	role, _ := r.Context().Value(AccountRoleCtxKey).(string)
+	if authenticatedByAPIKey(r) {
+		scopes, _ := r.Context().Value(ScopesCtxKey).([]permission.Permission)
+		if permission.HasScoped(role, scopes, perm) {
+			return true, nil
+		}
+		// the key scopes narrow down the scoped roles as well
+		if !slices.Contains(scopes, perm) {
+			return false, nil
+		}
+	} else if permission.Has(role, perm) {
+		return true, nil
+	}
+
+	scope, ok := r.Context().Value(scopeCtxKey).(*scopeResolver)
+	if !ok {
+		return false, nil
+	}
+	scopedRoles, err := scope.roles(r)
+	if err != nil {
+		return false, err
+	}
+	for _, sr := range scopedRoles {
+		if permission.Has(sr, perm) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasPermission is the permission check of RequirePermission for the handlers which
+// decide on the request body, e.g. creating a match on behalf of other players
+func HasPermission(r *http.Request, perm permission.Permission) (bool, error) {
+	return hasPermission(r, perm)
+}
+
+// ScopedRoleLookup returns the roles granted to the account within the season or the league.
+// A league grant applies to the league, a season grant to the season and all its leagues
+type ScopedRoleLookup = func(ctx context.Context, accountId, seasonId, leagueId string) ([]string, error)
+
+// scopeResolver looks the scoped roles up once per request, only when a global permission is missing
+type scopeResolver struct {
+	lookup ScopedRoleLookup
+	once   sync.Once
+	found  []string
+	err    error
+}
+
+func (sr *scopeResolver) roles(r *http.Request) ([]string, error) {
+	sr.once.Do(func() {
+		seasonId := chi.URLParam(r, "season_id")
+		leagueId := chi.URLParam(r, "league_id")
+		if seasonId == "" && leagueId == "" {
 			return
 		}
-		// todo: uuid validation
-		playerId, ok := claims["player_id"].(string)
-		if !ok {
-			response.WriteFailure(w, failure.New("account unauthorized", failure.ErrUnauthorized))
+		accountId, _ := r.Context().Value(AccountIdCtxKey).(string)
+		sr.found, sr.err = sr.lookup(r.Context(), accountId, seasonId, leagueId)
+	})
+	return sr.found, sr.err
+}
+
+// ResolveScope lets the permission checks accept the roles granted within the season or the league
+// of the season_id and league_id url params. The url params are only known once the route matched,
+// so the scoped roles are looked up by the permission checks of the route itself
+func ResolveScope(lookup ScopedRoleLookup) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), scopeCtxKey, &scopeResolver{lookup: lookup})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writePermissionFailure writes the error of a failed scoped role lookup
+func writePermissionFailure(w http.ResponseWriter, err error) {
+	if f, ok := err.(*failure.Failure); ok {
+		response.WriteFailure(w, f)
+		return
+	}
+	response.WriteFailure(w, failure.New("unable to check permission", fmt.Errorf("%w -> %v", failure.ErrInternal, err)))
+}
+
+// Verifier seeks the jwt in the authorization header or the jwt cookie and verifies it against
+// the key ring. The result is stored with jwtauth.NewContext so jwtauth.FromContext keeps working
+func Verifier(kr *sec.KeyRing) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if authenticatedByAPIKey(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			val := jwtauth.TokenFromHeader(r)
+			if val == "" {
+				val = jwtauth.TokenFromCookie(r)
+			}
+
+			var tkn jwt.Token
+			var err error
+			if val == "" {
+				err = jwtauth.ErrNoTokenFound
+			} else {
+				tkn, err = kr.Verify(val)
+			}
+
+			ctx := jwtauth.NewContext(r.Context(), tkn, err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Authenticator rejects the requests which didn't pass the Verifier
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authenticatedByAPIKey(r) {
+			next.ServeHTTP(w, r)
+			return
 		}
 
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, AccountIdCtxKey, accountId)
-		ctx = context.WithValue(ctx, AccountRoleCtxKey, role)
-		ctx = context.WithValue(ctx, PlayerIdCtxKey, playerId)
+		tkn, _, err := jwtauth.FromContext(r.Context())
+		if err != nil {
+			response.WriteFailure(w, failure.New(err.Error(), failure.ErrUnauthorized))
+			return
+		}
+		if tkn == nil {
+			response.WriteFailure(w, failure.New("token is unauthorized", failure.ErrUnauthorized))
+			return
+		}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r)
 	})
 }
 
+// OwnershipChecker type is used so we can provide the RequireOwnershipOrPermission middleware with
+// a store function to check if the authenticated requestor created the resource
+type OwnershipChecker = func(ctx context.Context, resourceId, accountId string) (bool, error)
+
+// AccountStateLoader returns the current state of the account. The jwts stay valid until they
+// expire, so the deactivation and the token version have to be checked on every request
+type AccountStateLoader = func(ctx context.Context, accountId string) (session.State, error)
+
+// AccountInfo sets the authenticated account info to the context for easier access in the handlers.
+// It rejects the deactivated accounts and the tokens issued before the account token version changed
+func AccountInfo(loadState AccountStateLoader) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if authenticatedByAPIKey(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			_, claims, _ := jwtauth.FromContext(r.Context())
+
+			// the 2fa challenge token only proves the password step, it's not an access token
+			if sec.IsChallengeToken(claims) {
+				response.WriteFailure(w, failure.New("account unauthorized", failure.ErrUnauthorized))
+				return
+			}
+
+			// todo: uuid validation
+			accountId, ok := claims["sub"].(string)
+			if !ok {
+				response.WriteFailure(w, failure.New("account unauthorized", failure.ErrUnauthorized))
+				return
+			}
+			role, ok := claims["role"].(string)
+			if !ok {
+				response.WriteFailure(w, failure.New("account unauthorized", failure.ErrUnauthorized))
+				return
+			}
+			// todo: uuid validation
+			playerId, ok := claims["player_id"].(string)
+			if !ok {
+				response.WriteFailure(w, failure.New("account unauthorized", failure.ErrUnauthorized))
+				return
+			}
+
+			state, err := loadState(r.Context(), accountId)
+			if err != nil {
+				if f, ok := err.(*failure.Failure); ok {
+					response.WriteFailure(w, f)
+					return
+				}
+				response.WriteFailure(w, failure.New("unable to check account status", fmt.Errorf("%w -> %v", failure.ErrInternal, err)))
+				return
+			}
+			if !state.Active {
+				response.WriteFailure(w, failure.New("account is deactivated", failure.ErrUnauthorized))
+				return
+			}
+			if tokenVersionFromClaims(claims) != state.TokenVersion {
+				response.WriteFailure(w, failure.New("token has been revoked", failure.ErrUnauthorized))
+				return
+			}
+
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, AccountIdCtxKey, accountId)
+			ctx = context.WithValue(ctx, AccountRoleCtxKey, role)
+			ctx = context.WithValue(ctx, PlayerIdCtxKey, playerId)
+			ctx = context.WithValue(ctx, AuthMethodsCtxKey, authMethodsFromClaims(claims))
+
+			logging.Add(ctx, slog.String("account_id", accountId), slog.String("player_id", playerId))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireTwoFactor rejects the accounts with one of the provided roles which didn't
+// authenticate with a totp code
+func RequireTwoFactor(roles []string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// creating an api key already required two-factor authentication
+			if authenticatedByAPIKey(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			role, _ := r.Context().Value(AccountRoleCtxKey).(string)
+			if !slices.Contains(roles, role) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			amr, _ := r.Context().Value(AuthMethodsCtxKey).([]string)
+			if !slices.Contains(amr, sec.AmrOTP) {
+				response.WriteFailure(w, failure.New("two-factor authentication required for this role", failure.ErrForbidden))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tokenVersionFromClaims reads the token_version claim, tokens issued before the claim
+// existed are treated as version 0. Numeric claims are decoded as float64
+func tokenVersionFromClaims(claims map[string]interface{}) int {
+	switch v := claims["token_version"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// authMethodsFromClaims reads the amr claim, tokens issued before the claim existed
+// are treated as password authenticated
+func authMethodsFromClaims(claims map[string]interface{}) []string {
+	if amr, ok := claims["amr"].([]string); ok {
+		return amr
+	}
+
+	raw, ok := claims["amr"].([]interface{})
+	if !ok {
+		return []string{sec.AmrPassword}
+	}
+
+	amr := make([]string, 0, len(raw))
+	for _, m := range raw {
+		if s, ok := m.(string); ok {
+			amr = append(amr, s)
+		}
+	}
+	return amr
+}
+
 // RequirePermission checks if the current account role has permission to access a specific resource
 func RequirePermission(perm permission.Permission) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			role := r.Context().Value(AccountRoleCtxKey).(string)
-
 			// check permission
-			if !permission.Has(role, perm) {
+			ok, err := hasPermission(r, perm)
+			if err != nil {
+				writePermissionFailure(w, err)
+				return
+			}
+			if !ok {
 				response.WriteFailure(w, failure.New("insufficient permission", failure.ErrForbidden))
 				return
 			}
@@ -114,10 +445,13 @@ func RequirePermissionOrOwnership(perm permission.Permission, oc OwnershipChecke
 				ownerId = r.Context().Value(PlayerIdCtxKey).(string)
 			}
 
-			role := r.Context().Value(AccountRoleCtxKey).(string)
-
 			// check permission
-			if permission.Has(role, perm) {
+			ok, err := hasPermission(r, perm)
+			if err != nil {
+				writePermissionFailure(w, err)
+				return
+			}
+			if ok {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// NoCache keeps the responses out of the shared caches and makes the clients revalidate them
+// on every use. Unlike chi's NoCache it leaves the conditional headers of the request alone, the
+// handlers answer If-Match and If-None-Match against the tags of their responses
+func NoCache(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache, private, max-age=0")
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("Expires", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+		next.ServeHTTP(w, r)
+	})
+}
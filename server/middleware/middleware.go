@@ -5,6 +5,10 @@ import (
 	"net/http"
 )
 
+// MaxBodyBytes limits the size of the request bodies, the json decoders of the handlers and
+// the idempotency keys read at most that much
+const MaxBodyBytes = 1 << 20
+
 // New will create a new middleware handler from a http.Handler
 func New(h http.Handler) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -0,0 +1,321 @@
+// Package oidc implements the relying party side of the OpenID Connect
+// authorization code flow with PKCE.
+//
+// The provider metadata is discovered lazily on first use so the api can
+// start even when the identity provider is temporarily unreachable.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/sec"
+)
+
+var (
+	ErrNotConfigured = errors.New("oidc provider not configured")
+	ErrInvalidToken  = errors.New("invalid id token")
+)
+
+// Claims holds the identity provider claims gdsi uses for linking and provisioning accounts
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Birthdate     string
+	Gender        string
+	PhoneNumber   string
+}
+
+// AuthRequest holds the values which have to be stored between the
+// authorization redirect and the callback
+type AuthRequest struct {
+	URL          string
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type Provider struct {
+	issuer       string
+	clientId     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	client       *http.Client
+
+	mu   sync.Mutex
+	meta *metadata
+	keys jwk.Set
+}
+
+// NewProvider returns the provider configured in cfg. It returns nil if the
+// oidc issuer is not configured
+func NewProvider(cfg *config.Config) *Provider {
+	if cfg.OidcIssuerUrl == "" {
+		return nil
+	}
+	return &Provider{
+		issuer:       strings.TrimSuffix(cfg.OidcIssuerUrl, "/"),
+		clientId:     cfg.OidcClientId,
+		clientSecret: cfg.OidcClientSecret,
+		redirectURL:  cfg.OidcRedirectUrl,
+		scopes:       strings.Fields(cfg.OidcScopes),
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Issuer returns the configured issuer identifier
+func (p *Provider) Issuer() string {
+	return p.issuer
+}
+
+// AuthCodeURL builds the authorization url together with a new state, nonce and pkce verifier
+func (p *Provider) AuthCodeURL(ctx context.Context) (*AuthRequest, error) {
+	if p == nil {
+		return nil, ErrNotConfigured
+	}
+
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := sec.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := sec.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := sec.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.clientId)
+	q.Set("redirect_uri", p.redirectURL)
+	q.Set("scope", strings.Join(p.scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", CodeChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return &AuthRequest{
+		URL:          meta.AuthorizationEndpoint + sep + q.Encode(),
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, nil
+}
+
+// Exchange redeems the authorization code at the token endpoint and verifies the returned id token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	if p == nil {
+		return nil, ErrNotConfigured
+	}
+
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.clientId)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientId), url.QueryEscape(p.clientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting oidc token -> %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc token endpoint responded with status %d", res.StatusCode)
+	}
+
+	var body struct {
+		IdToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding oidc token response -> %w", err)
+	}
+	if body.IdToken == "" {
+		return nil, fmt.Errorf("%w: token response is missing the id token", ErrInvalidToken)
+	}
+
+	return p.verify(ctx, body.IdToken, nonce)
+}
+
+// verify checks the id token signature, issuer, audience, expiration and nonce
+func (p *Provider) verify(ctx context.Context, idToken, nonce string) (*Claims, error) {
+	keys, err := p.keySet(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := func(keys jwk.Set) []jwt.ParseOption {
+		return []jwt.ParseOption{
+			jwt.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)),
+			jwt.WithValidate(true),
+			jwt.WithIssuer(p.issuer),
+			jwt.WithAudience(p.clientId),
+			jwt.WithAcceptableSkew(time.Minute),
+		}
+	}
+
+	tkn, err := jwt.Parse([]byte(idToken), opts(keys)...)
+	if err != nil {
+		// the provider might have rotated its signing keys, refetch them once
+		keys, kerr := p.keySet(ctx, true)
+		if kerr != nil {
+			return nil, kerr
+		}
+		tkn, err = jwt.Parse([]byte(idToken), opts(keys)...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+	}
+
+	if tkn.Subject() == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	claims := tkn.PrivateClaims()
+
+	if n, _ := claims["nonce"].(string); n == "" || n != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+
+	c := &Claims{
+		Issuer:  tkn.Issuer(),
+		Subject: tkn.Subject(),
+	}
+	c.Email, _ = claims["email"].(string)
+	c.Name, _ = claims["name"].(string)
+	c.Birthdate, _ = claims["birthdate"].(string)
+	c.Gender, _ = claims["gender"].(string)
+	c.PhoneNumber, _ = claims["phone_number"].(string)
+
+	// some providers send email_verified as a string
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		c.EmailVerified = v
+	case string:
+		c.EmailVerified = v == "true"
+	}
+
+	return c, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching oidc discovery document -> %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery responded with status %d", res.StatusCode)
+	}
+
+	var meta metadata
+	err = json.NewDecoder(res.Body).Decode(&meta)
+	if err != nil {
+		return nil, fmt.Errorf("decoding oidc discovery document -> %w", err)
+	}
+
+	if strings.TrimSuffix(meta.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("oidc discovery issuer %q does not match the configured issuer %q", meta.Issuer, p.issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JwksURI == "" {
+		return nil, fmt.Errorf("oidc discovery document is missing required endpoints")
+	}
+
+	p.meta = &meta
+	return p.meta, nil
+}
+
+func (p *Provider) keySet(ctx context.Context, refresh bool) (jwk.Set, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil && !refresh {
+		return p.keys, nil
+	}
+
+	keys, err := jwk.Fetch(ctx, meta.JwksURI, jwk.WithHTTPClient(p.client))
+	if err != nil {
+		return nil, fmt.Errorf("fetching oidc signing keys -> %w", err)
+	}
+
+	p.keys = keys
+	return p.keys, nil
+}
+
+// CodeChallenge derives the S256 pkce code challenge from the verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
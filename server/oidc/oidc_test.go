@@ -0,0 +1,276 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/markovidakovic/gdsi/server/config"
+)
+
+// mockIssuer is a minimal oidc provider serving discovery, jwks and the token endpoint
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu        sync.Mutex
+	key       jwk.Key
+	codes     map[string]mockGrant
+	audience  string
+	overrides map[string]interface{}
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	m := &mockIssuer{
+		t:         t,
+		codes:     map[string]mockGrant{},
+		audience:  "gdsi-client",
+		overrides: map[string]interface{}{},
+	}
+	m.rotateKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		set := jwk.NewSet()
+		pub, err := m.key.PublicKey()
+		if err != nil {
+			t.Fatalf("public key: %v", err)
+		}
+		set.AddKey(pub)
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("/token", m.token)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockIssuer) rotateKey(kid string) {
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		m.t.Fatalf("generating rsa key: %v", err)
+	}
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		m.t.Fatalf("creating jwk: %v", err)
+	}
+	key.Set(jwk.KeyIDKey, kid)
+	key.Set(jwk.AlgorithmKey, jwa.RS256)
+
+	m.mu.Lock()
+	m.key = key
+	m.mu.Unlock()
+}
+
+// authorize simulates the user logging in at the provider and returns the issued code
+func (m *mockIssuer) authorize(authURL string) (code, state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatalf("parsing authorization url: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		m.t.Fatalf("expected S256 code challenge method, got %q", q.Get("code_challenge_method"))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code = "code-" + q.Get("state")[:8]
+	m.codes[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	return code, q.Get("state")
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	grant, ok := m.codes[r.PostForm.Get("code")]
+	if !ok || CodeChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	delete(m.codes, r.PostForm.Get("code"))
+
+	now := time.Now()
+	tkn := jwt.New()
+	tkn.Set(jwt.IssuerKey, m.server.URL)
+	tkn.Set(jwt.SubjectKey, "user-123")
+	tkn.Set(jwt.AudienceKey, m.audience)
+	tkn.Set(jwt.IssuedAtKey, now)
+	tkn.Set(jwt.ExpirationKey, now.Add(5*time.Minute))
+	tkn.Set("nonce", grant.nonce)
+	tkn.Set("email", "player@club.test")
+	tkn.Set("email_verified", true)
+	tkn.Set("name", "Club Player")
+	for k, v := range m.overrides {
+		tkn.Set(k, v)
+	}
+
+	signed, err := jwt.Sign(tkn, jwt.WithKey(jwa.RS256, m.key))
+	if err != nil {
+		m.t.Fatalf("signing id token: %v", err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "provider-access-token",
+		"token_type":   "Bearer",
+		"id_token":     string(signed),
+	})
+}
+
+func (m *mockIssuer) provider() *Provider {
+	return NewProvider(&config.Config{
+		OidcIssuerUrl:   m.server.URL,
+		OidcClientId:    "gdsi-client",
+		OidcRedirectUrl: "gdsi://oidc/callback",
+		OidcScopes:      "openid email profile",
+	})
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ValidLogin", func(t *testing.T) {
+		m := newMockIssuer(t)
+		p := m.provider()
+
+		ar, err := p.AuthCodeURL(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		code, state := m.authorize(ar.URL)
+		if state != ar.State {
+			t.Fatalf("expected state %q, got %q", ar.State, state)
+		}
+
+		claims, err := p.Exchange(ctx, code, ar.CodeVerifier, ar.Nonce)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if claims.Subject != "user-123" || claims.Email != "player@club.test" || !claims.EmailVerified || claims.Name != "Club Player" {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+		if claims.Issuer != m.server.URL {
+			t.Errorf("expected issuer %q, got %q", m.server.URL, claims.Issuer)
+		}
+	})
+
+	t.Run("WrongCodeVerifier", func(t *testing.T) {
+		m := newMockIssuer(t)
+		p := m.provider()
+
+		ar, _ := p.AuthCodeURL(ctx)
+		code, _ := m.authorize(ar.URL)
+
+		_, err := p.Exchange(ctx, code, "not-the-verifier", ar.Nonce)
+		if err == nil {
+			t.Fatal("expected error for a wrong pkce verifier")
+		}
+	})
+
+	t.Run("NonceMismatch", func(t *testing.T) {
+		m := newMockIssuer(t)
+		p := m.provider()
+
+		ar, _ := p.AuthCodeURL(ctx)
+		code, _ := m.authorize(ar.URL)
+
+		_, err := p.Exchange(ctx, code, ar.CodeVerifier, "other-nonce")
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("WrongAudience", func(t *testing.T) {
+		m := newMockIssuer(t)
+		m.audience = "another-client"
+		p := m.provider()
+
+		ar, _ := p.AuthCodeURL(ctx)
+		code, _ := m.authorize(ar.URL)
+
+		_, err := p.Exchange(ctx, code, ar.CodeVerifier, ar.Nonce)
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		m := newMockIssuer(t)
+		m.overrides[jwt.ExpirationKey] = time.Now().Add(-time.Hour)
+		p := m.provider()
+
+		ar, _ := p.AuthCodeURL(ctx)
+		code, _ := m.authorize(ar.URL)
+
+		_, err := p.Exchange(ctx, code, ar.CodeVerifier, ar.Nonce)
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("KeyRotation", func(t *testing.T) {
+		m := newMockIssuer(t)
+		p := m.provider()
+
+		ar, _ := p.AuthCodeURL(ctx)
+		code, _ := m.authorize(ar.URL)
+		if _, err := p.Exchange(ctx, code, ar.CodeVerifier, ar.Nonce); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// the cached key set no longer contains the signing key
+		m.rotateKey("key-2")
+
+		ar, _ = p.AuthCodeURL(ctx)
+		code, _ = m.authorize(ar.URL)
+		if _, err := p.Exchange(ctx, code, ar.CodeVerifier, ar.Nonce); err != nil {
+			t.Fatalf("expected the rotated key to be refetched, got %v", err)
+		}
+	})
+}
+
+func TestNewProviderNotConfigured(t *testing.T) {
+	p := NewProvider(&config.Config{})
+	if p != nil {
+		t.Fatal("expected nil provider without an issuer")
+	}
+
+	_, err := p.AuthCodeURL(context.Background())
+	if !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("expected ErrNotConfigured, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package params
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/response"
+)
+
+// IfMatch holds the tags listed in the If-Match header of the request. The zero value
+// accepts every version, as does a missing header or *
+type IfMatch struct {
+	set  bool
+	tags []string
+}
+
+func NewIfMatch(h http.Header) IfMatch {
+	value := strings.TrimSpace(h.Get("If-Match"))
+	if value == "" || value == "*" {
+		return IfMatch{}
+	}
+
+	m := IfMatch{set: true}
+	for _, tag := range strings.Split(value, ",") {
+		// If-Match compares the tags strongly (RFC 7232), a weak tag never matches and
+		// the W/ prefix fails the quote check
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		m.tags = append(m.tags, tag)
+	}
+	return m
+}
+
+// Check fails with ErrStale when the current version of the resource isn't one the client expects
+func (m IfMatch) Check(resource string, version int) error {
+	return m.check(resource, response.ETag(version))
+}
+
+// CheckData is Check for the resources tagged by their representation, the data is the current
+// representation the client would read, see response.BodyETag
+func (m IfMatch) CheckData(resource string, data interface{}) error {
+	if !m.set {
+		return nil
+	}
+	tag, err := response.BodyETag(data)
+	if err != nil {
+		return failure.New(fmt.Sprintf("unable to check the %s version", resource), fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return m.check(resource, tag)
+}
+
+func (m IfMatch) check(resource, tag string) error {
+	if !m.set || slices.Contains(m.tags, tag) {
+		return nil
+	}
+	return failure.New(fmt.Sprintf("%s was changed since it was read", resource), failure.ErrStale)
+}
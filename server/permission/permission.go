@@ -1,5 +1,14 @@
 package permission
 
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 type Permission string
 
 const (
@@ -27,21 +36,73 @@ const (
 	// player permission
 	UpdatePlayer Permission = "update:player"
 	DeletePlayer Permission = "delete:player"
+
+	// service account permissions
+	ManageServiceAccounts Permission = "manage:service_account"
+
+	// account permissions
+	ManageAccounts Permission = "manage:account"
+
+	// role permissions
+	ManageRoles Permission = "manage:role"
+
+	// audit log permissions
+	ViewAudit Permission = "view:audit"
+
+	// invite code permissions
+	ManageInvites Permission = "manage:invite"
+
+	// system permissions
+	ViewSystem Permission = "view:system"
 )
 
-var rolePermissions = map[string][]Permission{
+// all holds every permission the api checks, the roles can only be granted these
+var all = []Permission{
+	CreateCourt, UpdateCourt, DeleteCourt,
+	CreateSeason, UpdateSeason, DeleteSeason,
+	CreateLeague, UpdateLeague, DeleteLeague,
+	CreateMatch, UpdateMatch, DeleteMatch, SubmitScore,
+	UpdatePlayer, DeletePlayer,
+	ManageServiceAccounts,
+	ManageAccounts,
+	ManageRoles,
+	ViewAudit,
+	ManageInvites,
+	ViewSystem,
+}
+
+// RoleService is the role of the service accounts. It has no permissions
+// of its own, service accounts only get the scopes of their api keys
+const RoleService = "service"
+
+// RoleDeveloper is the role holding every permission, including the role management
+const RoleDeveloper = "developer"
+
+// defaultGrants are the grants of the built-in roles. The same grants are seeded into the
+// role_permission table, they're only used until a loader is configured with Use
+var defaultGrants = map[string][]Permission{
 	"developer": {
 		CreateCourt, UpdateCourt, DeleteCourt,
 		CreateSeason, UpdateSeason, DeleteSeason,
 		CreateLeague, UpdateLeague, DeleteLeague,
 		CreateMatch, UpdateMatch, DeleteMatch, SubmitScore,
 		UpdatePlayer, DeletePlayer,
+		ManageServiceAccounts,
+		ManageAccounts,
+		ManageRoles,
+		ViewAudit,
+		ManageInvites,
+		ViewSystem,
 	},
 	"admin": {
 		CreateCourt, UpdateCourt, DeleteCourt,
 		CreateSeason, UpdateSeason, DeleteSeason,
 		CreateLeague, UpdateLeague, DeleteLeague,
 		CreateMatch, UpdateMatch, DeleteMatch, SubmitScore,
+		ManageServiceAccounts,
+		ManageAccounts,
+		ViewAudit,
+		ManageInvites,
 	},
 	"user": {
 		CreateMatch,
@@ -49,20 +110,111 @@ var rolePermissions = map[string][]Permission{
 	},
 }
 
+// DefaultGrants returns the grants of the built-in roles
+func DefaultGrants() map[string][]Permission {
+	grants := make(map[string][]Permission, len(defaultGrants))
+	for role, perms := range defaultGrants {
+		grants[role] = slices.Clone(perms)
+	}
+	return grants
+}
+
+// Loader returns the permissions granted to each role
+type Loader = func(ctx context.Context) (map[string][]Permission, error)
+
+// registry holds the role grants Has checks against. Once the grants are older than
+// the ttl they are reloaded in the background, the checks keep using the previous
+// grants meanwhile so they never wait on the db
+type registry struct {
+	mu       sync.RWMutex
+	grants   map[string][]Permission
+	load     Loader
+	ttl      time.Duration
+	loadedAt time.Time
+
+	refreshing atomic.Bool
+}
+
+var reg = &registry{grants: defaultGrants}
+
+// Use makes the loader the source of the role grants. The grants are loaded at once
+// and reloaded when they get older than the ttl, a zero ttl only reloads on Reload
+func Use(ctx context.Context, load Loader, ttl time.Duration) error {
+	reg.mu.Lock()
+	reg.load = load
+	reg.ttl = ttl
+	reg.mu.Unlock()
+
+	return Reload(ctx)
+}
+
+// Reload loads the role grants right away, it's called after the roles change
+func Reload(ctx context.Context) error {
+	reg.mu.RLock()
+	load := reg.load
+	reg.mu.RUnlock()
+
+	if load == nil {
+		return nil
+	}
+
+	grants, err := load(ctx)
+	if err != nil {
+		return err
+	}
+
+	reg.mu.Lock()
+	reg.grants = grants
+	reg.loadedAt = time.Now()
+	reg.mu.Unlock()
+
+	return nil
+}
+
+func (r *registry) current() map[string][]Permission {
+	r.mu.RLock()
+	grants := r.grants
+	stale := r.load != nil && r.ttl > 0 && time.Since(r.loadedAt) > r.ttl
+	r.mu.RUnlock()
+
+	if stale && r.refreshing.CompareAndSwap(false, true) {
+		go func() {
+			defer r.refreshing.Store(false)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := Reload(ctx); err != nil {
+				slog.ErrorContext(ctx, "reloading role permissions", "error", err)
+			}
+		}()
+	}
+
+	return grants
+}
+
 // Has accepts the account role and the needed permission to access the resource.
 // It gets the permissions for a specific role and looks if the account role
 // satisfies the required permission
 func Has(role string, perm Permission) bool {
-	perms, exists := rolePermissions[role]
-	if !exists {
+	return slices.Contains(reg.current()[role], perm)
+}
+
+// HasScoped is Has for the requests authenticated with an api key. The key scopes
+// narrow down the role permissions, service accounts only get the scopes
+func HasScoped(role string, scopes []Permission, perm Permission) bool {
+	if !slices.Contains(scopes, perm) {
 		return false
 	}
+	return role == RoleService || Has(role, perm)
+}
 
-	for _, p := range perms {
-		if p == perm {
-			return true
-		}
-	}
+// Exists reports if the permission is known, used for validating api key scopes and role grants
+func Exists(perm Permission) bool {
+	return slices.Contains(all, perm)
+}
 
-	return false
+// All returns every permission the api checks
+func All() []Permission {
+	return slices.Clone(all)
 }
@@ -1 +1,124 @@
 package permission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// resetRegistry restores the default grants after a test swapped the loader
+func resetRegistry(t *testing.T) {
+	t.Cleanup(func() {
+		reg.mu.Lock()
+		reg.grants = defaultGrants
+		reg.load = nil
+		reg.ttl = 0
+		reg.loadedAt = time.Time{}
+		reg.mu.Unlock()
+	})
+}
+
+func TestHasDefaultGrants(t *testing.T) {
+	if !Has("admin", CreateCourt) {
+		t.Error("expected admin to create courts")
+	}
+	if Has("user", CreateCourt) {
+		t.Error("expected user not to create courts")
+	}
+	if Has("unknown", CreateMatch) {
+		t.Error("expected unknown role to have no permissions")
+	}
+	if !Has(RoleDeveloper, ManageRoles) || Has("admin", ManageRoles) {
+		t.Error("expected only developers to manage roles")
+	}
+}
+
+func TestUse(t *testing.T) {
+	resetRegistry(t)
+	ctx := context.Background()
+
+	grants := map[string][]Permission{
+		"coordinator": {CreateMatch, UpdateMatch},
+	}
+	load := func(ctx context.Context) (map[string][]Permission, error) {
+		return grants, nil
+	}
+
+	if err := Use(ctx, load, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !Has("coordinator", UpdateMatch) {
+		t.Error("expected the loaded grant")
+	}
+	if Has("admin", CreateCourt) {
+		t.Error("expected the default grants to be replaced")
+	}
+
+	grants = map[string][]Permission{"coordinator": {CreateMatch}}
+	if !Has("coordinator", UpdateMatch) {
+		t.Error("expected the grants to stay cached until reloaded")
+	}
+	if err := Reload(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if Has("coordinator", UpdateMatch) {
+		t.Error("expected the revoked grant after reload")
+	}
+}
+
+func TestUseLoadError(t *testing.T) {
+	resetRegistry(t)
+
+	load := func(ctx context.Context) (map[string][]Permission, error) {
+		return nil, errors.New("db down")
+	}
+
+	if err := Use(context.Background(), load, 0); err == nil {
+		t.Fatal("expected the load error")
+	}
+	if !Has("admin", CreateCourt) {
+		t.Error("expected the previous grants to be kept")
+	}
+}
+
+func TestStaleGrantsReloaded(t *testing.T) {
+	resetRegistry(t)
+
+	loads := make(chan struct{}, 10)
+	load := func(ctx context.Context) (map[string][]Permission, error) {
+		loads <- struct{}{}
+		return map[string][]Permission{"user": {CreateMatch}}, nil
+	}
+
+	if err := Use(context.Background(), load, time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-loads
+
+	time.Sleep(5 * time.Millisecond)
+	Has("user", CreateMatch)
+
+	select {
+	case <-loads:
+	case <-time.After(time.Second):
+		t.Fatal("expected the stale grants to be reloaded in the background")
+	}
+}
+
+func TestHasScoped(t *testing.T) {
+	scopes := []Permission{CreateMatch}
+
+	if !HasScoped("user", scopes, CreateMatch) {
+		t.Error("expected the scoped permission")
+	}
+	if HasScoped("user", scopes, SubmitScore) {
+		t.Error("expected the scope to narrow the role permissions")
+	}
+	if !HasScoped(RoleService, scopes, CreateMatch) {
+		t.Error("expected the service role to get the key scopes")
+	}
+	if HasScoped("user", []Permission{CreateCourt}, CreateCourt) {
+		t.Error("expected the scope not to exceed the role permissions")
+	}
+}
@@ -0,0 +1,206 @@
+// Package privacy decides which personal data of a player the viewer can see.
+//
+// Every player chooses the visibility of their phone number, email, date of birth and physique
+// (height and weight). The visibilities are ordered, everyone sees more than league mates, league
+// mates more than opponents with a scheduled match and nobody hides the data from the other
+// players. The player themselves and the accounts managing players always see everything.
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+type Visibility string
+
+const (
+	Everyone    Visibility = "everyone"
+	LeagueMates Visibility = "league_mates"
+	Opponents   Visibility = "opponents"
+	Nobody      Visibility = "nobody"
+)
+
+func (v Visibility) IsValid() bool {
+	switch v {
+	case Everyone, LeagueMates, Opponents, Nobody:
+		return true
+	}
+	return false
+}
+
+// Allows reports whether the viewer with the relation to the player can see the data
+func (v Visibility) Allows(rel Relation) bool {
+	if rel.Self || rel.Privileged {
+		return true
+	}
+
+	switch v {
+	case Everyone:
+		return true
+	case LeagueMates:
+		return rel.LeagueMate || rel.Opponent
+	case Opponents:
+		return rel.Opponent
+	}
+	return false
+}
+
+// Settings are the visibilities a player chose for their personal data
+type Settings struct {
+	PhoneNumber Visibility `json:"phone_number" swaggertype:"string" enums:"everyone,league_mates,opponents,nobody"`
+	Email       Visibility `json:"email" swaggertype:"string" enums:"everyone,league_mates,opponents,nobody"`
+	Dob         Visibility `json:"dob" swaggertype:"string" enums:"everyone,league_mates,opponents,nobody"`
+	Physique    Visibility `json:"physique" swaggertype:"string" enums:"everyone,league_mates,opponents,nobody"`
+}
+
+// Default returns the settings of players who never changed them, they match the column defaults
+func Default() Settings {
+	return Settings{
+		PhoneNumber: Opponents,
+		Email:       LeagueMates,
+		Dob:         Nobody,
+		Physique:    Everyone,
+	}
+}
+
+func (s Settings) Validate(location string) []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	fields := []struct {
+		name string
+		v    Visibility
+	}{
+		{"phone_number", s.PhoneNumber},
+		{"email", s.Email},
+		{"dob", s.Dob},
+		{"physique", s.Physique},
+	}
+	for _, f := range fields {
+		if !f.v.IsValid() {
+			inv = append(inv, failure.InvalidField{
+				Field:    f.name,
+				Message:  fmt.Sprintf("Visibility must be one of %s, %s, %s or %s", Everyone, LeagueMates, Opponents, Nobody),
+				Location: location,
+			})
+		}
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+// Relation is how the viewer relates to the viewed player
+type Relation struct {
+	Self       bool
+	Privileged bool
+	LeagueMate bool
+	Opponent   bool
+}
+
+// Viewer is the one the player data is served to. Accounts without a player (service accounts)
+// have an empty PlayerId and are league mates or opponents of nobody
+type Viewer struct {
+	PlayerId   string
+	Privileged bool
+}
+
+// ViewerFromRequest returns the viewer of the authenticated request. Accounts allowed to update
+// players manage the player data and see all of it
+func ViewerFromRequest(r *http.Request) (Viewer, error) {
+	playerId, _ := r.Context().Value(middleware.PlayerIdCtxKey).(string)
+
+	privileged, err := middleware.HasPermission(r, permission.UpdatePlayer)
+	if err != nil {
+		return Viewer{}, err
+	}
+
+	return Viewer{PlayerId: playerId, Privileged: privileged}, nil
+}
+
+// RelationFinder returns the relation of the viewer to each of the players, the stores pass
+// theirs to the models protecting the player data
+type RelationFinder func(ctx context.Context, viewer Viewer, playerIds []string) (map[string]Relation, error)
+
+// BaseRelations are the relations known without a lookup, done reports there's nothing to look up.
+// The lookups of the other backends start from them
+func BaseRelations(viewer Viewer, playerIds []string) (result map[string]Relation, done bool) {
+	result = make(map[string]Relation, len(playerIds))
+	for _, id := range playerIds {
+		result[id] = Relation{Self: id == viewer.PlayerId, Privileged: viewer.Privileged}
+	}
+
+	return result, viewer.Privileged || viewer.PlayerId == "" || len(playerIds) == 0
+}
+
+// Relations returns the relation of the viewer to each of the players
+func Relations(ctx context.Context, q db.Querier, viewer Viewer, playerIds []string) (map[string]Relation, error) {
+	result, done := BaseRelations(viewer, playerIds)
+	if done {
+		return result, nil
+	}
+
+	// a match without a winner is still to be played
+	sql := `
+		select
+			player.id,
+			viewer.current_league_id is not null and player.current_league_id = viewer.current_league_id as league_mate,
+			exists (
+				select 1
+				from match
+				where match.winner_id is null
+				and (
+					(match.player_one_id = player.id and match.player_two_id = viewer.id)
+					or
+					(match.player_one_id = viewer.id and match.player_two_id = player.id)
+				)
+			) as opponent
+		from player
+		join player viewer on viewer.id = $1
+		where player.id = any($2)
+	`
+
+	rows, err := q.Query(ctx, sql, viewer.PlayerId, playerIds)
+	if err != nil {
+		return nil, failure.New("unable to find player relations", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var leagueMate, opponent bool
+		err := rows.Scan(&id, &leagueMate, &opponent)
+		if err != nil {
+			return nil, failure.New("unable to find player relations", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+
+		rel := result[id]
+		rel.LeagueMate = leagueMate
+		rel.Opponent = opponent
+		result[id] = rel
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to find player relations", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return result, nil
+}
+
+// Age returns the age in full years at the time
+func Age(dob, at time.Time) int {
+	age := at.Year() - dob.Year()
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
@@ -0,0 +1,69 @@
+package privacy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllows(t *testing.T) {
+	stranger := Relation{}
+	mate := Relation{LeagueMate: true}
+	opponent := Relation{LeagueMate: true, Opponent: true}
+
+	tests := []struct {
+		v        Visibility
+		rel      Relation
+		expected bool
+	}{
+		{Everyone, stranger, true},
+		{LeagueMates, stranger, false},
+		{LeagueMates, mate, true},
+		{LeagueMates, Relation{Opponent: true}, true},
+		{Opponents, mate, false},
+		{Opponents, opponent, true},
+		{Nobody, opponent, false},
+		{Nobody, Relation{Self: true}, true},
+		{Nobody, Relation{Privileged: true}, true},
+		{Visibility("unknown"), opponent, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.v.Allows(tt.rel); got != tt.expected {
+			t.Errorf("%s.Allows(%+v) = %v, expected %v", tt.v, tt.rel, got, tt.expected)
+		}
+	}
+}
+
+func TestSettingsValidate(t *testing.T) {
+	if inv := Default().Validate("body"); inv != nil {
+		t.Errorf("expected default settings to be valid, got %v", inv)
+	}
+
+	s := Default()
+	s.Email = "friends"
+	s.Physique = ""
+	inv := s.Validate("body")
+	if len(inv) != 2 || inv[0].Field != "email" || inv[1].Field != "physique" {
+		t.Errorf("expected email and physique to be invalid, got %v", inv)
+	}
+}
+
+func TestAge(t *testing.T) {
+	dob := time.Date(1990, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		at       time.Time
+		expected int
+	}{
+		{time.Date(2020, time.June, 14, 0, 0, 0, 0, time.UTC), 29},
+		{time.Date(2020, time.June, 15, 0, 0, 0, 0, time.UTC), 30},
+		{time.Date(2020, time.May, 30, 0, 0, 0, 0, time.UTC), 29},
+		{time.Date(2020, time.December, 1, 0, 0, 0, 0, time.UTC), 30},
+	}
+
+	for _, tt := range tests {
+		if got := Age(dob, tt.at); got != tt.expected {
+			t.Errorf("Age at %s = %d, expected %d", tt.at.Format("2006-01-02"), got, tt.expected)
+		}
+	}
+}
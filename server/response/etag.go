@@ -0,0 +1,98 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag is the entity tag of the row version, the If-Match header of the updates refers to it
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// BodyETag is the strong tag hashed from the encoded data. The representations joining other
+// rows, e.g. the matches with the names of their players, or differing by the viewer, e.g. the
+// players with the personal data hidden by the privacy settings, change without their version
+func BodyETag(data interface{}) (string, error) {
+	body, err := encode(data)
+	if err != nil {
+		return "", err
+	}
+	return bodyTag(body), nil
+}
+
+// WriteTagged writes the representation of a row with the tag of its version
+func WriteTagged(w http.ResponseWriter, status int, version int, data interface{}) {
+	w.Header().Set("ETag", ETag(version))
+	WriteSuccess(w, status, data)
+}
+
+// WriteBodyTagged writes the representation with the tag of its bytes, see BodyETag
+func WriteBodyTagged(w http.ResponseWriter, status int, data interface{}) {
+	body, err := encode(data)
+	if err != nil {
+		slog.Error("encoding the success response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", bodyTag(body))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// WriteCached writes the data with a tag hashed from the body, the request whose If-None-Match
+// holds the tag gets a 304 without the body. It's meant for the lists, which have no version
+func WriteCached(w http.ResponseWriter, r *http.Request, data interface{}) {
+	body, err := encode(data)
+	if err != nil {
+		slog.Error("encoding the success response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tag := bodyTag(body)
+	w.Header().Set("ETag", tag)
+
+	if noneMatch(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// encode returns the same body as WriteSuccess encodes
+func encode(data interface{}) ([]byte, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
+
+func bodyTag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// noneMatch reports whether the If-None-Match value holds the tag, it compares the tags weakly
+func noneMatch(value, tag string) bool {
+	if strings.TrimSpace(value) == "*" {
+		return true
+	}
+	for _, t := range strings.Split(value, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,12 +1,14 @@
 package response
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/logging"
 )
 
 type FailureWriter interface {
@@ -19,6 +21,8 @@ var failureStatusCodes = map[error]int{
 	failure.ErrBadRequest:   http.StatusBadRequest,
 	failure.ErrDuplicate:    http.StatusConflict,
 	failure.ErrCantModify:   http.StatusConflict,
+	failure.ErrMismatch:     http.StatusUnprocessableEntity,
+	failure.ErrStale:        http.StatusPreconditionFailed,
 	failure.ErrUnauthorized: http.StatusUnauthorized,
 	failure.ErrForbidden:    http.StatusForbidden,
 	failure.ErrInternal:     http.StatusInternalServerError,
@@ -34,16 +38,16 @@ func statusCodeFromFailure(err error) int {
 }
 
 func WriteFailure(w http.ResponseWriter, fw FailureWriter) {
-	log.Printf("%v", fw.Error())
 	statusCode := statusCodeFromFailure(fw)
 
-	// if statusCode >= 500 {
-	// 	log.Printf("%v", fw.Error())
-	// }
+	// the failure is logged with the request, at the level of its class
+	if !logging.RecordFailure(w, fw) {
+		slog.Log(context.Background(), logging.LevelFor(statusCode), "request failed", "status", statusCode, "error", fw.Error())
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(fw); err != nil {
-		log.Printf("error encoding the response: %v", err)
+		slog.Error("encoding the failure response", "error", err)
 	}
 }
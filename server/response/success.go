@@ -2,7 +2,7 @@ package response
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
@@ -15,6 +15,6 @@ func WriteSuccess(w http.ResponseWriter, status int, data interface{}) {
 		return
 	}
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Error encoding success response: %v", err)
+		slog.Error("encoding the success response", "error", err)
 	}
 }
@@ -0,0 +1,394 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/health"
+	"github.com/markovidakovic/gdsi/server/idempotency"
+	"github.com/markovidakovic/gdsi/server/logging"
+	"github.com/markovidakovic/gdsi/server/metrics"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/tracing"
+	v1 "github.com/markovidakovic/gdsi/server/v1"
+	"github.com/markovidakovic/gdsi/server/v1/auth"
+	"github.com/markovidakovic/gdsi/server/v1/roles"
+	"github.com/markovidakovic/gdsi/server/v1/seasons"
+	"github.com/markovidakovic/gdsi/server/wellknown"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+type server struct {
+	Cfg            *config.Config
+	Db             *db.Conn
+	Rtr            *chi.Mux
+	stopTracing    func(ctx context.Context) error
+	http           *http.Server
+	workers        workers
+	draining       atomic.Bool
+	swaggerEnabled bool
+}
+
+type serverOption func(*server) error
+
+// NewServer builds the server, the config is read through the layers of the options
+func NewServer(cfgOpts config.Options) (*server, error) {
+	var srv = &server{}
+
+	opts := []serverOption{
+		withConfig(cfgOpts),
+		withLogging(),
+		withTracing(),
+		withDatabase(),
+		withPermissions(),
+		withRouter(),
+		withHttpServer(),
+		withWorkers(),
+		withMetrics(),
+		withSwagger(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(srv); err != nil {
+			return nil, err
+		}
+	}
+
+	return srv, nil
+}
+
+// @title Gdsi API
+// @version 1.0.0
+// @description Documentation for the gdsi API
+
+// @host localhost:8080
+// @BasePath /
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Enter the Bearer token in the format: Bearer token
+func (s *server) MountRouters() {
+	s.mountRouters(v1.NewRepositories(s.Db))
+}
+
+// mountRouters mounts the routes backed by the repositories behind the middleware stack
+func (s *server) mountRouters(repos *v1.Repositories) {
+	s.setupMiddleware()
+
+	// liveness and readiness probes
+	s.Rtr.Group(health.New(s.healthChecks()...).Mount)
+
+	// mount v1
+	s.Rtr.Route("/v1", v1.New(s.Cfg, repos).Mount)
+
+	// public keys of the jwt signing keys
+	s.Rtr.Route("/.well-known", wellknown.New(s.Cfg).Mount)
+
+	if s.swaggerEnabled {
+		s.Rtr.Get("/swagger/*", httpSwagger.WrapHandler)
+	}
+}
+
+// healthChecks are the dependencies the readiness probe checks
+func (s *server) healthChecks() []health.Check {
+	return []health.Check{
+		{Name: "database", Check: func(ctx context.Context) error { return s.Db.Ping(ctx) }},
+		{Name: "migrations", Check: func(ctx context.Context) error {
+			pending, err := db.PendingMigrations(ctx, s.Db)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+			}
+			return nil
+		}},
+		{Name: "workers", Check: s.workers.check},
+		// the instance stops getting traffic as soon as the drain starts
+		{Name: "shutdown", Check: func(ctx context.Context) error {
+			if s.draining.Load() {
+				return fmt.Errorf("server is shutting down")
+			}
+			return nil
+		}},
+	}
+}
+
+// Run starts the workers and serves the requests until Shutdown is called. It returns nil
+// after a shutdown and the error of the listener otherwise
+func (s *server) Run() error {
+	s.workers.start()
+
+	var err error
+	if s.Cfg.TlsCertFile != "" {
+		slog.Info("api server started", "port", s.Cfg.ApiPort, "tls", true)
+		err = s.http.ListenAndServeTLS(s.Cfg.TlsCertFile, s.Cfg.TlsKeyFile)
+	} else {
+		slog.Info("api server started", "port", s.Cfg.ApiPort, "tls", false)
+		err = s.http.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting new connections and waits for the in-flight requests to complete,
+// then stops the workers and closes the db. The ctx bounds the whole drain. The connections
+// are only closed after the drain delay, until then the readiness probe fails and the load
+// balancer takes the instance out of rotation
+func (s *server) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
+
+	var errs []error
+
+	if s.http != nil && s.Cfg.HttpDrainDelay > 0 {
+		slog.Info("api server draining", "delay", s.Cfg.HttpDrainDelay)
+		select {
+		case <-time.After(s.Cfg.HttpDrainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	if s.http != nil {
+		if err := s.http.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("draining the http connections: %v", err))
+		}
+	}
+
+	if err := s.workers.stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("stopping the workers: %v", err))
+	}
+
+	// flush the spans of the drained requests
+	if s.stopTracing != nil {
+		if err := s.stopTracing(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping the tracing: %v", err))
+		}
+	}
+
+	// Close the db connection
+	if s.Db != nil {
+		if err := db.Disconnect(ctx, s.Db); err != nil {
+			errs = append(errs, fmt.Errorf("error closing the database connection: %v", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	slog.Info("api server shutdown completed")
+
+	return nil
+}
+
+func (s *server) setupMiddleware() {
+	corsOpts := cors.Options{
+		AllowedOrigins:   s.Cfg.CorsAllowedOrigins,
+		AllowedMethods:   s.Cfg.CorsAllowedMethods,
+		AllowedHeaders:   s.Cfg.CorsAllowedHeaders,
+		AllowCredentials: s.Cfg.CorsAllowCredentials,
+		MaxAge:           s.Cfg.CorsMaxAge,
+	}
+	// cors allows every origin for an empty list, the config allows none
+	if len(corsOpts.AllowedOrigins) == 0 {
+		corsOpts.AllowOriginFunc = func(r *http.Request, origin string) bool { return false }
+	}
+	s.Rtr.Use(cors.Handler(corsOpts))
+	s.Rtr.Use(chimiddleware.RequestID)
+	// the request span comes first so the log lines carry its trace id
+	s.Rtr.Use(tracing.Requests)
+	if s.Cfg.MetricsEnabled {
+		s.Rtr.Use(metrics.Requests)
+	}
+	s.Rtr.Use(logging.Requests)
+	s.Rtr.Use(chimiddleware.AllowContentType("application/json"))
+	s.Rtr.Use(chimiddleware.RequestSize(middleware.MaxBodyBytes))
+	s.Rtr.Use(chimiddleware.CleanPath)
+	// chi's NoCache would drop the If-Match and If-None-Match headers the handlers check
+	s.Rtr.Use(middleware.NoCache)
+	s.Rtr.Use(chimiddleware.StripSlashes)
+	s.Rtr.Use(chimiddleware.Heartbeat("/"))
+}
+
+func withConfig(opts config.Options) serverOption {
+	return func(s *server) error {
+		cfg, err := config.LoadOptions(opts)
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		s.Cfg = cfg
+		return nil
+	}
+}
+
+// withLogging makes the structured logger the default one, the lines of the log package
+// are written through it as well
+func withLogging() serverOption {
+	return func(s *server) error {
+		if s.Cfg == nil {
+			return fmt.Errorf("config must be initialized before logging")
+		}
+		logger, err := logging.New(os.Stderr, s.Cfg.LogFormat, s.Cfg.LogLevel)
+		if err != nil {
+			return fmt.Errorf("setting up logging: %w", err)
+		}
+		slog.SetDefault(logger)
+		return nil
+	}
+}
+
+// withTracing installs the tracer provider before the db is connected, so the queries are traced
+func withTracing() serverOption {
+	return func(s *server) error {
+		if s.Cfg == nil {
+			return fmt.Errorf("config must be initialized before tracing")
+		}
+		stop, err := tracing.Setup(context.Background(), tracing.Options{
+			Exporter:     s.Cfg.TracingExporter,
+			OTLPEndpoint: s.Cfg.TracingOtlpEndpoint,
+			SampleRatio:  s.Cfg.TracingSampleRatio,
+			ServiceName:  s.Cfg.TracingServiceName,
+		})
+		if err != nil {
+			return fmt.Errorf("setting up tracing: %w", err)
+		}
+		s.stopTracing = stop
+		return nil
+	}
+}
+
+func withDatabase() serverOption {
+	return func(s *server) error {
+		if s.Cfg == nil {
+			return fmt.Errorf("config must be initialized before database")
+		}
+		conn, err := db.Connect(s.Cfg)
+		if err != nil {
+			return err
+		}
+		s.Db = conn
+
+		if s.Cfg.DbMigrateOnStart {
+			applied, err := db.MigrateUp(context.Background(), conn)
+			if err != nil {
+				return fmt.Errorf("applying the migrations: %v", err)
+			}
+			slog.Info("migrations applied", "count", len(applied))
+		}
+		return nil
+	}
+}
+
+// withPermissions loads the role grants from the db, permission.Has checks against them
+func withPermissions() serverOption {
+	return func(s *server) error {
+		if s.Db == nil {
+			return fmt.Errorf("database must be initialized before permissions")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := permission.Use(ctx, roles.NewLoader(roles.NewStore(s.Db)), s.Cfg.RoleCacheTtl); err != nil {
+			return fmt.Errorf("loading role permissions: %w", err)
+		}
+		return nil
+	}
+}
+
+func withRouter() serverOption {
+	return func(s *server) error {
+		s.Rtr = chi.NewRouter()
+		return nil
+	}
+}
+
+func withHttpServer() serverOption {
+	return func(s *server) error {
+		if s.Rtr == nil {
+			return fmt.Errorf("router must be initialized before the http server")
+		}
+		s.http = &http.Server{
+			Addr:              ":" + s.Cfg.ApiPort,
+			Handler:           s.Rtr,
+			ReadTimeout:       s.Cfg.HttpReadTimeout,
+			ReadHeaderTimeout: s.Cfg.HttpReadHeaderTimeout,
+			WriteTimeout:      s.Cfg.HttpWriteTimeout,
+			IdleTimeout:       s.Cfg.HttpIdleTimeout,
+			MaxHeaderBytes:    s.Cfg.HttpMaxHeaderBytes,
+		}
+		return nil
+	}
+}
+
+// withWorkers registers the background workers of the api
+func withWorkers() serverOption {
+	return func(s *server) error {
+		if s.Db == nil {
+			return fmt.Errorf("database must be initialized before workers")
+		}
+		s.RegisterWorker("refresh token purge", Periodic(time.Hour, auth.NewTokenPurger(auth.NewStore(s.Db))))
+		s.RegisterWorker("idempotency key purge", Periodic(time.Hour, idempotency.NewPurger(idempotency.NewStore(s.Db))))
+		return nil
+	}
+}
+
+// withMetrics registers the metrics read on scrape. They're served on their own port by a worker,
+// so the listener follows the server lifecycle
+func withMetrics() serverOption {
+	return func(s *server) error {
+		if !s.Cfg.MetricsEnabled {
+			return nil
+		}
+		if s.Db == nil {
+			return fmt.Errorf("database must be initialized before metrics")
+		}
+
+		metrics.RegisterPool(s.Db)
+		metrics.RegisterActiveSeasons(seasons.NewActiveCounter(seasons.NewStore(s.Db)))
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		srv := &http.Server{
+			Addr:              ":" + s.Cfg.MetricsPort,
+			Handler:           mux,
+			ReadHeaderTimeout: s.Cfg.HttpReadHeaderTimeout,
+		}
+		s.RegisterWorker("metrics server", func(ctx context.Context) {
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Shutdown(shutdownCtx)
+			}()
+
+			slog.Info("metrics server started", "port", s.Cfg.MetricsPort)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics server failed", "error", err)
+			}
+		})
+
+		return nil
+	}
+}
+
+func withSwagger() serverOption {
+	return func(s *server) error {
+		s.swaggerEnabled = s.Cfg.SwaggerEnabled
+		return nil
+	}
+}
@@ -0,0 +1,193 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	v1 "github.com/markovidakovic/gdsi/server/v1"
+)
+
+// newTestServer serves the routes behind the middleware stack of the api, backed by the
+// in-memory database
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	cfg, err := config.LoadOptions(config.Options{Overrides: map[string]string{
+		"DB_DRIVER":                   "postgres",
+		"DB_HOST":                     "localhost",
+		"DB_NAME":                     "gdsi",
+		"DB_PORT":                     "5432",
+		"DB_USER":                     "gdsi",
+		"DB_PASSWORD":                 "gdsi",
+		"JWT_SECRET":                  "test-secret-test-secret-test-secret",
+		"REGISTRATION_MODE":           config.RegistrationOpen,
+		"PASSWORD_ARGON2_MEMORY":      "1024",
+		"PASSWORD_ARGON2_ITERATIONS":  "1",
+		"PASSWORD_ARGON2_PARALLELISM": "1",
+	}})
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+
+	s := &server{Cfg: cfg, Rtr: chi.NewRouter()}
+	s.mountRouters(v1.NewMemRepositories(memdb.New()))
+
+	srv := httptest.NewServer(s.Rtr)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// send sends the request with the headers and decodes the response into out
+func send(t *testing.T, srv *httptest.Server, method, path, token string, header http.Header, body, out any) *http.Response {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encoding the body: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, srv.URL+path, &buf)
+	if err != nil {
+		t.Fatalf("building the request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding the response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func TestConditionalRequestsPassTheMiddleware(t *testing.T) {
+	srv := newTestServer(t)
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	send(t, srv, http.MethodPost, "/v1/auth/signup", "", nil, map[string]string{
+		"name":         "Alice",
+		"email":        "alice@gdsi.test",
+		"dob":          "1990-01-01",
+		"gender":       "female",
+		"phone_number": "+385911234567",
+		"password":     "correct-horse-battery",
+	}, &tokens)
+	token := tokens.AccessToken
+
+	var me struct {
+		Player struct {
+			Id string `json:"id"`
+		} `json:"player"`
+	}
+	send(t, srv, http.MethodGet, "/v1/me", token, nil, nil, &me)
+	playerPath := "/v1/players/" + me.Player.Id
+
+	read := send(t, srv, http.MethodGet, playerPath, token, nil, nil, nil)
+	// the player representation differs by the viewer, its strong tag is hashed from the body
+	etag := read.Header.Get("ETag")
+	if !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("expected the player to be tagged strongly, got %q", etag)
+	}
+	if cc := read.Header.Get("Cache-Control"); cc == "" {
+		t.Error("expected the responses to keep their cache control")
+	}
+
+	// If-Match compares strongly, the weak form of the tag doesn't match
+	weak := http.Header{"If-Match": {"W/" + etag}}
+	if resp := send(t, srv, http.MethodPut, playerPath, token, weak, map[string]string{"racket": "Wilson"}, nil); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for the weak tag, got %d", resp.StatusCode)
+	}
+
+	// the second update is based on the version the first one replaced
+	stale := http.Header{"If-Match": {etag}}
+	if resp := send(t, srv, http.MethodPut, playerPath, token, stale, map[string]string{"racket": "Wilson"}, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the first update to pass, got %d", resp.StatusCode)
+	}
+	if resp := send(t, srv, http.MethodPut, playerPath, token, stale, map[string]string{"racket": "Head"}, nil); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for the stale update, got %d", resp.StatusCode)
+	}
+
+	list := send(t, srv, http.MethodGet, "/v1/players", token, nil, nil, nil)
+	cached := http.Header{"If-None-Match": {list.Header.Get("ETag")}}
+	if resp := send(t, srv, http.MethodGet, "/v1/players", token, cached, nil, nil); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304 for the unchanged list, got %d", resp.StatusCode)
+	}
+}
+
+func TestShutdownDrainDelay(t *testing.T) {
+	const delay = 200 * time.Millisecond
+
+	s := &server{Cfg: &config.Config{HttpDrainDelay: delay}}
+	// the readiness of the shutdown check, the other checks need the db
+	s.http = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.draining.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	go s.http.Serve(ln)
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- s.Shutdown(context.Background()) }()
+
+	for !s.draining.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	// the instance still answers during the delay, only not ready anymore
+	resp, err := http.Get("http://" + ln.Addr().String() + "/readyz")
+	if err != nil {
+		t.Fatalf("expected the server to accept requests during the drain delay: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz = %d; want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("expected the shutdown to wait the drain delay, it took %v", elapsed)
+	}
+	if _, err := http.Get("http://" + ln.Addr().String() + "/readyz"); err == nil {
+		t.Errorf("expected the server to refuse requests after the shutdown")
+	}
+}
+
+func TestCorsAllowsNoOriginByDefault(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := send(t, srv, http.MethodGet, "/healthz", "", http.Header{"Origin": {"https://evil.example"}}, nil, nil)
+	if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin != "" {
+		t.Errorf("expected no origin to be allowed without CORS_ALLOWED_ORIGINS, got %q", origin)
+	}
+}
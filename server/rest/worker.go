@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/logging"
+)
+
+// Worker is a background task of the server. It's started with the server and must return
+// once the ctx is canceled on shutdown
+type Worker func(ctx context.Context)
+
+type workers struct {
+	named  map[string]Worker
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	states map[string]*workerState
+}
+
+// workerState is what the readiness check sees of a worker
+type workerState struct {
+	running atomic.Bool
+}
+
+// check fails if a started worker stopped before the shutdown. The failed runs of the periodic
+// tasks are only logged, the instance keeps serving the requests they don't affect
+func (ws *workers) check(ctx context.Context) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	var errs []error
+	for name, state := range ws.states {
+		if !state.running.Load() {
+			errs = append(errs, fmt.Errorf("worker %s is not running", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RegisterWorker adds the worker to the server lifecycle, it must be called before Run
+func (s *server) RegisterWorker(name string, w Worker) {
+	if s.workers.named == nil {
+		s.workers.named = make(map[string]Worker)
+	}
+	s.workers.named[name] = w
+}
+
+func (ws *workers) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	ws.cancel = cancel
+
+	ws.mu.Lock()
+	ws.states = make(map[string]*workerState, len(ws.named))
+	ws.mu.Unlock()
+
+	for name, w := range ws.named {
+		// the lines the worker logs with its ctx carry its name
+		wctx := logging.WithFields(ctx)
+		logging.Add(wctx, slog.String("worker", name))
+
+		state := &workerState{}
+		state.running.Store(true)
+
+		ws.mu.Lock()
+		ws.states[name] = state
+		ws.mu.Unlock()
+
+		ws.wg.Add(1)
+		go func() {
+			defer ws.wg.Done()
+			slog.Info("worker started", "worker", name)
+			w(wctx)
+			slog.Info("worker stopped", "worker", name)
+
+			state.running.Store(false)
+		}()
+	}
+}
+
+// stop cancels the workers and waits for them to return, or for the ctx to be done
+func (ws *workers) stop(ctx context.Context) error {
+	if ws.cancel == nil {
+		return nil
+	}
+	ws.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		ws.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Periodic returns the worker running the task every interval. A failed run is logged
+// and retried on the next tick
+func Periodic(interval time.Duration, task func(ctx context.Context) error) Worker {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := task(ctx); err != nil && ctx.Err() == nil {
+					slog.ErrorContext(ctx, "periodic task failed", "error", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkersCheck(t *testing.T) {
+	t.Run("FailedRunKeepsReady", func(t *testing.T) {
+		var ws workers
+		ran := make(chan struct{}, 1)
+		ws.named = map[string]Worker{"purge": Periodic(time.Millisecond, func(ctx context.Context) error {
+			select {
+			case ran <- struct{}{}:
+			default:
+			}
+			return errors.New("db is down")
+		})}
+		ws.start()
+		t.Cleanup(func() { ws.stop(context.Background()) })
+
+		<-ran
+		if err := ws.check(context.Background()); err != nil {
+			t.Errorf("expected a failed run not to fail the check, got %v", err)
+		}
+	})
+
+	t.Run("StoppedWorkerFails", func(t *testing.T) {
+		var ws workers
+		ws.named = map[string]Worker{"server": func(ctx context.Context) {}}
+		ws.start()
+		t.Cleanup(func() { ws.stop(context.Background()) })
+
+		ws.wg.Wait()
+		if err := ws.check(context.Background()); err == nil {
+			t.Error("expected the stopped worker to fail the check")
+		}
+	})
+}
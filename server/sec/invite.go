@@ -0,0 +1,22 @@
+package sec
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// GenerateInviteCode returns a new invite code in the xxxxx-xxxxx-xxxxx format, short enough to be
+// shared and typed in by hand
+func GenerateInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	enc := strings.ToLower(b32.EncodeToString(b))[:15]
+	return enc[:5] + "-" + enc[5:10] + "-" + enc[10:], nil
+}
+
+// NormalizeInviteCode makes the user input comparable with the generated codes
+func NormalizeInviteCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
@@ -0,0 +1,19 @@
+package sec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateInviteCode(t *testing.T) {
+	code, err := GenerateInviteCode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(code) != 17 || strings.Count(code, "-") != 2 {
+		t.Errorf("unexpected code format %q", code)
+	}
+	if NormalizeInviteCode(" "+strings.ToUpper(code)+"\n") != code {
+		t.Errorf("expected the typed in code to match %q", code)
+	}
+}
@@ -0,0 +1,282 @@
+package sec
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// kid of the shared secret key used when no asymmetric keys are configured
+const hmacKid = "hs256"
+
+// KeyRing holds the keys used for signing and verifying the gdsi jwts. Exactly one key
+// signs new tokens, the others are only used for verification so tokens signed before
+// a rotation stay valid until the old key is retired
+type KeyRing struct {
+	signing jwk.Key
+	keys    []ringKey
+}
+
+type ringKey struct {
+	key      jwk.Key
+	public   jwk.Key
+	retireAt *time.Time
+}
+
+func newRingKey(key jwk.Key, retireAt *time.Time) (ringKey, error) {
+	pub, err := key.PublicKey()
+	if err != nil {
+		return ringKey{}, err
+	}
+	return ringKey{key: key, public: pub, retireAt: retireAt}, nil
+}
+
+// KeySpec describes a single key of the key ring. The key is dropped from the
+// verification set and the published jwks once RetireAt passes
+type KeySpec struct {
+	Kid      string
+	Path     string
+	RetireAt *time.Time
+}
+
+// ParseKeySpecs parses the key list in the <kid>=<pem path>[@<retire at rfc3339>]
+// format, entries separated by commas
+func ParseKeySpecs(val string) ([]KeySpec, error) {
+	var specs []KeySpec
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, rest, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || rest == "" {
+			return nil, fmt.Errorf("invalid jwt key entry %q, expected <kid>=<path>[@<retire at>]", entry)
+		}
+
+		spec := KeySpec{Kid: kid, Path: rest}
+		if path, retire, ok := strings.Cut(rest, "@"); ok {
+			t, err := time.Parse(time.RFC3339, retire)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retire time of jwt key %q -> %w", kid, err)
+			}
+			spec.Path = path
+			spec.RetireAt = &t
+		}
+
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// NewKeyRing loads the pem encoded private keys of the specs. RSA keys sign with RS256
+// and ed25519 keys with EdDSA. If no specs are provided the key ring falls back to
+// HS256 with the shared secret, which can't be published in the jwks
+func NewKeyRing(specs []KeySpec, signingKid string, secret []byte) (*KeyRing, error) {
+	kr := &KeyRing{}
+
+	if len(specs) == 0 {
+		key, err := jwk.FromRaw(secret)
+		if err != nil {
+			return nil, fmt.Errorf("creating hmac key -> %w", err)
+		}
+		key.Set(jwk.KeyIDKey, hmacKid)
+		key.Set(jwk.AlgorithmKey, jwa.HS256)
+
+		rk, err := newRingKey(key, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		kr.signing = key
+		kr.keys = append(kr.keys, rk)
+		slog.Warn("no jwt keys configured, signing tokens with the shared HS256 secret")
+		return kr, nil
+	}
+
+	for _, spec := range specs {
+		key, err := loadPrivateKey(spec.Path)
+		if err != nil {
+			return nil, fmt.Errorf("loading jwt key %q -> %w", spec.Kid, err)
+		}
+		key.Set(jwk.KeyIDKey, spec.Kid)
+
+		for _, rk := range kr.keys {
+			if rk.key.KeyID() == spec.Kid {
+				return nil, fmt.Errorf("duplicate jwt key id %q", spec.Kid)
+			}
+		}
+
+		rk, err := newRingKey(key, spec.RetireAt)
+		if err != nil {
+			return nil, fmt.Errorf("loading jwt key %q -> %w", spec.Kid, err)
+		}
+
+		kr.keys = append(kr.keys, rk)
+		if spec.Kid == signingKid {
+			if spec.RetireAt != nil {
+				return nil, fmt.Errorf("jwt signing key %q can't have a retire time", spec.Kid)
+			}
+			kr.signing = key
+		}
+	}
+
+	if kr.signing == nil {
+		return nil, fmt.Errorf("jwt signing key %q is not one of the configured keys", signingKid)
+	}
+
+	return kr, nil
+}
+
+// SigningKid returns the key id new tokens are signed with
+func (kr *KeyRing) SigningKid() string {
+	return kr.signing.KeyID()
+}
+
+// Encode signs the claims with the current signing key, the key id is set in the token header
+func (kr *KeyRing) Encode(claims map[string]interface{}) (jwt.Token, string, error) {
+	t := jwt.New()
+	for k, v := range claims {
+		if err := t.Set(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	signed, err := jwt.Sign(t, jwt.WithKey(kr.signing.Algorithm(), kr.signing))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return t, string(signed), nil
+}
+
+// Verify checks the token signature against the key matching its kid and validates the
+// registered claims. Tokens without a kid were signed before the key ring, they are checked
+// against the shared HS256 secret if it is still in the ring. The errors are normalized the
+// same way as in jwtauth
+func (kr *KeyRing) Verify(val string) (jwt.Token, error) {
+	set, err := kr.verificationSet(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	keyOpt := jwt.WithKeySet(set, jws.WithRequireKid(true))
+	if msg, err := jws.Parse([]byte(val)); err == nil && len(msg.Signatures()) == 1 && msg.Signatures()[0].ProtectedHeaders().KeyID() == "" {
+		if key, ok := set.LookupKeyID(hmacKid); ok {
+			keyOpt = jwt.WithKey(jwa.HS256, key)
+		}
+	}
+
+	tkn, err := jwt.Parse([]byte(val), keyOpt, jwt.WithValidate(false))
+	if err != nil {
+		return nil, jwtauth.ErrorReason(err)
+	}
+
+	if err := jwt.Validate(tkn); err != nil {
+		return tkn, jwtauth.ErrorReason(err)
+	}
+
+	return tkn, nil
+}
+
+// JWKS returns the public keys which are not retired yet. The shared HS256
+// secret is never published
+func (kr *KeyRing) JWKS() (jwk.Set, error) {
+	set := jwk.NewSet()
+	now := time.Now()
+
+	for _, rk := range kr.keys {
+		if rk.retired(now) || rk.key.KeyType() == "oct" {
+			continue
+		}
+
+		pub, err := rk.public.Clone()
+		if err != nil {
+			return nil, err
+		}
+		pub.Set(jwk.KeyUsageKey, jwk.ForSignature)
+
+		if err := set.AddKey(pub); err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
+func (kr *KeyRing) verificationSet(now time.Time) (jwk.Set, error) {
+	set := jwk.NewSet()
+	for _, rk := range kr.keys {
+		if rk.retired(now) {
+			continue
+		}
+		if err := set.AddKey(rk.public); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func (rk ringKey) retired(now time.Time) bool {
+	return rk.retireAt != nil && now.After(*rk.retireAt)
+}
+
+func loadPrivateKey(path string) (jwk.Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	var raw crypto.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		raw, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		raw, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var alg jwa.SignatureAlgorithm
+	switch k := raw.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("rsa keys must be at least 2048 bits")
+		}
+		alg = jwa.RS256
+	case ed25519.PrivateKey:
+		alg = jwa.EdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use rsa or ed25519", raw)
+	}
+
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+	key.Set(jwk.AlgorithmKey, alg)
+
+	return key, nil
+}
@@ -0,0 +1,248 @@
+package sec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+func writeKey(t *testing.T, dir, name string, raw interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(raw)
+	if err != nil {
+		t.Fatalf("marshaling key: %v", err)
+	}
+
+	path := filepath.Join(dir, name)
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+	return path
+}
+
+func testKeyFiles(t *testing.T) (rsaPath, edPath string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating rsa key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating ed25519 key: %v", err)
+	}
+
+	return writeKey(t, dir, "rsa.pem", rsaKey), writeKey(t, dir, "ed.pem", edKey)
+}
+
+func testClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"sub": "account-id",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Minute).Unix(),
+	}
+}
+
+func TestParseKeySpecs(t *testing.T) {
+	specs, err := ParseKeySpecs("old=/keys/old.pem@2026-11-01T00:00:00Z, new=/keys/new.pem")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 specs, got %d", len(specs))
+	}
+	if specs[0].Kid != "old" || specs[0].Path != "/keys/old.pem" || specs[0].RetireAt == nil {
+		t.Errorf("unexpected spec %+v", specs[0])
+	}
+	if specs[1].Kid != "new" || specs[1].Path != "/keys/new.pem" || specs[1].RetireAt != nil {
+		t.Errorf("unexpected spec %+v", specs[1])
+	}
+
+	for _, val := range []string{"missing-path", "=/keys/a.pem", "a=/keys/a.pem@tomorrow"} {
+		if _, err := ParseKeySpecs(val); err == nil {
+			t.Errorf("expected error for %q", val)
+		}
+	}
+}
+
+func TestKeyRing(t *testing.T) {
+	rsaPath, edPath := testKeyFiles(t)
+
+	t.Run("SignAndVerify", func(t *testing.T) {
+		for _, kid := range []string{"rsa", "ed"} {
+			kr, err := NewKeyRing([]KeySpec{{Kid: "rsa", Path: rsaPath}, {Kid: "ed", Path: edPath}}, kid, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, val, err := kr.Encode(testClaims())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			tkn, err := kr.Verify(val)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", kid, err)
+			}
+			if tkn.Subject() != "account-id" {
+				t.Errorf("%s: expected subject account-id, got %q", kid, tkn.Subject())
+			}
+		}
+	})
+
+	t.Run("RotationOverlap", func(t *testing.T) {
+		before, err := NewKeyRing([]KeySpec{{Kid: "rsa", Path: rsaPath}}, "rsa", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, val, err := before.Encode(testClaims())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// the new key signs, the old one still verifies
+		retireAt := time.Now().Add(time.Hour)
+		after, err := NewKeyRing([]KeySpec{{Kid: "rsa", Path: rsaPath, RetireAt: &retireAt}, {Kid: "ed", Path: edPath}}, "ed", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := after.Verify(val); err != nil {
+			t.Fatalf("expected token of the previous key to verify, got %v", err)
+		}
+
+		// once retired the old key is gone
+		retired := time.Now().Add(-time.Second)
+		final, err := NewKeyRing([]KeySpec{{Kid: "rsa", Path: rsaPath, RetireAt: &retired}, {Kid: "ed", Path: edPath}}, "ed", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := final.Verify(val); err == nil {
+			t.Fatal("expected token of the retired key to be rejected")
+		}
+
+		set, err := final.JWKS()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if set.Len() != 1 {
+			t.Fatalf("expected 1 published key, got %d", set.Len())
+		}
+		if _, ok := set.LookupKeyID("ed"); !ok {
+			t.Error("expected the ed key to be published")
+		}
+	})
+
+	t.Run("JWKSOnlyPublicKeys", func(t *testing.T) {
+		kr, err := NewKeyRing([]KeySpec{{Kid: "rsa", Path: rsaPath}, {Kid: "ed", Path: edPath}}, "rsa", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		set, err := kr.JWKS()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i := 0; i < set.Len(); i++ {
+			k, _ := set.Key(i)
+			if _, ok := k.Get("d"); ok {
+				t.Errorf("key %q exposes private material", k.KeyID())
+			}
+		}
+	})
+
+	t.Run("HMACFallback", func(t *testing.T) {
+		kr, err := NewKeyRing(nil, "", []byte("secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, val, err := kr.Encode(testClaims())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := kr.Verify(val); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		set, err := kr.JWKS()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if set.Len() != 0 {
+			t.Errorf("expected the shared secret not to be published")
+		}
+	})
+
+	t.Run("HMACWithoutKid", func(t *testing.T) {
+		// tokens signed before the key ring carry no kid
+		tkn := jwt.New()
+		tkn.Set(jwt.SubjectKey, "account-id")
+		tkn.Set(jwt.ExpirationKey, time.Now().Add(time.Minute))
+		val, err := jwt.Sign(tkn, jwt.WithKey(jwa.HS256, []byte("secret")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		kr, err := NewKeyRing(nil, "", []byte("secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := kr.Verify(string(val)); err != nil {
+			t.Fatalf("expected the kid-less token to verify, got %v", err)
+		}
+
+		other, err := NewKeyRing(nil, "", []byte("other secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := other.Verify(string(val)); err == nil {
+			t.Fatal("expected the kid-less token of another secret to be rejected")
+		}
+
+		asym, err := NewKeyRing([]KeySpec{{Kid: "rsa", Path: rsaPath}}, "rsa", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := asym.Verify(string(val)); err == nil {
+			t.Fatal("expected the kid-less token to be rejected without the shared secret")
+		}
+	})
+
+	t.Run("ForeignToken", func(t *testing.T) {
+		other, err := NewKeyRing(nil, "", []byte("other secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, val, err := other.Encode(testClaims())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		kr, err := NewKeyRing([]KeySpec{{Kid: "rsa", Path: rsaPath}}, "rsa", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := kr.Verify(val); err == nil {
+			t.Fatal("expected token signed with an unknown key to be rejected")
+		}
+	})
+
+	t.Run("UnknownSigningKid", func(t *testing.T) {
+		if _, err := NewKeyRing([]KeySpec{{Kid: "rsa", Path: rsaPath}}, "missing", nil); err == nil {
+			t.Fatal("expected error for an unknown signing kid")
+		}
+	})
+}
@@ -1,11 +1,207 @@
 package sec
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
 
-func EncryptPwd(pwd string) (string, error) {
-	pwdBytes, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
-	if err != nil {
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The password hashes are stored in the PHC string format, the prefix tells the algorithm apart
+// so the hashes created with an older algorithm or older parameters keep verifying:
+//
+//	argon2id  $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+//	bcrypt    $2a$10$<salt and key> (the hashes created before argon2id)
+
+// PasswordParams are the argon2id parameters the new hashes are created with
+type PasswordParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultPasswordParams follow the second recommended option of rfc 9106 with less memory
+var DefaultPasswordParams = PasswordParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// PasswordHasher hashes the passwords with argon2id and verifies the hashes of every supported version
+type PasswordHasher struct {
+	params PasswordParams
+}
+
+func NewPasswordHasher(params PasswordParams) (*PasswordHasher, error) {
+	if params.Iterations < 1 || params.Parallelism < 1 {
+		return nil, fmt.Errorf("password hashing -> iterations and parallelism must be at least 1")
+	}
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return nil, fmt.Errorf("password hashing -> memory must be at least 8 KiB per lane")
+	}
+	if params.SaltLength < 8 || params.KeyLength < 16 {
+		return nil, fmt.Errorf("password hashing -> salt must be at least 8 and key at least 16 bytes")
+	}
+	return &PasswordHasher{params: params}, nil
+}
+
+// Hash returns the argon2id hash of the password with a random salt
+func (ph *PasswordHasher) Hash(pwd string) (string, error) {
+	salt := make([]byte, ph.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	return string(pwdBytes), nil
+
+	p := ph.params
+	key := argon2.IDKey([]byte(pwd), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether the password matches the hash. rehash is set for matching passwords whose
+// hash was created with another algorithm or other parameters, they should be hashed again
+func (ph *PasswordHasher) Verify(encoded, pwd string) (ok, rehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		other := argon2.IDKey([]byte(pwd), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false, nil
+		}
+		p.SaltLength = uint32(len(salt))
+		p.KeyLength = uint32(len(key))
+		return true, p != ph.params, nil
+	case strings.HasPrefix(encoded, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(pwd))
+		if err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, err
+		}
+		return true, true, nil
+	}
+	return false, false, errMalformedHash
+}
+
+func decodeArgon2id(encoded string) (PasswordParams, []byte, []byte, error) {
+	var p PasswordParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+
+	return p, salt, key, nil
+}
+
+// the longest password accepted, argon2 has no limit of its own and hashing huge inputs is a cheap dos
+const maxPasswordLength = 256
+
+//go:embed common_passwords.txt
+var commonPasswords []byte
+
+// PasswordPolicy decides which new passwords are acceptable. It's checked when a password is set,
+// the existing passwords keep working
+type PasswordPolicy struct {
+	minLength int
+	blocked   map[string]struct{}
+}
+
+// NewPasswordPolicy returns the policy with the minimum length. The common passwords shipped with
+// the server are always blocked, the optional blocklist file adds one password per line
+func NewPasswordPolicy(minLength int, blocklistFile string) (*PasswordPolicy, error) {
+	pp := &PasswordPolicy{
+		minLength: minLength,
+		blocked:   map[string]struct{}{},
+	}
+
+	pp.block(commonPasswords)
+
+	if blocklistFile != "" {
+		b, err := os.ReadFile(blocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading password blocklist -> %w", err)
+		}
+		pp.block(b)
+	}
+
+	return pp, nil
+}
+
+func (pp *PasswordPolicy) block(list []byte) {
+	sc := bufio.NewScanner(bytes.NewReader(list))
+	for sc.Scan() {
+		line := strings.ToLower(strings.TrimSpace(sc.Text()))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			pp.blocked[line] = struct{}{}
+		}
+	}
+}
+
+// Check returns why the password is not acceptable, or an empty string if it is. personal holds the
+// account data the password must not be made of, like the email and the name
+func (pp *PasswordPolicy) Check(pwd string, personal ...string) string {
+	n := utf8.RuneCountInString(pwd)
+	if n < pp.minLength {
+		return fmt.Sprintf("Password must be at least %d characters long", pp.minLength)
+	}
+	if n > maxPasswordLength {
+		return fmt.Sprintf("Password must be at most %d characters long", maxPasswordLength)
+	}
+
+	lower := strings.ToLower(pwd)
+	if _, ok := pp.blocked[lower]; ok {
+		return "Password is too common"
+	}
+	for _, p := range personal {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		local, _, _ := strings.Cut(p, "@")
+		if lower == p || lower == local {
+			return "Password must not be your email or name"
+		}
+	}
+
+	return ""
 }
@@ -0,0 +1,111 @@
+package sec
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams keep the tests fast, the verification doesn't depend on the cost
+var testParams = PasswordParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasher(t *testing.T) {
+	ph, err := NewPasswordHasher(testParams)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hash, err := ph.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+
+	ok, rehash, err := ph.Verify(hash, "correct horse battery staple")
+	if err != nil || !ok || rehash {
+		t.Errorf("expected the password to match without rehash, got ok=%v rehash=%v err=%v", ok, rehash, err)
+	}
+
+	ok, _, err = ph.Verify(hash, "wrong horse battery staple")
+	if err != nil || ok {
+		t.Errorf("expected the wrong password to be rejected, got ok=%v err=%v", ok, err)
+	}
+
+	other, _ := ph.Hash("correct horse battery staple")
+	if other == hash {
+		t.Error("expected a random salt per hash")
+	}
+}
+
+func TestPasswordHasherRehash(t *testing.T) {
+	old, _ := NewPasswordHasher(testParams)
+	hash, _ := old.Hash("correct horse battery staple")
+
+	stronger := testParams
+	stronger.Iterations = 2
+	ph, _ := NewPasswordHasher(stronger)
+
+	ok, rehash, err := ph.Verify(hash, "correct horse battery staple")
+	if err != nil || !ok || !rehash {
+		t.Errorf("expected the old parameters to need a rehash, got ok=%v rehash=%v err=%v", ok, rehash, err)
+	}
+
+	b, err := bcrypt.GenerateFromPassword([]byte("correct horse battery staple"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ok, rehash, err = ph.Verify(string(b), "correct horse battery staple")
+	if err != nil || !ok || !rehash {
+		t.Errorf("expected the bcrypt hash to verify and need a rehash, got ok=%v rehash=%v err=%v", ok, rehash, err)
+	}
+
+	ok, _, err = ph.Verify(string(b), "wrong")
+	if err != nil || ok {
+		t.Errorf("expected the wrong password to be rejected, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestPasswordHasherMalformed(t *testing.T) {
+	ph, _ := NewPasswordHasher(testParams)
+
+	for _, h := range []string{"", "plain", "$argon2id$v=19$m=64,t=1,p=1$salt", "$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5"} {
+		if _, _, err := ph.Verify(h, "pwd"); err == nil {
+			t.Errorf("expected %q to be rejected as malformed", h)
+		}
+	}
+
+	if _, err := NewPasswordHasher(PasswordParams{Memory: 64, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32}); err == nil {
+		t.Error("expected zero iterations to be rejected")
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	pp, err := NewPasswordPolicy(10, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		pwd   string
+		valid bool
+	}{
+		{"short", false},
+		{"Password123", false},
+		{"QWERTYUIOP", false},
+		{"jane.doe.smith", false},
+		{"Jane Doe Smith", false},
+		{"correct horse battery staple", true},
+		{strings.Repeat("a", 300), false},
+	}
+
+	for _, tt := range tests {
+		msg := pp.Check(tt.pwd, "jane.doe.smith@example.com", "Jane Doe Smith")
+		if (msg == "") != tt.valid {
+			t.Errorf("Check(%q) = %q, expected valid=%v", tt.pwd, msg, tt.valid)
+		}
+	}
+}
@@ -1,11 +1,192 @@
 package sec
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func HashToken(val string) string {
 	hash := sha256.Sum256([]byte(val))
 	return hex.EncodeToString(hash[:])
 }
+
+// RandomToken returns a hex encoded cryptographically secure random
+// token built from n random bytes
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// authentication methods (rfc 8176) stored in the amr claim. fed is not registered
+// in the rfc, it marks a login through an external identity provider
+const (
+	AmrPassword  = "pwd"
+	AmrOTP       = "otp"
+	AmrFederated = "fed"
+)
+
+// token use of the short lived token issued between the password and totp login steps
+const challengeTokenUse = "2fa_challenge"
+
+type Token struct {
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Value     string
+}
+
+// GenerateAuthTokens creates a new access and refresh jwt pair for the provided account.
+// amr holds the methods the account authenticated with, tokenVersion the current account
+// token version. The tokens are rejected once the account version moves past it
+func GenerateAuthTokens(kr *KeyRing, durAccess, durRefresh time.Duration, accountId, role, playerId string, tokenVersion int, amr []string) (accessTkn, refreshTkn Token, err error) {
+	var iss string = "gdsi api"
+	var aud string = "gdsi app"
+
+	now := time.Now()
+
+	// expiration dates for tokens
+	var expAccess time.Time = now.Add(durAccess)
+	var expRefresh time.Time = now.Add(durRefresh)
+
+	// jwt claims
+	var claims = map[string]interface{}{
+		"iss":           iss,
+		"sub":           accountId,
+		"aud":           aud,
+		"exp":           expAccess.Unix(),
+		"nbf":           now.Unix(),
+		"iat":           now.Unix(),
+		"role":          role,
+		"player_id":     playerId,
+		"amr":           amr,
+		"token_version": tokenVersion,
+	}
+
+	// unique token id so two pairs issued within the same second never collide
+	claims["jti"] = uuid.NewString()
+
+	_, accessTknEnc, err := kr.Encode(claims)
+	if err != nil {
+		return
+	}
+
+	// change the exp and jti values for refresh token
+	claims["exp"] = expRefresh.Unix()
+	claims["jti"] = uuid.NewString()
+
+	_, refreshTknEnc, err := kr.Encode(claims)
+	if err != nil {
+		return
+	}
+
+	accessTkn = Token{
+		IssuedAt:  now,
+		ExpiresAt: expAccess,
+		Value:     accessTknEnc,
+	}
+	refreshTkn = Token{
+		IssuedAt:  now,
+		ExpiresAt: expRefresh,
+		Value:     refreshTknEnc,
+	}
+
+	return
+}
+
+// GenerateChallengeToken creates the token which proves the first step of the login
+// succeeded. It can only be exchanged for auth tokens together with a totp code.
+// amr holds the methods used in the first step
+func GenerateChallengeToken(kr *KeyRing, accountId string, ttl time.Duration, amr []string) (Token, error) {
+	now := time.Now()
+	exp := now.Add(ttl)
+
+	claims := map[string]interface{}{
+		"iss":       "gdsi api",
+		"sub":       accountId,
+		"aud":       "gdsi app",
+		"exp":       exp.Unix(),
+		"nbf":       now.Unix(),
+		"iat":       now.Unix(),
+		"jti":       uuid.NewString(),
+		"token_use": challengeTokenUse,
+		"amr":       amr,
+	}
+
+	_, enc, err := kr.Encode(claims)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return Token{
+		IssuedAt:  now,
+		ExpiresAt: exp,
+		Value:     enc,
+	}, nil
+}
+
+// ParseChallengeToken verifies the challenge token and returns the account id it was
+// issued for together with the methods used in the first login step
+func ParseChallengeToken(kr *KeyRing, val string) (string, []string, error) {
+	tkn, err := kr.Verify(val)
+	if err != nil {
+		return "", nil, err
+	}
+
+	use, _ := tkn.Get("token_use")
+	if use != challengeTokenUse {
+		return "", nil, fmt.Errorf("token is not a challenge token")
+	}
+
+	if tkn.Subject() == "" {
+		return "", nil, fmt.Errorf("challenge token is missing the subject")
+	}
+
+	amr := []string{}
+	raw, _ := tkn.Get("amr")
+	if methods, ok := raw.([]interface{}); ok {
+		for _, m := range methods {
+			if s, ok := m.(string); ok {
+				amr = append(amr, s)
+			}
+		}
+	}
+	if len(amr) == 0 {
+		amr = append(amr, AmrPassword)
+	}
+
+	return tkn.Subject(), amr, nil
+}
+
+// IsChallengeToken reports if the claims belong to a challenge token, these
+// must never be accepted as access tokens
+func IsChallengeToken(claims map[string]interface{}) bool {
+	use, ok := claims["token_use"].(string)
+	return ok && use == challengeTokenUse
+}
+
+// APIKeyPrefix marks the api keys so they can be told apart from jwts
+const APIKeyPrefix = "gdsi_"
+
+// GenerateAPIKey returns a new api key together with its public prefix. The prefix
+// is stored in plain text so the owner can recognize the key, the key itself only hashed
+func GenerateAPIKey() (key, prefix string, err error) {
+	secret, err := RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + secret
+	return key, key[:len(APIKeyPrefix)+8], nil
+}
+
+// IsAPIKey reports if the bearer value is an api key
+func IsAPIKey(val string) bool {
+	return strings.HasPrefix(val, APIKeyPrefix)
+}
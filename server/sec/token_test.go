@@ -0,0 +1,48 @@
+package sec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !IsAPIKey(key) {
+		t.Errorf("expected %q to be recognized as an api key", key)
+	}
+	if !strings.HasPrefix(key, prefix) || len(prefix) != len(APIKeyPrefix)+8 {
+		t.Errorf("unexpected prefix %q of the key %q", prefix, key)
+	}
+
+	other, _, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other == key {
+		t.Errorf("expected the keys to differ")
+	}
+}
+
+func TestIsAPIKey(t *testing.T) {
+	testCases := []struct {
+		name     string
+		val      string
+		expected bool
+	}{
+		{name: "APIKey", val: APIKeyPrefix + "abc", expected: true},
+		{name: "JWT", val: "eyJhbGciOiJIUzI1NiJ9.e30.sig", expected: false},
+		{name: "Empty", val: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := IsAPIKey(tc.val); result != tc.expected {
+				t.Errorf("IsAPIKey(%q) = %v; want %v", tc.val, result, tc.expected)
+			}
+		})
+	}
+}
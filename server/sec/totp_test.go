@@ -0,0 +1,100 @@
+package sec
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// rfc 6238 appendix b sha1 test vectors (truncated to 6 digits)
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		name     string
+		unix     int64
+		expected string
+	}{
+		{name: "59", unix: 59, expected: "287082"},
+		{name: "1111111109", unix: 1111111109, expected: "081804"},
+		{name: "1111111111", unix: 1111111111, expected: "050471"},
+		{name: "1234567890", unix: 1234567890, expected: "005924"},
+		{name: "2000000000", unix: 2000000000, expected: "279037"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := TOTPCode(secret, TOTPStep(time.Unix(tc.unix, 0)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != tc.expected {
+				t.Errorf("TOTPCode() = %q; want %q", code, tc.expected)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	step := TOTPStep(now)
+
+	testCases := []struct {
+		name     string
+		codeStep int64
+		valid    bool
+	}{
+		{name: "CurrentStep", codeStep: step, valid: true},
+		{name: "PreviousStep", codeStep: step - 1, valid: true},
+		{name: "NextStep", codeStep: step + 1, valid: true},
+		{name: "OutsideSkew", codeStep: step - 3, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := TOTPCode(secret, tc.codeStep)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			matched, ok := ValidateTOTP(secret, code, now)
+			if ok != tc.valid {
+				t.Fatalf("ValidateTOTP() valid = %v; want %v", ok, tc.valid)
+			}
+			if ok && matched != tc.codeStep {
+				t.Errorf("ValidateTOTP() step = %d; want %d", matched, tc.codeStep)
+			}
+		})
+	}
+
+	if _, ok := ValidateTOTP(secret, "abc", now); ok {
+		t.Errorf("expected malformed code to be rejected")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("expected 10 codes, got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || strings.Count(c, "-") != 1 {
+			t.Errorf("unexpected code format %q", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate code %q", c)
+		}
+		seen[c] = true
+	}
+}
@@ -0,0 +1,145 @@
+// Package session keeps track of the account state the access tokens are checked against.
+//
+// The access tokens carry the account role and player id until they expire. Every account
+// has a token version which is bumped when the role changes, the password is reset or the
+// account gets deactivated, so the tokens issued before are rejected at once. The state is
+// cached in-process for a short time, the services invalidate it right after a change.
+//
+// The invalidation only reaches the cache of the instance which made the change. When several
+// instances of the api run, the others keep accepting the revoked tokens until their entry
+// expires, so the revocation is delayed by up to the ACCOUNT_CACHE_TTL there.
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+)
+
+// maximum number of cached accounts, the expired entries are dropped once it's reached
+const maxEntries = 10000
+
+// State is the part of the account deciding if its access tokens are still valid
+type State struct {
+	Active       bool
+	TokenVersion int
+}
+
+type entry struct {
+	state     State
+	expiresAt time.Time
+}
+
+// Loader returns the current state of the account
+type Loader = func(ctx context.Context, accountId string) (State, error)
+
+type Cache struct {
+	load Loader
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry
+	// generation is bumped by every Invalidate, a load which started before
+	// an invalidation may have read the old state and isn't stored
+	generation uint64
+}
+
+// NewCache creates the cache loading the account state from the db. A zero ttl disables the
+// caching, every check then hits the db
+func NewCache(db *db.Conn, ttl time.Duration) *Cache {
+	return New(func(ctx context.Context, accountId string) (State, error) {
+		return loadState(ctx, db, accountId)
+	}, ttl)
+}
+
+// New creates the cache loading the account state with the loader, it's how the other
+// backends get their cache
+func New(load Loader, ttl time.Duration) *Cache {
+	return &Cache{
+		load:    load,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]entry),
+	}
+}
+
+// Get returns the account state, loading it if it's not cached or the cached state expired.
+// The deleted accounts are reported as inactive
+func (c *Cache) Get(ctx context.Context, accountId string) (State, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	e, ok := c.entries[accountId]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && now.Before(e.expiresAt) {
+		return e.state, nil
+	}
+
+	state, err := c.load(ctx, accountId)
+	if err != nil {
+		return State{}, err
+	}
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		if c.generation == generation {
+			if len(c.entries) >= maxEntries {
+				c.evict(now)
+			}
+			c.entries[accountId] = entry{state: state, expiresAt: now.Add(c.ttl)}
+		}
+		c.mu.Unlock()
+	}
+
+	return state, nil
+}
+
+// Invalidate drops the cached state of the account, the services call it after bumping the
+// token version. The loads still running keep their state out of the cache. Other instances
+// of the api pick the change up once their entry expires, after at most the cache ttl
+func (c *Cache) Invalidate(accountId string) {
+	c.mu.Lock()
+	delete(c.entries, accountId)
+	c.generation++
+	c.mu.Unlock()
+}
+
+// evict drops the expired entries, or all of them if none expired. Must be called with the lock held
+func (c *Cache) evict(now time.Time) {
+	for id, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	if len(c.entries) >= maxEntries {
+		c.entries = make(map[string]entry)
+	}
+}
+
+func loadState(ctx context.Context, db *db.Conn, accountId string) (State, error) {
+	var state State
+
+	sql := `
+		select deactivated_at is null, token_version
+		from account
+		where id = $1
+	`
+
+	err := db.QueryRow(ctx, sql, accountId).Scan(&state.Active, &state.TokenVersion)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return State{}, nil
+		}
+		return State{}, failure.New("unable to load account state", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return state, nil
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	states map[string]State
+	loads  int
+	err    error
+}
+
+func (f *fakeStore) load(ctx context.Context, accountId string) (State, error) {
+	f.loads++
+	if f.err != nil {
+		return State{}, f.err
+	}
+	return f.states[accountId], nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CachesUntilExpired", func(t *testing.T) {
+		fs := &fakeStore{states: map[string]State{"acc": {Active: true, TokenVersion: 1}}}
+		c := New(fs.load, time.Minute)
+		now := time.Now()
+		c.now = func() time.Time { return now }
+
+		for i := 0; i < 3; i++ {
+			st, err := c.Get(ctx, "acc")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !st.Active || st.TokenVersion != 1 {
+				t.Fatalf("unexpected state %+v", st)
+			}
+		}
+		if fs.loads != 1 {
+			t.Errorf("expected 1 load, got %d", fs.loads)
+		}
+
+		fs.states["acc"] = State{Active: true, TokenVersion: 2}
+		now = now.Add(2 * time.Minute)
+
+		st, _ := c.Get(ctx, "acc")
+		if st.TokenVersion != 2 || fs.loads != 2 {
+			t.Errorf("expected the expired entry to be reloaded, got %+v after %d loads", st, fs.loads)
+		}
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		fs := &fakeStore{states: map[string]State{"acc": {Active: true}}}
+		c := New(fs.load, time.Minute)
+
+		c.Get(ctx, "acc")
+		fs.states["acc"] = State{Active: false}
+		c.Invalidate("acc")
+
+		st, _ := c.Get(ctx, "acc")
+		if st.Active {
+			t.Error("expected the invalidated state to be reloaded")
+		}
+	})
+
+	t.Run("LoadRacingInvalidate", func(t *testing.T) {
+		fs := &fakeStore{states: map[string]State{"acc": {Active: true, TokenVersion: 1}}}
+		var c *Cache
+		// the load reads the old state, then the account is revoked before the load returns
+		c = New(func(ctx context.Context, accountId string) (State, error) {
+			st, err := fs.load(ctx, accountId)
+			if fs.loads == 1 {
+				fs.states["acc"] = State{Active: true, TokenVersion: 2}
+				c.Invalidate("acc")
+			}
+			return st, err
+		}, time.Minute)
+
+		if st, _ := c.Get(ctx, "acc"); st.TokenVersion != 1 {
+			t.Fatalf("expected the state read by the load, got %+v", st)
+		}
+		st, _ := c.Get(ctx, "acc")
+		if st.TokenVersion != 2 || fs.loads != 2 {
+			t.Errorf("expected the state read before the invalidation not to be cached, got %+v after %d loads", st, fs.loads)
+		}
+	})
+
+	t.Run("ZeroTTLDisablesCaching", func(t *testing.T) {
+		fs := &fakeStore{states: map[string]State{"acc": {Active: true}}}
+		c := New(fs.load, 0)
+
+		c.Get(ctx, "acc")
+		c.Get(ctx, "acc")
+		if fs.loads != 2 {
+			t.Errorf("expected 2 loads, got %d", fs.loads)
+		}
+	})
+
+	t.Run("LoadErrorNotCached", func(t *testing.T) {
+		fs := &fakeStore{err: errors.New("db down")}
+		c := New(fs.load, time.Minute)
+
+		if _, err := c.Get(ctx, "acc"); err == nil {
+			t.Fatal("expected error")
+		}
+
+		fs.err = nil
+		fs.states = map[string]State{"acc": {Active: true}}
+		st, err := c.Get(ctx, "acc")
+		if err != nil || !st.Active {
+			t.Errorf("expected the state to load after the error, got %+v, %v", st, err)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the longest sql kept on a span, the stores' queries fit, the rest is cut
+const maxQueryLength = 2048
+
+// QueryTracer starts a span for every db query, it's set as a tracer of the pool connections.
+// The query arguments are left out, they can hold personal data and secrets
+type QueryTracer struct{}
+
+var _ pgx.QueryTracer = QueryTracer{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	sql := data.SQL
+	if len(sql) > maxQueryLength {
+		sql = sql[:maxQueryLength]
+	}
+
+	ctx, _ = Start(ctx, "db.query",
+		semconv.DBSystemPostgreSQL,
+		semconv.DBQueryText(sql),
+	)
+	// the span is ended by TraceQueryEnd
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		Fail(span, data.Err)
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
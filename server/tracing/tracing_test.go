@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestSetup(t *testing.T) {
+	stop, err := Setup(context.Background(), Options{Exporter: ExporterNone})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := stop(context.Background()); err != nil {
+		t.Errorf("expected no error stopping, got %v", err)
+	}
+
+	if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Error("expected an error for an unknown exporter")
+	}
+}
+
+func TestRequests(t *testing.T) {
+	rec := useRecorder(t)
+
+	r := chi.NewRouter()
+	r.Use(Requests)
+	r.Get("/v1/seasons/{season_id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "seasons.processGetSeason")
+		Fail(span, errors.New("boom"))
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/seasons/abc", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	service, server := spans[0], spans[1]
+	if server.Name() != "GET /v1/seasons/{season_id}" {
+		t.Errorf("expected the server span to be named after the route, got %q", server.Name())
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("expected the server span to fail, got %v", server.Status())
+	}
+	if service.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("expected the service span to be a child of the server span")
+	}
+	if service.Status().Code != codes.Error || len(service.Events()) != 1 {
+		t.Errorf("expected the service span to record the error, got %v", service.Status())
+	}
+}
@@ -0,0 +1,43 @@
+package accounts
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/router"
+	"github.com/markovidakovic/gdsi/server/session"
+)
+
+type api struct {
+	hdl *handler
+}
+
+var _ router.Mounter = (*api)(nil)
+
+func New(cfg *config.Config, repo Repository, sessions *session.Cache) *api {
+	return &api{
+		hdl: newHandler(cfg, repo, sessions),
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Use(middleware.RequirePermission(permission.ManageAccounts))
+
+	r.With(middleware.URLQueryPaginationParams).Get("/", a.hdl.getAccounts)
+	r.With(middleware.URLPathUUIDParams("account_id")).Get("/{account_id}", a.hdl.getAccount)
+	r.With(middleware.URLPathUUIDParams("account_id")).Put("/{account_id}/role", a.hdl.updateAccountRole)
+	r.With(middleware.URLPathUUIDParams("account_id")).Post("/{account_id}/deactivate", a.hdl.deactivateAccount)
+	r.With(middleware.URLPathUUIDParams("account_id")).Post("/{account_id}/reactivate", a.hdl.reactivateAccount)
+	r.With(middleware.URLPathUUIDParams("account_id")).Post("/{account_id}/approve", a.hdl.approveAccount)
+	r.With(middleware.URLPathUUIDParams("account_id")).Post("/{account_id}/reject", a.hdl.rejectAccount)
+	r.With(middleware.URLPathUUIDParams("account_id")).Post("/{account_id}/logout", a.hdl.logoutAccount)
+	r.With(middleware.URLPathUUIDParams("account_id")).Get("/{account_id}/grants", a.hdl.getScopedGrants)
+	r.With(middleware.URLPathUUIDParams("account_id")).Post("/{account_id}/grants", a.hdl.createScopedGrant)
+	r.With(middleware.URLPathUUIDParams("account_id", "grant_id")).Delete("/{account_id}/grants/{grant_id}", a.hdl.deleteScopedGrant)
+}
+
+// NewScopedRoleLookup returns the scoped role lookup used by middleware.ResolveScope
+func NewScopedRoleLookup(repo Repository) middleware.ScopedRoleLookup {
+	return repo.findScopedRoles
+}
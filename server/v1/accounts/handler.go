@@ -0,0 +1,413 @@
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/pagination"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/response"
+	"github.com/markovidakovic/gdsi/server/session"
+)
+
+type handler struct {
+	store   Repository
+	service *service
+}
+
+func newHandler(cfg *config.Config, repo Repository, sessions *session.Cache) *handler {
+	h := &handler{}
+	h.store = repo
+	h.service = newService(cfg, h.store, sessions)
+	return h
+}
+
+// @Summary Get
+// @Description Get accounts, searchable by name or email
+// @Tags admin accounts
+// @Produce json
+// @Param page query int false "page"
+// @Param per_page query int false "per page"
+// @Param order_by query string false "order by"
+// @Param search query string false "name or email search"
+// @Param role query string false "role filter"
+// @Param status query string false "active, deactivated, pending (the approval queue) or deleted"
+// @Success 200 {array} accounts.AccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts [get]
+func (h *handler) getAccounts(w http.ResponseWriter, r *http.Request) {
+	query := params.NewQuery(r.URL.Query())
+
+	accounts, count, err := h.service.processGetAccounts(r.Context(), query)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	result := pagination.NewPaginated(query.Page, query.PerPage, count, accounts)
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Get by id
+// @Description Get account by id
+// @Tags admin accounts
+// @Produce json
+// @Param account_id path string true "account id"
+// @Success 200 {object} accounts.AccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id} [get]
+func (h *handler) getAccount(w http.ResponseWriter, r *http.Request) {
+	result, err := h.store.findAccount(r.Context(), nil, chi.URLParam(r, "account_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Update role
+// @Description Change the account role, only developers can assign the developer role. The current access tokens of the account are revoked
+// @Tags admin accounts
+// @Accept json
+// @Produce json
+// @Param account_id path string true "account id"
+// @Param body body accounts.UpdateAccountRoleRequestModel true "Request body"
+// @Success 200 {object} accounts.AccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/role [put]
+func (h *handler) updateAccountRole(w http.ResponseWriter, r *http.Request) {
+	var model UpdateAccountRoleRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	requesterId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	requesterRole := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	result, err := h.service.processUpdateAccountRole(r.Context(), requesterId, requesterRole, chi.URLParam(r, "account_id"), model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Deactivate
+// @Description Deactivate the account, its tokens are rejected from now on
+// @Tags admin accounts
+// @Produce json
+// @Param account_id path string true "account id"
+// @Success 200 {object} accounts.AccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/deactivate [post]
+func (h *handler) deactivateAccount(w http.ResponseWriter, r *http.Request) {
+	h.setAccountDeactivated(w, r, true)
+}
+
+// @Summary Reactivate
+// @Description Reactivate a deactivated account
+// @Tags admin accounts
+// @Produce json
+// @Param account_id path string true "account id"
+// @Success 200 {object} accounts.AccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/reactivate [post]
+func (h *handler) reactivateAccount(w http.ResponseWriter, r *http.Request) {
+	h.setAccountDeactivated(w, r, false)
+}
+
+func (h *handler) setAccountDeactivated(w http.ResponseWriter, r *http.Request, deactivated bool) {
+	requesterId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	requesterRole := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	result, err := h.service.processSetAccountDeactivated(r.Context(), requesterId, requesterRole, chi.URLParam(r, "account_id"), deactivated)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Approve
+// @Description Approve an account waiting in the approval queue, its player can join leagues from now on
+// @Tags admin accounts
+// @Produce json
+// @Param account_id path string true "account id"
+// @Success 200 {object} accounts.AccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/approve [post]
+func (h *handler) approveAccount(w http.ResponseWriter, r *http.Request) {
+	h.reviewAccount(w, r, true)
+}
+
+// @Summary Reject
+// @Description Reject an account waiting in the approval queue, the account is deactivated
+// @Tags admin accounts
+// @Produce json
+// @Param account_id path string true "account id"
+// @Success 200 {object} accounts.AccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/reject [post]
+func (h *handler) rejectAccount(w http.ResponseWriter, r *http.Request) {
+	h.reviewAccount(w, r, false)
+}
+
+func (h *handler) reviewAccount(w http.ResponseWriter, r *http.Request, approve bool) {
+	requesterId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	requesterRole := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	result, err := h.service.processReviewAccount(r.Context(), requesterId, requesterRole, chi.URLParam(r, "account_id"), approve)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Logout
+// @Description Force logout the account by revoking all of its access and refresh tokens
+// @Tags admin accounts
+// @Param account_id path string true "account id"
+// @Success 204 "No content"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/logout [post]
+func (h *handler) logoutAccount(w http.ResponseWriter, r *http.Request) {
+	requesterRole := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	err := h.service.processLogoutAccount(r.Context(), requesterRole, chi.URLParam(r, "account_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
+
+// @Summary Get scoped grants
+// @Description Get the roles granted to the account within a season or a league
+// @Tags admin accounts
+// @Produce json
+// @Param account_id path string true "account id"
+// @Success 200 {array} accounts.ScopedGrantModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/grants [get]
+func (h *handler) getScopedGrants(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.processGetScopedGrants(r.Context(), chi.URLParam(r, "account_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Create scoped grant
+// @Description Grant a role to the account only within a season or a league, e.g. a league coordinator. Exactly one of season_id or league_id is required
+// @Tags admin accounts
+// @Accept json
+// @Produce json
+// @Param account_id path string true "account id"
+// @Param body body accounts.CreateScopedGrantRequestModel true "Request body"
+// @Success 201 {object} accounts.ScopedGrantModel "Created"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/grants [post]
+func (h *handler) createScopedGrant(w http.ResponseWriter, r *http.Request) {
+	var model CreateScopedGrantRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	requesterId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	requesterRole := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	result, err := h.service.processCreateScopedGrant(r.Context(), requesterId, requesterRole, chi.URLParam(r, "account_id"), model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusCreated, result)
+}
+
+// @Summary Delete scoped grant
+// @Description Revoke a role granted within a season or a league
+// @Tags admin accounts
+// @Param account_id path string true "account id"
+// @Param grant_id path string true "grant id"
+// @Success 204 "No content"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/grants/{grant_id} [delete]
+func (h *handler) deleteScopedGrant(w http.ResponseWriter, r *http.Request) {
+	requesterId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	requesterRole := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	err := h.service.processDeleteScopedGrant(r.Context(), requesterId, requesterRole, chi.URLParam(r, "account_id"), chi.URLParam(r, "grant_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
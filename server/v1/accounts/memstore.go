@@ -0,0 +1,380 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortingFields = map[string]func(a, b AccountModel) int{
+	"name":       func(a, b AccountModel) int { return memdb.CompareText(a.Name, b.Name) },
+	"email":      func(a, b AccountModel) int { return memdb.CompareText(a.Email, b.Email) },
+	"role":       func(a, b AccountModel) int { return memdb.CompareText(a.Role, b.Role) },
+	"created_at": func(a, b AccountModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toAccountModel(t *memdb.Tables, a db.Account) AccountModel {
+	var am AccountModel
+	am.Id = a.Id
+	am.Name = a.Name
+	am.Email = a.Email
+	am.Role = a.Role
+	if p, ok := t.PlayerOf(a.Id); ok {
+		am.PlayerId = &p.Id
+	}
+	am.DeactivatedAt = memdb.TimePtr(a.DeactivatedAt)
+	am.ApprovedAt = memdb.TimePtr(a.ApprovedAt)
+	am.DeletedAt = memdb.TimePtr(a.DeletedAt)
+	am.CreatedAt = a.CreatedAt
+	return am
+}
+
+// matchAccount is filterAccounts of the in-memory backend
+func matchAccount(filter AccountFilter, a db.Account) bool {
+	if a.Role == permission.RoleService {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(a.Name), search) && !strings.Contains(strings.ToLower(a.Email), search) {
+			return false
+		}
+	}
+	if filter.Role != "" && a.Role != filter.Role {
+		return false
+	}
+	switch filter.Status {
+	case "active":
+		return !a.DeactivatedAt.Valid
+	case "deactivated":
+		return a.DeactivatedAt.Valid && !a.DeletedAt.Valid
+	case "pending":
+		return !a.ApprovedAt.Valid && !a.DeactivatedAt.Valid
+	case "deleted":
+		return a.DeletedAt.Valid
+	}
+	return true
+}
+
+func (s *memStore) findAccounts(ctx context.Context, filter AccountFilter, limit, offset int, orderBy *params.OrderBy) ([]AccountModel, error) {
+	dest := []AccountModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, a := range t.Accounts {
+			if matchAccount(filter, a) {
+				dest = append(dest, toAccountModel(t, a))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, orderBy, memSortingFields, func(a, b AccountModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countAccounts(ctx context.Context, filter AccountFilter) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, a := range t.Accounts {
+			if matchAccount(filter, a) {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findAccount(ctx context.Context, tx db.Tx, accountId string) (*AccountModel, error) {
+	var dest *AccountModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		if a, ok := t.Accounts[accountId]; ok && a.Role != permission.RoleService {
+			am := toAccountModel(t, a)
+			dest = &am
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("account not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) roleExists(ctx context.Context, tx db.Tx, role string) (bool, error) {
+	var exists bool
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		_, exists = t.Roles[role]
+		return nil
+	})
+	if err != nil {
+		return false, failure.New("unable to check role existance", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return exists, nil
+}
+
+// updateAccount applies fn to the account, a missing account is left alone like an update
+// matching no rows
+func (s *memStore) updateAccount(tx db.Tx, accountId string, fn func(a *db.Account)) error {
+	return s.db.Write(tx, func(t *memdb.Tables) error {
+		a, ok := t.Accounts[accountId]
+		if !ok {
+			return nil
+		}
+		fn(&a)
+		t.Accounts[accountId] = a
+		return nil
+	})
+}
+
+func (s *memStore) updateAccountRole(ctx context.Context, tx db.Tx, accountId, role string) error {
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Roles[role]; !ok {
+			return memdb.ErrForeignKey
+		}
+		return nil
+	})
+	if err == nil {
+		err = s.updateAccount(tx, accountId, func(a *db.Account) { a.Role = role })
+	}
+	if err != nil {
+		return failure.New("unable to update account role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) updateAccountDeactivated(ctx context.Context, tx db.Tx, accountId string, deactivated bool) error {
+	err := s.updateAccount(tx, accountId, func(a *db.Account) {
+		switch {
+		case !deactivated:
+			a.DeactivatedAt = sql.NullTime{}
+		case !a.DeactivatedAt.Valid:
+			a.DeactivatedAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+		}
+	})
+	if err != nil {
+		return failure.New("unable to update account status", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) approveAccount(ctx context.Context, tx db.Tx, accountId, approverId string) error {
+	err := s.updateAccount(tx, accountId, func(a *db.Account) {
+		if a.ApprovedAt.Valid {
+			return
+		}
+		a.ApprovedAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+		a.ApprovedBy = sql.NullString{String: approverId, Valid: true}
+	})
+	if err != nil {
+		return failure.New("unable to approve account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		for id, rt := range t.RefreshTokens {
+			if rt.AccountId == accountId && !rt.IsRevoked {
+				rt.IsRevoked = true
+				t.RefreshTokens[id] = rt
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to revoke account refresh tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) bumpTokenVersion(ctx context.Context, tx db.Tx, accountId string) error {
+	err := s.updateAccount(tx, accountId, func(a *db.Account) { a.TokenVersion++ })
+	if err != nil {
+		return failure.New("unable to revoke account tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) findRolePermissions(ctx context.Context, tx db.Tx, role string) ([]permission.Permission, error) {
+	perms := []permission.Permission{}
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		for _, rp := range t.RolePermissions {
+			if rp.RoleName == role {
+				perms = append(perms, permission.Permission(rp.Permission))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return perms, nil
+}
+
+func toScopedGrantModel(g db.ScopedRoleGrant) ScopedGrantModel {
+	return ScopedGrantModel{
+		Id:        g.Id,
+		Role:      g.RoleName,
+		SeasonId:  memdb.StringPtr(g.SeasonId),
+		LeagueId:  memdb.StringPtr(g.LeagueId),
+		GrantedBy: memdb.StringPtr(g.GrantedBy),
+		CreatedAt: g.CreatedAt,
+	}
+}
+
+func (s *memStore) findScopedGrants(ctx context.Context, accountId string) ([]ScopedGrantModel, error) {
+	grants := []ScopedGrantModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, g := range t.ScopedRoleGrants {
+			if g.AccountId == accountId {
+				grants = append(grants, toScopedGrantModel(g))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find scoped grants", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	slices.SortStableFunc(grants, func(a, b ScopedGrantModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) })
+
+	return grants, nil
+}
+
+func (s *memStore) insertScopedGrant(ctx context.Context, tx db.Tx, accountId, grantedBy string, model CreateScopedGrantRequestModel) (*ScopedGrantModel, error) {
+	var dest ScopedGrantModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		g := db.ScopedRoleGrant{
+			Id:        memdb.NewId(),
+			AccountId: accountId,
+			RoleName:  model.Role,
+			SeasonId:  sql.NullString{String: model.SeasonId, Valid: model.SeasonId != ""},
+			LeagueId:  sql.NullString{String: model.LeagueId, Valid: model.LeagueId != ""},
+			GrantedBy: sql.NullString{String: grantedBy, Valid: true},
+			CreatedAt: s.db.Now(),
+		}
+		if _, ok := t.Seasons[g.SeasonId.String]; g.SeasonId.Valid && !ok {
+			return memdb.ErrForeignKey
+		}
+		if _, ok := t.Leagues[g.LeagueId.String]; g.LeagueId.Valid && !ok {
+			return memdb.ErrForeignKey
+		}
+		for _, other := range t.ScopedRoleGrants {
+			if other.AccountId == g.AccountId && other.RoleName == g.RoleName && other.SeasonId == g.SeasonId && other.LeagueId == g.LeagueId {
+				return memdb.ErrUnique
+			}
+		}
+		t.ScopedRoleGrants[g.Id] = g
+		dest = toScopedGrantModel(g)
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, memdb.ErrUnique):
+			return nil, failure.New("role already granted in this scope", fmt.Errorf("%w -> %v", failure.ErrDuplicate, err))
+		case errors.Is(err, memdb.ErrForeignKey):
+			field := "season_id"
+			if model.LeagueId != "" {
+				field = "league_id"
+			}
+			return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+				{Field: field, Message: "Scope not found", Location: "body"},
+			})
+		}
+		return nil, failure.New("unable to grant scoped role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &dest, nil
+}
+
+func (s *memStore) deleteScopedGrant(ctx context.Context, tx db.Tx, accountId, grantId string) (*ScopedGrantModel, error) {
+	var dest *ScopedGrantModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		g, ok := t.ScopedRoleGrants[grantId]
+		if !ok || g.AccountId != accountId {
+			return nil
+		}
+		delete(t.ScopedRoleGrants, grantId)
+		sg := toScopedGrantModel(g)
+		dest = &sg
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to revoke scoped grant", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("scoped grant not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findScopedRoles(ctx context.Context, accountId, seasonId, leagueId string) ([]string, error) {
+	roles := []string{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		// a season grant applies to the season of the league, or to the season in scope
+		scopeSeasonId := seasonId
+		if league, ok := t.Leagues[leagueId]; ok {
+			scopeSeasonId = league.SeasonId
+		}
+		for _, g := range t.ScopedRoleGrants {
+			if g.AccountId != accountId {
+				continue
+			}
+			inLeague := leagueId != "" && g.LeagueId.Valid && g.LeagueId.String == leagueId
+			inSeason := scopeSeasonId != "" && g.SeasonId.Valid && g.SeasonId.String == scopeSeasonId &&
+				(seasonId == "" || g.SeasonId.String == seasonId)
+			if (inLeague || inSeason) && !slices.Contains(roles, g.RoleName) {
+				roles = append(roles, g.RoleName)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find scoped roles", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return roles, nil
+}
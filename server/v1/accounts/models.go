@@ -0,0 +1,177 @@
+package accounts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/failure"
+)
+
+type AccountModel struct {
+	Id            string     `json:"id"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	PlayerId      *string    `json:"player_id"`
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	ApprovedAt    *time.Time `json:"approved_at"`
+	DeletedAt     *time.Time `json:"deleted_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// AuditState is the account without its name and email
+func (am AccountModel) AuditState() any {
+	return struct {
+		Id            string     `json:"id"`
+		Role          string     `json:"role"`
+		PlayerId      *string    `json:"player_id"`
+		DeactivatedAt *time.Time `json:"deactivated_at"`
+		ApprovedAt    *time.Time `json:"approved_at"`
+		DeletedAt     *time.Time `json:"deleted_at"`
+		CreatedAt     time.Time  `json:"created_at"`
+	}{am.Id, am.Role, am.PlayerId, am.DeactivatedAt, am.ApprovedAt, am.DeletedAt, am.CreatedAt}
+}
+
+func (am *AccountModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&am.Id, &am.Name, &am.Email, &am.Role, &am.PlayerId, &am.DeactivatedAt, &am.ApprovedAt, &am.DeletedAt, &am.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning account row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+func (am *AccountModel) ScanRows(rows pgx.Rows) error {
+	err := rows.Scan(&am.Id, &am.Name, &am.Email, &am.Role, &am.PlayerId, &am.DeactivatedAt, &am.ApprovedAt, &am.DeletedAt, &am.CreatedAt)
+	if err != nil {
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// AccountFilter holds the optional search and filter query params of the account list
+type AccountFilter struct {
+	Search string
+	Role   string
+	Status string
+}
+
+func (f AccountFilter) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if f.Status != "" && f.Status != "active" && f.Status != "deactivated" && f.Status != "pending" && f.Status != "deleted" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "status",
+			Message:  "Status must be active, deactivated, pending or deleted",
+			Location: "query",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+type UpdateAccountRoleRequestModel struct {
+	Role string `json:"role"`
+}
+
+func (m UpdateAccountRoleRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Role == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "role",
+			Message:  "Role field is required",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+// ScopedGrantModel is a role granted to the account only within a season or a league
+type ScopedGrantModel struct {
+	Id        string    `json:"id"`
+	Role      string    `json:"role"`
+	SeasonId  *string   `json:"season_id"`
+	LeagueId  *string   `json:"league_id"`
+	GrantedBy *string   `json:"granted_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (sg *ScopedGrantModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&sg.Id, &sg.Role, &sg.SeasonId, &sg.LeagueId, &sg.GrantedBy, &sg.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning scoped grant row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+func (sg *ScopedGrantModel) ScanRows(rows pgx.Rows) error {
+	err := rows.Scan(&sg.Id, &sg.Role, &sg.SeasonId, &sg.LeagueId, &sg.GrantedBy, &sg.CreatedAt)
+	if err != nil {
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+type CreateScopedGrantRequestModel struct {
+	Role     string `json:"role"`
+	SeasonId string `json:"season_id"`
+	LeagueId string `json:"league_id"`
+}
+
+func (m CreateScopedGrantRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Role == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "role",
+			Message:  "Role field is required",
+			Location: "body",
+		})
+	}
+
+	if (m.SeasonId == "") == (m.LeagueId == "") {
+		inv = append(inv, failure.InvalidField{
+			Field:    "season_id",
+			Message:  "Exactly one of season id or league id is required",
+			Location: "body",
+		})
+	} else if m.SeasonId != "" {
+		if err := uuid.Validate(m.SeasonId); err != nil {
+			inv = append(inv, failure.InvalidField{
+				Field:    "season_id",
+				Message:  "Invalid uuid format",
+				Location: "body",
+			})
+		}
+	} else if err := uuid.Validate(m.LeagueId); err != nil {
+		inv = append(inv, failure.InvalidField{
+			Field:    "league_id",
+			Message:  "Invalid uuid format",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
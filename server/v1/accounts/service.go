@@ -0,0 +1,485 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/session"
+	"github.com/markovidakovic/gdsi/server/tracing"
+)
+
+type service struct {
+	cfg      *config.Config
+	store    Repository
+	sessions *session.Cache
+}
+
+func newService(cfg *config.Config, store Repository, sessions *session.Cache) *service {
+	return &service{
+		cfg,
+		store,
+		sessions,
+	}
+}
+
+func (s *service) processGetAccounts(ctx context.Context, query *params.Query) ([]AccountModel, int, error) {
+	ctx, span := tracing.Start(ctx, "accounts.processGetAccounts")
+	defer span.End()
+
+	filter := AccountFilter{
+		Search: query.Additional["search"],
+		Role:   query.Additional["role"],
+		Status: query.Additional["status"],
+	}
+	if inv := filter.Validate(); inv != nil {
+		return nil, 0, failure.NewValidation("validation failed", inv)
+	}
+
+	count, err := s.store.countAccounts(ctx, filter)
+	if err != nil {
+		return nil, 0, failure.New("unable to get accounts", err)
+	}
+
+	limit, offset := query.CalcLimitAndOffset(count)
+
+	result, err := s.store.findAccounts(ctx, filter, limit, offset, query.OrderBy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, count, nil
+}
+
+// checkManageable guards the developer accounts and the developer role, only developers can touch them.
+// The requester can't lock themselves out by changing their own role or deactivating themselves, and
+// the deleted accounts can't be managed at all
+func checkManageable(requesterId, requesterRole string, target *AccountModel, newRole string) error {
+	if target.Id == requesterId {
+		return failure.New("you can't modify your own account", failure.ErrCantModify)
+	}
+	// the deletion is final, the anonymized account can't be reactivated, approved or given a role
+	if target.DeletedAt != nil {
+		return failure.New("account was deleted", failure.ErrCantModify)
+	}
+	if requesterRole != permission.RoleDeveloper && (target.Role == permission.RoleDeveloper || newRole == permission.RoleDeveloper) {
+		return failure.New("only developers can manage developer accounts", failure.ErrForbidden)
+	}
+	return nil
+}
+
+// processUpdateAccountRole changes the account role. The access tokens carry the role, so they
+// are revoked and the account gets the new role with the next token refresh
+func (s *service) processUpdateAccountRole(ctx context.Context, requesterId, requesterRole, accountId string, model UpdateAccountRoleRequestModel) (*AccountModel, error) {
+	ctx, span := tracing.Start(ctx, "accounts.processUpdateAccountRole")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update account role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		if tx != nil {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
+			}
+		}
+	}()
+
+	// service accounts are managed through their own endpoints
+	exists, err := s.store.roleExists(ctx, tx, model.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || model.Role == permission.RoleService {
+		return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: "role", Message: fmt.Sprintf("Unknown role %q", model.Role), Location: "body"},
+		})
+	}
+
+	account, err := s.store.findAccount(ctx, tx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkManageable(requesterId, requesterRole, account, model.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	// like the scoped grants, the requester can't hand out the permissions they don't hold
+	perms, err := s.store.findRolePermissions(ctx, tx, model.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, perm := range perms {
+		if !permission.Has(requesterRole, perm) {
+			return nil, failure.New(fmt.Sprintf("you can't grant the %q permission of the role", perm), failure.ErrForbidden)
+		}
+	}
+
+	err = s.store.updateAccountRole(ctx, tx, accountId, model.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.bumpTokenVersion(ctx, tx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	before := account
+	account, err = s.store.findAccount(ctx, tx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUpdateRole, ResourceType: audit.Account, ResourceId: accountId, Before: before, After: account})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update account role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	s.sessions.Invalidate(accountId)
+
+	return account, nil
+}
+
+// processSetAccountDeactivated deactivates or reactivates the account. Deactivation also revokes
+// all the tokens, so a reactivated account has to log in again
+func (s *service) processSetAccountDeactivated(ctx context.Context, requesterId, requesterRole, accountId string, deactivated bool) (*AccountModel, error) {
+	ctx, span := tracing.Start(ctx, "accounts.processSetAccountDeactivated")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update account status", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		if tx != nil {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
+			}
+		}
+	}()
+
+	account, err := s.store.findAccount(ctx, tx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkManageable(requesterId, requesterRole, account, "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.updateAccountDeactivated(ctx, tx, accountId, deactivated)
+	if err != nil {
+		return nil, err
+	}
+
+	if deactivated {
+		err = s.store.revokeAccountRefreshTokens(ctx, tx, accountId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.store.bumpTokenVersion(ctx, tx, accountId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before := account
+	account, err = s.store.findAccount(ctx, tx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	action := audit.ActionReactivate
+	if deactivated {
+		action = audit.ActionDeactivate
+	}
+	err = audit.Record(ctx, tx, audit.Entry{Action: action, ResourceType: audit.Account, ResourceId: accountId, Before: before, After: account})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update account status", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	s.sessions.Invalidate(accountId)
+
+	return account, nil
+}
+
+// processReviewAccount approves or rejects an account waiting in the approval queue. A rejected
+// account is deactivated and logged out, reactivating it puts it back into the queue
+func (s *service) processReviewAccount(ctx context.Context, requesterId, requesterRole, accountId string, approve bool) (*AccountModel, error) {
+	ctx, span := tracing.Start(ctx, "accounts.processReviewAccount")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to review account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "rolling back tx", "error", err)
+		}
+	}()
+
+	account, err := s.store.findAccount(ctx, tx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkManageable(requesterId, requesterRole, account, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if account.ApprovedAt != nil {
+		return nil, failure.New("account already approved", failure.ErrCantModify)
+	}
+
+	action := audit.ActionApprove
+	if approve {
+		err = s.store.approveAccount(ctx, tx, accountId, requesterId)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		action = audit.ActionReject
+
+		err = s.store.updateAccountDeactivated(ctx, tx, accountId, true)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.store.revokeAccountRefreshTokens(ctx, tx, accountId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.store.bumpTokenVersion(ctx, tx, accountId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before := account
+	account, err = s.store.findAccount(ctx, tx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: action, ResourceType: audit.Account, ResourceId: accountId, Before: before, After: account})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to review account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	if !approve {
+		s.sessions.Invalidate(accountId)
+	}
+
+	return account, nil
+}
+
+// processLogoutAccount revokes all the access and refresh tokens of the account
+func (s *service) processLogoutAccount(ctx context.Context, requesterRole, accountId string) error {
+	ctx, span := tracing.Start(ctx, "accounts.processLogoutAccount")
+	defer span.End()
+
+	account, err := s.store.findAccount(ctx, nil, accountId)
+	if err != nil {
+		return err
+	}
+
+	if requesterRole != permission.RoleDeveloper && account.Role == permission.RoleDeveloper {
+		return failure.New("only developers can manage developer accounts", failure.ErrForbidden)
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to logout account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		if tx != nil {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
+			}
+		}
+	}()
+
+	err = s.store.revokeAccountRefreshTokens(ctx, tx, accountId)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.bumpTokenVersion(ctx, tx, accountId)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionLogout, ResourceType: audit.Account, ResourceId: accountId})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to logout account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	s.sessions.Invalidate(accountId)
+
+	return nil
+}
+
+func (s *service) processGetScopedGrants(ctx context.Context, accountId string) ([]ScopedGrantModel, error) {
+	ctx, span := tracing.Start(ctx, "accounts.processGetScopedGrants")
+	defer span.End()
+
+	_, err := s.store.findAccount(ctx, nil, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.findScopedGrants(ctx, accountId)
+}
+
+// processCreateScopedGrant grants the role to the account within a season or a league. The requester
+// can only grant roles whose permissions they hold, so a scoped grant never exceeds their own access
+func (s *service) processCreateScopedGrant(ctx context.Context, requesterId, requesterRole, accountId string, model CreateScopedGrantRequestModel) (*ScopedGrantModel, error) {
+	ctx, span := tracing.Start(ctx, "accounts.processCreateScopedGrant")
+	defer span.End()
+
+	account, err := s.store.findAccount(ctx, nil, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkManageable(requesterId, requesterRole, account, model.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.store.roleExists(ctx, nil, model.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || model.Role == permission.RoleService {
+		return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: "role", Message: fmt.Sprintf("Unknown role %q", model.Role), Location: "body"},
+		})
+	}
+
+	perms, err := s.store.findRolePermissions(ctx, nil, model.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, perm := range perms {
+		if !permission.Has(requesterRole, perm) {
+			return nil, failure.New(fmt.Sprintf("you can't grant the %q permission of the role", perm), failure.ErrForbidden)
+		}
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to grant scoped role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "rolling back tx", "error", err)
+		}
+	}()
+
+	grant, err := s.store.insertScopedGrant(ctx, tx, accountId, requesterId, model)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionGrant, ResourceType: audit.Account, ResourceId: accountId, After: grant})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to grant scoped role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return grant, nil
+}
+
+func (s *service) processDeleteScopedGrant(ctx context.Context, requesterId, requesterRole, accountId, grantId string) error {
+	ctx, span := tracing.Start(ctx, "accounts.processDeleteScopedGrant")
+	defer span.End()
+
+	account, err := s.store.findAccount(ctx, nil, accountId)
+	if err != nil {
+		return err
+	}
+
+	err = checkManageable(requesterId, requesterRole, account, "")
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to revoke scoped grant", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "rolling back tx", "error", err)
+		}
+	}()
+
+	grant, err := s.store.deleteScopedGrant(ctx, tx, accountId, grantId)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionRevokeGrant, ResourceType: audit.Account, ResourceId: accountId, Before: grant})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to revoke scoped grant", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
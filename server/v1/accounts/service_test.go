@@ -0,0 +1,75 @@
+package accounts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/markovidakovic/gdsi/server/failure"
+)
+
+func TestCheckManageable(t *testing.T) {
+	user := &AccountModel{Id: "user", Role: "user"}
+	developer := &AccountModel{Id: "developer", Role: "developer"}
+
+	testCases := []struct {
+		name          string
+		requesterId   string
+		requesterRole string
+		target        *AccountModel
+		newRole       string
+		expected      error
+	}{
+		{name: "AdminManagesUser", requesterId: "admin", requesterRole: "admin", target: user, expected: nil},
+		{name: "AdminPromotesToAdmin", requesterId: "admin", requesterRole: "admin", target: user, newRole: "admin", expected: nil},
+		{name: "OwnAccount", requesterId: "user", requesterRole: "admin", target: user, expected: failure.ErrCantModify},
+		{name: "AdminManagesDeveloper", requesterId: "admin", requesterRole: "admin", target: developer, expected: failure.ErrForbidden},
+		{name: "AdminPromotesToDeveloper", requesterId: "admin", requesterRole: "admin", target: user, newRole: "developer", expected: failure.ErrForbidden},
+		{name: "DeveloperManagesDeveloper", requesterId: "other", requesterRole: "developer", target: developer, newRole: "user", expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkManageable(tc.requesterId, tc.requesterRole, tc.target, tc.newRole)
+			if tc.expected == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("checkManageable() = %v; want %v", err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAccountFilterValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		filter  AccountFilter
+		invalid bool
+	}{
+		{name: "Empty", filter: AccountFilter{}, invalid: false},
+		{name: "Active", filter: AccountFilter{Status: "active", Search: "alice"}, invalid: false},
+		{name: "Deactivated", filter: AccountFilter{Status: "deactivated"}, invalid: false},
+		{name: "UnknownStatus", filter: AccountFilter{Status: "archived"}, invalid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inv := tc.filter.Validate()
+			if (inv != nil) != tc.invalid {
+				t.Errorf("Validate() = %v; want invalid %v", inv, tc.invalid)
+			}
+		})
+	}
+}
+
+func TestUpdateAccountRoleRequestModelValidate(t *testing.T) {
+	if inv := (UpdateAccountRoleRequestModel{}).Validate(); len(inv) != 1 || inv[0].Field != "role" {
+		t.Errorf("expected the missing role to be rejected, got %v", inv)
+	}
+	if inv := (UpdateAccountRoleRequestModel{Role: "admin"}).Validate(); inv != nil {
+		t.Errorf("expected the role to be accepted, got %v", inv)
+	}
+}
@@ -0,0 +1,480 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+// Repository holds the accounts as the admins manage them: the status, the role and the roles
+// granted within a season or league
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	findAccounts(ctx context.Context, filter AccountFilter, limit, offset int, orderBy *params.OrderBy) ([]AccountModel, error)
+	countAccounts(ctx context.Context, filter AccountFilter) (int, error)
+	findAccount(ctx context.Context, tx db.Tx, accountId string) (*AccountModel, error)
+	roleExists(ctx context.Context, tx db.Tx, role string) (bool, error)
+	updateAccountRole(ctx context.Context, tx db.Tx, accountId, role string) error
+	updateAccountDeactivated(ctx context.Context, tx db.Tx, accountId string, deactivated bool) error
+	approveAccount(ctx context.Context, tx db.Tx, accountId, approverId string) error
+	revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error
+	bumpTokenVersion(ctx context.Context, tx db.Tx, accountId string) error
+	findRolePermissions(ctx context.Context, tx db.Tx, role string) ([]permission.Permission, error)
+	findScopedGrants(ctx context.Context, accountId string) ([]ScopedGrantModel, error)
+	insertScopedGrant(ctx context.Context, tx db.Tx, accountId, grantedBy string, model CreateScopedGrantRequestModel) (*ScopedGrantModel, error)
+	deleteScopedGrant(ctx context.Context, tx db.Tx, accountId, grantId string) (*ScopedGrantModel, error)
+	findScopedRoles(ctx context.Context, accountId, seasonId, leagueId string) ([]string, error)
+}
+
+type store struct {
+	db *db.Conn
+}
+
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
+		db,
+	}
+}
+
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+var sortingFields = map[string]string{
+	"name":       "account.name",
+	"email":      "account.email",
+	"role":       "account.role",
+	"created_at": "account.created_at",
+}
+
+const selectAccount = `
+	select
+		account.id as account_id,
+		account.name as account_name,
+		account.email as account_email,
+		account.role as account_role,
+		player.id as player_id,
+		account.deactivated_at as account_deactivated_at,
+		account.approved_at as account_approved_at,
+		account.deleted_at as account_deleted_at,
+		account.created_at as account_created_at
+	from account
+	left join player on player.account_id = account.id
+`
+
+// filterAccounts builds the where clause of the account list, the service accounts are never listed
+func filterAccounts(filter AccountFilter) (string, []interface{}) {
+	sql := "where account.role != 'service'\n"
+	args := []interface{}{}
+	argCounter := 1
+
+	if filter.Search != "" {
+		sql += fmt.Sprintf("and (account.name ilike $%d or account.email ilike $%d)\n", argCounter, argCounter)
+		args = append(args, "%"+filter.Search+"%")
+		argCounter++
+	}
+	if filter.Role != "" {
+		sql += fmt.Sprintf("and account.role = $%d\n", argCounter)
+		args = append(args, filter.Role)
+	}
+	switch filter.Status {
+	case "active":
+		sql += "and account.deactivated_at is null\n"
+	case "deactivated":
+		// the deleted accounts are deactivated as well, they're listed on their own
+		sql += "and account.deactivated_at is not null and account.deleted_at is null\n"
+	case "pending":
+		// the approval queue, rejected accounts are deactivated and leave it
+		sql += "and account.approved_at is null and account.deactivated_at is null\n"
+	case "deleted":
+		sql += "and account.deleted_at is not null\n"
+	}
+
+	return sql, args
+}
+
+func (s *store) findAccounts(ctx context.Context, filter AccountFilter, limit, offset int, orderBy *params.OrderBy) ([]AccountModel, error) {
+	where, args := filterAccounts(filter)
+	sql := selectAccount + where
+
+	if orderBy != nil && orderBy.IsValid(sortingFields) {
+		sql += fmt.Sprintf("order by %s %s\n", sortingFields[orderBy.Field], orderBy.Direction)
+	} else {
+		sql += fmt.Sprintln("order by account.created_at desc")
+	}
+
+	if limit >= 0 {
+		sql += fmt.Sprintf("limit $%d offset $%d", len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, failure.New("unable to find accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	var dest = []AccountModel{}
+	for rows.Next() {
+		var am AccountModel
+		err := am.ScanRows(rows)
+		if err != nil {
+			return nil, failure.New("unable to find accounts", err)
+		}
+		dest = append(dest, am)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, failure.New("unable to find accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) countAccounts(ctx context.Context, filter AccountFilter) (int, error) {
+	where, args := filterAccounts(filter)
+	sql := "select count(*) from account\n" + where
+
+	var count int
+	err := s.db.QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, failure.New("unable to count accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *store) findAccount(ctx context.Context, tx db.Tx, accountId string) (*AccountModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := selectAccount + "where account.id = $1 and account.role != 'service'"
+
+	var dest AccountModel
+	row := q.QueryRow(ctx, sql, accountId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("account not found", err)
+		}
+		return nil, failure.New("unable to find account", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) roleExists(ctx context.Context, tx db.Tx, role string) (bool, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	var exists bool
+
+	sql := `select exists (select 1 from role where name = $1)`
+
+	err := q.QueryRow(ctx, sql, role).Scan(&exists)
+	if err != nil {
+		return false, failure.New("unable to check role existance", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return exists, nil
+}
+
+func (s *store) updateAccountRole(ctx context.Context, tx db.Tx, accountId, role string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set role = $1
+		where id = $2
+	`
+
+	_, err := q.Exec(ctx, sql, role, accountId)
+	if err != nil {
+		return failure.New("unable to update account role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) updateAccountDeactivated(ctx context.Context, tx db.Tx, accountId string, deactivated bool) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set deactivated_at = case when $1 then coalesce(deactivated_at, current_timestamp) else null end
+		where id = $2
+	`
+
+	_, err := q.Exec(ctx, sql, deactivated, accountId)
+	if err != nil {
+		return failure.New("unable to update account status", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) approveAccount(ctx context.Context, tx db.Tx, accountId, approverId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set approved_at = current_timestamp, approved_by = $1
+		where id = $2 and approved_at is null
+	`
+
+	_, err := q.Exec(ctx, sql, approverId, accountId)
+	if err != nil {
+		return failure.New("unable to approve account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update refresh_token
+		set is_revoked = true
+		where account_id = $1 and is_revoked = false
+	`
+
+	_, err := q.Exec(ctx, sql, accountId)
+	if err != nil {
+		return failure.New("failed to revoke account refresh tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// bumpTokenVersion invalidates all the access tokens issued to the account so far
+func (s *store) bumpTokenVersion(ctx context.Context, tx db.Tx, accountId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set token_version = token_version + 1
+		where id = $1
+	`
+
+	_, err := q.Exec(ctx, sql, accountId)
+	if err != nil {
+		return failure.New("unable to revoke account tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) findRolePermissions(ctx context.Context, tx db.Tx, role string) ([]permission.Permission, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `select permission from role_permission where role_name = $1`
+
+	rows, err := q.Query(ctx, sql, role)
+	if err != nil {
+		return nil, failure.New("unable to find role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	perms := []permission.Permission{}
+	for rows.Next() {
+		var perm permission.Permission
+		if err := rows.Scan(&perm); err != nil {
+			return nil, failure.New("unable to find role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		perms = append(perms, perm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to find role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return perms, nil
+}
+
+const selectScopedGrant = `
+	select
+		id,
+		role_name,
+		season_id,
+		league_id,
+		granted_by,
+		created_at
+	from scoped_role_grant
+`
+
+func (s *store) findScopedGrants(ctx context.Context, accountId string) ([]ScopedGrantModel, error) {
+	sql := selectScopedGrant + "where account_id = $1 order by created_at"
+
+	rows, err := s.db.Query(ctx, sql, accountId)
+	if err != nil {
+		return nil, failure.New("unable to find scoped grants", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	grants := []ScopedGrantModel{}
+	for rows.Next() {
+		var sg ScopedGrantModel
+		if err := sg.ScanRows(rows); err != nil {
+			return nil, err
+		}
+		grants = append(grants, sg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to find scoped grants", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return grants, nil
+}
+
+func (s *store) insertScopedGrant(ctx context.Context, tx db.Tx, accountId, grantedBy string, model CreateScopedGrantRequestModel) (*ScopedGrantModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into scoped_role_grant (account_id, role_name, season_id, league_id, granted_by)
+		values ($1, $2, nullif($3, '')::uuid, nullif($4, '')::uuid, $5)
+		returning id, role_name, season_id, league_id, granted_by, created_at
+	`
+
+	var dest ScopedGrantModel
+	err := q.QueryRow(ctx, sql, accountId, model.Role, model.SeasonId, model.LeagueId, grantedBy).
+		Scan(&dest.Id, &dest.Role, &dest.SeasonId, &dest.LeagueId, &dest.GrantedBy, &dest.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return nil, failure.New("role already granted in this scope", fmt.Errorf("%w -> %v", failure.ErrDuplicate, err))
+			case "23503":
+				field := "season_id"
+				if model.LeagueId != "" {
+					field = "league_id"
+				}
+				return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+					{Field: field, Message: "Scope not found", Location: "body"},
+				})
+			}
+		}
+		return nil, failure.New("unable to grant scoped role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &dest, nil
+}
+
+func (s *store) deleteScopedGrant(ctx context.Context, tx db.Tx, accountId, grantId string) (*ScopedGrantModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		delete from scoped_role_grant where id = $1 and account_id = $2
+		returning id, role_name, season_id, league_id, granted_by, created_at
+	`
+
+	var dest ScopedGrantModel
+	row := q.QueryRow(ctx, sql, grantId, accountId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("scoped grant not found", err)
+		}
+		return nil, failure.New("unable to revoke scoped grant", err)
+	}
+
+	return &dest, nil
+}
+
+// findScopedRoles returns the roles the account was granted in the league or in the season,
+// a season grant only applies to the leagues of that season
+func (s *store) findScopedRoles(ctx context.Context, accountId, seasonId, leagueId string) ([]string, error) {
+	sql := `
+		select distinct g.role_name
+		from scoped_role_grant g
+		where g.account_id = $1
+			and (
+				g.league_id = nullif($3, '')::uuid
+				or (
+					g.season_id = coalesce(
+						(select league.season_id from league where league.id = nullif($3, '')::uuid),
+						nullif($2, '')::uuid
+					)
+					and (nullif($2, '') is null or g.season_id = nullif($2, '')::uuid)
+				)
+			)
+	`
+
+	rows, err := s.db.Query(ctx, sql, accountId, seasonId, leagueId)
+	if err != nil {
+		return nil, failure.New("unable to find scoped roles", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, failure.New("unable to find scoped roles", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to find scoped roles", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return roles, nil
+}
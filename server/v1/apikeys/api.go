@@ -0,0 +1,31 @@
+package apikeys
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/router"
+)
+
+type api struct {
+	hdl *handler
+}
+
+var _ router.Mounter = (*api)(nil)
+
+func New(cfg *config.Config, repo Repository) *api {
+	return &api{
+		hdl: newHandler(cfg, repo),
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Get("/", a.hdl.getAPIKeys)
+	r.Post("/", a.hdl.createAPIKey)
+	r.With(middleware.URLPathUUIDParams("api_key_id")).Delete("/{api_key_id}", a.hdl.revokeAPIKey)
+}
+
+// NewLookup returns the api key lookup used by middleware.APIKeyVerifier
+func NewLookup(repo Repository) middleware.APIKeyLookup {
+	return repo.findIdentityByHash
+}
@@ -0,0 +1,135 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/response"
+)
+
+type handler struct {
+	store   Repository
+	service *service
+}
+
+func newHandler(cfg *config.Config, repo Repository) *handler {
+	h := &handler{}
+	h.store = repo
+	h.service = newService(cfg, h.store)
+	return h
+}
+
+// @Summary Create
+// @Description Create a personal api key. The key is only returned once, the scopes can't exceed the account role permissions
+// @Tags api keys
+// @Accept json
+// @Produce json
+// @Param body body apikeys.CreateAPIKeyRequestModel true "Request body"
+// @Success 201 {object} apikeys.CreatedAPIKeyModel "Created"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/api-keys [post]
+func (h *handler) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var model CreateAPIKeyRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	role := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+	_, viaAPIKey := r.Context().Value(middleware.APIKeyIdCtxKey).(string)
+
+	result, err := h.service.processCreateAPIKey(r.Context(), accountId, role, viaAPIKey, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusCreated, result)
+}
+
+// @Summary Get
+// @Description Get my api keys
+// @Tags api keys
+// @Produce json
+// @Success 200 {array} apikeys.APIKeyModel "OK"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/api-keys [get]
+func (h *handler) getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.store.findAPIKeys(r.Context(), accountId)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Revoke
+// @Description Revoke one of my api keys
+// @Tags api keys
+// @Param api_key_id path string true "api key id"
+// @Success 204 "No content"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/api-keys/{api_key_id} [delete]
+func (h *handler) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	err := h.service.processRevokeAPIKey(r.Context(), accountId, chi.URLParam(r, "api_key_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
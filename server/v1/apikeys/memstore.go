@@ -0,0 +1,166 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toAPIKeyModel(k db.APIKey) APIKeyModel {
+	return APIKeyModel{
+		Id:         k.Id,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     slices.Clone(k.Scopes),
+		ExpiresAt:  memdb.TimePtr(k.ExpiresAt),
+		LastUsedAt: memdb.TimePtr(k.LastUsedAt),
+		RevokedAt:  memdb.TimePtr(k.RevokedAt),
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+func (s *memStore) insertAPIKey(ctx context.Context, tx db.Tx, accountId, creatorId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*APIKeyModel, error) {
+	var dest APIKeyModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		account, ok := t.Accounts[accountId]
+		if !ok {
+			return fmt.Errorf("%w -> account %s not found", failure.ErrInternal, accountId)
+		}
+		for _, k := range t.APIKeys {
+			if k.KeyHash == keyHash {
+				return fmt.Errorf("%w -> duplicate api key hash", failure.ErrInternal)
+			}
+		}
+		k := db.APIKey{
+			Id:        memdb.NewId(),
+			AccountId: accountId,
+			Name:      name,
+			Prefix:    prefix,
+			KeyHash:   keyHash,
+			Scopes:    slices.Clone(scopes),
+			ExpiresAt: memdb.NullTime(expiresAt),
+			CreatorId: sql.NullString{String: creatorId, Valid: true},
+			// bumping the account token version revokes the key
+			TokenVersion: account.TokenVersion,
+			CreatedAt:    s.db.Now(),
+		}
+		t.APIKeys[k.Id] = k
+		dest = toAPIKeyModel(k)
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("failed to insert api key", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *memStore) findAPIKeys(ctx context.Context, accountId string) ([]APIKeyModel, error) {
+	dest := []APIKeyModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, k := range t.APIKeys {
+			if k.AccountId == accountId {
+				dest = append(dest, toAPIKeyModel(k))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	slices.SortFunc(dest, func(a, b APIKeyModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return dest, nil
+}
+
+func (s *memStore) revokeAPIKey(ctx context.Context, tx db.Tx, accountId, apiKeyId string) error {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		k, ok := t.APIKeys[apiKeyId]
+		if !ok || k.AccountId != accountId {
+			return nil
+		}
+		if !k.RevokedAt.Valid {
+			k.RevokedAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+		}
+		t.APIKeys[apiKeyId] = k
+		found = true
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to revoke api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("api key not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *memStore) findIdentityByHash(ctx context.Context, keyHash string) (*middleware.APIKeyIdentity, error) {
+	var dest *middleware.APIKeyIdentity
+	err := s.db.Write(nil, func(t *memdb.Tables) error {
+		now := s.db.Now()
+		for _, k := range t.APIKeys {
+			if k.KeyHash != keyHash || k.RevokedAt.Valid || (k.ExpiresAt.Valid && !k.ExpiresAt.Time.After(now)) {
+				continue
+			}
+			account, ok := t.Accounts[k.AccountId]
+			if !ok || account.DeactivatedAt.Valid || account.TokenVersion != k.TokenVersion {
+				return nil
+			}
+			if sa, ok := t.ServiceAccounts[account.Id]; ok && sa.DisabledAt.Valid {
+				return nil
+			}
+
+			dest = &middleware.APIKeyIdentity{KeyId: k.Id, AccountId: account.Id, Role: account.Role}
+			if p, ok := t.PlayerOf(account.Id); ok {
+				dest.PlayerId = p.Id
+			}
+			for _, sc := range k.Scopes {
+				dest.Scopes = append(dest.Scopes, permission.Permission(sc))
+			}
+
+			// the last used time is only written once a minute
+			if !k.LastUsedAt.Valid || k.LastUsedAt.Time.Before(now.Add(-time.Minute)) {
+				k.LastUsedAt = sql.NullTime{Time: now, Valid: true}
+				t.APIKeys[k.Id] = k
+			}
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to verify api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("invalid api key", failure.ErrUnauthorized)
+	}
+
+	return dest, nil
+}
@@ -0,0 +1,87 @@
+package apikeys
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+type APIKeyModel struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (akm *APIKeyModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&akm.Id, &akm.Name, &akm.Prefix, &akm.Scopes, &akm.ExpiresAt, &akm.LastUsedAt, &akm.RevokedAt, &akm.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning api key row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+func (akm *APIKeyModel) ScanRows(rows pgx.Rows) error {
+	err := rows.Scan(&akm.Id, &akm.Name, &akm.Prefix, &akm.Scopes, &akm.ExpiresAt, &akm.LastUsedAt, &akm.RevokedAt, &akm.CreatedAt)
+	if err != nil {
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// the plain key is only returned once, right after it's created
+type CreatedAPIKeyModel struct {
+	APIKeyModel
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyRequestModel struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (m CreateAPIKeyRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Name == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "name",
+			Message:  "Name field is required",
+			Location: "body",
+		})
+	}
+	for _, s := range m.Scopes {
+		if !permission.Exists(permission.Permission(s)) {
+			inv = append(inv, failure.InvalidField{
+				Field:    "scopes",
+				Message:  fmt.Sprintf("Unknown scope %q", s),
+				Location: "body",
+			})
+		}
+	}
+	if m.ExpiresAt != nil && !m.ExpiresAt.After(time.Now()) {
+		inv = append(inv, failure.InvalidField{
+			Field:    "expires_at",
+			Message:  "Expiration must be in the future",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
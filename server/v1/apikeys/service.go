@@ -0,0 +1,121 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/sec"
+	"github.com/markovidakovic/gdsi/server/tracing"
+)
+
+type service struct {
+	cfg   *config.Config
+	store Repository
+}
+
+func newService(cfg *config.Config, store Repository) *service {
+	return &service{
+		cfg,
+		store,
+	}
+}
+
+// processCreateAPIKey creates a personal api key. The scopes can't exceed the account role
+// permissions and a key can't be used to create more keys
+func (s *service) processCreateAPIKey(ctx context.Context, accountId, role string, viaAPIKey bool, model CreateAPIKeyRequestModel) (*CreatedAPIKeyModel, error) {
+	ctx, span := tracing.Start(ctx, "apikeys.processCreateAPIKey")
+	defer span.End()
+
+	if viaAPIKey {
+		return nil, failure.New("api keys can't be created with an api key", failure.ErrForbidden)
+	}
+
+	for _, sc := range model.Scopes {
+		if !permission.Has(role, permission.Permission(sc)) {
+			return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+				{Field: "scopes", Message: fmt.Sprintf("Scope %q exceeds your role permissions", sc), Location: "body"},
+			})
+		}
+	}
+
+	key, prefix, err := sec.GenerateAPIKey()
+	if err != nil {
+		return nil, failure.New("unable to create api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	if model.Scopes == nil {
+		model.Scopes = []string{}
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "rolling back tx", "error", err)
+		}
+	}()
+
+	created, err := s.store.insertAPIKey(ctx, tx, accountId, accountId, model.Name, prefix, sec.HashToken(key), model.Scopes, model.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.APIKey, ResourceId: created.Id, After: created})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &CreatedAPIKeyModel{
+		APIKeyModel: *created,
+		Key:         key,
+	}, nil
+}
+
+func (s *service) processRevokeAPIKey(ctx context.Context, accountId, apiKeyId string) error {
+	ctx, span := tracing.Start(ctx, "apikeys.processRevokeAPIKey")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to revoke api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "rolling back tx", "error", err)
+		}
+	}()
+
+	err = s.store.revokeAPIKey(ctx, tx, accountId, apiKeyId)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionRevoke, ResourceType: audit.APIKey, ResourceId: apiKeyId})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to revoke api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+// Repository holds the api keys of the accounts, only the hash of a key is stored
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	insertAPIKey(ctx context.Context, tx db.Tx, accountId, creatorId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*APIKeyModel, error)
+	findAPIKeys(ctx context.Context, accountId string) ([]APIKeyModel, error)
+	revokeAPIKey(ctx context.Context, tx db.Tx, accountId, apiKeyId string) error
+	findIdentityByHash(ctx context.Context, keyHash string) (*middleware.APIKeyIdentity, error)
+}
+
+type store struct {
+	db *db.Conn
+}
+
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
+		db,
+	}
+}
+
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func (s *store) insertAPIKey(ctx context.Context, tx db.Tx, accountId, creatorId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*APIKeyModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into api_key (account_id, creator_id, name, prefix, key_hash, scopes, expires_at, token_version)
+		values ($1, $2, $3, $4, $5, $6, $7, (select token_version from account where id = $1))
+		returning id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+	`
+
+	var dest APIKeyModel
+	row := q.QueryRow(ctx, sql, accountId, creatorId, name, prefix, keyHash, scopes, expiresAt)
+	err := dest.ScanRow(row)
+	if err != nil {
+		return nil, failure.New("failed to insert api key", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) findAPIKeys(ctx context.Context, accountId string) ([]APIKeyModel, error) {
+	sql := `
+		select id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		from api_key
+		where account_id = $1
+		order by created_at desc
+	`
+
+	rows, err := s.db.Query(ctx, sql, accountId)
+	if err != nil {
+		return nil, failure.New("unable to find api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	var dest = []APIKeyModel{}
+	for rows.Next() {
+		var akm APIKeyModel
+		err := akm.ScanRows(rows)
+		if err != nil {
+			return nil, failure.New("unable to find api keys", err)
+		}
+		dest = append(dest, akm)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, failure.New("unable to find api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) revokeAPIKey(ctx context.Context, tx db.Tx, accountId, apiKeyId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update api_key
+		set revoked_at = coalesce(revoked_at, current_timestamp)
+		where id = $1 and account_id = $2
+	`
+
+	ct, err := q.Exec(ctx, sql, apiKeyId, accountId)
+	if err != nil {
+		return failure.New("unable to revoke api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("api key not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+// findIdentityByHash resolves an active api key to its account. A key created before the
+// account token version changed is revoked like the jwts. The last used time is only
+// written once a minute so busy keys don't cause a write on every request
+func (s *store) findIdentityByHash(ctx context.Context, keyHash string) (*middleware.APIKeyIdentity, error) {
+	sql := `
+		select 
+			api_key.id as api_key_id,
+			account.id as account_id,
+			account.role as account_role,
+			coalesce(player.id::text, '') as player_id,
+			api_key.scopes as api_key_scopes
+		from api_key
+		join account on api_key.account_id = account.id
+		left join player on player.account_id = account.id
+		left join service_account on service_account.account_id = account.id
+		where api_key.key_hash = $1
+			and api_key.revoked_at is null
+			and (api_key.expires_at is null or api_key.expires_at > current_timestamp)
+			and service_account.disabled_at is null
+			and account.deactivated_at is null
+			and account.token_version = api_key.token_version
+	`
+
+	var dest middleware.APIKeyIdentity
+	var scopes []string
+
+	err := s.db.QueryRow(ctx, sql, keyHash).Scan(&dest.KeyId, &dest.AccountId, &dest.Role, &dest.PlayerId, &scopes)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, failure.New("invalid api key", failure.ErrUnauthorized)
+		}
+		return nil, failure.New("unable to verify api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	for _, sc := range scopes {
+		dest.Scopes = append(dest.Scopes, permission.Permission(sc))
+	}
+
+	sql = `
+		update api_key
+		set last_used_at = current_timestamp
+		where id = $1 and (last_used_at is null or last_used_at < current_timestamp - interval '1 minute')
+	`
+
+	_, err = s.db.Exec(ctx, sql, dest.KeyId)
+	if err != nil {
+		return nil, failure.New("unable to update api key last used", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &dest, nil
+}
@@ -0,0 +1,28 @@
+package auditlog
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/router"
+)
+
+type api struct {
+	hdl *handler
+}
+
+var _ router.Mounter = (*api)(nil)
+
+func New(cfg *config.Config, repo Repository) *api {
+	return &api{
+		hdl: newHandler(cfg, repo),
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Use(middleware.RequirePermission(permission.ViewAudit))
+
+	r.With(middleware.URLQueryPaginationParams).Get("/", a.hdl.getEntries)
+	r.With(middleware.URLPathUUIDParams("entry_id")).Get("/{entry_id}", a.hdl.getEntry)
+}
@@ -0,0 +1,99 @@
+package auditlog
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/pagination"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/response"
+)
+
+type handler struct {
+	store   Repository
+	service *service
+}
+
+func newHandler(cfg *config.Config, repo Repository) *handler {
+	h := &handler{}
+	h.store = repo
+	h.service = newService(cfg, h.store)
+	return h
+}
+
+// @Summary Get
+// @Description Get the audit log entries, newest first
+// @Tags admin audit
+// @Produce json
+// @Param page query int false "page"
+// @Param per_page query int false "per page"
+// @Param order_by query string false "order by"
+// @Param actor_id query string false "acting account id"
+// @Param action query string false "action, e.g. create, update, delete, submit_score"
+// @Param resource_type query string false "resource type, e.g. season, league, match"
+// @Param resource_id query string false "resource id"
+// @Param from query string false "RFC3339 timestamp, inclusive"
+// @Param to query string false "RFC3339 timestamp, exclusive"
+// @Success 200 {array} auditlog.EntryModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/audit [get]
+func (h *handler) getEntries(w http.ResponseWriter, r *http.Request) {
+	query := params.NewQuery(r.URL.Query())
+
+	entries, count, err := h.service.processGetEntries(r.Context(), query)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	result := pagination.NewPaginated(query.Page, query.PerPage, count, entries)
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Get by id
+// @Description Get an audit log entry by id
+// @Tags admin audit
+// @Produce json
+// @Param entry_id path string true "entry id"
+// @Success 200 {object} auditlog.EntryModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/audit/{entry_id} [get]
+func (h *handler) getEntry(w http.ResponseWriter, r *http.Request) {
+	result, err := h.store.findEntry(r.Context(), chi.URLParam(r, "entry_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
@@ -0,0 +1,147 @@
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortingFields = map[string]func(a, b EntryModel) int{
+	"created_at":    func(a, b EntryModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+	"action":        func(a, b EntryModel) int { return memdb.CompareText(a.Action, b.Action) },
+	"resource_type": func(a, b EntryModel) int { return memdb.CompareText(a.ResourceType, b.ResourceType) },
+}
+
+func toEntryModel(t *memdb.Tables, e db.AuditLog) EntryModel {
+	em := EntryModel{
+		Id:             e.Id,
+		ActorAccountId: memdb.StringPtr(e.ActorAccountId),
+		ActorAPIKeyId:  memdb.StringPtr(e.ActorApiKeyId),
+		Action:         e.Action,
+		ResourceType:   e.ResourceType,
+		ResourceId:     e.ResourceId,
+		Before:         e.Before,
+		After:          e.After,
+		Ip:             memdb.StringPtr(e.Ip),
+		UserAgent:      memdb.StringPtr(e.UserAgent),
+		RequestId:      memdb.StringPtr(e.RequestId),
+		Method:         memdb.StringPtr(e.Method),
+		Path:           memdb.StringPtr(e.Path),
+		CreatedAt:      e.CreatedAt,
+	}
+	if e.ActorAccountId.Valid {
+		if account, ok := t.Accounts[e.ActorAccountId.String]; ok {
+			em.ActorName = &account.Name
+		}
+	}
+	return em
+}
+
+// filter is the where clause of filterEntries, the bounds were validated as RFC3339 timestamps
+func (s *memStore) filter(filter EntryFilter) (func(e db.AuditLog) bool, error) {
+	var from, to time.Time
+	var err error
+	if filter.From != "" {
+		if from, err = time.Parse(time.RFC3339, filter.From); err != nil {
+			return nil, err
+		}
+	}
+	if filter.To != "" {
+		if to, err = time.Parse(time.RFC3339, filter.To); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(e db.AuditLog) bool {
+		return (filter.ActorId == "" || (e.ActorAccountId.Valid && e.ActorAccountId.String == filter.ActorId)) &&
+			(filter.Action == "" || e.Action == filter.Action) &&
+			(filter.ResourceType == "" || e.ResourceType == filter.ResourceType) &&
+			(filter.ResourceId == "" || e.ResourceId == filter.ResourceId) &&
+			(filter.From == "" || !e.CreatedAt.Before(from)) &&
+			(filter.To == "" || e.CreatedAt.Before(to))
+	}, nil
+}
+
+func (s *memStore) findEntries(ctx context.Context, filter EntryFilter, limit, offset int, orderBy *params.OrderBy) ([]EntryModel, error) {
+	match, err := s.filter(filter)
+	if err != nil {
+		return nil, failure.New("unable to find audit entries", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	dest := []EntryModel{}
+	err = s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, e := range t.AuditLogs {
+			if match(e) {
+				dest = append(dest, toEntryModel(t, e))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find audit entries", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, orderBy, memSortingFields, func(a, b EntryModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countEntries(ctx context.Context, filter EntryFilter) (int, error) {
+	match, err := s.filter(filter)
+	if err != nil {
+		return 0, failure.New("unable to count audit entries", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	var count int
+	err = s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, e := range t.AuditLogs {
+			if match(e) {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count audit entries", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findEntry(ctx context.Context, entryId string) (*EntryModel, error) {
+	var dest *EntryModel
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, e := range t.AuditLogs {
+			if e.Id == entryId {
+				em := toEntryModel(t, e)
+				dest = &em
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find audit entry", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("audit entry not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
@@ -0,0 +1,97 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/failure"
+)
+
+type EntryModel struct {
+	Id             string          `json:"id"`
+	ActorAccountId *string         `json:"actor_account_id"`
+	ActorName      *string         `json:"actor_name"`
+	ActorAPIKeyId  *string         `json:"actor_api_key_id"`
+	Action         string          `json:"action"`
+	ResourceType   string          `json:"resource_type"`
+	ResourceId     string          `json:"resource_id"`
+	Before         json.RawMessage `json:"before" swaggertype:"object"`
+	After          json.RawMessage `json:"after" swaggertype:"object"`
+	Ip             *string         `json:"ip"`
+	UserAgent      *string         `json:"user_agent"`
+	RequestId      *string         `json:"request_id"`
+	Method         *string         `json:"method"`
+	Path           *string         `json:"path"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+func (em *EntryModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&em.Id, &em.ActorAccountId, &em.ActorName, &em.ActorAPIKeyId, &em.Action, &em.ResourceType, &em.ResourceId, &em.Before, &em.After, &em.Ip, &em.UserAgent, &em.RequestId, &em.Method, &em.Path, &em.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning audit entry row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+func (em *EntryModel) ScanRows(rows pgx.Rows) error {
+	err := rows.Scan(&em.Id, &em.ActorAccountId, &em.ActorName, &em.ActorAPIKeyId, &em.Action, &em.ResourceType, &em.ResourceId, &em.Before, &em.After, &em.Ip, &em.UserAgent, &em.RequestId, &em.Method, &em.Path, &em.CreatedAt)
+	if err != nil {
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// EntryFilter holds the optional filter query params of the audit log
+type EntryFilter struct {
+	ActorId      string
+	Action       string
+	ResourceType string
+	ResourceId   string
+	From         string
+	To           string
+}
+
+func (f EntryFilter) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if f.ActorId != "" {
+		if err := uuid.Validate(f.ActorId); err != nil {
+			inv = append(inv, failure.InvalidField{
+				Field:    "actor_id",
+				Message:  "Invalid uuid format",
+				Location: "query",
+			})
+		}
+	}
+	if f.From != "" {
+		if _, err := time.Parse(time.RFC3339, f.From); err != nil {
+			inv = append(inv, failure.InvalidField{
+				Field:    "from",
+				Message:  "From must be an RFC3339 timestamp",
+				Location: "query",
+			})
+		}
+	}
+	if f.To != "" {
+		if _, err := time.Parse(time.RFC3339, f.To); err != nil {
+			inv = append(inv, failure.InvalidField{
+				Field:    "to",
+				Message:  "To must be an RFC3339 timestamp",
+				Location: "query",
+			})
+		}
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package auditlog
+
+import (
+	"context"
+
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/tracing"
+)
+
+type service struct {
+	cfg   *config.Config
+	store Repository
+}
+
+func newService(cfg *config.Config, store Repository) *service {
+	return &service{
+		cfg,
+		store,
+	}
+}
+
+func (s *service) processGetEntries(ctx context.Context, query *params.Query) ([]EntryModel, int, error) {
+	ctx, span := tracing.Start(ctx, "auditlog.processGetEntries")
+	defer span.End()
+
+	filter := EntryFilter{
+		ActorId:      query.Additional["actor_id"],
+		Action:       query.Additional["action"],
+		ResourceType: query.Additional["resource_type"],
+		ResourceId:   query.Additional["resource_id"],
+		From:         query.Additional["from"],
+		To:           query.Additional["to"],
+	}
+	if inv := filter.Validate(); inv != nil {
+		return nil, 0, failure.NewValidation("validation failed", inv)
+	}
+
+	count, err := s.store.countEntries(ctx, filter)
+	if err != nil {
+		return nil, 0, failure.New("unable to get audit entries", err)
+	}
+
+	limit, offset := query.CalcLimitAndOffset(count)
+
+	result, err := s.store.findEntries(ctx, filter, limit, offset, query.OrderBy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, count, nil
+}
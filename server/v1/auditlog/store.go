@@ -0,0 +1,162 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+// Repository lists the audit log entries, audit.Record is the only writer
+type Repository interface {
+	findEntries(ctx context.Context, filter EntryFilter, limit, offset int, orderBy *params.OrderBy) ([]EntryModel, error)
+	countEntries(ctx context.Context, filter EntryFilter) (int, error)
+	findEntry(ctx context.Context, entryId string) (*EntryModel, error)
+}
+
+type store struct {
+	db *db.Conn
+}
+
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
+		db,
+	}
+}
+
+var sortingFields = map[string]string{
+	"created_at":    "audit_log.created_at",
+	"action":        "audit_log.action",
+	"resource_type": "audit_log.resource_type",
+}
+
+const selectEntry = `
+	select
+		audit_log.id,
+		audit_log.actor_account_id,
+		account.name as actor_name,
+		audit_log.actor_api_key_id,
+		audit_log.action,
+		audit_log.resource_type,
+		audit_log.resource_id,
+		audit_log.before,
+		audit_log.after,
+		audit_log.ip,
+		audit_log.user_agent,
+		audit_log.request_id,
+		audit_log.method,
+		audit_log.path,
+		audit_log.created_at
+	from audit_log
+	left join account on audit_log.actor_account_id = account.id
+`
+
+// filterEntries builds the where clause of the audit log list
+func filterEntries(filter EntryFilter) (string, []interface{}) {
+	sql := "where true\n"
+	args := []interface{}{}
+	argCounter := 1
+
+	if filter.ActorId != "" {
+		sql += fmt.Sprintf("and audit_log.actor_account_id = $%d\n", argCounter)
+		args = append(args, filter.ActorId)
+		argCounter++
+	}
+	if filter.Action != "" {
+		sql += fmt.Sprintf("and audit_log.action = $%d\n", argCounter)
+		args = append(args, filter.Action)
+		argCounter++
+	}
+	if filter.ResourceType != "" {
+		sql += fmt.Sprintf("and audit_log.resource_type = $%d\n", argCounter)
+		args = append(args, filter.ResourceType)
+		argCounter++
+	}
+	if filter.ResourceId != "" {
+		sql += fmt.Sprintf("and audit_log.resource_id = $%d\n", argCounter)
+		args = append(args, filter.ResourceId)
+		argCounter++
+	}
+	if filter.From != "" {
+		sql += fmt.Sprintf("and audit_log.created_at >= $%d\n", argCounter)
+		args = append(args, filter.From)
+		argCounter++
+	}
+	if filter.To != "" {
+		sql += fmt.Sprintf("and audit_log.created_at < $%d\n", argCounter)
+		args = append(args, filter.To)
+	}
+
+	return sql, args
+}
+
+func (s *store) findEntries(ctx context.Context, filter EntryFilter, limit, offset int, orderBy *params.OrderBy) ([]EntryModel, error) {
+	where, args := filterEntries(filter)
+	sql := selectEntry + where
+
+	if orderBy != nil && orderBy.IsValid(sortingFields) {
+		sql += fmt.Sprintf("order by %s %s\n", sortingFields[orderBy.Field], orderBy.Direction)
+	} else {
+		sql += fmt.Sprintln("order by audit_log.created_at desc")
+	}
+
+	if limit >= 0 {
+		sql += fmt.Sprintf("limit $%d offset $%d", len(args)+1, len(args)+2)
+		args = append(args, limit, offset)
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, failure.New("unable to find audit entries", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	var dest = []EntryModel{}
+	for rows.Next() {
+		var em EntryModel
+		err := em.ScanRows(rows)
+		if err != nil {
+			return nil, failure.New("unable to find audit entries", err)
+		}
+		dest = append(dest, em)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, failure.New("unable to find audit entries", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) countEntries(ctx context.Context, filter EntryFilter) (int, error) {
+	where, args := filterEntries(filter)
+	sql := "select count(*) from audit_log\n" + where
+
+	var count int
+	err := s.db.QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, failure.New("unable to count audit entries", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *store) findEntry(ctx context.Context, entryId string) (*EntryModel, error) {
+	sql := selectEntry + "where audit_log.id = $1"
+
+	var dest EntryModel
+	row := s.db.QueryRow(ctx, sql, entryId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("audit entry not found", err)
+		}
+		return nil, failure.New("unable to find audit entry", err)
+	}
+
+	return &dest, nil
+}
@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"context"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/oidc"
 	"github.com/markovidakovic/gdsi/server/router"
 )
 
@@ -13,14 +15,24 @@ type api struct {
 
 var _ router.Mounter = (*api)(nil)
 
-func New(cfg *config.Config, db *db.Conn) *api {
+func New(cfg *config.Config, repo Repository, provider *oidc.Provider) *api {
 	return &api{
-		hdl: newHandler(cfg, db),
+		hdl: newHandler(cfg, repo, provider),
 	}
 }
 
 func (a *api) Mount(r chi.Router) {
+	r.Get("/registration", a.hdl.getRegistration)
 	r.Post("/signup", a.hdl.signup)
 	r.Post("/tokens/access", a.hdl.login)
+	r.Post("/tokens/2fa", a.hdl.verifyTwoFactor)
 	r.Post("/tokens/refresh", a.hdl.refreshToken)
+	r.Get("/oidc/authorize", a.hdl.oidcAuthorize)
+	r.Post("/oidc/callback", a.hdl.oidcCallback)
+}
+
+// NewTokenPurger returns the task deleting the expired refresh tokens, it's run periodically
+// by the server
+func NewTokenPurger(repo Repository) func(ctx context.Context) error {
+	return repo.deleteExpiredRefreshTokens
 }
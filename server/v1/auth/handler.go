@@ -6,8 +6,8 @@ import (
 	"net/http"
 
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/oidc"
 	"github.com/markovidakovic/gdsi/server/response"
 )
 
@@ -15,21 +15,31 @@ type handler struct {
 	service *service
 }
 
-func newHandler(cfg *config.Config, db *db.Conn) *handler {
+func newHandler(cfg *config.Config, repo Repository, provider *oidc.Provider) *handler {
 	h := &handler{}
-	store := newStore(db)
-	h.service = newService(cfg, store)
+	h.service = newService(cfg, repo, provider)
 	return h
 }
 
+// @Summary Registration
+// @Description Get the registration mode so the signup form knows whether to ask for an invite code
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.RegistrationResponseModel "OK"
+// @Router /v1/auth/registration [get]
+func (h *handler) getRegistration(w http.ResponseWriter, r *http.Request) {
+	response.WriteSuccess(w, http.StatusOK, h.service.processGetRegistration())
+}
+
 // @Summary Signup
-// @Description Signup a new account
+// @Description Signup a new account. Depending on the registration mode an invite code is required or the account waits for approval before it can join leagues
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param body body SignupRequestModel true "Request body"
 // @Success 200 {object} auth.TokensResponseModel "OK"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 409 {object} failure.Failure "Conflict"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Router /v1/auth/signup [post]
 func (h *handler) signup(w http.ResponseWriter, r *http.Request) {
@@ -69,12 +79,13 @@ func (h *handler) signup(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Login
-// @Description Login and get a new access token
+// @Description Login and get a new access token. Accounts with two-factor authentication enabled get a challenge which is completed at /v1/auth/tokens/2fa
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param body body LoginRequestModel true "Request body"
 // @Success 200 {object} auth.TokensResponseModel "OK"
+// @Success 202 {object} auth.TwoFactorChallengeResponseModel "Two-factor authentication required"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Router /v1/auth/tokens/access [post]
@@ -103,7 +114,7 @@ func (h *handler) login(w http.ResponseWriter, r *http.Request) {
 	// fmt.Printf("port: %v\n", port)
 	// fmt.Printf("r.UserAgent(): %v\n", r.UserAgent())
 
-	accessToken, refreshToken, err := h.service.processLogin(r.Context(), model)
+	tokens, challenge, err := h.service.processLogin(r.Context(), model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.Failure:
@@ -118,12 +129,55 @@ func (h *handler) login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	resp := TokensResponseModel{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	if challenge != nil {
+		response.WriteSuccess(w, http.StatusAccepted, challenge)
+		return
 	}
 
-	response.WriteSuccess(w, http.StatusOK, resp)
+	response.WriteSuccess(w, http.StatusOK, tokens)
+}
+
+// @Summary Verify two-factor
+// @Description Complete the login with a totp code or a recovery code and get a new access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body VerifyTwoFactorRequestModel true "Request body"
+// @Success 200 {object} auth.TokensResponseModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Router /v1/auth/tokens/2fa [post]
+func (h *handler) verifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var model VerifyTwoFactorRequestModel
+
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	result, err := h.service.processVerifyTwoFactor(r.Context(), model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
 }
 
 // @Summary Refresh token
@@ -171,3 +225,81 @@ func (h *handler) refreshToken(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteSuccess(w, http.StatusOK, resp)
 }
+
+// @Summary Start OIDC login
+// @Description Start a login through the configured OpenID Connect provider. The client opens the authorization url and forwards the returned code and state to /v1/auth/oidc/callback
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.OidcAuthorizationResponseModel "OK"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Router /v1/auth/oidc/authorize [get]
+func (h *handler) oidcAuthorize(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.processOidcAuthorize(r.Context())
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Complete OIDC login
+// @Description Complete the OpenID Connect login with the authorization code and get a new access token. Accounts with two-factor authentication enabled get a challenge which is completed at /v1/auth/tokens/2fa
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body OidcCallbackRequestModel true "Request body"
+// @Success 200 {object} auth.TokensResponseModel "OK"
+// @Success 202 {object} auth.TwoFactorChallengeResponseModel "Two-factor authentication required"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Router /v1/auth/oidc/callback [post]
+func (h *handler) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	var model OidcCallbackRequestModel
+
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	tokens, challenge, err := h.service.processOidcCallback(r.Context(), model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	if challenge != nil {
+		response.WriteSuccess(w, http.StatusAccepted, challenge)
+		return
+	}
+
+	response.WriteSuccess(w, http.StatusOK, tokens)
+}
@@ -0,0 +1,594 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toAccountModel(t *memdb.Tables, a db.Account) AccountModel {
+	var am AccountModel
+	am.Id = a.Id
+	am.Name = a.Name
+	am.Email = a.Email
+	am.Dob = memdb.TimePtr(a.Dob)
+	am.Gender = memdb.StringPtr(a.Gender)
+	am.PhoneNumber = memdb.StringPtr(a.PhoneNumber)
+	am.Password = a.Password
+	am.Role = a.Role
+	if p, ok := t.PlayerOf(a.Id); ok {
+		am.PlayerId = &p.Id
+	}
+	am.CreatedAt = a.CreatedAt
+	return am
+}
+
+// insertAccountRow adds the account with the column defaults of the account table
+func (s *memStore) insertAccountRow(tx db.Tx, a db.Account, approved bool, inviteId *string) (AccountModel, error) {
+	var dest AccountModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		a.Id = memdb.NewId()
+		a.Role = "user"
+		a.CreatedAt = s.db.Now()
+		if approved {
+			a.ApprovedAt = sql.NullTime{Time: a.CreatedAt, Valid: true}
+		}
+		a.InviteCodeId = memdb.NullString(inviteId)
+		if err := t.InsertAccount(a); err != nil {
+			return err
+		}
+		// the new account has no player yet
+		dest = toAccountModel(t, a)
+		dest.PlayerId = nil
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("failed to insert account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) insertAccount(ctx context.Context, tx db.Tx, model SignupRequestModel, approved bool, inviteId *string) (AccountModel, error) {
+	dob, err := memdb.ParseTime(model.Dob)
+	if err != nil {
+		return AccountModel{}, failure.New("failed to insert account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return s.insertAccountRow(tx, db.Account{
+		Name:        model.Name,
+		Email:       model.Email,
+		Dob:         sql.NullTime{Time: dob, Valid: true},
+		Gender:      sql.NullString{String: model.Gender, Valid: true},
+		PhoneNumber: sql.NullString{String: model.PhoneNumber, Valid: true},
+		Password:    model.Password,
+	}, approved, inviteId)
+}
+
+func (s *memStore) rehashPassword(ctx context.Context, tx db.Tx, accountId, oldHash, newHash string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if a, ok := t.Accounts[accountId]; ok && a.Password == oldHash {
+			a.Password = newHash
+			t.Accounts[accountId] = a
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to rehash password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) insertPlayer(ctx context.Context, tx db.Tx, accountId string) (string, error) {
+	p := db.Player{Id: memdb.NewId(), AccountId: accountId, CreatedAt: s.db.Now()}
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		return t.InsertPlayer(p)
+	})
+	if err != nil {
+		return "", failure.New("failed to insert player", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return p.Id, nil
+}
+
+// findAccountWhere returns the account fn picks out of the accounts, nil if there's none
+func (s *memStore) findAccountWhere(tx db.Tx, fn func(t *memdb.Tables) (db.Account, bool)) (*AccountModel, error) {
+	var dest *AccountModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		if a, ok := fn(t); ok {
+			am := toAccountModel(t, a)
+			dest = &am
+		}
+		return nil
+	})
+	return dest, err
+}
+
+func (s *memStore) findAccountByEmail(ctx context.Context, tx db.Tx, email string) (*AccountModel, error) {
+	dest, err := s.findAccountWhere(tx, func(t *memdb.Tables) (db.Account, bool) {
+		return t.AccountByEmail(email)
+	})
+	if err != nil {
+		return nil, failure.New("unable to retreive account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New(fmt.Sprintf("account with email %s not found", email), failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findAccountById(ctx context.Context, tx db.Tx, accountId string) (*AccountModel, error) {
+	dest, err := s.findAccountWhere(tx, func(t *memdb.Tables) (db.Account, bool) {
+		a, ok := t.Accounts[accountId]
+		return a, ok
+	})
+	if err != nil {
+		return nil, failure.New("unable to retreive account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("account not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findTokenVersion(ctx context.Context, tx db.Tx, accountId string) (int, error) {
+	var version int
+	var found bool
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		var a db.Account
+		a, found = t.Accounts[accountId]
+		version = a.TokenVersion
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to find account token version", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return 0, failure.New("account not found", failure.ErrNotFound)
+	}
+
+	return version, nil
+}
+
+func (s *memStore) isAccountDeactivated(ctx context.Context, tx db.Tx, accountId string) (bool, error) {
+	var deactivated bool
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		deactivated = t.Accounts[accountId].DeactivatedAt.Valid
+		return nil
+	})
+	if err != nil {
+		return false, failure.New("unable to check account status", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return deactivated, nil
+}
+
+func (s *memStore) insertRefreshToken(ctx context.Context, tx db.Tx, accountId string, token string, issuedAt, expiresAt time.Time, amr []string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Accounts[accountId]; !ok {
+			return memdb.ErrForeignKey
+		}
+		rt := db.RefreshToken{
+			Id:        memdb.NewId(),
+			AccountId: accountId,
+			TokenHash: token,
+			IssuedAt:  issuedAt,
+			ExpiresAt: expiresAt,
+			Amr:       amr,
+		}
+		t.RefreshTokens[rt.Id] = rt
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to insert refresh token", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// updateRefreshTokens applies fn to the refresh tokens match picks
+func (s *memStore) updateRefreshTokens(tx db.Tx, match func(rt db.RefreshToken) bool, fn func(rt *db.RefreshToken)) error {
+	return s.db.Write(tx, func(t *memdb.Tables) error {
+		for id, rt := range t.RefreshTokens {
+			if match(rt) {
+				fn(&rt)
+				t.RefreshTokens[id] = rt
+			}
+		}
+		return nil
+	})
+}
+
+func (s *memStore) revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error {
+	err := s.updateRefreshTokens(tx, func(rt db.RefreshToken) bool {
+		return rt.AccountId == accountId && !rt.IsRevoked
+	}, func(rt *db.RefreshToken) {
+		rt.IsRevoked = true
+	})
+	if err != nil {
+		return failure.New("failed to revoke account refresh tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) deleteExpiredRefreshTokens(ctx context.Context) error {
+	now := s.db.Now()
+	err := s.db.Write(nil, func(t *memdb.Tables) error {
+		for id, rt := range t.RefreshTokens {
+			if rt.ExpiresAt.Before(now) {
+				delete(t.RefreshTokens, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to delete expired refresh tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) findRefreshTokenByHash(ctx context.Context, tx db.Tx, rt string) (*RefreshTokenModel, error) {
+	var dest *RefreshTokenModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		for _, token := range t.RefreshTokens {
+			if token.TokenHash != rt {
+				continue
+			}
+			// the tokens of the accounts without a player aren't joined
+			a := t.Accounts[token.AccountId]
+			p, ok := t.PlayerOf(a.Id)
+			if !ok {
+				return nil
+			}
+			dest = &RefreshTokenModel{
+				Id:           token.Id,
+				AccountId:    a.Id,
+				AccountRole:  a.Role,
+				TokenHash:    token.TokenHash,
+				DeviceId:     memdb.StringPtr(token.DeviceId),
+				IpAddress:    memdb.StringPtr(token.IpAddress),
+				UserAgent:    memdb.StringPtr(token.UserAgent),
+				IssuedAt:     token.IssuedAt,
+				ExpiresAt:    token.ExpiresAt,
+				LastUsedAt:   memdb.TimePtr(token.LastUsedAt),
+				IsRevoked:    token.IsRevoked,
+				PlayerId:     p.Id,
+				Amr:          token.Amr,
+				TokenVersion: a.TokenVersion,
+			}
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to retreive refresh token", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("refresh token not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) updateRefreshToken(ctx context.Context, tx db.Tx, rtId string) error {
+	lua := s.db.Now()
+	err := s.updateRefreshTokens(tx, func(rt db.RefreshToken) bool {
+		return rt.Id == rtId
+	}, func(rt *db.RefreshToken) {
+		rt.LastUsedAt = sql.NullTime{Time: lua, Valid: true}
+	})
+	if err != nil {
+		return failure.New("unable to update refresh token", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) revokeRefreshToken(ctx context.Context, tx db.Tx, rtId string) error {
+	err := s.updateRefreshTokens(tx, func(rt db.RefreshToken) bool {
+		return rt.Id == rtId
+	}, func(rt *db.RefreshToken) {
+		rt.IsRevoked = true
+	})
+	if err != nil {
+		return failure.New("unable to revoke refresh token", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) findTOTP(ctx context.Context, tx db.Tx, accountId string) (*TOTPModel, error) {
+	var dest *TOTPModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		totp, ok := t.AccountTotps[accountId]
+		if !ok {
+			return nil
+		}
+		dest = &TOTPModel{
+			AccountId:      totp.AccountId,
+			Secret:         totp.Secret,
+			EnabledAt:      memdb.TimePtr(totp.EnabledAt),
+			FailedAttempts: totp.FailedAttempts,
+			LastFailedAt:   memdb.TimePtr(totp.LastFailedAt),
+		}
+		if totp.LastUsedStep.Valid {
+			dest.LastUsedStep = &totp.LastUsedStep.Int64
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to retreive totp", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("totp not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+// updateTOTP applies fn to the totp of the account and reports if it was changed, fn returns
+// false to leave the totp alone
+func (s *memStore) updateTOTP(tx db.Tx, accountId string, fn func(totp *db.AccountTotp) bool) (bool, error) {
+	var updated bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		totp, ok := t.AccountTotps[accountId]
+		if !ok || !fn(&totp) {
+			return nil
+		}
+		t.AccountTotps[accountId] = totp
+		updated = true
+		return nil
+	})
+	return updated, err
+}
+
+func (s *memStore) useTOTPStep(ctx context.Context, tx db.Tx, accountId string, step int64) (bool, error) {
+	used, err := s.updateTOTP(tx, accountId, func(totp *db.AccountTotp) bool {
+		if totp.LastUsedStep.Valid && totp.LastUsedStep.Int64 >= step {
+			return false
+		}
+		totp.LastUsedStep = sql.NullInt64{Int64: step, Valid: true}
+		totp.FailedAttempts = 0
+		totp.LastFailedAt = sql.NullTime{}
+		return true
+	})
+	if err != nil {
+		return false, failure.New("unable to update totp last used step", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return used, nil
+}
+
+func (s *memStore) claimTOTPAttempt(ctx context.Context, tx db.Tx, accountId string, max int, window time.Duration) (bool, error) {
+	now := s.db.Now()
+	claimed, err := s.updateTOTP(tx, accountId, func(totp *db.AccountTotp) bool {
+		if !totp.LastFailedAt.Valid || totp.LastFailedAt.Time.Before(now.Add(-window)) {
+			totp.FailedAttempts = 1
+		} else if totp.FailedAttempts < max {
+			totp.FailedAttempts++
+		} else {
+			return false
+		}
+		totp.LastFailedAt = sql.NullTime{Time: now, Valid: true}
+		return true
+	})
+	if err != nil {
+		return false, failure.New("unable to count totp attempt", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return claimed, nil
+}
+
+func (s *memStore) resetTOTPFailedAttempts(ctx context.Context, tx db.Tx, accountId string) error {
+	_, err := s.updateTOTP(tx, accountId, func(totp *db.AccountTotp) bool {
+		totp.FailedAttempts = 0
+		totp.LastFailedAt = sql.NullTime{}
+		return true
+	})
+	if err != nil {
+		return failure.New("unable to reset totp failed attempts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) useRecoveryCode(ctx context.Context, tx db.Tx, accountId, codeHash string) (bool, error) {
+	var used bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		for id, rc := range t.TotpRecoveryCodes {
+			if rc.AccountId == accountId && rc.CodeHash == codeHash && !rc.UsedAt.Valid {
+				rc.UsedAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+				t.TotpRecoveryCodes[id] = rc
+				used = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, failure.New("unable to use recovery code", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return used, nil
+}
+
+func (s *memStore) insertOidcAccount(ctx context.Context, tx db.Tx, model OidcAccountModel) (AccountModel, error) {
+	return s.insertAccountRow(tx, db.Account{
+		Name:             model.Name,
+		Email:            model.Email,
+		Dob:              memdb.NullTime(model.Dob),
+		Gender:           memdb.NullString(model.Gender),
+		PhoneNumber:      memdb.NullString(model.PhoneNumber),
+		Password:         model.Password,
+		PasswordUnusable: true,
+	}, model.Approved, model.InviteId)
+}
+
+func (s *memStore) redeemInviteCode(ctx context.Context, tx db.Tx, codeHash string) (string, error) {
+	var inviteId string
+	now := s.db.Now()
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		for id, ic := range t.InviteCodes {
+			if ic.CodeHash != codeHash || ic.RevokedAt.Valid || (ic.ExpiresAt.Valid && !ic.ExpiresAt.Time.After(now)) || ic.Uses >= ic.MaxUses {
+				continue
+			}
+			ic.Uses++
+			t.InviteCodes[id] = ic
+			inviteId = id
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return "", failure.New("unable to redeem invite code", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if inviteId == "" {
+		return "", failure.New("invite code not found", failure.ErrNotFound)
+	}
+
+	return inviteId, nil
+}
+
+func (s *memStore) findAccountByIdentity(ctx context.Context, tx db.Tx, issuer, subject string) (*AccountModel, error) {
+	dest, err := s.findAccountWhere(tx, func(t *memdb.Tables) (db.Account, bool) {
+		for _, ai := range t.AccountIdentities {
+			if ai.Issuer == issuer && ai.Subject == subject {
+				a, ok := t.Accounts[ai.AccountId]
+				return a, ok
+			}
+		}
+		return db.Account{}, false
+	})
+	if err != nil {
+		return nil, failure.New("unable to retreive account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("account identity not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) insertAccountIdentity(ctx context.Context, tx db.Tx, accountId, issuer, subject, email string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Accounts[accountId]; !ok {
+			return memdb.ErrForeignKey
+		}
+		for _, ai := range t.AccountIdentities {
+			if ai.Issuer == issuer && ai.Subject == subject {
+				return memdb.ErrUnique
+			}
+		}
+		now := s.db.Now()
+		ai := db.AccountIdentity{
+			Id:          memdb.NewId(),
+			AccountId:   accountId,
+			Issuer:      issuer,
+			Subject:     subject,
+			Email:       sql.NullString{String: email, Valid: email != ""},
+			LastLoginAt: sql.NullTime{Time: now, Valid: true},
+			CreatedAt:   now,
+		}
+		t.AccountIdentities[ai.Id] = ai
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to insert account identity", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) updateAccountIdentityLogin(ctx context.Context, tx db.Tx, issuer, subject, email string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		for id, ai := range t.AccountIdentities {
+			if ai.Issuer != issuer || ai.Subject != subject {
+				continue
+			}
+			ai.LastLoginAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+			if email != "" {
+				ai.Email = sql.NullString{String: email, Valid: true}
+			}
+			t.AccountIdentities[id] = ai
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to update account identity", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) insertOidcAuthRequest(ctx context.Context, tx db.Tx, stateHash, nonce, codeVerifier string, expiresAt time.Time) error {
+	now := s.db.Now()
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		for hash, ar := range t.OidcAuthRequests {
+			if ar.ExpiresAt.Before(now) {
+				delete(t.OidcAuthRequests, hash)
+			}
+		}
+		if _, ok := t.OidcAuthRequests[stateHash]; ok {
+			return memdb.ErrUnique
+		}
+		t.OidcAuthRequests[stateHash] = db.OidcAuthRequest{
+			StateHash:    stateHash,
+			Nonce:        nonce,
+			CodeVerifier: codeVerifier,
+			ExpiresAt:    expiresAt,
+			CreatedAt:    now,
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to insert oidc auth request", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) consumeOidcAuthRequest(ctx context.Context, tx db.Tx, stateHash string) (*OidcAuthRequestModel, error) {
+	var dest *OidcAuthRequestModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		ar, ok := t.OidcAuthRequests[stateHash]
+		if !ok {
+			return nil
+		}
+		delete(t.OidcAuthRequests, stateHash)
+		dest = &OidcAuthRequestModel{Nonce: ar.Nonce, CodeVerifier: ar.CodeVerifier, ExpiresAt: ar.ExpiresAt}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to retreive oidc auth request", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("oidc auth request not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
@@ -11,16 +11,16 @@ import (
 )
 
 type AccountModel struct {
-	Id          string    `json:"id"`
-	Name        string    `json:"name"`
-	Email       string    `json:"email"`
-	Dob         time.Time `json:"dob"`
-	Gender      string    `json:"gender"`
-	PhoneNumber string    `json:"phone_number"`
-	Password    string    `json:"-"`
-	Role        string    `json:"role"`
-	PlayerId    *string   `json:"player_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	Id          string     `json:"id"`
+	Name        string     `json:"name"`
+	Email       string     `json:"email"`
+	Dob         *time.Time `json:"dob"`
+	Gender      *string    `json:"gender"`
+	PhoneNumber *string    `json:"phone_number"`
+	Password    string     `json:"-"`
+	Role        string     `json:"role"`
+	PlayerId    *string    `json:"player_id"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 func (am *AccountModel) ScanRow(row pgx.Row) error {
@@ -36,22 +36,24 @@ func (am *AccountModel) ScanRow(row pgx.Row) error {
 }
 
 type RefreshTokenModel struct {
-	Id          string     `json:"id"`
-	AccountId   string     `json:"account_id"`
-	AccountRole string     `json:"account_role"`
-	TokenHash   string     `json:"token_hash"`
-	DeviceId    *string    `json:"device_id"`
-	IpAddress   *string    `json:"ip_address"`
-	UserAgent   *string    `json:"user_agent"`
-	IssuedAt    time.Time  `json:"issued_at"`
-	ExpiresAt   time.Time  `json:"expires_at"`
-	LastUsedAt  *time.Time `json:"last_used_at"`
-	IsRevoked   bool       `json:"is_revoked"`
-	PlayerId    string     `json:"player_id"`
+	Id           string     `json:"id"`
+	AccountId    string     `json:"account_id"`
+	AccountRole  string     `json:"account_role"`
+	TokenHash    string     `json:"token_hash"`
+	DeviceId     *string    `json:"device_id"`
+	IpAddress    *string    `json:"ip_address"`
+	UserAgent    *string    `json:"user_agent"`
+	IssuedAt     time.Time  `json:"issued_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	IsRevoked    bool       `json:"is_revoked"`
+	PlayerId     string     `json:"player_id"`
+	Amr          []string   `json:"amr"`
+	TokenVersion int        `json:"-"`
 }
 
 func (rtm *RefreshTokenModel) ScanRow(row pgx.Row) error {
-	err := row.Scan(&rtm.Id, &rtm.AccountId, &rtm.AccountRole, &rtm.TokenHash, &rtm.DeviceId, &rtm.IpAddress, &rtm.UserAgent, &rtm.IssuedAt, &rtm.ExpiresAt, &rtm.LastUsedAt, &rtm.IsRevoked, &rtm.PlayerId)
+	err := row.Scan(&rtm.Id, &rtm.AccountId, &rtm.AccountRole, &rtm.TokenHash, &rtm.DeviceId, &rtm.IpAddress, &rtm.UserAgent, &rtm.IssuedAt, &rtm.ExpiresAt, &rtm.LastUsedAt, &rtm.IsRevoked, &rtm.PlayerId, &rtm.Amr, &rtm.TokenVersion)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return failure.New("scanning refresh token row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
@@ -69,6 +71,7 @@ type SignupRequestModel struct {
 	Gender      string `json:"gender"`
 	PhoneNumber string `json:"phone_number"`
 	Password    string `json:"password"`
+	InviteCode  string `json:"invite_code"` // required in the invite-only registration mode
 }
 
 func (m SignupRequestModel) Validate() []failure.InvalidField {
@@ -195,6 +198,146 @@ type TokensResponseModel struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// two-factor challenge response model
+// v1/tokens/access when the account has two-factor authentication enabled
+type TwoFactorChallengeResponseModel struct {
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// verify two-factor request body model
+type VerifyTwoFactorRequestModel struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (m VerifyTwoFactorRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.ChallengeToken == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "challenge_token",
+			Message:  "Challenge token field is required",
+			Location: "body",
+		})
+	}
+	if m.Code == "" && m.RecoveryCode == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "code",
+			Message:  "Code or recovery code field is required",
+			Location: "body",
+		})
+	} else if m.Code != "" && m.RecoveryCode != "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "code",
+			Message:  "Provide either the code or the recovery code",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+type TOTPModel struct {
+	AccountId      string
+	Secret         string
+	EnabledAt      *time.Time
+	LastUsedStep   *int64
+	FailedAttempts int
+	LastFailedAt   *time.Time
+}
+
+func (tm *TOTPModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&tm.AccountId, &tm.Secret, &tm.EnabledAt, &tm.LastUsedStep, &tm.FailedAttempts, &tm.LastFailedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning totp row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// oidc authorization response model. the client opens the authorization url
+// and forwards the code and state it gets back to the callback endpoint
+type OidcAuthorizationResponseModel struct {
+	AuthorizationUrl string    `json:"authorization_url"`
+	State            string    `json:"state"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// oidc callback request body model
+type OidcCallbackRequestModel struct {
+	Code       string `json:"code"`
+	State      string `json:"state"`
+	InviteCode string `json:"invite_code"` // only used when the login provisions a new account
+}
+
+func (m OidcCallbackRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Code == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "code",
+			Message:  "Code field is required",
+			Location: "body",
+		})
+	}
+	if m.State == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "state",
+			Message:  "State field is required",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+type OidcAuthRequestModel struct {
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+func (oarm *OidcAuthRequestModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&oarm.Nonce, &oarm.CodeVerifier, &oarm.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning oidc auth request row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// account data taken from the identity provider claims when provisioning a new account
+type OidcAccountModel struct {
+	Name        string
+	Email       string
+	Dob         *time.Time
+	Gender      *string
+	PhoneNumber *string
+	Password    string
+	Approved    bool
+	InviteId    *string
+}
+
+// RegistrationResponseModel tells the clients how new accounts are admitted
+type RegistrationResponseModel struct {
+	Mode               string `json:"mode" enums:"open,invite-only,approval-required"`
+	InviteCodeRequired bool   `json:"invite_code_required"`
+}
+
 // forgotten password request body model
 type ForgottenPasswordRequestModel struct {
 	Email string `json:"email"`
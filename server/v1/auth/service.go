@@ -4,36 +4,59 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
-
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/metrics"
+	"github.com/markovidakovic/gdsi/server/oidc"
 	"github.com/markovidakovic/gdsi/server/sec"
+	"github.com/markovidakovic/gdsi/server/tracing"
+)
+
+const (
+	// how long the challenge between the password and totp login steps stays valid
+	challengeExpiration = 5 * time.Minute
+	// failed totp verifications allowed before the account is locked out for a while
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+	// how long the user has to complete the login at the identity provider
+	oidcAuthRequestExpiration = 10 * time.Minute
 )
 
 type service struct {
-	cfg   *config.Config
-	store *store
+	cfg      *config.Config
+	store    Repository
+	provider *oidc.Provider
 }
 
-func newService(cfg *config.Config, store *store) *service {
+func newService(cfg *config.Config, store Repository, provider *oidc.Provider) *service {
 	var s = &service{
 		cfg,
 		store,
+		provider,
 	}
 	return s
 }
 
 func (s *service) processSignup(ctx context.Context, model SignupRequestModel) (string, string, error) {
+	ctx, span := tracing.Start(ctx, "auth.processSignup")
+	defer span.End()
+
 	var err error
 
+	if msg := s.cfg.PasswordPolicy.Check(model.Password, model.Email, model.Name); msg != "" {
+		return "", "", failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: "password", Message: msg, Location: "body"},
+		})
+	}
+
 	// hash the password
-	model.Password, err = sec.EncryptPwd(model.Password)
+	model.Password, err = s.cfg.Passwords.Hash(model.Password)
 	if err != nil {
 		return "", "", failure.New("signup failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -50,7 +73,7 @@ func (s *service) processSignup(ctx context.Context, model SignupRequestModel) (
 	}
 
 	// begin tx
-	tx, err := s.store.db.Begin(ctx)
+	tx, err := s.store.begin(ctx)
 	if err != nil {
 		return "", "", failure.New("signup failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -59,13 +82,18 @@ func (s *service) processSignup(ctx context.Context, model SignupRequestModel) (
 		if tx != nil {
 			err := tx.Rollback(ctx)
 			if err != nil && err != pgx.ErrTxClosed {
-				log.Printf("rolling back tx: %v", err)
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
 			}
 		}
 	}()
 
+	approved, inviteId, err := s.admitAccount(ctx, tx, model.InviteCode)
+	if err != nil {
+		return "", "", err
+	}
+
 	// insert account
-	account, err := s.store.insertAccount(ctx, tx, model)
+	account, err := s.store.insertAccount(ctx, tx, model, approved, inviteId)
 	if err != nil {
 		return "", "", failure.New("signup failed", err)
 	}
@@ -79,16 +107,16 @@ func (s *service) processSignup(ctx context.Context, model SignupRequestModel) (
 	account.PlayerId = &playerId
 
 	// generate jwts
-	accessTkn, refreshTkn, err := generateAuthTokens(s.cfg.JwtAuth, s.cfg.JwtAccessExpiration, s.cfg.JwtRefreshExpiration, account.Id, account.Role, *account.PlayerId)
+	accessTkn, refreshTkn, err := sec.GenerateAuthTokens(s.cfg.JwtKeys, s.cfg.JwtAccessExpiration, s.cfg.JwtRefreshExpiration, account.Id, account.Role, *account.PlayerId, 0, []string{sec.AmrPassword})
 	if err != nil {
 		return "", "", failure.New("signup failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
 	// hash the refresh token
-	hashedRfrTkn := sec.HashToken(refreshTkn.val)
+	hashedRfrTkn := sec.HashToken(refreshTkn.Value)
 
 	// insert refresh token
-	err = s.store.insertRefreshToken(ctx, tx, account.Id, hashedRfrTkn, refreshTkn.issAt, refreshTkn.expAt)
+	err = s.store.insertRefreshToken(ctx, tx, account.Id, hashedRfrTkn, refreshTkn.IssuedAt, refreshTkn.ExpiresAt, []string{sec.AmrPassword})
 	if err != nil {
 		return "", "", failure.New("signup failed", err)
 	}
@@ -98,10 +126,51 @@ func (s *service) processSignup(ctx context.Context, model SignupRequestModel) (
 		return "", "", failure.New("signup failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
-	return accessTkn.val, refreshTkn.val, nil
+	return accessTkn.Value, refreshTkn.Value, nil
 }
 
-func (s *service) processLogin(ctx context.Context, model LoginRequestModel) (string, string, error) {
+// admitAccount applies the registration mode to a new account. A valid invite code admits the
+// account right away in every mode, without one the account is refused in the invite-only mode
+// and waits for approval in the approval-required mode
+func (s *service) admitAccount(ctx context.Context, tx db.Tx, inviteCode string) (bool, *string, error) {
+	if inviteCode != "" {
+		inviteId, err := s.store.redeemInviteCode(ctx, tx, sec.HashToken(sec.NormalizeInviteCode(inviteCode)))
+		if err != nil {
+			if errors.Is(err, failure.ErrNotFound) {
+				return false, nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+					{Field: "invite_code", Message: "Invalid or expired invite code", Location: "body"},
+				})
+			}
+			return false, nil, failure.New("signup failed", err)
+		}
+		return true, &inviteId, nil
+	}
+
+	switch s.cfg.RegistrationMode {
+	case config.RegistrationInviteOnly:
+		return false, nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: "invite_code", Message: "Invite code field is required", Location: "body"},
+		})
+	case config.RegistrationApprovalRequired:
+		return false, nil, nil
+	}
+
+	return true, nil, nil
+}
+
+func (s *service) processGetRegistration() *RegistrationResponseModel {
+	return &RegistrationResponseModel{
+		Mode:               s.cfg.RegistrationMode,
+		InviteCodeRequired: s.cfg.RegistrationMode == config.RegistrationInviteOnly,
+	}
+}
+
+// processLogin verifies the account credentials. If the account has two-factor authentication
+// enabled, a challenge is returned instead of the tokens which must be completed with a totp code
+func (s *service) processLogin(ctx context.Context, model LoginRequestModel) (*TokensResponseModel, *TwoFactorChallengeResponseModel, error) {
+	ctx, span := tracing.Start(ctx, "auth.processLogin")
+	defer span.End()
+
 	account, err := s.store.findAccountByEmail(ctx, nil, model.Email)
 	if err != nil {
 		// special case here. the findAccountByEmail method returns failure.ErrNotFound or failure.ErrInternal
@@ -109,59 +178,224 @@ func (s *service) processLogin(ctx context.Context, model LoginRequestModel) (st
 		// rather, we want to return the failure.ErrBadRequest, so we disregard the previous error from the store method
 		// the drawback is that the error msg from the store method will not be logged - for now this is ok
 		if errors.Is(err, failure.ErrNotFound) {
-			return "", "", failure.New("invalid email or password", failure.ErrBadRequest)
+			metrics.LoginFailed()
+			return nil, nil, failure.New("invalid email or password", failure.ErrBadRequest)
 		}
-		return "", "", failure.New("login failed", err)
+		return nil, nil, failure.New("login failed", err)
 	}
 
 	// validate password
-	err = bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(model.Password))
+	ok, rehash, err := s.cfg.Passwords.Verify(account.Password, model.Password)
+	if err != nil {
+		return nil, nil, failure.New("login failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !ok {
+		metrics.LoginFailed()
+		return nil, nil, failure.New("invalid email or password", failure.ErrBadRequest)
+	}
+
+	// the password is only known right now, hashes of older algorithms or parameters are upgraded.
+	// a failed upgrade doesn't fail the login, it's retried on the next one
+	if rehash {
+		hashed, err := s.cfg.Passwords.Hash(model.Password)
+		if err == nil {
+			err = s.store.rehashPassword(ctx, nil, account.Id, account.Password, hashed)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to rehash the password", "account_id", account.Id, "error", err)
+		}
+	}
+
+	tokens, challenge, err := s.startSession(ctx, account, []string{sec.AmrPassword})
+	if err != nil {
+		return nil, nil, failure.New("login failed", err)
+	}
+
+	return tokens, challenge, nil
+}
+
+// startSession issues the tokens for an account which completed the first login step.
+// If the account has two-factor authentication enabled a challenge is returned instead
+func (s *service) startSession(ctx context.Context, account *AccountModel, amr []string) (*TokensResponseModel, *TwoFactorChallengeResponseModel, error) {
+	err := s.checkAccountActive(ctx, account.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	totp, err := s.store.findTOTP(ctx, nil, account.Id)
+	if err != nil && !errors.Is(err, failure.ErrNotFound) {
+		return nil, nil, err
+	}
+
+	if totp != nil && totp.EnabledAt != nil {
+		challenge, err := sec.GenerateChallengeToken(s.cfg.JwtKeys, account.Id, challengeExpiration, amr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w -> %v", failure.ErrInternal, err)
+		}
+		return nil, &TwoFactorChallengeResponseModel{
+			ChallengeToken: challenge.Value,
+			ExpiresAt:      challenge.ExpiresAt,
+		}, nil
+	}
+
+	tokens, err := s.issueTokens(ctx, account, amr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, nil, nil
+}
+
+// processVerifyTwoFactor completes the login of an account with two-factor authentication
+// enabled by verifying the totp code or a recovery code against the challenge
+func (s *service) processVerifyTwoFactor(ctx context.Context, model VerifyTwoFactorRequestModel) (*TokensResponseModel, error) {
+	ctx, span := tracing.Start(ctx, "auth.processVerifyTwoFactor")
+	defer span.End()
+
+	accountId, amr, err := sec.ParseChallengeToken(s.cfg.JwtKeys, model.ChallengeToken)
+	if err != nil {
+		return nil, failure.New("invalid challenge token", fmt.Errorf("%w -> %v", failure.ErrUnauthorized, err))
+	}
+
+	account, err := s.store.findAccountById(ctx, nil, accountId)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("invalid challenge token", failure.ErrUnauthorized)
+		}
+		return nil, failure.New("two-factor verification failed", err)
+	}
+
+	err = s.checkAccountActive(ctx, account.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := s.store.findTOTP(ctx, nil, account.Id)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("two-factor authentication not enabled", failure.ErrBadRequest)
+		}
+		return nil, failure.New("two-factor verification failed", err)
+	}
+	if totp.EnabledAt == nil {
+		return nil, failure.New("two-factor authentication not enabled", failure.ErrBadRequest)
+	}
+
+	// the attempt is counted up front, the parallel guesses can't all pass the limit
+	claimed, err := s.store.claimTOTPAttempt(ctx, nil, account.Id, maxTwoFactorAttempts, twoFactorLockout)
+	if err != nil {
+		return nil, failure.New("two-factor verification failed", err)
+	}
+	if !claimed {
+		return nil, failure.New("too many failed two-factor attempts, try again later", failure.ErrUnauthorized)
+	}
+
+	var verified bool
+	if model.Code != "" {
+		step, ok := sec.ValidateTOTP(totp.Secret, model.Code, time.Now())
+		if ok {
+			// a code can only be used once
+			verified, err = s.store.useTOTPStep(ctx, nil, account.Id, step)
+			if err != nil {
+				return nil, failure.New("two-factor verification failed", err)
+			}
+		}
+	} else {
+		verified, err = s.store.useRecoveryCode(ctx, nil, account.Id, sec.HashToken(sec.NormalizeRecoveryCode(model.RecoveryCode)))
+		if err != nil {
+			return nil, failure.New("two-factor verification failed", err)
+		}
+		if verified {
+			err = s.store.resetTOTPFailedAttempts(ctx, nil, account.Id)
+			if err != nil {
+				return nil, failure.New("two-factor verification failed", err)
+			}
+		}
+	}
+
+	if !verified {
+		metrics.LoginFailed()
+		return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: "code", Message: "Invalid two-factor code", Location: "body"},
+		})
+	}
+
+	tokens, err := s.issueTokens(ctx, account, append(amr, sec.AmrOTP))
+	if err != nil {
+		return nil, failure.New("two-factor verification failed", err)
+	}
+
+	return tokens, nil
+}
+
+// checkAccountActive rejects the accounts deactivated by an admin
+func (s *service) checkAccountActive(ctx context.Context, accountId string) error {
+	deactivated, err := s.store.isAccountDeactivated(ctx, nil, accountId)
+	if err != nil {
+		return err
+	}
+	if deactivated {
+		return failure.New("account is deactivated", failure.ErrForbidden)
+	}
+	return nil
+}
+
+// issueTokens generates a new token pair for the account and replaces the previously issued refresh tokens
+func (s *service) issueTokens(ctx context.Context, account *AccountModel, amr []string) (*TokensResponseModel, error) {
+	tokenVersion, err := s.store.findTokenVersion(ctx, nil, account.Id)
 	if err != nil {
-		// todo: maybe refactor this err msg later
-		return "", "", failure.New("invalid email or password", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err))
+		return nil, err
 	}
 
-	accessTkn, refreshTkn, err := generateAuthTokens(s.cfg.JwtAuth, s.cfg.JwtAccessExpiration, s.cfg.JwtRefreshExpiration, account.Id, account.Role, *account.PlayerId)
+	accessTkn, refreshTkn, err := sec.GenerateAuthTokens(s.cfg.JwtKeys, s.cfg.JwtAccessExpiration, s.cfg.JwtRefreshExpiration, account.Id, account.Role, *account.PlayerId, tokenVersion, amr)
 	if err != nil {
-		return "", "", failure.New("login failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		return nil, fmt.Errorf("%w -> %v", failure.ErrInternal, err)
 	}
 
-	tx, err := s.store.db.Begin(ctx)
+	tx, err := s.store.begin(ctx)
 	if err != nil {
-		return "", "", failure.New("login failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		return nil, fmt.Errorf("%w -> %v", failure.ErrInternal, err)
 	}
 
 	defer func() {
 		if tx != nil {
 			err := tx.Rollback(ctx)
 			if err != nil && err != pgx.ErrTxClosed {
-				log.Printf("rolling back tx: %v", err)
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
 			}
 		}
 	}()
 
 	err = s.store.revokeAccountRefreshTokens(ctx, tx, account.Id)
 	if err != nil {
-		return "", "", failure.New("login failed", err)
+		return nil, err
 	}
 
-	err = s.store.insertRefreshToken(ctx, tx, account.Id, sec.HashToken(refreshTkn.val), refreshTkn.issAt, refreshTkn.expAt)
+	err = s.store.insertRefreshToken(ctx, tx, account.Id, sec.HashToken(refreshTkn.Value), refreshTkn.IssuedAt, refreshTkn.ExpiresAt, amr)
 	if err != nil {
-		return "", "", failure.New("login failed", err)
+		return nil, err
 	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
-		return "", "", failure.New("login failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		return nil, fmt.Errorf("%w -> %v", failure.ErrInternal, err)
 	}
 
-	return accessTkn.val, refreshTkn.val, nil
+	metrics.LoginSucceeded()
+
+	return &TokensResponseModel{
+		AccessToken:  accessTkn.Value,
+		RefreshToken: refreshTkn.Value,
+	}, nil
 }
 
 func (s *service) processRefreshTokens(ctx context.Context, model RefreshTokenRequestModel) (string, string, error) {
+	ctx, span := tracing.Start(ctx, "auth.processRefreshTokens")
+	defer span.End()
+
 	rtHash := sec.HashToken(model.RefreshToken)
 
-	tx, err := s.store.db.Begin(ctx)
+	tx, err := s.store.begin(ctx)
 	if err != nil {
 		return "", "", failure.New("refresh tokens failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -169,8 +403,8 @@ func (s *service) processRefreshTokens(ctx context.Context, model RefreshTokenRe
 	defer func() {
 		if tx != nil {
 			err := tx.Rollback(ctx)
-			if err != nil && err == pgx.ErrTxClosed {
-				log.Printf("rollbacking tx: %v", err)
+			if err != nil && err != pgx.ErrTxClosed {
+				slog.ErrorContext(ctx, "rollbacking tx", "error", err)
 			}
 		}
 	}()
@@ -194,6 +428,11 @@ func (s *service) processRefreshTokens(ctx context.Context, model RefreshTokenRe
 		return "", "", failure.New("refresh token expired", failure.ErrUnauthorized)
 	}
 
+	err = s.checkAccountActive(ctx, rt.AccountId)
+	if err != nil {
+		return "", "", err
+	}
+
 	// update prev RT - it just updates the lat column
 	err = s.store.updateRefreshToken(ctx, tx, rt.Id)
 	if err != nil {
@@ -205,12 +444,12 @@ func (s *service) processRefreshTokens(ctx context.Context, model RefreshTokenRe
 		return "", "", err
 	}
 
-	accessTkn, refreshTkn, err := generateAuthTokens(s.cfg.JwtAuth, s.cfg.JwtAccessExpiration, s.cfg.JwtRefreshExpiration, rt.AccountId, rt.AccountRole, rt.PlayerId)
+	accessTkn, refreshTkn, err := sec.GenerateAuthTokens(s.cfg.JwtKeys, s.cfg.JwtAccessExpiration, s.cfg.JwtRefreshExpiration, rt.AccountId, rt.AccountRole, rt.PlayerId, rt.TokenVersion, rt.Amr)
 	if err != nil {
 		return "", "", failure.New("refresh tokens failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
-	err = s.store.insertRefreshToken(ctx, tx, rt.AccountId, sec.HashToken(refreshTkn.val), refreshTkn.issAt, refreshTkn.expAt)
+	err = s.store.insertRefreshToken(ctx, tx, rt.AccountId, sec.HashToken(refreshTkn.Value), refreshTkn.IssuedAt, refreshTkn.ExpiresAt, rt.Amr)
 	if err != nil {
 		return "", "", failure.New("refresh tokens failed", err)
 	}
@@ -220,70 +459,188 @@ func (s *service) processRefreshTokens(ctx context.Context, model RefreshTokenRe
 		return "", "", failure.New("refresh tokens failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
-	return accessTkn.val, refreshTkn.val, nil
+	return accessTkn.Value, refreshTkn.Value, nil
 }
 
-type token struct {
-	issAt time.Time
-	expAt time.Time
-	val   string
+// processOidcAuthorize starts an oidc login. The state, nonce and pkce verifier are stored
+// until the client returns with the authorization code
+func (s *service) processOidcAuthorize(ctx context.Context) (*OidcAuthorizationResponseModel, error) {
+	ctx, span := tracing.Start(ctx, "auth.processOidcAuthorize")
+	defer span.End()
+
+	if s.provider == nil {
+		return nil, failure.New("oidc login is not enabled", failure.ErrNotFound)
+	}
+
+	ar, err := s.provider.AuthCodeURL(ctx)
+	if err != nil {
+		return nil, failure.New("unable to start oidc login", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	expiresAt := time.Now().Add(oidcAuthRequestExpiration)
+
+	err = s.store.insertOidcAuthRequest(ctx, nil, sec.HashToken(ar.State), ar.Nonce, ar.CodeVerifier, expiresAt)
+	if err != nil {
+		return nil, failure.New("unable to start oidc login", err)
+	}
+
+	return &OidcAuthorizationResponseModel{
+		AuthorizationUrl: ar.URL,
+		State:            ar.State,
+		ExpiresAt:        expiresAt,
+	}, nil
 }
 
-func generateAuthTokens(ja *jwtauth.JWTAuth, jwtAccessExp, jwtRefreshExp, accountId, role, playerId string) (accessTkn, refreshTkn token, err error) {
-	// parse config vars
-	durAccess, err := time.ParseDuration(jwtAccessExp)
+// processOidcCallback redeems the authorization code and logs in the account linked to the
+// identity. Unknown identities are linked to the account with the same verified email, or
+// a new account and player get provisioned
+func (s *service) processOidcCallback(ctx context.Context, model OidcCallbackRequestModel) (*TokensResponseModel, *TwoFactorChallengeResponseModel, error) {
+	ctx, span := tracing.Start(ctx, "auth.processOidcCallback")
+	defer span.End()
+
+	if s.provider == nil {
+		return nil, nil, failure.New("oidc login is not enabled", failure.ErrNotFound)
+	}
+
+	ar, err := s.store.consumeOidcAuthRequest(ctx, nil, sec.HashToken(model.State))
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, nil, failure.New("invalid oidc state", failure.ErrUnauthorized)
+		}
+		return nil, nil, failure.New("oidc login failed", err)
+	}
+	if time.Now().After(ar.ExpiresAt) {
+		return nil, nil, failure.New("oidc login expired", failure.ErrUnauthorized)
+	}
+
+	claims, err := s.provider.Exchange(ctx, model.Code, ar.CodeVerifier, ar.Nonce)
+	if err != nil {
+		return nil, nil, failure.New("oidc login failed", fmt.Errorf("%w -> %v", failure.ErrUnauthorized, err))
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, nil, failure.New("oidc login failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "rolling back tx", "error", err)
+		}
+	}()
+
+	account, err := s.store.findAccountByIdentity(ctx, tx, claims.Issuer, claims.Subject)
+	if err != nil && !errors.Is(err, failure.ErrNotFound) {
+		return nil, nil, failure.New("oidc login failed", err)
+	}
+
+	if account != nil {
+		err = s.store.updateAccountIdentityLogin(ctx, tx, claims.Issuer, claims.Subject, claims.Email)
+		if err != nil {
+			return nil, nil, failure.New("oidc login failed", err)
+		}
+	} else {
+		account, err = s.linkOidcIdentity(ctx, tx, claims, model.InviteCode)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
-		return
+		return nil, nil, failure.New("oidc login failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
-	durRefresh, err := time.ParseDuration(jwtRefreshExp)
+
+	tokens, challenge, err := s.startSession(ctx, account, []string{sec.AmrFederated})
 	if err != nil {
-		return
+		return nil, nil, failure.New("oidc login failed", err)
 	}
 
-	var iss string = "gdsi api"
-	var aud string = "gdsi app"
+	return tokens, challenge, nil
+}
 
-	now := time.Now()
+// linkOidcIdentity links the identity to an existing account with the same email, or provisions
+// a new account and player. Existing accounts are only linked if the provider verified the email
+func (s *service) linkOidcIdentity(ctx context.Context, tx db.Tx, claims *oidc.Claims, inviteCode string) (*AccountModel, error) {
+	if claims.Email == "" {
+		return nil, failure.New("the identity provider did not return an email", failure.ErrBadRequest)
+	}
 
-	// expiration dates for tokens
-	var expAccess time.Time = now.Add(durAccess)
-	var expRefresh time.Time = now.Add(durRefresh)
+	account, err := s.store.findAccountByEmail(ctx, tx, claims.Email)
+	if err != nil && !errors.Is(err, failure.ErrNotFound) {
+		return nil, failure.New("oidc login failed", err)
+	}
 
-	// jwt claims
-	var claims = map[string]interface{}{
-		"iss":       iss,
-		"sub":       accountId,
-		"aud":       aud,
-		"exp":       expAccess.Unix(),
-		"nbf":       now.Unix(),
-		"iat":       now.Unix(),
-		"role":      role,
-		"player_id": playerId,
+	if account != nil {
+		if !claims.EmailVerified {
+			return nil, failure.New("email already registered, verify it at the identity provider to link the account", failure.ErrDuplicate)
+		}
+	} else {
+		account, err = s.provisionOidcAccount(ctx, tx, claims, inviteCode)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	_, accessTknEnc, err := ja.Encode(claims)
+	err = s.store.insertAccountIdentity(ctx, tx, account.Id, claims.Issuer, claims.Subject, claims.Email)
 	if err != nil {
-		return
+		return nil, failure.New("oidc login failed", err)
 	}
 
-	// change the exp value for refresh token
-	claims["exp"] = expRefresh.Unix()
+	return account, nil
+}
+
+// provisionOidcAccount creates the account of a new identity, the registration mode applies the same as to the signup
+func (s *service) provisionOidcAccount(ctx context.Context, tx db.Tx, claims *oidc.Claims, inviteCode string) (*AccountModel, error) {
+	approved, inviteId, err := s.admitAccount(ctx, tx, inviteCode)
+	if err != nil {
+		return nil, err
+	}
 
-	_, refreshTknEnc, err := ja.Encode(claims)
+	// the account can't login with a password until one is set
+	unusable, err := sec.RandomToken(32)
 	if err != nil {
-		return
+		return nil, failure.New("oidc login failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	password, err := s.cfg.Passwords.Hash(unusable)
+	if err != nil {
+		return nil, failure.New("oidc login failed", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	model := OidcAccountModel{
+		Name:     strings.TrimSpace(claims.Name),
+		Email:    claims.Email,
+		Password: password,
+		Approved: approved,
+		InviteId: inviteId,
+	}
+	if model.Name == "" {
+		model.Name, _, _ = strings.Cut(claims.Email, "@")
 	}
 
-	accessTkn = token{
-		issAt: now,
-		expAt: expAccess,
-		val:   accessTknEnc,
+	// optional profile claims are only taken over if they are valid for gdsi
+	if dob, err := time.Parse("2006-01-02", claims.Birthdate); err == nil {
+		model.Dob = &dob
 	}
-	refreshTkn = token{
-		issAt: now,
-		expAt: expRefresh,
-		val:   refreshTknEnc,
+	if claims.Gender == "male" || claims.Gender == "female" {
+		model.Gender = &claims.Gender
 	}
+	if claims.PhoneNumber != "" && sec.IsValidPhone(claims.PhoneNumber) {
+		model.PhoneNumber = &claims.PhoneNumber
+	}
+
+	account, err := s.store.insertOidcAccount(ctx, tx, model)
+	if err != nil {
+		return nil, failure.New("oidc login failed", err)
+	}
+
+	playerId, err := s.store.insertPlayer(ctx, tx, account.Id)
+	if err != nil {
+		return nil, failure.New("oidc login failed", err)
+	}
+
+	account.PlayerId = &playerId
 
-	return
+	return &account, nil
 }
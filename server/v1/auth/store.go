@@ -11,21 +11,57 @@ import (
 	"github.com/markovidakovic/gdsi/server/failure"
 )
 
+// Repository holds the accounts as they sign up and sign in: their refresh tokens, second
+// factors, oidc identities and the invite codes they redeem
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	insertAccount(ctx context.Context, tx db.Tx, model SignupRequestModel, approved bool, inviteId *string) (AccountModel, error)
+	rehashPassword(ctx context.Context, tx db.Tx, accountId, oldHash, newHash string) error
+	insertPlayer(ctx context.Context, tx db.Tx, accountId string) (string, error)
+	findAccountByEmail(ctx context.Context, tx db.Tx, email string) (*AccountModel, error)
+	findAccountById(ctx context.Context, tx db.Tx, accountId string) (*AccountModel, error)
+	findTokenVersion(ctx context.Context, tx db.Tx, accountId string) (int, error)
+	isAccountDeactivated(ctx context.Context, tx db.Tx, accountId string) (bool, error)
+	insertRefreshToken(ctx context.Context, tx db.Tx, accountId string, token string, issuedAt, expiresAt time.Time, amr []string) error
+	revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error
+	deleteExpiredRefreshTokens(ctx context.Context) error
+	findRefreshTokenByHash(ctx context.Context, tx db.Tx, rt string) (*RefreshTokenModel, error)
+	updateRefreshToken(ctx context.Context, tx db.Tx, rtId string) error
+	revokeRefreshToken(ctx context.Context, tx db.Tx, rtId string) error
+	findTOTP(ctx context.Context, tx db.Tx, accountId string) (*TOTPModel, error)
+	useTOTPStep(ctx context.Context, tx db.Tx, accountId string, step int64) (bool, error)
+	claimTOTPAttempt(ctx context.Context, tx db.Tx, accountId string, max int, window time.Duration) (bool, error)
+	resetTOTPFailedAttempts(ctx context.Context, tx db.Tx, accountId string) error
+	useRecoveryCode(ctx context.Context, tx db.Tx, accountId, codeHash string) (bool, error)
+	insertOidcAccount(ctx context.Context, tx db.Tx, model OidcAccountModel) (AccountModel, error)
+	redeemInviteCode(ctx context.Context, tx db.Tx, codeHash string) (string, error)
+	findAccountByIdentity(ctx context.Context, tx db.Tx, issuer, subject string) (*AccountModel, error)
+	insertAccountIdentity(ctx context.Context, tx db.Tx, accountId, issuer, subject, email string) error
+	updateAccountIdentityLogin(ctx context.Context, tx db.Tx, issuer, subject, email string) error
+	insertOidcAuthRequest(ctx context.Context, tx db.Tx, stateHash, nonce, codeVerifier string, expiresAt time.Time) error
+	consumeOidcAuthRequest(ctx context.Context, tx db.Tx, stateHash string) (*OidcAuthRequestModel, error)
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
-	var r = &store{
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
 		db,
 	}
-	return r
 }
 
-func (s *store) insertAccount(ctx context.Context, tx pgx.Tx, model SignupRequestModel) (AccountModel, error) {
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func (s *store) insertAccount(ctx context.Context, tx db.Tx, model SignupRequestModel, approved bool, inviteId *string) (AccountModel, error) {
 	sql := `
-		insert into account (name, email, dob, gender, phone_number, password)
-		values ($1, $2, $3, $4, $5, $6)
+		insert into account (name, email, dob, gender, phone_number, password, approved_at, invite_code_id)
+		values ($1, $2, $3, $4, $5, $6, case when $7 then current_timestamp end, $8)
 		returning id, name, email, dob, gender, phone_number, password, role, NULL as player_id, created_at
 	`
 
@@ -37,7 +73,7 @@ func (s *store) insertAccount(ctx context.Context, tx pgx.Tx, model SignupReques
 	}
 
 	var dest AccountModel
-	row := q.QueryRow(ctx, sql, model.Name, model.Email, model.Dob, model.Gender, model.PhoneNumber, model.Password)
+	row := q.QueryRow(ctx, sql, model.Name, model.Email, model.Dob, model.Gender, model.PhoneNumber, model.Password, approved, inviteId)
 	err := dest.ScanRow(row)
 	if err != nil {
 		return dest, failure.New("failed to insert account", err)
@@ -46,8 +82,32 @@ func (s *store) insertAccount(ctx context.Context, tx pgx.Tx, model SignupReques
 	return dest, nil
 }
 
+// rehashPassword replaces the password hash with the same password hashed by the current algorithm.
+// The hash is only replaced if the password didn't change in the meantime
+func (s *store) rehashPassword(ctx context.Context, tx db.Tx, accountId, oldHash, newHash string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set password = $1
+		where id = $2 and password = $3
+	`
+
+	_, err := q.Exec(ctx, sql, newHash, accountId, oldHash)
+	if err != nil {
+		return failure.New("unable to rehash password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
 // todo: instead of returning string return the full model here
-func (s *store) insertPlayer(ctx context.Context, tx pgx.Tx, accountId string) (string, error) {
+func (s *store) insertPlayer(ctx context.Context, tx db.Tx, accountId string) (string, error) {
 	sql := `
 		insert into player (account_id)
 		values ($1)
@@ -71,7 +131,7 @@ func (s *store) insertPlayer(ctx context.Context, tx pgx.Tx, accountId string) (
 	return playerId, nil
 }
 
-func (s *store) findAccountByEmail(ctx context.Context, tx pgx.Tx, email string) (*AccountModel, error) {
+func (s *store) findAccountByEmail(ctx context.Context, tx db.Tx, email string) (*AccountModel, error) {
 	var dest AccountModel
 
 	sql := `
@@ -110,10 +170,94 @@ func (s *store) findAccountByEmail(ctx context.Context, tx pgx.Tx, email string)
 	return &dest, nil
 }
 
-func (s *store) insertRefreshToken(ctx context.Context, tx pgx.Tx, accountId string, token string, issuedAt, expiresAt time.Time) error {
+func (s *store) findAccountById(ctx context.Context, tx db.Tx, accountId string) (*AccountModel, error) {
+	var dest AccountModel
+
 	sql := `
-		insert into refresh_token (account_id, token_hash, issued_at, expires_at)
-		values ($1, $2, $3, $4)
+		select 
+			account.id as account_id, 
+			account.name as account_name, 
+			account.email as account_email, 
+			account.dob as account_dob, 
+			account.gender as account_gender, 
+			account.phone_number as account_phone_number, 
+			account.password as account_password, 
+			account.role as account_role, 
+			player.id as player_id, 
+			account.created_at as account_created_at
+		from account
+		left join player on player.account_id = account.id
+		where account.id = $1
+	`
+
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	row := q.QueryRow(ctx, sql, accountId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("account not found", err)
+		}
+		return nil, failure.New("unable to retreive account", err)
+	}
+
+	return &dest, nil
+}
+
+// findTokenVersion returns the version the new access tokens of the account have to carry
+func (s *store) findTokenVersion(ctx context.Context, tx db.Tx, accountId string) (int, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	var version int
+
+	sql := `select token_version from account where id = $1`
+
+	err := q.QueryRow(ctx, sql, accountId).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, failure.New("account not found", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return 0, failure.New("unable to find account token version", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return version, nil
+}
+
+// isAccountDeactivated reports if an admin deactivated the account
+func (s *store) isAccountDeactivated(ctx context.Context, tx db.Tx, accountId string) (bool, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	var deactivated bool
+
+	sql := `select exists (select 1 from account where id = $1 and deactivated_at is not null)`
+
+	err := q.QueryRow(ctx, sql, accountId).Scan(&deactivated)
+	if err != nil {
+		return false, failure.New("unable to check account status", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return deactivated, nil
+}
+
+func (s *store) insertRefreshToken(ctx context.Context, tx db.Tx, accountId string, token string, issuedAt, expiresAt time.Time, amr []string) error {
+	sql := `
+		insert into refresh_token (account_id, token_hash, issued_at, expires_at, amr)
+		values ($1, $2, $3, $4, $5)
 		returning id, account_id, token_hash, device_id, ip_address, user_agent, issued_at, expires_at, last_used_at, is_revoked
 	`
 
@@ -124,7 +268,7 @@ func (s *store) insertRefreshToken(ctx context.Context, tx pgx.Tx, accountId str
 		q = s.db
 	}
 
-	_, err := q.Exec(ctx, sql, accountId, token, issuedAt, expiresAt)
+	_, err := q.Exec(ctx, sql, accountId, token, issuedAt, expiresAt, amr)
 	if err != nil {
 		return failure.New("failed to insert refresh token", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -132,7 +276,7 @@ func (s *store) insertRefreshToken(ctx context.Context, tx pgx.Tx, accountId str
 	return nil
 }
 
-func (s *store) revokeAccountRefreshTokens(ctx context.Context, tx pgx.Tx, accountId string) error {
+func (s *store) revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error {
 	sql := `
 		update refresh_token
 		set is_revoked = true
@@ -154,7 +298,20 @@ func (s *store) revokeAccountRefreshTokens(ctx context.Context, tx pgx.Tx, accou
 	return nil
 }
 
-func (s *store) findRefreshTokenByHash(ctx context.Context, tx pgx.Tx, rt string) (*RefreshTokenModel, error) {
+// deleteExpiredRefreshTokens drops the refresh tokens which can't be used anymore, the revoked
+// ones are kept until they expire so a reused token is still recognized
+func (s *store) deleteExpiredRefreshTokens(ctx context.Context) error {
+	sql := `delete from refresh_token where expires_at < current_timestamp`
+
+	_, err := s.db.Exec(ctx, sql)
+	if err != nil {
+		return failure.New("failed to delete expired refresh tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) findRefreshTokenByHash(ctx context.Context, tx db.Tx, rt string) (*RefreshTokenModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -175,7 +332,9 @@ func (s *store) findRefreshTokenByHash(ctx context.Context, tx pgx.Tx, rt string
 			refresh_token.expires_at,
 			refresh_token.last_used_at,
 			refresh_token.is_revoked,
-			player.id as player_id
+			player.id as player_id,
+			refresh_token.amr,
+			account.token_version as account_token_version
 		from refresh_token
 		join account on refresh_token.account_id = account.id
 		join player on account.id = player.account_id
@@ -197,7 +356,7 @@ func (s *store) findRefreshTokenByHash(ctx context.Context, tx pgx.Tx, rt string
 }
 
 // todo: refactor this to return a full refresh token model and not just the error
-func (s *store) updateRefreshToken(ctx context.Context, tx pgx.Tx, rtId string) error {
+func (s *store) updateRefreshToken(ctx context.Context, tx db.Tx, rtId string) error {
 	sql := `
 		update refresh_token
 		set last_used_at = $1
@@ -225,7 +384,7 @@ func (s *store) updateRefreshToken(ctx context.Context, tx pgx.Tx, rtId string)
 	return nil
 }
 
-func (s *store) revokeRefreshToken(ctx context.Context, tx pgx.Tx, rtId string) error {
+func (s *store) revokeRefreshToken(ctx context.Context, tx db.Tx, rtId string) error {
 	sql := `
 		update refresh_token
 		set is_revoked = true
@@ -249,3 +408,324 @@ func (s *store) revokeRefreshToken(ctx context.Context, tx pgx.Tx, rtId string)
 
 	return nil
 }
+
+func (s *store) findTOTP(ctx context.Context, tx db.Tx, accountId string) (*TOTPModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		select account_id, secret, enabled_at, last_used_step, failed_attempts, last_failed_at
+		from account_totp
+		where account_id = $1
+	`
+
+	var dest TOTPModel
+
+	row := q.QueryRow(ctx, sql, accountId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("totp not found", err)
+		}
+		return nil, failure.New("unable to retreive totp", err)
+	}
+
+	return &dest, nil
+}
+
+// useTOTPStep marks the time step as used. It returns false if the same or a later
+// step was already used, which means the code is being replayed
+func (s *store) useTOTPStep(ctx context.Context, tx db.Tx, accountId string, step int64) (bool, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account_totp
+		set last_used_step = $1, failed_attempts = 0, last_failed_at = null
+		where account_id = $2 and (last_used_step is null or last_used_step < $1)
+	`
+
+	ct, err := q.Exec(ctx, sql, step, accountId)
+	if err != nil {
+		return false, failure.New("unable to update totp last used step", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return ct.RowsAffected() > 0, nil
+}
+
+// claimTOTPAttempt counts the verification as failed before the code is checked, a verified
+// code resets the counter. The counter starts over if the last failure happened outside of the
+// lockout window. It returns false while the account is locked out, the check and the increment
+// are one statement so the parallel attempts can't pass the limit
+func (s *store) claimTOTPAttempt(ctx context.Context, tx db.Tx, accountId string, max int, window time.Duration) (bool, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account_totp
+		set
+			failed_attempts = case when last_failed_at is null or last_failed_at < $1 then 1 else failed_attempts + 1 end,
+			last_failed_at = current_timestamp
+		where account_id = $2 and (last_failed_at is null or last_failed_at < $1 or failed_attempts < $3)
+	`
+
+	ct, err := q.Exec(ctx, sql, time.Now().Add(-window), accountId, max)
+	if err != nil {
+		return false, failure.New("unable to count totp attempt", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return ct.RowsAffected() > 0, nil
+}
+
+func (s *store) resetTOTPFailedAttempts(ctx context.Context, tx db.Tx, accountId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account_totp
+		set failed_attempts = 0, last_failed_at = null
+		where account_id = $1
+	`
+
+	_, err := q.Exec(ctx, sql, accountId)
+	if err != nil {
+		return failure.New("unable to reset totp failed attempts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// useRecoveryCode marks an unused recovery code as used and reports if it was found
+func (s *store) useRecoveryCode(ctx context.Context, tx db.Tx, accountId, codeHash string) (bool, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update totp_recovery_code
+		set used_at = current_timestamp
+		where account_id = $1 and code_hash = $2 and used_at is null
+	`
+
+	ct, err := q.Exec(ctx, sql, accountId, codeHash)
+	if err != nil {
+		return false, failure.New("unable to use recovery code", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return ct.RowsAffected() > 0, nil
+}
+
+func (s *store) insertOidcAccount(ctx context.Context, tx db.Tx, model OidcAccountModel) (AccountModel, error) {
+	sql := `
+		insert into account (name, email, dob, gender, phone_number, password, password_unusable, approved_at, invite_code_id)
+		values ($1, $2, $3, $4, $5, $6, true, case when $7 then current_timestamp end, $8)
+		returning id, name, email, dob, gender, phone_number, password, role, NULL as player_id, created_at
+	`
+
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	var dest AccountModel
+	row := q.QueryRow(ctx, sql, model.Name, model.Email, model.Dob, model.Gender, model.PhoneNumber, model.Password, model.Approved, model.InviteId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		return dest, failure.New("failed to insert account", err)
+	}
+
+	return dest, nil
+}
+
+// redeemInviteCode uses up one use of a valid invite code and returns its id. Concurrent signups
+// can't redeem the last use twice, the update locks the row
+func (s *store) redeemInviteCode(ctx context.Context, tx db.Tx, codeHash string) (string, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update invite_code
+		set uses = uses + 1
+		where code_hash = $1
+			and revoked_at is null
+			and (expires_at is null or expires_at > current_timestamp)
+			and uses < max_uses
+		returning id
+	`
+
+	var inviteId string
+	err := q.QueryRow(ctx, sql, codeHash).Scan(&inviteId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", failure.New("invite code not found", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return "", failure.New("unable to redeem invite code", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return inviteId, nil
+}
+
+func (s *store) findAccountByIdentity(ctx context.Context, tx db.Tx, issuer, subject string) (*AccountModel, error) {
+	var dest AccountModel
+
+	sql := `
+		select 
+			account.id as account_id, 
+			account.name as account_name, 
+			account.email as account_email, 
+			account.dob as account_dob, 
+			account.gender as account_gender, 
+			account.phone_number as account_phone_number, 
+			account.password as account_password, 
+			account.role as account_role, 
+			player.id as player_id, 
+			account.created_at as account_created_at
+		from account_identity
+		join account on account.id = account_identity.account_id
+		left join player on player.account_id = account.id
+		where account_identity.issuer = $1 and account_identity.subject = $2
+	`
+
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	row := q.QueryRow(ctx, sql, issuer, subject)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("account identity not found", err)
+		}
+		return nil, failure.New("unable to retreive account", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) insertAccountIdentity(ctx context.Context, tx db.Tx, accountId, issuer, subject, email string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into account_identity (account_id, issuer, subject, email, last_login_at)
+		values ($1, $2, $3, nullif($4, ''), current_timestamp)
+	`
+
+	_, err := q.Exec(ctx, sql, accountId, issuer, subject, email)
+	if err != nil {
+		return failure.New("failed to insert account identity", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) updateAccountIdentityLogin(ctx context.Context, tx db.Tx, issuer, subject, email string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account_identity
+		set last_login_at = current_timestamp, email = coalesce(nullif($3, ''), email)
+		where issuer = $1 and subject = $2
+	`
+
+	_, err := q.Exec(ctx, sql, issuer, subject, email)
+	if err != nil {
+		return failure.New("failed to update account identity", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// insertOidcAuthRequest stores the state of a started oidc login. The expired
+// requests are cleaned up on the way
+func (s *store) insertOidcAuthRequest(ctx context.Context, tx db.Tx, stateHash, nonce, codeVerifier string, expiresAt time.Time) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	_, err := q.Exec(ctx, `delete from oidc_auth_request where expires_at < current_timestamp`)
+	if err != nil {
+		return failure.New("failed to delete expired oidc auth requests", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	sql := `
+		insert into oidc_auth_request (state_hash, nonce, code_verifier, expires_at)
+		values ($1, $2, $3, $4)
+	`
+
+	_, err = q.Exec(ctx, sql, stateHash, nonce, codeVerifier, expiresAt)
+	if err != nil {
+		return failure.New("failed to insert oidc auth request", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// consumeOidcAuthRequest deletes and returns the oidc auth request so a state can only be used once
+func (s *store) consumeOidcAuthRequest(ctx context.Context, tx db.Tx, stateHash string) (*OidcAuthRequestModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		delete from oidc_auth_request
+		where state_hash = $1
+		returning nonce, code_verifier, expires_at
+	`
+
+	var dest OidcAuthRequestModel
+
+	row := q.QueryRow(ctx, sql, stateHash)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("oidc auth request not found", err)
+		}
+		return nil, failure.New("unable to retreive oidc auth request", err)
+	}
+
+	return &dest, nil
+}
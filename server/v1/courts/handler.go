@@ -7,7 +7,6 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/middleware"
 	"github.com/markovidakovic/gdsi/server/pagination"
@@ -16,13 +15,13 @@ import (
 )
 
 type handler struct {
-	store   *store
+	store   Repository
 	service *service
 }
 
-func newHandler(cfg *config.Config, db *db.Conn) *handler {
+func newHandler(cfg *config.Config, repo Repository) *handler {
 	h := &handler{}
-	h.store = newStore(db)
+	h.store = repo
 	h.service = newService(cfg, h.store)
 	return h
 }
@@ -54,7 +53,7 @@ func (h *handler) createCourt(w http.ResponseWriter, r *http.Request) {
 
 	model.CreatorId = r.Context().Value(middleware.AccountIdCtxKey).(string)
 
-	result, err := h.store.insertCourt(r.Context(), nil, model.Name, model.CreatorId)
+	result, err := h.service.processCreateCourt(r.Context(), model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -69,7 +68,7 @@ func (h *handler) createCourt(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusCreated, result)
+	response.WriteTagged(w, http.StatusCreated, result.Version, result)
 }
 
 // @Summary Get
@@ -79,7 +78,9 @@ func (h *handler) createCourt(w http.ResponseWriter, r *http.Request) {
 // @Param page query int false "page"
 // @Param per_page query int false "per page"
 // @Param order_by query string false "order by"
+// @Param If-None-Match header string false "etag of the list the client has"
 // @Success 200 {array} courts.CourtModel "OK"
+// @Success 304 "Not modified"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 500 {object} failure.Failure "Internal server error"
@@ -105,7 +106,7 @@ func (h *handler) getCourts(w http.ResponseWriter, r *http.Request) {
 
 	result := pagination.NewPaginated(query.Page, query.PerPage, count, courts)
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteCached(w, r, result)
 }
 
 // @Summary Get by id
@@ -114,6 +115,7 @@ func (h *handler) getCourts(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param court_id path string true "court id"
 // @Success 200 {object} courts.CourtModel "OK"
+// @Header 200 {string} ETag "version of the court"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
@@ -135,7 +137,7 @@ func (h *handler) getCourt(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteTagged(w, http.StatusOK, result.Version, result)
 }
 
 // @Summary Update
@@ -144,11 +146,14 @@ func (h *handler) getCourt(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param court_id path string true "court id"
+// @Param If-Match header string false "etag of the court the update is based on"
 // @Param body body courts.UpdateCourtRequestModel true "Request body"
 // @Success 200 {object} courts.CourtModel "OK"
+// @Header 200 {string} ETag "version of the updated court"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/courts/{court_id} [put]
@@ -165,7 +170,7 @@ func (h *handler) updateCourt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.store.updateCourt(r.Context(), nil, chi.URLParam(r, "court_id"), model.Name)
+	result, err := h.service.processUpdateCourt(r.Context(), chi.URLParam(r, "court_id"), params.NewIfMatch(r.Header), model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -180,7 +185,7 @@ func (h *handler) updateCourt(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteTagged(w, http.StatusOK, result.Version, result)
 }
 
 // @Summary Delete
@@ -188,15 +193,17 @@ func (h *handler) updateCourt(w http.ResponseWriter, r *http.Request) {
 // @Tags courts
 // @Produce json
 // @Param court_id path string true "court id"
+// @Param If-Match header string false "etag of the court the deletion is based on"
 // @Success 204 "No content"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/courts/{court_id} [delete]
 func (h *handler) deleteCourt(w http.ResponseWriter, r *http.Request) {
-	err := h.store.deleteCourt(r.Context(), nil, chi.URLParam(r, "court_id"))
+	err := h.service.processDeleteCourt(r.Context(), chi.URLParam(r, "court_id"), params.NewIfMatch(r.Header))
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
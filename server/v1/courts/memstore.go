@@ -0,0 +1,153 @@
+package courts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortingFields = map[string]func(a, b CourtModel) int{
+	"name":       func(a, b CourtModel) int { return memdb.CompareText(a.Name, b.Name) },
+	"created_at": func(a, b CourtModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toCourtModel(t *memdb.Tables, c db.Court) CourtModel {
+	var cm CourtModel
+	cm.Id = c.Id
+	cm.Name = c.Name
+	cm.Creator.Id = c.CreatorId
+	cm.Creator.Name = t.Accounts[c.CreatorId].Name
+	cm.Version = c.Version
+	cm.CreatedAt = c.CreatedAt
+	return cm
+}
+
+func (s *memStore) insertCourt(ctx context.Context, tx db.Tx, name, creatorId string) (CourtModel, error) {
+	var dest CourtModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Accounts[creatorId]; !ok {
+			return fmt.Errorf("%w -> creator %s not found", failure.ErrInternal, creatorId)
+		}
+		c := db.Court{Id: memdb.NewId(), Name: name, CreatorId: creatorId, Version: 1, CreatedAt: s.db.Now()}
+		t.Courts[c.Id] = c
+		dest = toCourtModel(t, c)
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("failed to insert court", err)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findCourts(ctx context.Context, limit, offset int, orderBy *params.OrderBy) ([]CourtModel, error) {
+	dest := []CourtModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, c := range t.Courts {
+			dest = append(dest, toCourtModel(t, c))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find courts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, orderBy, memSortingFields, func(a, b CourtModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countCourts(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		count = len(t.Courts)
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count courts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findCourt(ctx context.Context, courtId string) (*CourtModel, error) {
+	var dest *CourtModel
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		if c, ok := t.Courts[courtId]; ok {
+			cm := toCourtModel(t, c)
+			dest = &cm
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("court not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) updateCourt(ctx context.Context, tx db.Tx, courtId string, version int, name string) (*CourtModel, error) {
+	var dest *CourtModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		c, ok := t.Courts[courtId]
+		if !ok || c.Version != version {
+			return nil
+		}
+		c.Name = name
+		c.Version++
+		t.Courts[courtId] = c
+		cm := toCourtModel(t, c)
+		dest = &cm
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to update court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("court was changed since it was read", failure.ErrStale)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) deleteCourt(ctx context.Context, tx db.Tx, courtId string, version int) error {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if c, ok := t.Courts[courtId]; !ok || c.Version != version {
+			return nil
+		}
+		var err error
+		found, err = t.DeleteCourt(courtId)
+		return err
+	})
+	if err != nil {
+		return failure.New("unable to delete court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("court was changed since it was read", failure.ErrStale)
+	}
+
+	return nil
+}
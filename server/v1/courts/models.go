@@ -16,11 +16,12 @@ type CourtModel struct {
 		Id   string `json:"id"`
 		Name string `json:"name"`
 	} `json:"creator"`
+	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 func (cm *CourtModel) ScanRow(row pgx.Row) error {
-	err := row.Scan(&cm.Id, &cm.Name, &cm.Creator.Id, &cm.Creator.Name, &cm.CreatedAt)
+	err := row.Scan(&cm.Id, &cm.Name, &cm.Creator.Id, &cm.Creator.Name, &cm.Version, &cm.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return failure.New("scanning court row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
@@ -31,7 +32,7 @@ func (cm *CourtModel) ScanRow(row pgx.Row) error {
 }
 
 func (cm *CourtModel) ScanRows(rows pgx.Rows) error {
-	err := rows.Scan(&cm.Id, &cm.Name, &cm.Creator.Id, &cm.Creator.Name, &cm.CreatedAt)
+	err := rows.Scan(&cm.Id, &cm.Name, &cm.Creator.Id, &cm.Creator.Name, &cm.Version, &cm.CreatedAt)
 	if err != nil {
 		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
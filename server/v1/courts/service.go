@@ -2,18 +2,23 @@ package courts
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
 	"github.com/markovidakovic/gdsi/server/config"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/tracing"
 )
 
 type service struct {
 	cfg   *config.Config
-	store *store
+	store Repository
 }
 
-func newService(cfg *config.Config, store *store) *service {
+func newService(cfg *config.Config, store Repository) *service {
 	return &service{
 		cfg,
 		store,
@@ -21,6 +26,9 @@ func newService(cfg *config.Config, store *store) *service {
 }
 
 func (s *service) processGetCourts(ctx context.Context, query *params.Query) ([]CourtModel, int, error) {
+	ctx, span := tracing.Start(ctx, "courts.processGetCourts")
+	defer span.End()
+
 	count, err := s.store.countCourts(ctx)
 	if err != nil {
 		return nil, 0, failure.New("unable to get courts", err)
@@ -35,3 +43,121 @@ func (s *service) processGetCourts(ctx context.Context, query *params.Query) ([]
 
 	return result, count, nil
 }
+
+func (s *service) processCreateCourt(ctx context.Context, model CreateCourtRequestModel) (*CourtModel, error) {
+	ctx, span := tracing.Start(ctx, "courts.processCreateCourt")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the create court tx", "error", err)
+		}
+	}()
+
+	cm, err := s.store.insertCourt(ctx, tx, model.Name, model.CreatorId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.Court, ResourceId: cm.Id, After: cm})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &cm, nil
+}
+
+func (s *service) processUpdateCourt(ctx context.Context, courtId string, ifMatch params.IfMatch, model UpdateCourtRequestModel) (*CourtModel, error) {
+	ctx, span := tracing.Start(ctx, "courts.processUpdateCourt")
+	defer span.End()
+
+	before, err := s.store.findCourt(ctx, courtId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ifMatch.Check("court", before.Version); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the update court tx", "error", err)
+		}
+	}()
+
+	cm, err := s.store.updateCourt(ctx, tx, courtId, before.Version, model.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUpdate, ResourceType: audit.Court, ResourceId: courtId, Before: before, After: cm})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return cm, nil
+}
+
+func (s *service) processDeleteCourt(ctx context.Context, courtId string, ifMatch params.IfMatch) error {
+	ctx, span := tracing.Start(ctx, "courts.processDeleteCourt")
+	defer span.End()
+
+	before, err := s.store.findCourt(ctx, courtId)
+	if err != nil {
+		return err
+	}
+	if err := ifMatch.Check("court", before.Version); err != nil {
+		return err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to delete court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the delete court tx", "error", err)
+		}
+	}()
+
+	err = s.store.deleteCourt(ctx, tx, courtId, before.Version)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionDelete, ResourceType: audit.Court, ResourceId: courtId, Before: before})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to delete court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
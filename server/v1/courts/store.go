@@ -11,22 +11,39 @@ import (
 	"github.com/markovidakovic/gdsi/server/params"
 )
 
+// Repository holds the courts the matches are played on
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	insertCourt(ctx context.Context, tx db.Tx, name, creatorId string) (CourtModel, error)
+	findCourts(ctx context.Context, limit, offset int, orderBy *params.OrderBy) ([]CourtModel, error)
+	countCourts(ctx context.Context) (int, error)
+	findCourt(ctx context.Context, courtId string) (*CourtModel, error)
+	updateCourt(ctx context.Context, tx db.Tx, courtId string, version int, name string) (*CourtModel, error)
+	deleteCourt(ctx context.Context, tx db.Tx, courtId string, version int) error
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
 	return &store{
 		db,
 	}
 }
 
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
 var sortingFields = map[string]string{
 	"name":       "court.name",
 	"created_at": "court.created_at",
 }
 
-func (s *store) insertCourt(ctx context.Context, tx pgx.Tx, name, creatorId string) (CourtModel, error) {
+func (s *store) insertCourt(ctx context.Context, tx db.Tx, name, creatorId string) (CourtModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -38,13 +55,14 @@ func (s *store) insertCourt(ctx context.Context, tx pgx.Tx, name, creatorId stri
 		with inserted_court as (
 			insert into court (name, creator_id)
 			values ($1, $2)
-			returning id, name, creator_id, created_at			
+			returning id, name, creator_id, version, created_at			
 		)
 		select 
 			ic.id as court_id, 
 			ic.name as court_name, 
 			account.id as creator_id, 
 			account.name as creator_name, 
+			ic.version as court_version,
 			ic.created_at as court_created_at
 		from inserted_court ic
 		join account on ic.creator_id = account.id
@@ -67,6 +85,7 @@ func (s *store) findCourts(ctx context.Context, limit, offset int, orderBy *para
 			court.name as court_name,
 			account.id as creator_id,
 			account.name as creator_name,
+			court.version as court_version,
 			court.created_at as court_created_at
 		from court
 		join account on court.creator_id = account.id
@@ -128,6 +147,7 @@ func (s *store) findCourt(ctx context.Context, courtId string) (*CourtModel, err
 			court.name as court_name,
 			account.id as creator_id,
 			account.name as creator_name,
+			court.version as court_version,
 			court.created_at as court_created_at
 		from court
 		join account on court.creator_id = account.id
@@ -146,7 +166,8 @@ func (s *store) findCourt(ctx context.Context, courtId string) (*CourtModel, err
 	return &dest, nil
 }
 
-func (s *store) updateCourt(ctx context.Context, tx pgx.Tx, courtId string, name string) (*CourtModel, error) {
+// updateCourt changes the court if it's still at the version, it fails with ErrStale otherwise
+func (s *store) updateCourt(ctx context.Context, tx db.Tx, courtId string, version int, name string) (*CourtModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -160,24 +181,25 @@ func (s *store) updateCourt(ctx context.Context, tx pgx.Tx, courtId string, name
 		with updated_court as (
 			update court
 			set name = $1
-			where id = $2
-			returning id, name, creator_id, created_at
+			where id = $2 and version = $3
+			returning id, name, creator_id, version, created_at
 		)
 		select
 			uc.id as court_id,
 			uc.name as court_name,
 			account.id as creator_id,
 			account.name as creator_name,
+			uc.version as court_version,
 			uc.created_at as court_created_at
 		from updated_court uc
 		join account on uc.creator_id = account.id
 	`
 
-	row := q.QueryRow(ctx, sql, name, courtId)
+	row := q.QueryRow(ctx, sql, name, courtId, version)
 	err := dest.ScanRow(row)
 	if err != nil {
 		if errors.Is(err, failure.ErrNotFound) {
-			return nil, failure.New("court not found", err)
+			return nil, failure.New("court was changed since it was read", fmt.Errorf("%w -> %v", failure.ErrStale, err))
 		}
 		return nil, failure.New("unable to update court", err)
 	}
@@ -185,7 +207,8 @@ func (s *store) updateCourt(ctx context.Context, tx pgx.Tx, courtId string, name
 	return &dest, nil
 }
 
-func (s *store) deleteCourt(ctx context.Context, tx pgx.Tx, courtId string) error {
+// deleteCourt removes the court if it's still at the version, it fails with ErrStale otherwise
+func (s *store) deleteCourt(ctx context.Context, tx db.Tx, courtId string, version int) error {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -194,15 +217,15 @@ func (s *store) deleteCourt(ctx context.Context, tx pgx.Tx, courtId string) erro
 	}
 
 	sql := `
-		delete from court where id = $1
+		delete from court where id = $1 and version = $2
 	`
 
-	ct, err := q.Exec(ctx, sql, courtId)
+	ct, err := q.Exec(ctx, sql, courtId, version)
 	if err != nil {
 		return failure.New("unable to delete court", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 	if ct.RowsAffected() == 0 {
-		return failure.New("court not found", failure.ErrNotFound)
+		return failure.New("court was changed since it was read", failure.ErrStale)
 	}
 
 	return nil
@@ -0,0 +1,29 @@
+package invites
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/router"
+)
+
+type api struct {
+	hdl *handler
+}
+
+var _ router.Mounter = (*api)(nil)
+
+func New(cfg *config.Config, repo Repository) *api {
+	return &api{
+		hdl: newHandler(cfg, repo),
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Use(middleware.RequirePermission(permission.ManageInvites))
+
+	r.With(middleware.URLQueryPaginationParams).Get("/", a.hdl.getInvites)
+	r.Post("/", a.hdl.createInvite)
+	r.With(middleware.URLPathUUIDParams("invite_id")).Delete("/{invite_id}", a.hdl.revokeInvite)
+}
@@ -0,0 +1,142 @@
+package invites
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/pagination"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/response"
+)
+
+type handler struct {
+	store   Repository
+	service *service
+}
+
+func newHandler(cfg *config.Config, repo Repository) *handler {
+	h := &handler{}
+	h.store = repo
+	h.service = newService(cfg, h.store)
+	return h
+}
+
+// @Summary Get
+// @Description Get the invite codes, the codes themselves are never shown again after creation
+// @Tags admin invites
+// @Produce json
+// @Param page query int false "page"
+// @Param per_page query int false "per page"
+// @Param order_by query string false "order by"
+// @Param status query string false "usable, used, expired or revoked"
+// @Success 200 {array} invites.InviteModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/invites [get]
+func (h *handler) getInvites(w http.ResponseWriter, r *http.Request) {
+	query := params.NewQuery(r.URL.Query())
+
+	invites, count, err := h.service.processGetInvites(r.Context(), query)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	result := pagination.NewPaginated(query.Page, query.PerPage, count, invites)
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Create
+// @Description Create an invite code for the signup. The code is only returned once
+// @Tags admin invites
+// @Accept json
+// @Produce json
+// @Param body body invites.CreateInviteRequestModel true "Request body"
+// @Success 201 {object} invites.CreatedInviteModel "Created"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/invites [post]
+func (h *handler) createInvite(w http.ResponseWriter, r *http.Request) {
+	var model CreateInviteRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	creatorId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processCreateInvite(r.Context(), creatorId, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusCreated, result)
+}
+
+// @Summary Revoke
+// @Description Revoke an invite code, the accounts already created with it are kept
+// @Tags admin invites
+// @Param invite_id path string true "invite id"
+// @Success 204 "No content"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/invites/{invite_id} [delete]
+func (h *handler) revokeInvite(w http.ResponseWriter, r *http.Request) {
+	err := h.service.processRevokeInvite(r.Context(), chi.URLParam(r, "invite_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,157 @@
+package invites
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortingFields = map[string]func(a, b InviteModel) int{
+	"created_at": func(a, b InviteModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+	"expires_at": func(a, b InviteModel) int { return memdb.CompareTimePtr(a.ExpiresAt, b.ExpiresAt) },
+	"uses":       func(a, b InviteModel) int { return cmp.Compare(a.Uses, b.Uses) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toInviteModel(ic db.InviteCode) InviteModel {
+	return InviteModel{
+		Id:        ic.Id,
+		Note:      memdb.StringPtr(ic.Note),
+		MaxUses:   ic.MaxUses,
+		Uses:      ic.Uses,
+		ExpiresAt: memdb.TimePtr(ic.ExpiresAt),
+		RevokedAt: memdb.TimePtr(ic.RevokedAt),
+		CreatorId: memdb.StringPtr(ic.CreatorId),
+		CreatedAt: ic.CreatedAt,
+	}
+}
+
+// matchInvite is the where clause of filterInvites
+func matchInvite(filter InviteFilter, ic db.InviteCode, now time.Time) bool {
+	expired := ic.ExpiresAt.Valid && !ic.ExpiresAt.Time.After(now)
+	switch filter.Status {
+	case "usable":
+		return !ic.RevokedAt.Valid && !expired && ic.Uses < ic.MaxUses
+	case "used":
+		return ic.Uses >= ic.MaxUses
+	case "expired":
+		return expired
+	case "revoked":
+		return ic.RevokedAt.Valid
+	}
+	return true
+}
+
+func (s *memStore) findInvites(ctx context.Context, filter InviteFilter, limit, offset int, orderBy *params.OrderBy) ([]InviteModel, error) {
+	now := time.Now()
+
+	dest := []InviteModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, ic := range t.InviteCodes {
+			if matchInvite(filter, ic, now) {
+				dest = append(dest, toInviteModel(ic))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find invites", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, orderBy, memSortingFields, func(a, b InviteModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countInvites(ctx context.Context, filter InviteFilter) (int, error) {
+	now := time.Now()
+
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, ic := range t.InviteCodes {
+			if matchInvite(filter, ic, now) {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count invites", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) insertInvite(ctx context.Context, tx db.Tx, creatorId, codeHash string, note *string, maxUses int, expiresAt *time.Time) (*InviteModel, error) {
+	var dest InviteModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		for _, ic := range t.InviteCodes {
+			if ic.CodeHash == codeHash {
+				return fmt.Errorf("%w -> duplicate invite code hash", failure.ErrInternal)
+			}
+		}
+		ic := db.InviteCode{
+			Id:        memdb.NewId(),
+			CodeHash:  codeHash,
+			Note:      memdb.NullString(note),
+			MaxUses:   maxUses,
+			ExpiresAt: memdb.NullTime(expiresAt),
+			CreatorId: sql.NullString{String: creatorId, Valid: true},
+			CreatedAt: s.db.Now(),
+		}
+		t.InviteCodes[ic.Id] = ic
+		dest = toInviteModel(ic)
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("failed to insert invite", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *memStore) revokeInvite(ctx context.Context, tx db.Tx, inviteId string) (*InviteModel, error) {
+	var dest *InviteModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		ic, ok := t.InviteCodes[inviteId]
+		if !ok {
+			return nil
+		}
+		if !ic.RevokedAt.Valid {
+			ic.RevokedAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+		}
+		t.InviteCodes[inviteId] = ic
+		im := toInviteModel(ic)
+		dest = &im
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to revoke invite", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("invite not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
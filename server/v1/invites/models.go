@@ -0,0 +1,100 @@
+package invites
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/failure"
+)
+
+type InviteModel struct {
+	Id        string     `json:"id"`
+	Note      *string    `json:"note"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatorId *string    `json:"creator_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (im *InviteModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&im.Id, &im.Note, &im.MaxUses, &im.Uses, &im.ExpiresAt, &im.RevokedAt, &im.CreatorId, &im.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning invite row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+func (im *InviteModel) ScanRows(rows pgx.Rows) error {
+	err := rows.Scan(&im.Id, &im.Note, &im.MaxUses, &im.Uses, &im.ExpiresAt, &im.RevokedAt, &im.CreatorId, &im.CreatedAt)
+	if err != nil {
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// the plain code is only returned once, right after it's created
+type CreatedInviteModel struct {
+	InviteModel
+	Code string `json:"code"`
+}
+
+// InviteFilter holds the optional filter query params of the invite list
+type InviteFilter struct {
+	Status string
+}
+
+func (f InviteFilter) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if f.Status != "" && f.Status != "usable" && f.Status != "used" && f.Status != "expired" && f.Status != "revoked" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "status",
+			Message:  "Status must be usable, used, expired or revoked",
+			Location: "query",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+type CreateInviteRequestModel struct {
+	Note      *string    `json:"note"`
+	MaxUses   int        `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (m CreateInviteRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.MaxUses < 1 {
+		inv = append(inv, failure.InvalidField{
+			Field:    "max_uses",
+			Message:  "Max uses must be at least 1",
+			Location: "body",
+		})
+	}
+	if m.ExpiresAt != nil && !m.ExpiresAt.After(time.Now()) {
+		inv = append(inv, failure.InvalidField{
+			Field:    "expires_at",
+			Message:  "Expiration must be in the future",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package invites
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/sec"
+	"github.com/markovidakovic/gdsi/server/tracing"
+)
+
+type service struct {
+	cfg   *config.Config
+	store Repository
+}
+
+func newService(cfg *config.Config, store Repository) *service {
+	return &service{
+		cfg,
+		store,
+	}
+}
+
+func (s *service) processGetInvites(ctx context.Context, query *params.Query) ([]InviteModel, int, error) {
+	ctx, span := tracing.Start(ctx, "invites.processGetInvites")
+	defer span.End()
+
+	filter := InviteFilter{
+		Status: query.Additional["status"],
+	}
+	if inv := filter.Validate(); inv != nil {
+		return nil, 0, failure.NewValidation("validation failed", inv)
+	}
+
+	count, err := s.store.countInvites(ctx, filter)
+	if err != nil {
+		return nil, 0, failure.New("unable to get invites", err)
+	}
+
+	limit, offset := query.CalcLimitAndOffset(count)
+
+	result, err := s.store.findInvites(ctx, filter, limit, offset, query.OrderBy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, count, nil
+}
+
+// processCreateInvite issues a new invite code. Only its hash is stored, the code is returned once
+func (s *service) processCreateInvite(ctx context.Context, creatorId string, model CreateInviteRequestModel) (*CreatedInviteModel, error) {
+	ctx, span := tracing.Start(ctx, "invites.processCreateInvite")
+	defer span.End()
+
+	code, err := sec.GenerateInviteCode()
+	if err != nil {
+		return nil, failure.New("unable to create invite", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create invite", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the create invite tx", "error", err)
+		}
+	}()
+
+	im, err := s.store.insertInvite(ctx, tx, creatorId, sec.HashToken(code), model.Note, model.MaxUses, model.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.InviteCode, ResourceId: im.Id, After: im})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create invite", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &CreatedInviteModel{InviteModel: *im, Code: code}, nil
+}
+
+// processRevokeInvite makes the invite code unusable, the accounts created with it stay
+func (s *service) processRevokeInvite(ctx context.Context, inviteId string) error {
+	ctx, span := tracing.Start(ctx, "invites.processRevokeInvite")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to revoke invite", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the revoke invite tx", "error", err)
+		}
+	}()
+
+	im, err := s.store.revokeInvite(ctx, tx, inviteId)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionRevoke, ResourceType: audit.InviteCode, ResourceId: inviteId, After: im})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to revoke invite", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
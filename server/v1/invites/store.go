@@ -0,0 +1,174 @@
+package invites
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+// Repository holds the invite codes with their usage limits and expiry
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	findInvites(ctx context.Context, filter InviteFilter, limit, offset int, orderBy *params.OrderBy) ([]InviteModel, error)
+	countInvites(ctx context.Context, filter InviteFilter) (int, error)
+	insertInvite(ctx context.Context, tx db.Tx, creatorId, codeHash string, note *string, maxUses int, expiresAt *time.Time) (*InviteModel, error)
+	revokeInvite(ctx context.Context, tx db.Tx, inviteId string) (*InviteModel, error)
+}
+
+type store struct {
+	db *db.Conn
+}
+
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
+		db,
+	}
+}
+
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+var sortingFields = map[string]string{
+	"created_at": "invite_code.created_at",
+	"expires_at": "invite_code.expires_at",
+	"uses":       "invite_code.uses",
+}
+
+const selectInvite = `
+	select
+		invite_code.id as invite_id,
+		invite_code.note as invite_note,
+		invite_code.max_uses as invite_max_uses,
+		invite_code.uses as invite_uses,
+		invite_code.expires_at as invite_expires_at,
+		invite_code.revoked_at as invite_revoked_at,
+		invite_code.creator_id as invite_creator_id,
+		invite_code.created_at as invite_created_at
+	from invite_code
+`
+
+// filterInvites builds the where clause of the invite list
+func filterInvites(filter InviteFilter) string {
+	switch filter.Status {
+	case "usable":
+		return "where revoked_at is null and (expires_at is null or expires_at > current_timestamp) and uses < max_uses\n"
+	case "used":
+		return "where uses >= max_uses\n"
+	case "expired":
+		return "where expires_at <= current_timestamp\n"
+	case "revoked":
+		return "where revoked_at is not null\n"
+	}
+	return ""
+}
+
+func (s *store) findInvites(ctx context.Context, filter InviteFilter, limit, offset int, orderBy *params.OrderBy) ([]InviteModel, error) {
+	sql := selectInvite + filterInvites(filter)
+
+	if orderBy != nil && orderBy.IsValid(sortingFields) {
+		sql += fmt.Sprintf("order by %s %s\n", sortingFields[orderBy.Field], orderBy.Direction)
+	} else {
+		sql += fmt.Sprintln("order by invite_code.created_at desc")
+	}
+
+	var err error
+	var rows pgx.Rows
+	if limit >= 0 {
+		sql += `limit $1 offset $2`
+		rows, err = s.db.Query(ctx, sql, limit, offset)
+	} else {
+		rows, err = s.db.Query(ctx, sql)
+	}
+	if err != nil {
+		return nil, failure.New("unable to find invites", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	var dest = []InviteModel{}
+	for rows.Next() {
+		var im InviteModel
+		err := im.ScanRows(rows)
+		if err != nil {
+			return nil, failure.New("unable to find invites", err)
+		}
+		dest = append(dest, im)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, failure.New("unable to find invites", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) countInvites(ctx context.Context, filter InviteFilter) (int, error) {
+	sql := "select count(*) from invite_code\n" + filterInvites(filter)
+
+	var count int
+	err := s.db.QueryRow(ctx, sql).Scan(&count)
+	if err != nil {
+		return 0, failure.New("unable to count invites", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *store) insertInvite(ctx context.Context, tx db.Tx, creatorId, codeHash string, note *string, maxUses int, expiresAt *time.Time) (*InviteModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into invite_code (code_hash, note, max_uses, expires_at, creator_id)
+		values ($1, $2, $3, $4, $5)
+		returning id, note, max_uses, uses, expires_at, revoked_at, creator_id, created_at
+	`
+
+	var dest InviteModel
+	row := q.QueryRow(ctx, sql, codeHash, note, maxUses, expiresAt, creatorId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		return nil, failure.New("failed to insert invite", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) revokeInvite(ctx context.Context, tx db.Tx, inviteId string) (*InviteModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update invite_code
+		set revoked_at = coalesce(revoked_at, current_timestamp)
+		where id = $1
+		returning id, note, max_uses, uses, expires_at, revoked_at, creator_id, created_at
+	`
+
+	var dest InviteModel
+	row := q.QueryRow(ctx, sql, inviteId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("invite not found", err)
+		}
+		return nil, failure.New("unable to revoke invite", err)
+	}
+
+	return &dest, nil
+}
@@ -5,11 +5,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/middleware"
 	"github.com/markovidakovic/gdsi/server/pagination"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/privacy"
 	"github.com/markovidakovic/gdsi/server/response"
 	"github.com/markovidakovic/gdsi/server/validation"
 )
@@ -18,10 +18,9 @@ type handler struct {
 	service *service
 }
 
-func newHandler(cfg *config.Config, db *db.Conn, validator *validation.Validator) *handler {
+func newHandler(cfg *config.Config, repo Repository, validator *validation.Validator) *handler {
 	h := &handler{}
-	store := newStore(db)
-	h.service = newService(cfg, store, validator)
+	h.service = newService(cfg, repo, validator)
 	return h
 }
 
@@ -35,7 +34,9 @@ func newHandler(cfg *config.Config, db *db.Conn, validator *validation.Validator
 // @Param per_page query int false "per page"
 // @Param order_by query string false "order by"
 // @Param match_available query bool false "match available"
+// @Param If-None-Match header string false "etag of the list the client has"
 // @Success 200 {array} players.PlayerModel "OK"
+// @Success 304 "Not modified"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 500 {object} failure.Failure "Internal server error"
@@ -46,7 +47,13 @@ func (h *handler) getLeaguePlayers(w http.ResponseWriter, r *http.Request) {
 	requestingPlayerId := ctx.Value(middleware.PlayerIdCtxKey).(string)
 	query := params.NewQuery(r.URL.Query())
 
-	leaguePlayers, count, err := h.service.processGetLeaguePlayers(ctx, chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), requestingPlayerId, query)
+	viewer, err := privacy.ViewerFromRequest(r)
+	if err != nil {
+		response.WriteFailure(w, failure.New("unable to check permissions", err))
+		return
+	}
+
+	leaguePlayers, count, err := h.service.processGetLeaguePlayers(ctx, viewer, chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), requestingPlayerId, query)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -62,7 +69,7 @@ func (h *handler) getLeaguePlayers(w http.ResponseWriter, r *http.Request) {
 
 	result := pagination.NewPaginated(query.Page, query.PerPage, count, leaguePlayers)
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteCached(w, r, result)
 }
 
 // @Summary Get
@@ -73,13 +80,20 @@ func (h *handler) getLeaguePlayers(w http.ResponseWriter, r *http.Request) {
 // @Param league_id path string true "league id"
 // @Param player_id path string true "player id"
 // @Success 200 {object} players.PlayerModel "OK"
+// @Header 200 {string} ETag "tag of the player representation, it differs by the viewer"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id}/leagues/{league_id}/players/{player_id} [get]
 func (h *handler) getLeaguePlayer(w http.ResponseWriter, r *http.Request) {
-	result, err := h.service.processGetLeaguePlayer(r.Context(), chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), chi.URLParam(r, "player_id"))
+	viewer, err := privacy.ViewerFromRequest(r)
+	if err != nil {
+		response.WriteFailure(w, failure.New("unable to check permissions", err))
+		return
+	}
+
+	result, err := h.service.processGetLeaguePlayer(r.Context(), viewer, chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), chi.URLParam(r, "player_id"))
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -93,7 +107,7 @@ func (h *handler) getLeaguePlayer(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
 
 // @Summary Assign
@@ -111,7 +125,13 @@ func (h *handler) getLeaguePlayer(w http.ResponseWriter, r *http.Request) {
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id}/leagues/{league_id}/players/{player_id}/assign [post]
 func (h *handler) assignPlayerToLeague(w http.ResponseWriter, r *http.Request) {
-	result, err := h.service.processAssignPlayerToLeague(r.Context(), chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), chi.URLParam(r, "player_id"))
+	viewer, err := privacy.ViewerFromRequest(r)
+	if err != nil {
+		response.WriteFailure(w, failure.New("unable to check permissions", err))
+		return
+	}
+
+	result, err := h.service.processAssignPlayerToLeague(r.Context(), viewer, chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), chi.URLParam(r, "player_id"))
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -125,7 +145,7 @@ func (h *handler) assignPlayerToLeague(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
 
 // @Summary Remove
@@ -143,7 +163,13 @@ func (h *handler) assignPlayerToLeague(w http.ResponseWriter, r *http.Request) {
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id}/leagues/{league_id}/players/{player_id}/assign [delete]
 func (h *handler) unassignPlayerFromLeague(w http.ResponseWriter, r *http.Request) {
-	result, err := h.service.processUnassignPlayerFromLeague(r.Context(), chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), chi.URLParam(r, "player_id"))
+	viewer, err := privacy.ViewerFromRequest(r)
+	if err != nil {
+		response.WriteFailure(w, failure.New("unable to check permissions", err))
+		return
+	}
+
+	result, err := h.service.processUnassignPlayerFromLeague(r.Context(), viewer, chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), chi.URLParam(r, "player_id"))
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -157,5 +183,5 @@ func (h *handler) unassignPlayerFromLeague(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
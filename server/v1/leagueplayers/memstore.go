@@ -0,0 +1,184 @@
+package leagueplayers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/privacy"
+	"github.com/markovidakovic/gdsi/server/v1/players"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortFields = map[string]func(a, b players.PlayerModel) int{
+	"created_at": func(a, b players.PlayerModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func inLeague(p db.Player, leagueId string) bool {
+	return p.CurrentLeagueId.Valid && p.CurrentLeagueId.String == leagueId
+}
+
+// playedWith reports whether the players already have a match in the league
+func playedWith(t *memdb.Tables, leagueId, playerId, otherId string) bool {
+	for _, m := range t.Matches {
+		if m.LeagueId != leagueId {
+			continue
+		}
+		if (m.PlayerOneId == playerId && m.PlayerTwoId == otherId) || (m.PlayerOneId == otherId && m.PlayerTwoId == playerId) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memStore) findLeaguePlayers(ctx context.Context, leagueId string, requestingPlayerId string, matchAvailable bool, limit, offset int, sort *params.OrderBy) ([]players.PlayerModel, error) {
+	dest := []players.PlayerModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, p := range t.Players {
+			if !inLeague(p, leagueId) {
+				continue
+			}
+			if matchAvailable && (p.Id == requestingPlayerId || playedWith(t, leagueId, p.Id, requestingPlayerId)) {
+				continue
+			}
+			dest = append(dest, players.MemPlayerModel(t, p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find league players", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, sort, memSortFields, func(a, b players.PlayerModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countLeaguePlayers(ctx context.Context, leagueId string) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, p := range t.Players {
+			if inLeague(p, leagueId) {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count league players", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findLeaguePlayer(ctx context.Context, leagueId, playerId string) (players.PlayerModel, error) {
+	var dest players.PlayerModel
+	var found bool
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		if p, ok := t.Players[playerId]; ok && inLeague(p, leagueId) {
+			dest, found = players.MemPlayerModel(t, p), true
+		}
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("unable to find league player", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return dest, failure.New("league player not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findPlayer(ctx context.Context, tx db.Tx, playerId string) (players.PlayerModel, error) {
+	var dest players.PlayerModel
+	var found bool
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		if p, ok := t.Players[playerId]; ok {
+			dest, found = players.MemPlayerModel(t, p), true
+		}
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("unable to find player", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return dest, failure.New("player not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) updatePlayerCurrentLeague(ctx context.Context, tx db.Tx, leagueId *string, playerId string) (players.PlayerModel, error) {
+	var dest players.PlayerModel
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		p, ok := t.Players[playerId]
+		if !ok {
+			return nil
+		}
+		if leagueId != nil {
+			if _, ok := t.Leagues[*leagueId]; !ok {
+				return fmt.Errorf("%w -> league %s not found", failure.ErrInternal, *leagueId)
+			}
+		}
+		p.CurrentLeagueId = memdb.NullString(leagueId)
+		p.Version++
+		t.Players[playerId] = p
+		dest, found = players.MemPlayerModel(t, p), true
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("unable to update league player current league", err)
+	}
+	if !found {
+		return dest, failure.New("league player for updating current league not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) incrementPlayerSeasonsPlayed(ctx context.Context, tx db.Tx, leagueId, playerId string) (players.PlayerModel, error) {
+	var dest players.PlayerModel
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		p, ok := t.Players[playerId]
+		if !ok || !inLeague(p, leagueId) {
+			return nil
+		}
+		p.SeasonsPlayed++
+		p.Version++
+		t.Players[playerId] = p
+		dest, found = players.MemPlayerModel(t, p), true
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("unable to increment league player seasons played", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return dest, failure.New("league player for incrementing seasons played not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findRelations(ctx context.Context, viewer privacy.Viewer, playerIds []string) (map[string]privacy.Relation, error) {
+	return players.MemRelations(s.db, viewer, playerIds)
+}
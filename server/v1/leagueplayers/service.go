@@ -3,23 +3,26 @@ package leagueplayers
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
 	"github.com/markovidakovic/gdsi/server/config"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/privacy"
+	"github.com/markovidakovic/gdsi/server/tracing"
 	"github.com/markovidakovic/gdsi/server/v1/players"
 	"github.com/markovidakovic/gdsi/server/validation"
 )
 
 type service struct {
 	cfg       *config.Config
-	store     *store
+	store     Repository
 	validator *validation.Validator
 }
 
-func newService(cfg *config.Config, store *store, validator *validation.Validator) *service {
+func newService(cfg *config.Config, store Repository, validator *validation.Validator) *service {
 	return &service{
 		cfg,
 		store,
@@ -27,7 +30,10 @@ func newService(cfg *config.Config, store *store, validator *validation.Validato
 	}
 }
 
-func (s *service) processGetLeaguePlayers(ctx context.Context, seasonId, leagueId, requestingPlayerId string, query *params.Query) ([]players.PlayerModel, int, error) {
+func (s *service) processGetLeaguePlayers(ctx context.Context, viewer privacy.Viewer, seasonId, leagueId, requestingPlayerId string, query *params.Query) ([]players.PlayerModel, int, error) {
+	ctx, span := tracing.Start(ctx, "leagueplayers.processGetLeaguePlayers")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).
 		SeasonExists(seasonId, "path").
 		LeagueExists(leagueId, "path").
@@ -50,10 +56,22 @@ func (s *service) processGetLeaguePlayers(ctx context.Context, seasonId, leagueI
 		return nil, 0, err
 	}
 
+	pms := make([]*players.PlayerModel, len(lps))
+	for i := range lps {
+		pms[i] = &lps[i]
+	}
+	err = players.Protect(ctx, s.store.findRelations, viewer, pms...)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	return lps, count, nil
 }
 
-func (s *service) processGetLeaguePlayer(ctx context.Context, seasonId, leagueId, playerId string) (*players.PlayerModel, error) {
+func (s *service) processGetLeaguePlayer(ctx context.Context, viewer privacy.Viewer, seasonId, leagueId, playerId string) (*players.PlayerModel, error) {
+	ctx, span := tracing.Start(ctx, "leagueplayers.processGetLeaguePlayer")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).
 		SeasonExists(seasonId, "path").
 		LeagueExists(leagueId, "path").
@@ -69,21 +87,30 @@ func (s *service) processGetLeaguePlayer(ctx context.Context, seasonId, leagueId
 		return nil, err
 	}
 
+	err = players.Protect(ctx, s.store.findRelations, viewer, &lp)
+	if err != nil {
+		return nil, err
+	}
+
 	return &lp, nil
 }
 
-func (s *service) processAssignPlayerToLeague(ctx context.Context, seasonId, leagueId, playerId string) (*players.PlayerModel, error) {
+func (s *service) processAssignPlayerToLeague(ctx context.Context, viewer privacy.Viewer, seasonId, leagueId, playerId string) (*players.PlayerModel, error) {
+	ctx, span := tracing.Start(ctx, "leagueplayers.processAssignPlayerToLeague")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).
 		SeasonExists(seasonId, "path").
 		LeagueExists(leagueId, "path").
 		LeagueInSeason(seasonId, leagueId, "path").
 		PlayerExists(playerId, "path").
+		PlayerApproved(playerId, "path").
 		Result()
 	if err != nil {
 		return nil, err
 	}
 
-	tx, err := s.store.db.Begin(ctx)
+	tx, err := s.store.begin(ctx)
 	if err != nil {
 		return nil, failure.New("unable to assign player to league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -91,10 +118,15 @@ func (s *service) processAssignPlayerToLeague(ctx context.Context, seasonId, lea
 	defer func() {
 		err := tx.Rollback(ctx)
 		if err != nil && err != pgx.ErrTxClosed {
-			log.Printf("failed to rollback assign player to league tx: %v", err)
+			slog.ErrorContext(ctx, "failed to rollback assign player to league tx", "error", err)
 		}
 	}()
 
+	before, err := s.store.findPlayer(ctx, tx, playerId)
+	if err != nil {
+		return nil, failure.New("unable to assign player to league", err)
+	}
+
 	player, err := s.store.updatePlayerCurrentLeague(ctx, tx, &leagueId, playerId)
 	if err != nil {
 		// another option to do here? could just return nil, err
@@ -111,15 +143,28 @@ func (s *service) processAssignPlayerToLeague(ctx context.Context, seasonId, lea
 		return nil, failure.New("unable to assign player to league", err)
 	}
 
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionAssignLeague, ResourceType: audit.Player, ResourceId: playerId, Before: before, After: player})
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, failure.New("unable to assign player to league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
+	err = players.Protect(ctx, s.store.findRelations, viewer, &player)
+	if err != nil {
+		return nil, err
+	}
+
 	return &player, nil
 }
 
-func (s *service) processUnassignPlayerFromLeague(ctx context.Context, seasonId, leagueId, playerId string) (*players.PlayerModel, error) {
+func (s *service) processUnassignPlayerFromLeague(ctx context.Context, viewer privacy.Viewer, seasonId, leagueId, playerId string) (*players.PlayerModel, error) {
+	ctx, span := tracing.Start(ctx, "leagueplayers.processUnassignPlayerFromLeague")
+	defer span.End()
+
 	// todo: maybe do a validation in validation.go for playerInLeague
 	err := s.validator.NewValidation(ctx).
 		SeasonExists(seasonId, "path").
@@ -131,12 +176,44 @@ func (s *service) processUnassignPlayerFromLeague(ctx context.Context, seasonId,
 		return nil, err
 	}
 
-	lp, err := s.store.updatePlayerCurrentLeague(ctx, nil, nil, playerId)
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to unassign player from league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback unassign player from league tx", "error", err)
+		}
+	}()
+
+	before, err := s.store.findPlayer(ctx, tx, playerId)
+	if err != nil {
+		return nil, failure.New("unable to unassign player from league", err)
+	}
+
+	lp, err := s.store.updatePlayerCurrentLeague(ctx, tx, nil, playerId)
 	if err != nil {
 		// same as in other methods. this will most likely be a db error
 		// and not a player not found because the player existance is confirmed in the validation above
 		return nil, failure.New("unable to unassign player from league", err)
 	}
 
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUnassignLeague, ResourceType: audit.Player, ResourceId: playerId, Before: before, After: lp})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to unassign player from league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	err = players.Protect(ctx, s.store.findRelations, viewer, &lp)
+	if err != nil {
+		return nil, err
+	}
+
 	return &lp, nil
 }
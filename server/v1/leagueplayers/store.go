@@ -5,23 +5,41 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/privacy"
 	"github.com/markovidakovic/gdsi/server/v1/players"
 )
 
+// Repository holds the assignment of the players to the leagues
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	findLeaguePlayers(ctx context.Context, leagueId string, requestingPlayerId string, matchAvailable bool, limit, offset int, sort *params.OrderBy) ([]players.PlayerModel, error)
+	countLeaguePlayers(ctx context.Context, leagueId string) (int, error)
+	findLeaguePlayer(ctx context.Context, leagueId, playerId string) (players.PlayerModel, error)
+	findPlayer(ctx context.Context, tx db.Tx, playerId string) (players.PlayerModel, error)
+	updatePlayerCurrentLeague(ctx context.Context, tx db.Tx, leagueId *string, playerId string) (players.PlayerModel, error)
+	incrementPlayerSeasonsPlayed(ctx context.Context, tx db.Tx, leagueId, playerId string) (players.PlayerModel, error)
+	findRelations(ctx context.Context, viewer privacy.Viewer, playerIds []string) (map[string]privacy.Relation, error)
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
 	return &store{
 		db,
 	}
 }
 
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
 var allowedSortFeilds = map[string]string{
 	"created_at": "player.created_at",
 }
@@ -41,8 +59,16 @@ func (s *store) findLeaguePlayers(ctx context.Context, leagueId string, requesti
 			player.seasons_played,
 			account.id as account_id,
 			account.name as account_name,
+			account.email as account_email,
+			account.phone_number as account_phone_number,
+			account.dob as account_dob,
+			player.privacy_phone_number,
+			player.privacy_email,
+			player.privacy_dob,
+			player.privacy_physique,
 			league.id as current_league_id,
 			league.title as current_league_title,
+			player.version,
 			player.created_at
 		from player
 		join account on player.account_id = account.id
@@ -121,31 +147,40 @@ func (s *store) countLeaguePlayers(ctx context.Context, leagueId string) (int, e
 	return count, nil
 }
 
+const selectPlayer = `
+	select 
+		player.id,
+		player.height,
+		player.weight,
+		player.handedness,
+		player.racket,
+		player.matches_expected,
+		player.matches_played,
+		player.matches_won,
+		player.matches_scheduled,
+		player.seasons_played,
+		account.id as account_id,
+		account.name as account_name,
+		account.email as account_email,
+		account.phone_number as account_phone_number,
+		account.dob as account_dob,
+		player.privacy_phone_number,
+		player.privacy_email,
+		player.privacy_dob,
+		player.privacy_physique,
+		league.id as current_league_id,
+		league.title as current_league_title,
+		player.version,
+		player.created_at
+	from player
+	join account on player.account_id = account.id
+	left join league on player.current_league_id = league.id
+`
+
 func (s *store) findLeaguePlayer(ctx context.Context, leagueId, playerId string) (players.PlayerModel, error) {
 	var dest players.PlayerModel
 
-	sql := `
-		select 
-			player.id,
-			player.height,
-			player.weight,
-			player.handedness,
-			player.racket,
-			player.matches_expected,
-			player.matches_played,
-			player.matches_won,
-			player.matches_scheduled,
-			player.seasons_played,
-			account.id as account_id,
-			account.name as account_name,
-			league.id as current_league_id,
-			league.title as current_league_title,
-			player.created_at
-		from player
-		join account on player.account_id = account.id
-		left join league on player.current_league_id = league.id
-		where player.id = $1 and player.current_league_id = $2
-	`
+	sql := selectPlayer + "where player.id = $1 and player.current_league_id = $2"
 
 	row := s.db.QueryRow(ctx, sql, playerId, leagueId)
 	err := dest.ScanRow(row)
@@ -159,7 +194,32 @@ func (s *store) findLeaguePlayer(ctx context.Context, leagueId, playerId string)
 	return dest, nil
 }
 
-func (s *store) updatePlayerCurrentLeague(ctx context.Context, tx pgx.Tx, leagueId *string, playerId string) (players.PlayerModel, error) {
+// findPlayer returns the player regardless of its league, the audit log keeps its state before the league change
+func (s *store) findPlayer(ctx context.Context, tx db.Tx, playerId string) (players.PlayerModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	var dest players.PlayerModel
+
+	sql := selectPlayer + "where player.id = $1"
+
+	row := q.QueryRow(ctx, sql, playerId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return dest, failure.New("player not found", err)
+		}
+		return dest, failure.New("unable to find player", err)
+	}
+
+	return dest, nil
+}
+
+func (s *store) updatePlayerCurrentLeague(ctx context.Context, tx db.Tx, leagueId *string, playerId string) (players.PlayerModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -173,7 +233,7 @@ func (s *store) updatePlayerCurrentLeague(ctx context.Context, tx pgx.Tx, league
 			set 
 				current_league_id = $1
 			where id = $2
-			returning id, height, weight, handedness, racket, matches_expected, matches_played, matches_won, matches_scheduled, seasons_played, account_id, current_league_id, created_at
+			returning id, height, weight, handedness, racket, matches_expected, matches_played, matches_won, matches_scheduled, seasons_played, account_id, current_league_id, privacy_phone_number, privacy_email, privacy_dob, privacy_physique, version, created_at
 		)
 		select
 			up.id as player_id,
@@ -188,8 +248,16 @@ func (s *store) updatePlayerCurrentLeague(ctx context.Context, tx pgx.Tx, league
 			up.seasons_played as player_seasons_played,
 			account.id as player_account_id,
 			account.name as player_account_name,
+			account.email as player_account_email,
+			account.phone_number as player_account_phone_number,
+			account.dob as player_account_dob,
+			up.privacy_phone_number as player_privacy_phone_number,
+			up.privacy_email as player_privacy_email,
+			up.privacy_dob as player_privacy_dob,
+			up.privacy_physique as player_privacy_physique,
 			league.id as player_current_league_id,
 			league.title as player_current_league_title,
+			up.version,
 			up.created_at
 		from updated_player up
 		join account on up.account_id = account.id
@@ -210,7 +278,7 @@ func (s *store) updatePlayerCurrentLeague(ctx context.Context, tx pgx.Tx, league
 	return dest, nil
 }
 
-func (s *store) incrementPlayerSeasonsPlayed(ctx context.Context, tx pgx.Tx, leagueId, playerId string) (players.PlayerModel, error) {
+func (s *store) incrementPlayerSeasonsPlayed(ctx context.Context, tx db.Tx, leagueId, playerId string) (players.PlayerModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -224,7 +292,7 @@ func (s *store) incrementPlayerSeasonsPlayed(ctx context.Context, tx pgx.Tx, lea
 			set
 				seasons_played = seasons_played + 1
 			where id = $1 and current_league_id = $2
-			returning id, height, weight, handedness, racket, matches_expected, matches_played, matches_won, matches_scheduled, seasons_played, account_id, current_league_id, created_at
+			returning id, height, weight, handedness, racket, matches_expected, matches_played, matches_won, matches_scheduled, seasons_played, account_id, current_league_id, privacy_phone_number, privacy_email, privacy_dob, privacy_physique, version, created_at
 		)
 		select
 			up.id as player_id,
@@ -239,8 +307,16 @@ func (s *store) incrementPlayerSeasonsPlayed(ctx context.Context, tx pgx.Tx, lea
 			up.seasons_played as player_seasons_played,
 			account.id as player_account_id,
 			account.name as player_account_name,
+			account.email as player_account_email,
+			account.phone_number as player_account_phone_number,
+			account.dob as player_account_dob,
+			up.privacy_phone_number as player_privacy_phone_number,
+			up.privacy_email as player_privacy_email,
+			up.privacy_dob as player_privacy_dob,
+			up.privacy_physique as player_privacy_physique,
 			league.id as player_current_league_id,
 			league.title as player_current_league_title,
+			up.version as player_version,
 			up.created_at as player_created_at
 		from updated_player up
 		join account on up.account_id = account.id
@@ -259,3 +335,7 @@ func (s *store) incrementPlayerSeasonsPlayed(ctx context.Context, tx pgx.Tx, lea
 
 	return dest, nil
 }
+
+func (s *store) findRelations(ctx context.Context, viewer privacy.Viewer, playerIds []string) (map[string]privacy.Relation, error) {
+	return privacy.Relations(ctx, s.db, viewer, playerIds)
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/middleware"
 	"github.com/markovidakovic/gdsi/server/pagination"
@@ -18,12 +17,12 @@ import (
 
 type handler struct {
 	service *service
-	store   *store
+	store   Repository
 }
 
-func newHandler(cfg *config.Config, db *db.Conn, validator *validation.Validator) *handler {
+func newHandler(cfg *config.Config, repo Repository, validator *validation.Validator) *handler {
 	h := &handler{}
-	h.store = newStore(db)
+	h.store = repo
 	h.service = newService(cfg, h.store, validator)
 	return h
 }
@@ -71,7 +70,7 @@ func (h *handler) createLeague(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusCreated, result)
+	response.WriteTagged(w, http.StatusCreated, result.Version, result)
 }
 
 // @Summary Get
@@ -82,7 +81,9 @@ func (h *handler) createLeague(w http.ResponseWriter, r *http.Request) {
 // @Param page query int false "page"
 // @Param per_page query int false "per page"
 // @Param order_by query string false "order by"
+// @Param If-None-Match header string false "etag of the list the client has"
 // @Success 200 {array} leagues.LeagueModel "OK"
+// @Success 304 "Not modified"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 500 {object} failure.Failure "Internal server error"
@@ -108,7 +109,7 @@ func (h *handler) getLeagues(w http.ResponseWriter, r *http.Request) {
 
 	result := pagination.NewPaginated(query.Page, query.PerPage, count, leagues)
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteCached(w, r, result)
 }
 
 // @Summary Get by id
@@ -118,6 +119,7 @@ func (h *handler) getLeagues(w http.ResponseWriter, r *http.Request) {
 // @Param season_id path string true "season id"
 // @Param league_id path string true "league id"
 // @Success 200 {object} leagues.LeagueModel "OK"
+// @Header 200 {string} ETag "version of the league"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
@@ -140,7 +142,7 @@ func (h *handler) getLeague(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteTagged(w, http.StatusOK, result.Version, result)
 }
 
 // @Summary Update
@@ -150,11 +152,14 @@ func (h *handler) getLeague(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param season_id path string true "season id"
 // @Param league_id path string true "league id"
+// @Param If-Match header string false "etag of the league the update is based on"
 // @Param body body leagues.UpdateLeagueRequestModel true "Request body"
 // @Success 200 {object} leagues.LeagueModel "OK"
+// @Header 200 {string} ETag "version of the updated league"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id}/leagues/{league_id} [put]
@@ -173,7 +178,7 @@ func (h *handler) updateLeague(w http.ResponseWriter, r *http.Request) {
 	model.SeasonId = chi.URLParam(r, "season_id")
 	model.LeagueId = chi.URLParam(r, "league_id")
 
-	result, err := h.service.processUpdateLeague(r.Context(), model)
+	result, err := h.service.processUpdateLeague(r.Context(), params.NewIfMatch(r.Header), model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -188,7 +193,7 @@ func (h *handler) updateLeague(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteTagged(w, http.StatusOK, result.Version, result)
 }
 
 // @Summary Delete
@@ -197,15 +202,17 @@ func (h *handler) updateLeague(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param season_id path string true "season id"
 // @Param league_id path string true "league id"
+// @Param If-Match header string false "etag of the league the deletion is based on"
 // @Success 204 "No content"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id}/leagues/{league_id} [delete]
 func (h *handler) deleteLeague(w http.ResponseWriter, r *http.Request) {
-	err := h.service.processDeleteLeague(r.Context(), chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"))
+	err := h.service.processDeleteLeague(r.Context(), chi.URLParam(r, "season_id"), chi.URLParam(r, "league_id"), params.NewIfMatch(r.Header))
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
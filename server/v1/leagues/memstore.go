@@ -0,0 +1,174 @@
+package leagues
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortFields = map[string]func(a, b LeagueModel) int{
+	"title":      func(a, b LeagueModel) int { return memdb.CompareText(a.Title, b.Title) },
+	"created_at": func(a, b LeagueModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toLeagueModel(t *memdb.Tables, l db.League) LeagueModel {
+	var lm LeagueModel
+	lm.Id = l.Id
+	lm.Title = l.Title
+	lm.Description = memdb.StringPtr(l.Description)
+	lm.Season.Id = l.SeasonId
+	lm.Season.Title = t.Seasons[l.SeasonId].Title
+	lm.Creator.Id = l.CreatorId
+	lm.Creator.Name = t.Accounts[l.CreatorId].Name
+	lm.Version = l.Version
+	lm.CreatedAt = l.CreatedAt
+	return lm
+}
+
+func (s *memStore) insertLeague(ctx context.Context, tx db.Tx, title string, description *string, creatorId string, seasonId string) (LeagueModel, error) {
+	var dest LeagueModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Seasons[seasonId]; !ok {
+			return fmt.Errorf("%w -> season %s not found", failure.ErrInternal, seasonId)
+		}
+		if _, ok := t.Accounts[creatorId]; !ok {
+			return fmt.Errorf("%w -> creator %s not found", failure.ErrInternal, creatorId)
+		}
+		l := db.League{
+			Id:          memdb.NewId(),
+			Title:       title,
+			Description: memdb.NullString(description),
+			SeasonId:    seasonId,
+			CreatorId:   creatorId,
+			Version:     1,
+			CreatedAt:   s.db.Now(),
+		}
+		t.Leagues[l.Id] = l
+		dest = toLeagueModel(t, l)
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("failed to insert league", err)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findLeagues(ctx context.Context, seasonId string, limit, offset int, sort *params.OrderBy) ([]LeagueModel, error) {
+	dest := []LeagueModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, l := range t.Leagues {
+			if l.SeasonId == seasonId {
+				dest = append(dest, toLeagueModel(t, l))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find leagues", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, sort, memSortFields, func(a, b LeagueModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countLeagues(ctx context.Context, seasonId string) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, l := range t.Leagues {
+			if l.SeasonId == seasonId {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count leagues", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findLeague(ctx context.Context, seasonId, leagueId string) (*LeagueModel, error) {
+	var dest *LeagueModel
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		if l, ok := t.Leagues[leagueId]; ok && l.SeasonId == seasonId {
+			lm := toLeagueModel(t, l)
+			dest = &lm
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("league not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) updateLeague(ctx context.Context, tx db.Tx, title string, description *string, seasonId, leagueId string, version int) (LeagueModel, error) {
+	var dest LeagueModel
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		l, ok := t.Leagues[leagueId]
+		if !ok || l.SeasonId != seasonId || l.Version != version {
+			return nil
+		}
+		l.Title = title
+		l.Description = memdb.NullString(description)
+		l.Version++
+		t.Leagues[leagueId] = l
+		dest, found = toLeagueModel(t, l), true
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("unable to update league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return dest, failure.New("league was changed since it was read", failure.ErrStale)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) deleteLeague(ctx context.Context, tx db.Tx, seasonId, leagueId string, version int) error {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if l, ok := t.Leagues[leagueId]; !ok || l.SeasonId != seasonId || l.Version != version {
+			return nil
+		}
+		var err error
+		found, err = t.DeleteLeague(leagueId)
+		return err
+	})
+	if err != nil {
+		return failure.New("unable to delete league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("league was changed since it was read", failure.ErrStale)
+	}
+
+	return nil
+}
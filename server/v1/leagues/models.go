@@ -15,11 +15,12 @@ type LeagueModel struct {
 	Description *string      `json:"description"`
 	Season      SeasonModel  `json:"season"`
 	Creator     CreatorModel `json:"creator"`
+	Version     int          `json:"version"`
 	CreatedAt   time.Time    `json:"created_at"`
 }
 
 func (lm *LeagueModel) ScanRow(row pgx.Row) error {
-	err := row.Scan(&lm.Id, &lm.Title, &lm.Description, &lm.Season.Id, &lm.Season.Title, &lm.Creator.Id, &lm.Creator.Name, &lm.CreatedAt)
+	err := row.Scan(&lm.Id, &lm.Title, &lm.Description, &lm.Season.Id, &lm.Season.Title, &lm.Creator.Id, &lm.Creator.Name, &lm.Version, &lm.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return failure.New("scanning league row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
@@ -30,7 +31,7 @@ func (lm *LeagueModel) ScanRow(row pgx.Row) error {
 }
 
 func (lm *LeagueModel) ScanRows(rows pgx.Rows) error {
-	err := rows.Scan(&lm.Id, &lm.Title, &lm.Description, &lm.Season.Id, &lm.Season.Title, &lm.Creator.Id, &lm.Creator.Name, &lm.CreatedAt)
+	err := rows.Scan(&lm.Id, &lm.Title, &lm.Description, &lm.Season.Id, &lm.Season.Title, &lm.Creator.Id, &lm.Creator.Name, &lm.Version, &lm.CreatedAt)
 	if err != nil {
 		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -2,20 +2,25 @@ package leagues
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
 	"github.com/markovidakovic/gdsi/server/config"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/tracing"
 	"github.com/markovidakovic/gdsi/server/validation"
 )
 
 type service struct {
 	cfg       *config.Config
-	store     *store
+	store     Repository
 	validator *validation.Validator
 }
 
-func newService(cfg *config.Config, store *store, validator *validation.Validator) *service {
+func newService(cfg *config.Config, store Repository, validator *validation.Validator) *service {
 	return &service{
 		cfg,
 		store,
@@ -24,20 +29,48 @@ func newService(cfg *config.Config, store *store, validator *validation.Validato
 }
 
 func (s *service) processCreateLeague(ctx context.Context, model CreateLeagueRequestModel) (*LeagueModel, error) {
+	ctx, span := tracing.Start(ctx, "leagues.processCreateLeague")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).SeasonExists(model.SeasonId, "path").Result()
 	if err != nil {
 		return nil, err
 	}
 
-	lm, err := s.store.insertLeague(ctx, nil, model.Title, model.Description, model.CreatorId, model.SeasonId)
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the create league tx", "error", err)
+		}
+	}()
+
+	lm, err := s.store.insertLeague(ctx, tx, model.Title, model.Description, model.CreatorId, model.SeasonId)
 	if err != nil {
 		return nil, err
 	}
 
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.League, ResourceId: lm.Id, After: lm})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
 	return &lm, nil
 }
 
 func (s *service) processFindLeagues(ctx context.Context, seasonId string, query *params.Query) ([]LeagueModel, int, error) {
+	ctx, span := tracing.Start(ctx, "leagues.processFindLeagues")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).SeasonExists(seasonId, "path").Result()
 	if err != nil {
 		return nil, 0, err
@@ -59,6 +92,9 @@ func (s *service) processFindLeagues(ctx context.Context, seasonId string, query
 }
 
 func (s *service) processFindLeague(ctx context.Context, seasonId, leagueId string) (*LeagueModel, error) {
+	ctx, span := tracing.Start(ctx, "leagues.processFindLeague")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).SeasonExists(seasonId, "path").Result()
 	if err != nil {
 		return nil, err
@@ -72,30 +108,96 @@ func (s *service) processFindLeague(ctx context.Context, seasonId, leagueId stri
 	return lm, nil
 }
 
-func (s *service) processUpdateLeague(ctx context.Context, model UpdateLeagueRequestModel) (*LeagueModel, error) {
+func (s *service) processUpdateLeague(ctx context.Context, ifMatch params.IfMatch, model UpdateLeagueRequestModel) (*LeagueModel, error) {
+	ctx, span := tracing.Start(ctx, "leagues.processUpdateLeague")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).SeasonExists(model.SeasonId, "path").Result()
 	if err != nil {
 		return nil, err
 	}
 
-	lm, err := s.store.updateLeague(ctx, nil, model.Title, model.Description, model.SeasonId, model.LeagueId)
+	before, err := s.store.findLeague(ctx, model.SeasonId, model.LeagueId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ifMatch.Check("league", before.Version); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the update league tx", "error", err)
+		}
+	}()
+
+	lm, err := s.store.updateLeague(ctx, tx, model.Title, model.Description, model.SeasonId, model.LeagueId, before.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUpdate, ResourceType: audit.League, ResourceId: model.LeagueId, Before: before, After: lm})
 	if err != nil {
 		return nil, err
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
 	return &lm, nil
 }
 
-func (s *service) processDeleteLeague(ctx context.Context, seasonId, leagueId string) error {
+func (s *service) processDeleteLeague(ctx context.Context, seasonId, leagueId string, ifMatch params.IfMatch) error {
+	ctx, span := tracing.Start(ctx, "leagues.processDeleteLeague")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).SeasonExists(seasonId, "path").Result()
 	if err != nil {
 		return err
 	}
 
-	err = s.store.deleteLeague(ctx, nil, seasonId, leagueId)
+	before, err := s.store.findLeague(ctx, seasonId, leagueId)
 	if err != nil {
 		return err
 	}
+	if err := ifMatch.Check("league", before.Version); err != nil {
+		return err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to delete league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the delete league tx", "error", err)
+		}
+	}()
+
+	err = s.store.deleteLeague(ctx, tx, seasonId, leagueId, before.Version)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionDelete, ResourceType: audit.League, ResourceId: leagueId, Before: before})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to delete league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
 
 	return nil
 }
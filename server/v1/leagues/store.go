@@ -11,16 +11,33 @@ import (
 	"github.com/markovidakovic/gdsi/server/params"
 )
 
+// Repository holds the leagues, each belongs to a season
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	insertLeague(ctx context.Context, tx db.Tx, title string, description *string, creatorId string, seasonId string) (LeagueModel, error)
+	findLeagues(ctx context.Context, seasonId string, limit, offset int, sort *params.OrderBy) ([]LeagueModel, error)
+	countLeagues(ctx context.Context, seasonId string) (int, error)
+	findLeague(ctx context.Context, seasonId, leagueId string) (*LeagueModel, error)
+	updateLeague(ctx context.Context, tx db.Tx, title string, description *string, seasonId, leagueId string, version int) (LeagueModel, error)
+	deleteLeague(ctx context.Context, tx db.Tx, seasonId, leagueId string, version int) error
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
 	return &store{
 		db,
 	}
 }
 
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
 var allowedSortFields = map[string]string{
 	"title":      "league.title",
 	"start_date": "league.start_date",
@@ -28,7 +45,7 @@ var allowedSortFields = map[string]string{
 	"created_at": "league.created_at",
 }
 
-func (s *store) insertLeague(ctx context.Context, tx pgx.Tx, title string, description *string, creatorId string, seasonId string) (LeagueModel, error) {
+func (s *store) insertLeague(ctx context.Context, tx db.Tx, title string, description *string, creatorId string, seasonId string) (LeagueModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -40,7 +57,7 @@ func (s *store) insertLeague(ctx context.Context, tx pgx.Tx, title string, descr
 		with inserted_league as (
 			insert into league (title, description, season_id, creator_id)
 			values ($1, $2, $3, $4)
-			returning id, title, description, season_id, creator_id, created_at
+			returning id, title, description, season_id, creator_id, version, created_at
 		)
 		select
 			il.id,
@@ -50,6 +67,7 @@ func (s *store) insertLeague(ctx context.Context, tx pgx.Tx, title string, descr
 			season.title as season_title,
 			account.id as creator_id,
 			account.name as creator_name,
+			il.version,
 			il.created_at
 		from inserted_league il
 		join season on il.season_id = season.id
@@ -76,6 +94,7 @@ func (s *store) findLeagues(ctx context.Context, seasonId string, limit, offset
 			season.title as season_title,
 			account.id as creator_id,
 			account.name as creator_name,
+			league.version,
 			league.created_at
 		from league
 		join season on league.season_id = season.id
@@ -141,6 +160,7 @@ func (s *store) findLeague(ctx context.Context, seasonId, leagueId string) (*Lea
 			season.title as season_title,
 			account.id as creator_id,
 			account.name as creator_name,
+			league.version,
 			league.created_at
 		from league
 		join season on league.season_id = season.id
@@ -161,7 +181,8 @@ func (s *store) findLeague(ctx context.Context, seasonId, leagueId string) (*Lea
 	return &dest, nil
 }
 
-func (s *store) updateLeague(ctx context.Context, tx pgx.Tx, title string, description *string, seasonId, leagueId string) (LeagueModel, error) {
+// updateLeague changes the league if it's still at the version, it fails with ErrStale otherwise
+func (s *store) updateLeague(ctx context.Context, tx db.Tx, title string, description *string, seasonId, leagueId string, version int) (LeagueModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -173,8 +194,8 @@ func (s *store) updateLeague(ctx context.Context, tx pgx.Tx, title string, descr
 		with updated_league as (
 			update league
 			set title = $1, description = $2
-			where id = $3 and season_id = $4
-			returning id, title, description, season_id, creator_id, created_at
+			where id = $3 and season_id = $4 and version = $5
+			returning id, title, description, season_id, creator_id, version, created_at
 		)
 		select
 			ul.id,
@@ -184,6 +205,7 @@ func (s *store) updateLeague(ctx context.Context, tx pgx.Tx, title string, descr
 			season.title as season_title,
 			account.id as creator_id,
 			account.name as creator_name,
+			ul.version,
 			ul.created_at
 		from updated_league ul
 		join season on ul.season_id = season.id
@@ -191,11 +213,11 @@ func (s *store) updateLeague(ctx context.Context, tx pgx.Tx, title string, descr
 	`
 
 	var dest LeagueModel
-	row := q.QueryRow(ctx, sql, title, description, leagueId, seasonId)
+	row := q.QueryRow(ctx, sql, title, description, leagueId, seasonId, version)
 	err := dest.ScanRow(row)
 	if err != nil {
 		if errors.Is(err, failure.ErrNotFound) {
-			return dest, failure.New("league not found", err)
+			return dest, failure.New("league was changed since it was read", fmt.Errorf("%w -> %v", failure.ErrStale, err))
 		}
 		return dest, failure.New("unable to update league", err)
 	}
@@ -203,7 +225,8 @@ func (s *store) updateLeague(ctx context.Context, tx pgx.Tx, title string, descr
 	return dest, nil
 }
 
-func (s *store) deleteLeague(ctx context.Context, tx pgx.Tx, seasonId, leagueId string) error {
+// deleteLeague removes the league if it's still at the version, it fails with ErrStale otherwise
+func (s *store) deleteLeague(ctx context.Context, tx db.Tx, seasonId, leagueId string, version int) error {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -212,15 +235,15 @@ func (s *store) deleteLeague(ctx context.Context, tx pgx.Tx, seasonId, leagueId
 	}
 
 	sql := `
-		delete from league where id = $1 and season_id = $2
+		delete from league where id = $1 and season_id = $2 and version = $3
 	`
 
-	ct, err := q.Exec(ctx, sql, leagueId, seasonId)
+	ct, err := q.Exec(ctx, sql, leagueId, seasonId, version)
 	if err != nil {
 		return failure.New("unable to delete league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 	if ct.RowsAffected() == 0 {
-		return failure.New("league not found", failure.ErrNotFound)
+		return failure.New("league was changed since it was read", failure.ErrStale)
 	}
 
 	return nil
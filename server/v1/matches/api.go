@@ -3,8 +3,8 @@ package matches
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
 	"github.com/markovidakovic/gdsi/server/router"
 	"github.com/markovidakovic/gdsi/server/validation"
 )
@@ -15,9 +15,9 @@ type api struct {
 
 var _ router.Mounter = (*api)(nil)
 
-func New(cfg *config.Config, db *db.Conn, validator *validation.Validator) *api {
+func New(cfg *config.Config, repo Repository, validator *validation.Validator) *api {
 	return &api{
-		hdl: newHandler(cfg, db, validator),
+		hdl: newHandler(cfg, repo, validator),
 	}
 }
 
@@ -25,6 +25,7 @@ func (a *api) Mount(r chi.Router) {
 	r.With(middleware.URLPathUUIDParams("season_id", "league_id")).Post("/", a.hdl.createMatch)
 	r.With(middleware.URLPathUUIDParams("season_id", "league_id")).With(middleware.URLQueryPaginationParams).Get("/", a.hdl.getMatches)
 	r.With(middleware.URLPathUUIDParams("season_id", "league_id", "match_id")).Get("/{match_id}", a.hdl.getMatch)
-	r.With(middleware.URLPathUUIDParams("season_id", "league_id", "match_id")).With(middleware.RequireOwnership(a.hdl.store.checkMatchOwnership, "player", "match_id")).Put("/{match_id}", a.hdl.updateMatch)
-	r.With(middleware.URLPathUUIDParams("season_id", "league_id", "match_id")).With(middleware.RequireOwnership(a.hdl.store.checkMatchParticipation, "player", "match_id")).Post("/{match_id}/score", a.hdl.submitMatchScore)
+	r.With(middleware.URLPathUUIDParams("season_id", "league_id", "match_id")).With(middleware.RequirePermissionOrOwnership(permission.UpdateMatch, a.hdl.store.checkMatchOwnership, "player", "match_id")).Put("/{match_id}", a.hdl.updateMatch)
+	r.With(middleware.URLPathUUIDParams("season_id", "league_id", "match_id")).With(middleware.RequirePermissionOrOwnership(permission.UpdateMatch, a.hdl.store.checkMatchParticipation, "player", "match_id")).Post("/{match_id}/score", a.hdl.submitMatchScore)
+	r.With(middleware.URLPathUUIDParams("season_id", "league_id", "match_id")).With(middleware.RequirePermission(permission.UpdateMatch)).Put("/{match_id}/score", a.hdl.correctMatchScore)
 }
@@ -7,29 +7,29 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/middleware"
 	"github.com/markovidakovic/gdsi/server/pagination"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/permission"
 	"github.com/markovidakovic/gdsi/server/response"
 	"github.com/markovidakovic/gdsi/server/validation"
 )
 
 type handler struct {
 	service *service
-	store   *store
+	store   Repository
 }
 
-func newHandler(cfg *config.Config, db *db.Conn, validator *validation.Validator) *handler {
+func newHandler(cfg *config.Config, repo Repository, validator *validation.Validator) *handler {
 	h := &handler{}
-	h.store = newStore(db)
+	h.store = repo
 	h.service = newService(cfg, h.store, validator)
 	return h
 }
 
 // @Summary Create
-// @Description Create a new match
+// @Description Create a new match. The requester is player one unless player_one_id is set, which requires the update match permission in the league
 // @Tags matches
 // @Accept json
 // @Produce json
@@ -58,7 +58,22 @@ func (h *handler) createMatch(w http.ResponseWriter, r *http.Request) {
 
 	model.SeasonId = chi.URLParam(r, "season_id")
 	model.LeagueId = chi.URLParam(r, "league_id")
-	model.PlayerOneId = ctx.Value(middleware.PlayerIdCtxKey).(string)
+
+	// only the ones managing the league's matches can create matches for other players
+	playerId := ctx.Value(middleware.PlayerIdCtxKey).(string)
+	if model.PlayerOneId == "" {
+		model.PlayerOneId = playerId
+	} else if model.PlayerOneId != playerId {
+		ok, err := middleware.HasPermission(r, permission.UpdateMatch)
+		if err != nil {
+			response.WriteFailure(w, failure.New("unable to check permission", err))
+			return
+		}
+		if !ok {
+			response.WriteFailure(w, failure.New("insufficient permission to create matches for other players", failure.ErrForbidden))
+			return
+		}
+	}
 
 	result, err := h.service.processCreateMatch(ctx, model)
 	if err != nil {
@@ -75,7 +90,7 @@ func (h *handler) createMatch(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusCreated, result)
+	response.WriteBodyTagged(w, http.StatusCreated, result)
 }
 
 // @Summary Get
@@ -87,7 +102,9 @@ func (h *handler) createMatch(w http.ResponseWriter, r *http.Request) {
 // @Param page query int false "page"
 // @Param per_page query int false "per page"
 // @Param order_by query string false "order by"
+// @Param If-None-Match header string false "etag of the list the client has"
 // @Success 200 {array} matches.MatchModel "OK"
+// @Success 304 "Not modified"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 500 {object} failure.Failure "Internal server error"
@@ -113,7 +130,7 @@ func (h *handler) getMatches(w http.ResponseWriter, r *http.Request) {
 
 	result := pagination.NewPaginated(query.Page, query.PerPage, count, matches)
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteCached(w, r, result)
 }
 
 // @Summary Get by id
@@ -124,6 +141,7 @@ func (h *handler) getMatches(w http.ResponseWriter, r *http.Request) {
 // @Param league_id path string true "league id"
 // @Param match_id path string true "match id"
 // @Success 200 {object} matches.MatchModel "OK"
+// @Header 200 {string} ETag "tag of the match representation"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
@@ -146,7 +164,7 @@ func (h *handler) getMatch(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
 
 // @Summary Update
@@ -157,12 +175,15 @@ func (h *handler) getMatch(w http.ResponseWriter, r *http.Request) {
 // @Param season_id path string true "season id"
 // @Param league_id path string true "league id"
 // @Param match_id path string true "match id"
+// @Param If-Match header string false "etag of the match the update is based on"
 // @Param body body matches.UpdateMatchRequestModel true "Request body"
 // @Success 200 {object} matches.MatchModel "OK"
+// @Header 200 {string} ETag "tag of the updated match representation"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
 // @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id}/leagues/{league_id}/matches/{match_id} [put]
@@ -183,9 +204,8 @@ func (h *handler) updateMatch(w http.ResponseWriter, r *http.Request) {
 	model.SeasonId = chi.URLParam(r, "season_id")
 	model.LeagueId = chi.URLParam(r, "league_id")
 	model.MatchId = chi.URLParam(r, "match_id")
-	model.PlayerOneId = ctx.Value(middleware.PlayerIdCtxKey).(string)
 
-	result, err := h.service.processUpdateMatch(ctx, model)
+	result, err := h.service.processUpdateMatch(ctx, params.NewIfMatch(r.Header), model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -200,23 +220,26 @@ func (h *handler) updateMatch(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
 
 // @Summary Score
-// @Description Submit a match score
+// @Description Submit a match score, by one of the players or by the ones managing the league's matches
 // @Tags matches
 // @Accept json
 // @Produce json
 // @Param season_id path string true "season id"
 // @Param league_id path string true "league id"
 // @Param match_id path string true "match id"
+// @Param If-Match header string false "etag of the match the change is based on"
 // @Param body body matches.SubmitMatchScoreRequestModel true "Request body"
 // @Success 200 {object} matches.MatchModel "OK"
+// @Header 200 {string} ETag "tag of the updated match representation"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
 // @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id}/leagues/{league_id}/matches/{match_id}/score [post]
@@ -236,7 +259,62 @@ func (h *handler) submitMatchScore(w http.ResponseWriter, r *http.Request) {
 	model.LeagueId = chi.URLParam(r, "league_id")
 	model.MatchId = chi.URLParam(r, "match_id")
 
-	result, err := h.service.processSubmitMatchScore(r.Context(), model)
+	result, err := h.service.processSubmitMatchScore(r.Context(), params.NewIfMatch(r.Header), model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteBodyTagged(w, http.StatusOK, result)
+}
+
+// @Summary Correct score
+// @Description Correct the score of a match, the player statistics and standings are recalculated
+// @Tags matches
+// @Accept json
+// @Produce json
+// @Param season_id path string true "season id"
+// @Param league_id path string true "league id"
+// @Param match_id path string true "match id"
+// @Param If-Match header string false "etag of the match the update is based on"
+// @Param body body matches.SubmitMatchScoreRequestModel true "Request body"
+// @Success 200 {object} matches.MatchModel "OK"
+// @Header 200 {string} ETag "tag of the updated match representation"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/seasons/{season_id}/leagues/{league_id}/matches/{match_id}/score [put]
+func (h *handler) correctMatchScore(w http.ResponseWriter, r *http.Request) {
+	var model SubmitMatchScoreRequestModel
+	if err := json.NewDecoder(r.Body).Decode(&model); err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	model.SeasonId = chi.URLParam(r, "season_id")
+	model.LeagueId = chi.URLParam(r, "league_id")
+	model.MatchId = chi.URLParam(r, "match_id")
+
+	result, err := h.service.processCorrectMatchScore(r.Context(), params.NewIfMatch(r.Header), model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -251,5 +329,5 @@ func (h *handler) submitMatchScore(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
@@ -0,0 +1,379 @@
+package matches
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortFields = map[string]func(a, b MatchModel) int{
+	"created_at": func(a, b MatchModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func playerName(t *memdb.Tables, playerId string) string {
+	return t.Accounts[t.Players[playerId].AccountId].Name
+}
+
+func toMatchModel(t *memdb.Tables, m db.Match) MatchModel {
+	mm := MatchModel{
+		Id:          m.Id,
+		Court:       CourtModel{Id: m.CourtId, Name: t.Courts[m.CourtId].Name},
+		ScheduledAt: m.ScheduledAt,
+		PlayerOne:   PlayerModel{Id: m.PlayerOneId, Name: playerName(t, m.PlayerOneId)},
+		PlayerTwo:   PlayerModel{Id: m.PlayerTwoId, Name: playerName(t, m.PlayerTwoId)},
+		Score:       memdb.StringPtr(m.Score),
+		Season:      SeasonModel{Id: m.SeasonId, Title: t.Seasons[m.SeasonId].Title},
+		League:      LeagueModel{Id: m.LeagueId, Title: t.Leagues[m.LeagueId].Title},
+		Version:     m.Version,
+		CreatedAt:   m.CreatedAt,
+	}
+	if m.WinnerId.Valid {
+		mm.Winner = &PlayerModel{Id: m.WinnerId.String, Name: playerName(t, m.WinnerId.String)}
+	}
+	return mm
+}
+
+// checkMatchRefs fails like the foreign keys of the match table
+func checkMatchRefs(t *memdb.Tables, m db.Match) error {
+	_, court := t.Courts[m.CourtId]
+	_, one := t.Players[m.PlayerOneId]
+	_, two := t.Players[m.PlayerTwoId]
+	_, season := t.Seasons[m.SeasonId]
+	_, league := t.Leagues[m.LeagueId]
+	winner := true
+	if m.WinnerId.Valid {
+		_, winner = t.Players[m.WinnerId.String]
+	}
+	if !court || !one || !two || !season || !league || !winner {
+		return fmt.Errorf("%w -> the match references a missing row", failure.ErrInternal)
+	}
+	return nil
+}
+
+func (s *memStore) insertMatch(ctx context.Context, tx db.Tx, courtId, scheduledAt, playerOneId, playerTwoId string, winnerId, score *string, seasonId, leagueId string) (MatchModel, error) {
+	var dest MatchModel
+
+	at, err := memdb.ParseTime(scheduledAt)
+	if err != nil {
+		return dest, failure.New("unable to insert match", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	err = s.db.Write(tx, func(t *memdb.Tables) error {
+		m := db.Match{
+			Id:          memdb.NewId(),
+			CourtId:     courtId,
+			ScheduledAt: at,
+			PlayerOneId: playerOneId,
+			PlayerTwoId: playerTwoId,
+			WinnerId:    memdb.NullString(winnerId),
+			Score:       memdb.NullString(score),
+			SeasonId:    seasonId,
+			LeagueId:    leagueId,
+			CreatorId:   playerOneId,
+			Version:     1,
+			CreatedAt:   s.db.Now(),
+		}
+		if err := checkMatchRefs(t, m); err != nil {
+			return err
+		}
+		t.Matches[m.Id] = m
+		dest = toMatchModel(t, m)
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("unable to insert match", err)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findMatches(ctx context.Context, seasonId, leagueId string, limit, offset int, sort *params.OrderBy) ([]MatchModel, error) {
+	dest := []MatchModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, m := range t.Matches {
+			if m.SeasonId == seasonId && m.LeagueId == leagueId {
+				dest = append(dest, toMatchModel(t, m))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find matches", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, sort, memSortFields, func(a, b MatchModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countMatches(ctx context.Context, seasonId, leagueId string) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, m := range t.Matches {
+			if m.SeasonId == seasonId && m.LeagueId == leagueId {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count matches", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findMatch(ctx context.Context, seasonId, leagueId, matchId string) (*MatchModel, error) {
+	var dest *MatchModel
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		if m, ok := t.Matches[matchId]; ok && m.SeasonId == seasonId && m.LeagueId == leagueId {
+			mm := toMatchModel(t, m)
+			dest = &mm
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find match", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("match not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+// writeMatch applies the change to the match of the season and league at the version, dest is nil
+// when there's no such match
+func (s *memStore) writeMatch(tx db.Tx, seasonId, leagueId, matchId string, version int, change func(m *db.Match)) (*MatchModel, error) {
+	var dest *MatchModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		m, ok := t.Matches[matchId]
+		if !ok || m.SeasonId != seasonId || m.LeagueId != leagueId || m.Version != version {
+			return nil
+		}
+		change(&m)
+		m.Version++
+		if err := checkMatchRefs(t, m); err != nil {
+			return err
+		}
+		t.Matches[matchId] = m
+		mm := toMatchModel(t, m)
+		dest = &mm
+		return nil
+	})
+	return dest, err
+}
+
+func (s *memStore) updateMatch(ctx context.Context, tx db.Tx, courtId, scheduledAt, playerTwoId, seasonId, leagueId, matchId string, version int) (*MatchModel, error) {
+	at, err := memdb.ParseTime(scheduledAt)
+	if err != nil {
+		return nil, failure.New("unable to update match", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	dest, err := s.writeMatch(tx, seasonId, leagueId, matchId, version, func(m *db.Match) {
+		m.CourtId = courtId
+		m.ScheduledAt = at
+		m.PlayerTwoId = playerTwoId
+	})
+	if err != nil {
+		return nil, failure.New("unable to update match", err)
+	}
+	if dest == nil {
+		return nil, failure.New("match was changed since it was read", failure.ErrStale)
+	}
+
+	return dest, nil
+}
+
+// addPlayerStatistics adds the played match to the players, a negative sign takes it back
+func (s *memStore) addPlayerStatistics(tx db.Tx, sign int, winnerId, playerOneId, playerTwoId string) error {
+	return s.db.Write(tx, func(t *memdb.Tables) error {
+		for _, id := range []string{playerOneId, playerTwoId} {
+			p, ok := t.Players[id]
+			if !ok {
+				continue
+			}
+			p.MatchesPlayed += sign
+			if id == winnerId {
+				p.MatchesWon += sign
+			}
+			p.Version++
+			t.Players[id] = p
+			// the same player twice is a single row
+			if playerOneId == playerTwoId {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func (s *memStore) updatePlayerStatistics(ctx context.Context, tx db.Tx, winnerId, playerOneId, playerTwoId string) error {
+	err := s.addPlayerStatistics(tx, 1, winnerId, playerOneId, playerTwoId)
+	if err != nil {
+		return failure.New("unable to update player statistics", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) incrementPlayerMatchesScheduled(ctx context.Context, tx db.Tx, playerId string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if p, ok := t.Players[playerId]; ok {
+			p.MatchesScheduled++
+			p.Version++
+			t.Players[playerId] = p
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to increment player matches scheduled", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func findStanding(t *memdb.Tables, seasonId, leagueId, playerId string) (db.Standing, bool) {
+	for _, st := range t.Standings {
+		if st.SeasonId == seasonId && st.LeagueId == leagueId && st.PlayerId == playerId {
+			return st, true
+		}
+	}
+	return db.Standing{}, false
+}
+
+func addMatchStats(st *db.Standing, sign int, plStats MatchStats) {
+	st.Points += sign * plStats.Pts
+	st.MatchesPlayed += sign
+	st.MatchesWon += sign * plStats.WonMatches
+	st.SetsWon += sign * plStats.SetsWon
+	st.SetsLost += sign * plStats.SetsLost
+	st.GamesWon += sign * plStats.GamesWon
+	st.GamesLost += sign * plStats.GamesLost
+}
+
+func (s *memStore) updateStanding(ctx context.Context, tx db.Tx, seasonId, leagueId, playerId string, plStats MatchStats) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		st, ok := findStanding(t, seasonId, leagueId, playerId)
+		if !ok {
+			_, season := t.Seasons[seasonId]
+			_, league := t.Leagues[leagueId]
+			_, player := t.Players[playerId]
+			if !season || !league || !player {
+				return fmt.Errorf("%w -> the standing references a missing row", failure.ErrInternal)
+			}
+			st = db.Standing{Id: memdb.NewId(), SeasonId: seasonId, LeagueId: leagueId, PlayerId: playerId, CreatedAt: s.db.Now()}
+		}
+		addMatchStats(&st, 1, plStats)
+		t.Standings[st.Id] = st
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to update standings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) revertPlayerStatistics(ctx context.Context, tx db.Tx, winnerId, playerOneId, playerTwoId string) error {
+	err := s.addPlayerStatistics(tx, -1, winnerId, playerOneId, playerTwoId)
+	if err != nil {
+		return failure.New("unable to revert player statistics", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) revertStanding(ctx context.Context, tx db.Tx, seasonId, leagueId, playerId string, plStats MatchStats) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if st, ok := findStanding(t, seasonId, leagueId, playerId); ok {
+			addMatchStats(&st, -1, plStats)
+			t.Standings[st.Id] = st
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to revert standings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) updateMatchScore(ctx context.Context, tx db.Tx, seasonId, leagueId, matchId, score, winnerId string, version int) (*MatchModel, error) {
+	dest, err := s.writeMatch(tx, seasonId, leagueId, matchId, version, func(m *db.Match) {
+		m.Score = memdb.NullString(&score)
+		m.WinnerId = memdb.NullString(&winnerId)
+	})
+	if err != nil {
+		return nil, failure.New("unable to update match score", err)
+	}
+	if dest == nil {
+		return nil, failure.New("match was changed since it was read", failure.ErrStale)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) checkMatchParticipation(ctx context.Context, matchId, playerId string) (bool, error) {
+	var exists bool
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		m, ok := t.Matches[matchId]
+		exists = ok && (m.PlayerOneId == playerId || m.PlayerTwoId == playerId)
+		return nil
+	})
+	if err != nil {
+		return false, failure.New("unable to check if player is a match participant", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return exists, nil
+}
+
+func (s *memStore) checkMatchOwnership(ctx context.Context, matchId, playerId string) (bool, error) {
+	var exists bool
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		m, ok := t.Matches[matchId]
+		exists = ok && m.CreatorId == playerId
+		return nil
+	})
+	if err != nil {
+		return false, failure.New("unable to check match ownership", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return exists, nil
+}
+
+func (s *memStore) checkMatchScore(ctx context.Context, matchId string) (bool, error) {
+	var found, hasScore bool
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		var m db.Match
+		m, found = t.Matches[matchId]
+		hasScore = m.Score.Valid
+		return nil
+	})
+	if err != nil {
+		return false, failure.New("unable to find match score", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return false, failure.New("checking match score - match not found", failure.ErrNotFound)
+	}
+
+	return hasScore, nil
+}
@@ -23,12 +23,32 @@ type MatchModel struct {
 	Score       *string      `json:"score"`
 	Season      SeasonModel  `json:"season"`
 	League      LeagueModel  `json:"league"`
+	Version     int          `json:"version"`
 	CreatedAt   time.Time    `json:"created_at"`
 }
 
+// auditPlayer is a player of the match without the name
+type auditPlayer struct {
+	Id string `json:"id"`
+}
+
+// AuditState is the match without the names of its players
+func (mm MatchModel) AuditState() any {
+	state := struct {
+		MatchModel
+		PlayerOne auditPlayer  `json:"player_one"`
+		PlayerTwo auditPlayer  `json:"player_two"`
+		Winner    *auditPlayer `json:"winner"`
+	}{MatchModel: mm, PlayerOne: auditPlayer{mm.PlayerOne.Id}, PlayerTwo: auditPlayer{mm.PlayerTwo.Id}}
+	if mm.Winner != nil {
+		state.Winner = &auditPlayer{mm.Winner.Id}
+	}
+	return state
+}
+
 func (mm *MatchModel) ScanRow(row pgx.Row) error {
 	var winnerId, winnerName sql.NullString
-	err := row.Scan(&mm.Id, &mm.Court.Id, &mm.Court.Name, &mm.ScheduledAt, &mm.PlayerOne.Id, &mm.PlayerOne.Name, &mm.PlayerTwo.Id, &mm.PlayerTwo.Name, &winnerId, &winnerName, &mm.Score, &mm.Season.Id, &mm.Season.Title, &mm.League.Id, &mm.League.Title, &mm.CreatedAt)
+	err := row.Scan(&mm.Id, &mm.Court.Id, &mm.Court.Name, &mm.ScheduledAt, &mm.PlayerOne.Id, &mm.PlayerOne.Name, &mm.PlayerTwo.Id, &mm.PlayerTwo.Name, &winnerId, &winnerName, &mm.Score, &mm.Season.Id, &mm.Season.Title, &mm.League.Id, &mm.League.Title, &mm.Version, &mm.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return failure.New("scanning match row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
@@ -50,7 +70,7 @@ func (mm *MatchModel) ScanRow(row pgx.Row) error {
 
 func (mm *MatchModel) ScanRows(rows pgx.Rows) error {
 	var winnerId, winnerName sql.NullString
-	err := rows.Scan(&mm.Id, &mm.Court.Id, &mm.Court.Name, &mm.ScheduledAt, &mm.PlayerOne.Id, &mm.PlayerOne.Name, &mm.PlayerTwo.Id, &mm.PlayerTwo.Name, &winnerId, &winnerName, &mm.Score, &mm.Season.Id, &mm.Season.Title, &mm.League.Id, &mm.League.Title, &mm.CreatedAt)
+	err := rows.Scan(&mm.Id, &mm.Court.Id, &mm.Court.Name, &mm.ScheduledAt, &mm.PlayerOne.Id, &mm.PlayerOne.Name, &mm.PlayerTwo.Id, &mm.PlayerTwo.Name, &winnerId, &winnerName, &mm.Score, &mm.Season.Id, &mm.Season.Title, &mm.League.Id, &mm.League.Title, &mm.Version, &mm.CreatedAt)
 	if err != nil {
 		return failure.New("database error scanning match rows", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -90,7 +110,7 @@ type LeagueModel struct {
 type CreateMatchRequestModel struct {
 	CourtId     string  `json:"court_id"`
 	ScheduledAt string  `json:"scheduled_at"`
-	PlayerOneId string  `json:"-"`
+	PlayerOneId string  `json:"player_one_id,omitempty"`
 	PlayerTwoId string  `json:"player_two_id"`
 	WinnerId    *string `json:"-"`
 	Score       *string `json:"score"`
@@ -121,6 +141,15 @@ func (m CreateMatchRequestModel) Validate() []failure.InvalidField {
 			Location: "body",
 		})
 	}
+	if m.PlayerOneId != "" {
+		if err := uuid.Validate(m.PlayerOneId); err != nil {
+			inv = append(inv, failure.InvalidField{
+				Field:    "player_one_id",
+				Message:  "Invalid uuid format",
+				Location: "body",
+			})
+		}
+	}
 	if m.PlayerTwoId == "" {
 		inv = append(inv, failure.InvalidField{
 			Field:    "player_two_id",
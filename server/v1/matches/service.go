@@ -4,24 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
 	"github.com/markovidakovic/gdsi/server/config"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/metrics"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/tracing"
 	"github.com/markovidakovic/gdsi/server/validation"
 )
 
 type service struct {
 	cfg       *config.Config
-	store     *store
+	store     Repository
 	validator *validation.Validator
 }
 
-func newService(cfg *config.Config, store *store, validator *validation.Validator) *service {
+func newService(cfg *config.Config, store Repository, validator *validation.Validator) *service {
 	return &service{
 		cfg,
 		store,
@@ -30,6 +33,9 @@ func newService(cfg *config.Config, store *store, validator *validation.Validato
 }
 
 func (s *service) processCreateMatch(ctx context.Context, model CreateMatchRequestModel) (*MatchModel, error) {
+	ctx, span := tracing.Start(ctx, "matches.processCreateMatch")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).
 		CourtExists(model.CourtId, "body").
 		SeasonExists(model.SeasonId, "path").
@@ -41,7 +47,7 @@ func (s *service) processCreateMatch(ctx context.Context, model CreateMatchReque
 		return nil, err
 	}
 
-	tx, err := s.store.db.Begin(ctx)
+	tx, err := s.store.begin(ctx)
 	if err != nil {
 		return nil, failure.New("unable to create a match", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -49,7 +55,7 @@ func (s *service) processCreateMatch(ctx context.Context, model CreateMatchReque
 	defer func() {
 		err := tx.Rollback(ctx)
 		if err != nil && err != pgx.ErrTxClosed {
-			log.Printf("failed to rollback the create match tx: %v", err)
+			slog.ErrorContext(ctx, "failed to rollback the create match tx", "error", err)
 		}
 	}()
 
@@ -91,15 +97,25 @@ func (s *service) processCreateMatch(ctx context.Context, model CreateMatchReque
 		return nil, failure.New("unable to create a match", err)
 	}
 
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.Match, ResourceId: match.Id, After: match})
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, failure.New("unable to create a match", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
+	metrics.MatchCreated()
+
 	return &match, nil
 }
 
 func (s *service) processGetMatches(ctx context.Context, seasonId, leagueId string, query *params.Query) ([]MatchModel, int, error) {
+	ctx, span := tracing.Start(ctx, "matches.processGetMatches")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).
 		SeasonExists(seasonId, "path").
 		LeagueExists(leagueId, "path").LeagueInSeason(seasonId, leagueId, "path").
@@ -124,6 +140,9 @@ func (s *service) processGetMatches(ctx context.Context, seasonId, leagueId stri
 }
 
 func (s *service) processGetMatch(ctx context.Context, seasonId, leagueId, matchId string) (*MatchModel, error) {
+	ctx, span := tracing.Start(ctx, "matches.processGetMatch")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).
 		SeasonExists(seasonId, "path").
 		LeagueExists(leagueId, "path").LeagueInSeason(seasonId, leagueId, "path").
@@ -140,8 +159,21 @@ func (s *service) processGetMatch(ctx context.Context, seasonId, leagueId, match
 	return mm, nil
 }
 
-func (s *service) processUpdateMatch(ctx context.Context, model UpdateMatchRequestModel) (*MatchModel, error) {
-	err := s.validator.NewValidation(ctx).
+func (s *service) processUpdateMatch(ctx context.Context, ifMatch params.IfMatch, model UpdateMatchRequestModel) (*MatchModel, error) {
+	ctx, span := tracing.Start(ctx, "matches.processUpdateMatch")
+	defer span.End()
+
+	// player one stays the same, the match may be updated by someone managing the league
+	match, err := s.store.findMatch(ctx, model.SeasonId, model.LeagueId, model.MatchId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ifMatch.CheckData("match", match); err != nil {
+		return nil, err
+	}
+	model.PlayerOneId = match.PlayerOne.Id
+
+	err = s.validator.NewValidation(ctx).
 		CourtExists(model.CourtId, "body").
 		SeasonExists(model.SeasonId, "path").
 		LeagueExists(model.LeagueId, "path").LeagueInSeason(model.SeasonId, model.LeagueId, "path").
@@ -165,15 +197,40 @@ func (s *service) processUpdateMatch(ctx context.Context, model UpdateMatchReque
 		return nil, failure.New("not able to modify a match that has a score", failure.ErrCantModify)
 	}
 
-	mm, err := s.store.updateMatch(ctx, nil, model.CourtId, model.ScheduledAt, model.PlayerTwoId, model.SeasonId, model.LeagueId, model.MatchId)
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update match", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the update match tx", "error", err)
+		}
+	}()
+
+	mm, err := s.store.updateMatch(ctx, tx, model.CourtId, model.ScheduledAt, model.PlayerTwoId, model.SeasonId, model.LeagueId, model.MatchId, match.Version)
 	if err != nil {
 		return nil, err
 	}
 
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUpdate, ResourceType: audit.Match, ResourceId: model.MatchId, Before: match, After: mm})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update match", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
 	return mm, nil
 }
 
-func (s *service) processSubmitMatchScore(ctx context.Context, model SubmitMatchScoreRequestModel) (*MatchModel, error) {
+func (s *service) processSubmitMatchScore(ctx context.Context, ifMatch params.IfMatch, model SubmitMatchScoreRequestModel) (*MatchModel, error) {
+	ctx, span := tracing.Start(ctx, "matches.processSubmitMatchScore")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).
 		SeasonExists(model.SeasonId, "path").
 		LeagueExists(model.LeagueId, "path").LeagueInSeason(model.SeasonId, model.LeagueId, "path").
@@ -186,6 +243,9 @@ func (s *service) processSubmitMatchScore(ctx context.Context, model SubmitMatch
 	if err != nil {
 		return nil, err
 	}
+	if err := ifMatch.CheckData("match", match); err != nil {
+		return nil, err
+	}
 
 	// check if able to submit result
 	if match.Score != nil {
@@ -196,7 +256,7 @@ func (s *service) processSubmitMatchScore(ctx context.Context, model SubmitMatch
 	model.PlayerTwoId = match.PlayerTwo.Id
 	model.WinnerId = determineMatchWinner(model.Score, match.PlayerOne.Id, match.PlayerTwo.Id)
 
-	tx, err := s.store.db.Begin(ctx)
+	tx, err := s.store.begin(ctx)
 	if err != nil {
 		return nil, failure.New("not able to submit match score", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -204,12 +264,16 @@ func (s *service) processSubmitMatchScore(ctx context.Context, model SubmitMatch
 	defer func() {
 		err := tx.Rollback(ctx)
 		if err != nil && err != pgx.ErrTxClosed {
-			log.Printf("failed to rollback the submit match score tx: %v", err)
+			slog.ErrorContext(ctx, "failed to rollback the submit match score tx", "error", err)
 		}
 	}()
 
-	result, err := s.store.updateMatchScore(ctx, tx, model.SeasonId, model.LeagueId, model.MatchId, model.Score, model.WinnerId)
+	// the version keeps a concurrent reschedule or a second submission from slipping in between
+	result, err := s.store.updateMatchScore(ctx, tx, model.SeasonId, model.LeagueId, model.MatchId, model.Score, model.WinnerId, match.Version)
 	if err != nil {
+		if errors.Is(err, failure.ErrStale) {
+			return nil, err
+		}
 		// if we made it this far, the match exists and this error will be an internal error, so we format the message accordingly
 		return nil, failure.New("not able to submit match score", err)
 	}
@@ -233,11 +297,114 @@ func (s *service) processSubmitMatchScore(ctx context.Context, model SubmitMatch
 		return nil, failure.New("not able to submit match score", err)
 	}
 
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionSubmitScore, ResourceType: audit.Match, ResourceId: model.MatchId, Before: match, After: result})
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, failure.New("not able to submit match score", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
+	metrics.ScoreSubmitted()
+
+	return result, nil
+}
+
+// processCorrectMatchScore replaces the score of a match. The statistics and standings of the previous
+// score are reverted before the corrected score is counted, all in the same tx
+func (s *service) processCorrectMatchScore(ctx context.Context, ifMatch params.IfMatch, model SubmitMatchScoreRequestModel) (*MatchModel, error) {
+	ctx, span := tracing.Start(ctx, "matches.processCorrectMatchScore")
+	defer span.End()
+
+	err := s.validator.NewValidation(ctx).
+		SeasonExists(model.SeasonId, "path").
+		LeagueExists(model.LeagueId, "path").LeagueInSeason(model.SeasonId, model.LeagueId, "path").
+		Result()
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := s.store.findMatch(ctx, model.SeasonId, model.LeagueId, model.MatchId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ifMatch.CheckData("match", match); err != nil {
+		return nil, err
+	}
+
+	if match.Score == nil || match.Winner == nil {
+		return nil, failure.New("not able to correct match score, the match has no score", failure.ErrCantModify)
+	}
+
+	model.PlayerOneId = match.PlayerOne.Id
+	model.PlayerTwoId = match.PlayerTwo.Id
+	model.WinnerId = determineMatchWinner(model.Score, match.PlayerOne.Id, match.PlayerTwo.Id)
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("not able to correct match score", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the correct match score tx", "error", err)
+		}
+	}()
+
+	// revert the previous score
+	err = s.store.revertPlayerStatistics(ctx, tx, match.Winner.Id, model.PlayerOneId, model.PlayerTwoId)
+	if err != nil {
+		return nil, failure.New("not able to correct match score", err)
+	}
+
+	err = s.store.revertStanding(ctx, tx, model.SeasonId, model.LeagueId, model.PlayerOneId, calcMatchStats(*match.Score, true))
+	if err != nil {
+		return nil, failure.New("not able to correct match score", err)
+	}
+
+	err = s.store.revertStanding(ctx, tx, model.SeasonId, model.LeagueId, model.PlayerTwoId, calcMatchStats(*match.Score, false))
+	if err != nil {
+		return nil, failure.New("not able to correct match score", err)
+	}
+
+	// count the corrected one
+	result, err := s.store.updateMatchScore(ctx, tx, model.SeasonId, model.LeagueId, model.MatchId, model.Score, model.WinnerId, match.Version)
+	if err != nil {
+		if errors.Is(err, failure.ErrStale) {
+			return nil, err
+		}
+		return nil, failure.New("not able to correct match score", err)
+	}
+
+	err = s.store.updatePlayerStatistics(ctx, tx, model.WinnerId, model.PlayerOneId, model.PlayerTwoId)
+	if err != nil {
+		return nil, failure.New("not able to correct match score", err)
+	}
+
+	err = s.store.updateStanding(ctx, tx, model.SeasonId, model.LeagueId, model.PlayerOneId, calcMatchStats(model.Score, true))
+	if err != nil {
+		return nil, failure.New("not able to correct match score", err)
+	}
+
+	err = s.store.updateStanding(ctx, tx, model.SeasonId, model.LeagueId, model.PlayerTwoId, calcMatchStats(model.Score, false))
+	if err != nil {
+		return nil, failure.New("not able to correct match score", err)
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCorrectScore, ResourceType: audit.Match, ResourceId: model.MatchId, Before: match, After: result})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("not able to correct match score", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
 	return result, nil
 }
 
@@ -12,21 +12,47 @@ import (
 	"github.com/markovidakovic/gdsi/server/params"
 )
 
+// Repository holds the matches of a league and updates the player statistics and standings
+// their results change
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	insertMatch(ctx context.Context, tx db.Tx, courtId, scheduledAt, playerOneId, playerTwoId string, winnerId, score *string, seasonId, leagueId string) (MatchModel, error)
+	findMatches(ctx context.Context, seasonId, leagueId string, limit, offset int, sort *params.OrderBy) ([]MatchModel, error)
+	countMatches(ctx context.Context, seasonId, leagueId string) (int, error)
+	findMatch(ctx context.Context, seasonId, leagueId, matchId string) (*MatchModel, error)
+	updateMatch(ctx context.Context, tx db.Tx, courtId, scheduledAt, playerTwoId, seasonId, leagueId, matchId string, version int) (*MatchModel, error)
+	updatePlayerStatistics(ctx context.Context, tx db.Tx, winnerId, playerOneId, playerTwoId string) error
+	incrementPlayerMatchesScheduled(ctx context.Context, tx db.Tx, playerId string) error
+	updateStanding(ctx context.Context, tx db.Tx, seasonId, leagueId, playerId string, plStats MatchStats) error
+	revertPlayerStatistics(ctx context.Context, tx db.Tx, winnerId, playerOneId, playerTwoId string) error
+	revertStanding(ctx context.Context, tx db.Tx, seasonId, leagueId, playerId string, plStats MatchStats) error
+	updateMatchScore(ctx context.Context, tx db.Tx, seasonId, leagueId, matchId, score, winnerId string, version int) (*MatchModel, error)
+	checkMatchParticipation(ctx context.Context, matchId, playerId string) (bool, error)
+	checkMatchOwnership(ctx context.Context, matchId, playerId string) (bool, error)
+	checkMatchScore(ctx context.Context, matchId string) (bool, error)
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
 	return &store{
 		db,
 	}
 }
 
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
 var allowedSortFields = map[string]string{
 	"created_at": "match.created_at",
 }
 
-func (s *store) insertMatch(ctx context.Context, tx pgx.Tx, courtId, scheduledAt, playerOneId, playerTwoId string, winnerId, score *string, seasonId, leagueId string) (MatchModel, error) {
+func (s *store) insertMatch(ctx context.Context, tx db.Tx, courtId, scheduledAt, playerOneId, playerTwoId string, winnerId, score *string, seasonId, leagueId string) (MatchModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -38,7 +64,7 @@ func (s *store) insertMatch(ctx context.Context, tx pgx.Tx, courtId, scheduledAt
 		with inserted_match as (
 			insert into match (court_id, scheduled_at, player_one_id, player_two_id, winner_id, score, season_id, league_id, creator_id)
 			values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-			returning id, court_id, scheduled_at, player_one_id, player_two_id, winner_id, score, season_id, league_id, created_at
+			returning id, court_id, scheduled_at, player_one_id, player_two_id, winner_id, score, season_id, league_id, version, created_at
 		)
 		select
 			im.id,
@@ -56,6 +82,7 @@ func (s *store) insertMatch(ctx context.Context, tx pgx.Tx, courtId, scheduledAt
 			season.title as season_title,
 			league.id as league_id,
 			league.title as league_title,
+			im.version,
 			im.created_at
 		from inserted_match im
 		join court on im.court_id = court.id
@@ -98,6 +125,7 @@ func (s *store) findMatches(ctx context.Context, seasonId, leagueId string, limi
 			season.title as season_title,
 			league.id as league_id,
 			league.title as league_title,
+			match.version,
 			match.created_at
 		from match
 		join court on match.court_id = court.id
@@ -178,6 +206,7 @@ func (s *store) findMatch(ctx context.Context, seasonId, leagueId, matchId strin
 			season.title as season_title,
 			league.id as league_id,
 			league.title as league_title,
+			match.version,
 			match.created_at
 		from match
 		join court on match.court_id = court.id
@@ -206,7 +235,8 @@ func (s *store) findMatch(ctx context.Context, seasonId, leagueId, matchId strin
 	return &dest, nil
 }
 
-func (s *store) updateMatch(ctx context.Context, tx pgx.Tx, courtId, scheduledAt, playerTwoId, seasonId, leagueId, matchId string) (*MatchModel, error) {
+// updateMatch reschedules the match if it's still at the version, it fails with ErrStale otherwise
+func (s *store) updateMatch(ctx context.Context, tx db.Tx, courtId, scheduledAt, playerTwoId, seasonId, leagueId, matchId string, version int) (*MatchModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -218,8 +248,8 @@ func (s *store) updateMatch(ctx context.Context, tx pgx.Tx, courtId, scheduledAt
 		with updated_match as (
 			update match 
 			set court_id = $1, scheduled_at = $2, player_two_id = $3
-			where id = $4 and season_id = $5 and league_id = $6
-			returning id, court_id, scheduled_at, player_one_id, player_two_id, winner_id, score, season_id, league_id, created_at
+			where id = $4 and season_id = $5 and league_id = $6 and version = $7
+			returning id, court_id, scheduled_at, player_one_id, player_two_id, winner_id, score, season_id, league_id, version, created_at
 		)
 		select
 			um.id,
@@ -237,6 +267,7 @@ func (s *store) updateMatch(ctx context.Context, tx pgx.Tx, courtId, scheduledAt
 			season.title as season_title,
 			league.id as league_id,
 			league.title as league_title,
+			um.version,
 			um.created_at
 		from updated_match um
 		join court on um.court_id = court.id
@@ -252,11 +283,11 @@ func (s *store) updateMatch(ctx context.Context, tx pgx.Tx, courtId, scheduledAt
 
 	var dest MatchModel
 
-	row := q.QueryRow(ctx, sql, courtId, scheduledAt, playerTwoId, matchId, seasonId, leagueId)
+	row := q.QueryRow(ctx, sql, courtId, scheduledAt, playerTwoId, matchId, seasonId, leagueId, version)
 	err := dest.ScanRow(row)
 	if err != nil {
 		if errors.Is(err, failure.ErrNotFound) {
-			return nil, failure.New("match for update not found", err)
+			return nil, failure.New("match was changed since it was read", fmt.Errorf("%w -> %v", failure.ErrStale, err))
 		}
 		return nil, failure.New("unable to update match", err)
 	}
@@ -264,7 +295,7 @@ func (s *store) updateMatch(ctx context.Context, tx pgx.Tx, courtId, scheduledAt
 	return &dest, nil
 }
 
-func (s *store) updatePlayerStatistics(ctx context.Context, tx pgx.Tx, winnerId, playerOneId, playerTwoId string) error {
+func (s *store) updatePlayerStatistics(ctx context.Context, tx db.Tx, winnerId, playerOneId, playerTwoId string) error {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -291,7 +322,7 @@ func (s *store) updatePlayerStatistics(ctx context.Context, tx pgx.Tx, winnerId,
 	return nil
 }
 
-func (s *store) incrementPlayerMatchesScheduled(ctx context.Context, tx pgx.Tx, playerId string) error {
+func (s *store) incrementPlayerMatchesScheduled(ctx context.Context, tx db.Tx, playerId string) error {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -317,7 +348,7 @@ func (s *store) incrementPlayerMatchesScheduled(ctx context.Context, tx pgx.Tx,
 	return nil
 }
 
-func (s *store) updateStanding(ctx context.Context, tx pgx.Tx, seasonId, leagueId, playerId string, plStats MatchStats) error {
+func (s *store) updateStanding(ctx context.Context, tx db.Tx, seasonId, leagueId, playerId string, plStats MatchStats) error {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -347,7 +378,63 @@ func (s *store) updateStanding(ctx context.Context, tx pgx.Tx, seasonId, leagueI
 	return nil
 }
 
-func (s *store) updateMatchScore(ctx context.Context, tx pgx.Tx, seasonId, leagueId, matchId, score, winnerId string) (*MatchModel, error) {
+// revertPlayerStatistics takes back the statistics counted by updatePlayerStatistics
+func (s *store) revertPlayerStatistics(ctx context.Context, tx db.Tx, winnerId, playerOneId, playerTwoId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update player
+		set 
+			matches_played = matches_played - 1,
+			matches_won = matches_won - case when id = $1 then 1 else 0 end
+		where id in ($2, $3)
+	`
+
+	_, err := q.Exec(ctx, sql, winnerId, playerOneId, playerTwoId)
+	if err != nil {
+		return failure.New("unable to revert player statistics", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// revertStanding takes back the match stats counted by updateStanding
+func (s *store) revertStanding(ctx context.Context, tx db.Tx, seasonId, leagueId, playerId string, plStats MatchStats) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update standing
+		set
+			points = points - $1,
+			matches_played = matches_played - 1,
+			matches_won = matches_won - $2,
+			sets_won = sets_won - $3,
+			sets_lost = sets_lost - $4,
+			games_won = games_won - $5,
+			games_lost = games_lost - $6
+		where season_id = $7 and league_id = $8 and player_id = $9
+	`
+
+	_, err := q.Exec(ctx, sql, plStats.Pts, plStats.WonMatches, plStats.SetsWon, plStats.SetsLost, plStats.GamesWon, plStats.GamesLost, seasonId, leagueId, playerId)
+	if err != nil {
+		return failure.New("unable to revert standings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// updateMatchScore sets the score if the match is still at the version, it fails with ErrStale otherwise
+func (s *store) updateMatchScore(ctx context.Context, tx db.Tx, seasonId, leagueId, matchId, score, winnerId string, version int) (*MatchModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -359,8 +446,8 @@ func (s *store) updateMatchScore(ctx context.Context, tx pgx.Tx, seasonId, leagu
 		with updated_match as (
 			update match 
 			set score = $1, winner_id = $2
-			where id = $3 and season_id = $4 and league_id = $5
-			returning id, court_id, scheduled_at, player_one_id, player_two_id, winner_id, score, season_id, league_id, created_at
+			where id = $3 and season_id = $4 and league_id = $5 and version = $6
+			returning id, court_id, scheduled_at, player_one_id, player_two_id, winner_id, score, season_id, league_id, version, created_at
 		)
 		select
 			um.id,
@@ -378,6 +465,7 @@ func (s *store) updateMatchScore(ctx context.Context, tx pgx.Tx, seasonId, leagu
 			season.title as season_title,
 			league.id as league_id,
 			league.title as league_title,
+			um.version,
 			um.created_at
 		from updated_match um
 		join court on um.court_id = court.id
@@ -393,11 +481,11 @@ func (s *store) updateMatchScore(ctx context.Context, tx pgx.Tx, seasonId, leagu
 
 	var dest MatchModel
 
-	row := q.QueryRow(ctx, sql, score, winnerId, matchId, seasonId, leagueId)
+	row := q.QueryRow(ctx, sql, score, winnerId, matchId, seasonId, leagueId, version)
 	err := dest.ScanRow(row)
 	if err != nil {
 		if errors.Is(err, failure.ErrNotFound) {
-			return nil, failure.New("match for updating score not found", err)
+			return nil, failure.New("match was changed since it was read", fmt.Errorf("%w -> %v", failure.ErrStale, err))
 		}
 		return nil, failure.New("unable to update match score", err)
 	}
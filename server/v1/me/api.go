@@ -3,8 +3,9 @@ package me
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/mail"
 	"github.com/markovidakovic/gdsi/server/router"
+	"github.com/markovidakovic/gdsi/server/session"
 )
 
 type api struct {
@@ -13,13 +14,24 @@ type api struct {
 
 var _ router.Mounter = (*api)(nil)
 
-func New(cfg *config.Config, db *db.Conn) *api {
+func New(cfg *config.Config, repo Repository, mailer mail.Sender, sessions *session.Cache) *api {
 	return &api{
-		hdl: newHandler(cfg, db),
+		hdl: newHandler(cfg, repo, mailer, sessions),
 	}
 }
 
 func (a *api) Mount(r chi.Router) {
 	r.Get("/", a.hdl.getMe)
 	r.Put("/", a.hdl.updateMe)
+	r.Delete("/", a.hdl.deleteMe)
+	r.Get("/export", a.hdl.exportMe)
+	r.Put("/privacy", a.hdl.updatePrivacy)
+	r.Put("/password", a.hdl.updatePassword)
+	r.Post("/email", a.hdl.requestEmailChange)
+	r.Post("/email/confirm", a.hdl.confirmEmailChange)
+	r.Get("/2fa", a.hdl.getTwoFactor)
+	r.Post("/2fa/enroll", a.hdl.enrollTwoFactor)
+	r.Post("/2fa/enable", a.hdl.enableTwoFactor)
+	r.Post("/2fa/recovery-codes", a.hdl.regenerateRecoveryCodes)
+	r.Post("/2fa/disable", a.hdl.disableTwoFactor)
 }
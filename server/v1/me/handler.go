@@ -2,25 +2,29 @@ package me
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/mail"
 	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/params"
 	"github.com/markovidakovic/gdsi/server/response"
+	"github.com/markovidakovic/gdsi/server/session"
 )
 
 type handler struct {
 	service *service
-	store   *store
+	store   Repository
 }
 
-func newHandler(cfg *config.Config, db *db.Conn) *handler {
+func newHandler(cfg *config.Config, repo Repository, mailer mail.Sender, sessions *session.Cache) *handler {
 	h := &handler{}
-	h.store = newStore(db)
-	h.service = newService(cfg, h.store)
+	h.store = repo
+	h.service = newService(cfg, h.store, mailer, sessions)
 	return h
 }
 
@@ -29,6 +33,7 @@ func newHandler(cfg *config.Config, db *db.Conn) *handler {
 // @Tags me
 // @Produce json
 // @Success 200 {object} me.MeModel "OK"
+// @Header 200 {string} ETag "tag of the account representation"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
@@ -36,7 +41,7 @@ func newHandler(cfg *config.Config, db *db.Conn) *handler {
 func (h *handler) getMe(w http.ResponseWriter, r *http.Request) {
 	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
 
-	result, err := h.store.findMe(r.Context(), accountId)
+	result, err := h.store.findMe(r.Context(), nil, accountId)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -51,7 +56,7 @@ func (h *handler) getMe(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
 
 // @Summary Update
@@ -60,10 +65,13 @@ func (h *handler) getMe(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param body body me.UpdateMeRequestModel true "Request body"
+// @Param If-Match header string false "etag of the account the update is based on"
 // @Success 200 {object} me.MeModel "OK"
+// @Header 200 {string} ETag "tag of the updated account representation"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/me [put]
@@ -82,7 +90,466 @@ func (h *handler) updateMe(w http.ResponseWriter, r *http.Request) {
 
 	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
 
-	result, err := h.store.updateMe(r.Context(), nil, accountId, model)
+	result, err := h.service.processUpdateMe(r.Context(), accountId, params.NewIfMatch(r.Header), model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteBodyTagged(w, http.StatusOK, result)
+}
+
+// @Summary Update password
+// @Description Update my password. All other sessions get logged out and a new token pair is returned
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param body body me.UpdatePasswordRequestModel true "Request body"
+// @Success 200 {object} me.UpdatePasswordResponseModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/password [put]
+func (h *handler) updatePassword(w http.ResponseWriter, r *http.Request) {
+	var model UpdatePasswordRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	amr, _ := r.Context().Value(middleware.AuthMethodsCtxKey).([]string)
+
+	result, err := h.service.processUpdatePassword(r.Context(), accountId, amr, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Request email change
+// @Description Request an email change. A confirmation token is sent to the new email address
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param body body me.RequestEmailChangeRequestModel true "Request body"
+// @Success 202 {object} me.EmailChangeResponseModel "Accepted"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/email [post]
+func (h *handler) requestEmailChange(w http.ResponseWriter, r *http.Request) {
+	var model RequestEmailChangeRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processRequestEmailChange(r.Context(), accountId, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusAccepted, result)
+}
+
+// @Summary Confirm email change
+// @Description Confirm a requested email change with the token sent to the new email address
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param body body me.ConfirmEmailChangeRequestModel true "Request body"
+// @Success 200 {object} me.MeModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/email/confirm [post]
+func (h *handler) confirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var model ConfirmEmailChangeRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processConfirmEmailChange(r.Context(), accountId, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Get two-factor status
+// @Description Get the two-factor authentication status of my account
+// @Tags me
+// @Produce json
+// @Success 200 {object} me.TwoFactorStatusModel "OK"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/2fa [get]
+func (h *handler) getTwoFactor(w http.ResponseWriter, r *http.Request) {
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	role := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	result, err := h.service.processGetTwoFactor(r.Context(), accountId, role)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Enroll two-factor
+// @Description Generate a new totp secret. The enrollment has to be confirmed with a code before it's enabled
+// @Tags me
+// @Produce json
+// @Success 200 {object} me.TwoFactorEnrollmentModel "OK"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/2fa/enroll [post]
+func (h *handler) enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processEnrollTwoFactor(r.Context(), accountId)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Enable two-factor
+// @Description Confirm the two-factor enrollment with a code from the authenticator app. The recovery codes are returned only once
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param body body me.TwoFactorCodeRequestModel true "Request body"
+// @Success 200 {object} me.RecoveryCodesResponseModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/2fa/enable [post]
+func (h *handler) enableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var model TwoFactorCodeRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processEnableTwoFactor(r.Context(), accountId, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Regenerate recovery codes
+// @Description Replace the two-factor recovery codes, the previous codes stop working
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param body body me.TwoFactorCodeRequestModel true "Request body"
+// @Success 200 {object} me.RecoveryCodesResponseModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/2fa/recovery-codes [post]
+func (h *handler) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var model TwoFactorCodeRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processRegenerateRecoveryCodes(r.Context(), accountId, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Disable two-factor
+// @Description Disable two-factor authentication. Not allowed for roles where two-factor is mandatory
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param body body me.DisableTwoFactorRequestModel true "Request body"
+// @Success 204 "No content"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/2fa/disable [post]
+func (h *handler) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var model DisableTwoFactorRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	role := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	err = h.service.processDisableTwoFactor(r.Context(), accountId, role, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
+
+// @Summary Export
+// @Description Download all my personal data (account, player, matches, standings, sessions) as a json archive
+// @Tags me
+// @Produce json
+// @Success 200 {object} me.ExportModel "OK"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/export [get]
+func (h *handler) exportMe(w http.ResponseWriter, r *http.Request) {
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processExportMe(r.Context(), accountId)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gdsi-export-%s.json"`, result.ExportedAt.Format("2006-01-02")))
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Delete
+// @Description Delete my account. The personal data is erased and the account can't be signed in to anymore, the played matches stay in the league history
+// @Tags me
+// @Accept json
+// @Param body body me.DeleteMeRequestModel false "Request body, the password of the accounts which have one"
+// @Success 204 "No content"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me [delete]
+func (h *handler) deleteMe(w http.ResponseWriter, r *http.Request) {
+	var model DeleteMeRequestModel
+	// the accounts without a password have nothing to send
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil && !errors.Is(err, io.EOF) {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	err = h.service.processDeleteMe(r.Context(), accountId, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
+
+// @Summary Update privacy
+// @Description Set who can see my phone number, email, date of birth and physique: everyone, league mates, opponents with a scheduled match or nobody
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param body body me.UpdatePrivacyRequestModel true "Request body"
+// @Success 200 {object} me.MeModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/me/privacy [put]
+func (h *handler) updatePrivacy(w http.ResponseWriter, r *http.Request) {
+	var model UpdatePrivacyRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processUpdatePrivacy(r.Context(), accountId, model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -0,0 +1,704 @@
+package me
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/privacy"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toMeModel(t *memdb.Tables, a db.Account) MeModel {
+	var mm MeModel
+	mm.Id = a.Id
+	mm.Name = a.Name
+	mm.Email = a.Email
+	mm.Dob = memdb.TimePtr(a.Dob)
+	mm.Gender = memdb.StringPtr(a.Gender)
+	mm.PhoneNumber = memdb.StringPtr(a.PhoneNumber)
+	mm.Role = a.Role
+	mm.ApprovedAt = memdb.TimePtr(a.ApprovedAt)
+	mm.CreatedAt = a.CreatedAt
+	if p, ok := t.PlayerOf(a.Id); ok {
+		mm.Player = PlayerModel{
+			Id:               p.Id,
+			Height:           memdb.Float64Ptr(p.Height),
+			Weight:           memdb.Float64Ptr(p.Weight),
+			Handedness:       memdb.StringPtr(p.Handedness),
+			Racket:           memdb.StringPtr(p.Racket),
+			MatchesExpected:  p.MatchesExpected,
+			MatchesPlayed:    p.MatchesPlayed,
+			MatchesWon:       p.MatchesWon,
+			MatchesScheduled: p.MatchesScheduled,
+			SeasonsPlayed:    p.SeasonsPlayed,
+			Privacy: privacy.Settings{
+				PhoneNumber: privacy.Visibility(p.PrivacyPhone),
+				Email:       privacy.Visibility(p.PrivacyEmail),
+				Dob:         privacy.Visibility(p.PrivacyDob),
+				Physique:    privacy.Visibility(p.PrivacyPhysique),
+			},
+			CreatedAt: p.CreatedAt,
+		}
+		if l, ok := t.Leagues[p.CurrentLeagueId.String]; ok && p.CurrentLeagueId.Valid {
+			mm.Player.CurrentLeague = &CurrentLeagueModel{Id: l.Id, Title: l.Title}
+		}
+	}
+	return mm
+}
+
+func (s *memStore) findMe(ctx context.Context, tx db.Tx, accountId string) (*MeModel, error) {
+	var dest *MeModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		if a, ok := t.Accounts[accountId]; ok {
+			mm := toMeModel(t, a)
+			dest = &mm
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("account not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+// updateAccount applies fn to the account and reports if it exists
+func (s *memStore) updateAccount(tx db.Tx, accountId string, fn func(t *memdb.Tables, a *db.Account) error) (bool, error) {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		a, ok := t.Accounts[accountId]
+		if !ok {
+			return nil
+		}
+		if err := fn(t, &a); err != nil {
+			return err
+		}
+		t.Accounts[accountId] = a
+		found = true
+		return nil
+	})
+	return found, err
+}
+
+func (s *memStore) updateMe(ctx context.Context, tx db.Tx, accountId string, model UpdateMeRequestModel) (*MeModel, error) {
+	var dest MeModel
+	found, err := s.updateAccount(tx, accountId, func(t *memdb.Tables, a *db.Account) error {
+		a.Name = model.Name
+		dest = toMeModel(t, *a)
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to update account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return nil, failure.New("account for updating not found", failure.ErrNotFound)
+	}
+
+	return &dest, nil
+}
+
+func (s *memStore) updatePrivacy(ctx context.Context, tx db.Tx, accountId string, settings privacy.Settings) error {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		p, ok := t.PlayerOf(accountId)
+		if !ok {
+			return nil
+		}
+		p.PrivacyPhone = string(settings.PhoneNumber)
+		p.PrivacyEmail = string(settings.Email)
+		p.PrivacyDob = string(settings.Dob)
+		p.PrivacyPhysique = string(settings.Physique)
+		p.Version++
+		t.Players[p.Id] = p
+		found = true
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to update privacy settings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("player not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *memStore) findCredentials(ctx context.Context, tx db.Tx, accountId string) (*CredentialsModel, error) {
+	var dest *CredentialsModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		a, ok := t.Accounts[accountId]
+		if !ok {
+			return nil
+		}
+		// the accounts without a player aren't joined
+		p, ok := t.PlayerOf(accountId)
+		if !ok {
+			return nil
+		}
+		dest = &CredentialsModel{Id: a.Id, Email: a.Email, Password: a.Password, PasswordUnusable: a.PasswordUnusable, Role: a.Role, PlayerId: p.Id}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find account credentials", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("account not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) updatePassword(ctx context.Context, tx db.Tx, accountId, password string) error {
+	found, err := s.updateAccount(tx, accountId, func(t *memdb.Tables, a *db.Account) error {
+		a.Password = password
+		a.PasswordUnusable = false
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to update account password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("account for updating password not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *memStore) updateEmail(ctx context.Context, tx db.Tx, accountId, email string) error {
+	found, err := s.updateAccount(tx, accountId, func(t *memdb.Tables, a *db.Account) error {
+		if other, ok := t.AccountByEmail(email); ok && other.Id != accountId {
+			return memdb.ErrUnique
+		}
+		a.Email = email
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, memdb.ErrUnique) {
+			return failure.New("email already registered", fmt.Errorf("%w -> %v", failure.ErrDuplicate, err))
+		}
+		return failure.New("unable to update account email", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("account for updating email not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *memStore) checkEmailExists(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		_, exists = t.AccountByEmail(email)
+		return nil
+	})
+	if err != nil {
+		return false, failure.New("unable to check email existance", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return exists, nil
+}
+
+func (s *memStore) bumpTokenVersion(ctx context.Context, tx db.Tx, accountId string) (int, error) {
+	var version int
+	found, err := s.updateAccount(tx, accountId, func(t *memdb.Tables, a *db.Account) error {
+		a.TokenVersion++
+		version = a.TokenVersion
+		return nil
+	})
+	if err == nil && !found {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		return 0, failure.New("unable to revoke account tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return version, nil
+}
+
+func (s *memStore) revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		for id, rt := range t.RefreshTokens {
+			if rt.AccountId == accountId && !rt.IsRevoked {
+				rt.IsRevoked = true
+				t.RefreshTokens[id] = rt
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to revoke account refresh tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) insertRefreshToken(ctx context.Context, tx db.Tx, accountId string, token string, issuedAt, expiresAt time.Time, amr []string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Accounts[accountId]; !ok {
+			return memdb.ErrForeignKey
+		}
+		rt := db.RefreshToken{
+			Id:        memdb.NewId(),
+			AccountId: accountId,
+			TokenHash: token,
+			IssuedAt:  issuedAt,
+			ExpiresAt: expiresAt,
+			Amr:       amr,
+		}
+		t.RefreshTokens[rt.Id] = rt
+		return nil
+	})
+	if err != nil {
+		return failure.New("failed to insert refresh token", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) deletePendingEmailChanges(ctx context.Context, tx db.Tx, accountId string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		maps.DeleteFunc(t.EmailChanges, func(_ string, ec db.EmailChange) bool {
+			return ec.AccountId == accountId && !ec.ConfirmedAt.Valid
+		})
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to delete pending email changes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) insertEmailChange(ctx context.Context, tx db.Tx, accountId, newEmail, tokenHash string, expiresAt time.Time) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Accounts[accountId]; !ok {
+			return memdb.ErrForeignKey
+		}
+		ec := db.EmailChange{
+			Id:        memdb.NewId(),
+			AccountId: accountId,
+			NewEmail:  newEmail,
+			TokenHash: tokenHash,
+			ExpiresAt: expiresAt,
+			CreatedAt: s.db.Now(),
+		}
+		t.EmailChanges[ec.Id] = ec
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to insert email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) findEmailChange(ctx context.Context, tx db.Tx, accountId, tokenHash string) (*EmailChangeModel, error) {
+	var dest *EmailChangeModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		for _, ec := range t.EmailChanges {
+			if ec.AccountId == accountId && ec.TokenHash == tokenHash {
+				dest = &EmailChangeModel{
+					Id:          ec.Id,
+					AccountId:   ec.AccountId,
+					NewEmail:    ec.NewEmail,
+					ExpiresAt:   ec.ExpiresAt,
+					ConfirmedAt: memdb.TimePtr(ec.ConfirmedAt),
+				}
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("email change not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) confirmEmailChange(ctx context.Context, tx db.Tx, emailChangeId string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if ec, ok := t.EmailChanges[emailChangeId]; ok {
+			ec.ConfirmedAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+			t.EmailChanges[emailChangeId] = ec
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to confirm email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) findTOTP(ctx context.Context, tx db.Tx, accountId string) (*TOTPModel, error) {
+	var dest *TOTPModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		totp, ok := t.AccountTotps[accountId]
+		if !ok {
+			return nil
+		}
+		dest = &TOTPModel{AccountId: totp.AccountId, Secret: totp.Secret, EnabledAt: memdb.TimePtr(totp.EnabledAt)}
+		if totp.LastUsedStep.Valid {
+			dest.LastUsedStep = &totp.LastUsedStep.Int64
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find totp", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("two-factor authentication not enrolled", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) upsertPendingTOTP(ctx context.Context, tx db.Tx, accountId, secret string) error {
+	var stored bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Accounts[accountId]; !ok {
+			return memdb.ErrForeignKey
+		}
+		if totp, ok := t.AccountTotps[accountId]; ok && totp.EnabledAt.Valid {
+			return nil
+		}
+		t.AccountTotps[accountId] = db.AccountTotp{AccountId: accountId, Secret: secret, CreatedAt: s.db.Now()}
+		stored = true
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to store totp secret", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !stored {
+		return failure.New("two-factor authentication already enabled", failure.ErrCantModify)
+	}
+
+	return nil
+}
+
+// updateTOTP applies fn to the totp of the account and reports if it was changed, fn returns
+// false to leave the totp alone
+func (s *memStore) updateTOTP(tx db.Tx, accountId string, fn func(totp *db.AccountTotp) bool) (bool, error) {
+	var updated bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		totp, ok := t.AccountTotps[accountId]
+		if !ok || !fn(&totp) {
+			return nil
+		}
+		t.AccountTotps[accountId] = totp
+		updated = true
+		return nil
+	})
+	return updated, err
+}
+
+func (s *memStore) enableTOTP(ctx context.Context, tx db.Tx, accountId string, step int64) error {
+	enabled, err := s.updateTOTP(tx, accountId, func(totp *db.AccountTotp) bool {
+		if totp.EnabledAt.Valid {
+			return false
+		}
+		totp.EnabledAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+		totp.LastUsedStep = sql.NullInt64{Int64: step, Valid: true}
+		return true
+	})
+	if err != nil {
+		return failure.New("unable to enable totp", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !enabled {
+		return failure.New("two-factor authentication already enabled", failure.ErrCantModify)
+	}
+
+	return nil
+}
+
+func (s *memStore) useTOTPStep(ctx context.Context, tx db.Tx, accountId string, step int64) (bool, error) {
+	used, err := s.updateTOTP(tx, accountId, func(totp *db.AccountTotp) bool {
+		if totp.LastUsedStep.Valid && totp.LastUsedStep.Int64 >= step {
+			return false
+		}
+		totp.LastUsedStep = sql.NullInt64{Int64: step, Valid: true}
+		return true
+	})
+	if err != nil {
+		return false, failure.New("unable to update totp last used step", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return used, nil
+}
+
+func (s *memStore) deleteTOTP(ctx context.Context, tx db.Tx, accountId string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		maps.DeleteFunc(t.TotpRecoveryCodes, func(_ string, rc db.TotpRecoveryCode) bool { return rc.AccountId == accountId })
+		delete(t.AccountTotps, accountId)
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to delete totp", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) replaceRecoveryCodes(ctx context.Context, tx db.Tx, accountId string, codeHashes []string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		maps.DeleteFunc(t.TotpRecoveryCodes, func(_ string, rc db.TotpRecoveryCode) bool { return rc.AccountId == accountId })
+		now := s.db.Now()
+		for _, hash := range codeHashes {
+			rc := db.TotpRecoveryCode{Id: memdb.NewId(), AccountId: accountId, CodeHash: hash, CreatedAt: now}
+			t.TotpRecoveryCodes[rc.Id] = rc
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to insert recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) countUnusedRecoveryCodes(ctx context.Context, accountId string) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, rc := range t.TotpRecoveryCodes {
+			if rc.AccountId == accountId && !rc.UsedAt.Valid {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findExportIdentities(ctx context.Context, accountId string) ([]ExportIdentityModel, error) {
+	dest := []ExportIdentityModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, ai := range t.AccountIdentities {
+			if ai.AccountId == accountId {
+				dest = append(dest, ExportIdentityModel{
+					Issuer:      ai.Issuer,
+					Email:       memdb.StringPtr(ai.Email),
+					LastLoginAt: memdb.TimePtr(ai.LastLoginAt),
+					CreatedAt:   ai.CreatedAt,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to export identities", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	slices.SortStableFunc(dest, func(a, b ExportIdentityModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) })
+
+	return dest, nil
+}
+
+func (s *memStore) findExportMatches(ctx context.Context, playerId string) ([]ExportMatchModel, error) {
+	dest := []ExportMatchModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		accountName := func(playerId string) string {
+			return t.Accounts[t.Players[playerId].AccountId].Name
+		}
+		for _, m := range t.Matches {
+			if m.PlayerOneId != playerId && m.PlayerTwoId != playerId {
+				continue
+			}
+			mm := ExportMatchModel{
+				Id:            m.Id,
+				Court:         t.Courts[m.CourtId].Name,
+				ScheduledAt:   m.ScheduledAt,
+				PlayerOneName: accountName(m.PlayerOneId),
+				PlayerTwoName: accountName(m.PlayerTwoId),
+				Score:         memdb.StringPtr(m.Score),
+				Season:        t.Seasons[m.SeasonId].Title,
+				League:        t.Leagues[m.LeagueId].Title,
+				CreatedAt:     m.CreatedAt,
+			}
+			if m.WinnerId.Valid {
+				won := m.WinnerId.String == playerId
+				mm.Won = &won
+			}
+			dest = append(dest, mm)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to export matches", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	slices.SortStableFunc(dest, func(a, b ExportMatchModel) int { return memdb.CompareTime(a.ScheduledAt, b.ScheduledAt) })
+
+	return dest, nil
+}
+
+func (s *memStore) findExportStandings(ctx context.Context, playerId string) ([]ExportStandingModel, error) {
+	var standings []db.Standing
+	dest := []ExportStandingModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, st := range t.Standings {
+			if st.PlayerId == playerId {
+				standings = append(standings, st)
+			}
+		}
+		slices.SortStableFunc(standings, func(a, b db.Standing) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) })
+		for _, st := range standings {
+			dest = append(dest, ExportStandingModel{
+				Season:        t.Seasons[st.SeasonId].Title,
+				League:        t.Leagues[st.LeagueId].Title,
+				Points:        st.Points,
+				MatchesPlayed: st.MatchesPlayed,
+				MatchesWon:    st.MatchesWon,
+				SetsWon:       st.SetsWon,
+				SetsLost:      st.SetsLost,
+				GamesWon:      st.GamesWon,
+				GamesLost:     st.GamesLost,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to export standings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findExportSessions(ctx context.Context, accountId string) ([]ExportSessionModel, error) {
+	dest := []ExportSessionModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, rt := range t.RefreshTokens {
+			if rt.AccountId == accountId {
+				dest = append(dest, ExportSessionModel{
+					DeviceId:   memdb.StringPtr(rt.DeviceId),
+					IpAddress:  memdb.StringPtr(rt.IpAddress),
+					UserAgent:  memdb.StringPtr(rt.UserAgent),
+					IssuedAt:   rt.IssuedAt,
+					ExpiresAt:  rt.ExpiresAt,
+					LastUsedAt: memdb.TimePtr(rt.LastUsedAt),
+					IsRevoked:  rt.IsRevoked,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to export sessions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	slices.SortStableFunc(dest, func(a, b ExportSessionModel) int { return memdb.CompareTime(a.IssuedAt, b.IssuedAt) })
+
+	return dest, nil
+}
+
+func (s *memStore) findExportAPIKeys(ctx context.Context, accountId string) ([]ExportAPIKeyModel, error) {
+	dest := []ExportAPIKeyModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, k := range t.APIKeys {
+			if k.AccountId == accountId {
+				dest = append(dest, ExportAPIKeyModel{
+					Name:       k.Name,
+					Prefix:     k.Prefix,
+					Scopes:     k.Scopes,
+					ExpiresAt:  memdb.TimePtr(k.ExpiresAt),
+					LastUsedAt: memdb.TimePtr(k.LastUsedAt),
+					RevokedAt:  memdb.TimePtr(k.RevokedAt),
+					CreatedAt:  k.CreatedAt,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to export api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	slices.SortStableFunc(dest, func(a, b ExportAPIKeyModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) })
+
+	return dest, nil
+}
+
+func (s *memStore) anonymizeAccount(ctx context.Context, tx db.Tx, accountId, password string) error {
+	_, err := s.updateAccount(tx, accountId, func(t *memdb.Tables, a *db.Account) error {
+		now := s.db.Now()
+		a.Name = "Deleted player"
+		a.Email = "deleted-" + a.Id + "@deleted.invalid"
+		a.Dob = sql.NullTime{}
+		a.Gender = sql.NullString{}
+		a.PhoneNumber = sql.NullString{}
+		a.Password = password
+		if !a.DeactivatedAt.Valid {
+			a.DeactivatedAt = sql.NullTime{Time: now, Valid: true}
+		}
+		a.DeletedAt = sql.NullTime{Time: now, Valid: true}
+		a.TokenVersion++
+
+		if p, ok := t.PlayerOf(accountId); ok {
+			p.Height = sql.NullFloat64{}
+			p.Weight = sql.NullFloat64{}
+			p.Handedness = sql.NullString{}
+			p.Racket = sql.NullString{}
+			p.CurrentLeagueId = sql.NullString{}
+			p.Version++
+			t.Players[p.Id] = p
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to anonymize account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) deleteAccountData(ctx context.Context, tx db.Tx, accountId string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		maps.DeleteFunc(t.RefreshTokens, func(_ string, rt db.RefreshToken) bool { return rt.AccountId == accountId })
+		maps.DeleteFunc(t.EmailChanges, func(_ string, ec db.EmailChange) bool { return ec.AccountId == accountId })
+		delete(t.AccountTotps, accountId)
+		maps.DeleteFunc(t.TotpRecoveryCodes, func(_ string, rc db.TotpRecoveryCode) bool { return rc.AccountId == accountId })
+		maps.DeleteFunc(t.AccountIdentities, func(_ string, ai db.AccountIdentity) bool { return ai.AccountId == accountId })
+		maps.DeleteFunc(t.APIKeys, func(_ string, k db.APIKey) bool { return k.AccountId == accountId })
+		maps.DeleteFunc(t.ScopedRoleGrants, func(_ string, g db.ScopedRoleGrant) bool { return g.AccountId == accountId })
+		maps.DeleteFunc(t.IdempotencyKeys, func(id memdb.IdempotencyKeyId, _ db.IdempotencyKey) bool { return id.AccountId == accountId })
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to delete account data", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
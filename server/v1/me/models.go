@@ -8,22 +8,26 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/privacy"
+	"github.com/markovidakovic/gdsi/server/sec"
 )
 
 type MeModel struct {
 	Id          string      `json:"id"`
 	Name        string      `json:"name"`
 	Email       string      `json:"email"`
-	Dob         time.Time   `json:"dob"`
-	Gender      string      `json:"gender"`
-	PhoneNumber string      `json:"phone_number"`
+	Dob         *time.Time  `json:"dob"`
+	Gender      *string     `json:"gender"`
+	PhoneNumber *string     `json:"phone_number"`
 	Role        string      `json:"role"`
+	ApprovedAt  *time.Time  `json:"approved_at"` // null while the account waits for approval
 	Player      PlayerModel `json:"player"`
 	CreatedAt   time.Time   `json:"created_at"`
 }
 
 func (mm *MeModel) ScanRow(row pgx.Row) error {
 	var leagueId, leagueTitle sql.NullString
+	var phone, email, dob, physique sql.NullString
 	mm.Player = PlayerModel{}
 
 	err := row.Scan(
@@ -34,6 +38,7 @@ func (mm *MeModel) ScanRow(row pgx.Row) error {
 		&mm.Gender,
 		&mm.PhoneNumber,
 		&mm.Role,
+		&mm.ApprovedAt,
 		&mm.Player.Id,
 		&mm.Player.Height,
 		&mm.Player.Weight,
@@ -44,6 +49,10 @@ func (mm *MeModel) ScanRow(row pgx.Row) error {
 		&mm.Player.MatchesWon,
 		&mm.Player.MatchesScheduled,
 		&mm.Player.SeasonsPlayed,
+		&phone,
+		&email,
+		&dob,
+		&physique,
 		&leagueId,
 		&leagueTitle,
 		&mm.Player.CreatedAt,
@@ -57,6 +66,13 @@ func (mm *MeModel) ScanRow(row pgx.Row) error {
 		return failure.New("database error scanning me row", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
+	mm.Player.Privacy = privacy.Settings{
+		PhoneNumber: privacy.Visibility(phone.String),
+		Email:       privacy.Visibility(email.String),
+		Dob:         privacy.Visibility(dob.String),
+		Physique:    privacy.Visibility(physique.String),
+	}
+
 	if !leagueId.Valid {
 		mm.Player.CurrentLeague = nil
 	} else {
@@ -81,6 +97,7 @@ type PlayerModel struct {
 	MatchesScheduled int                 `json:"matches_scheduled"`
 	SeasonsPlayed    int                 `json:"seasons_played"`
 	CurrentLeague    *CurrentLeagueModel `json:"current_league"`
+	Privacy          privacy.Settings    `json:"privacy"`
 	CreatedAt        time.Time           `json:"created_at"`
 }
 
@@ -104,16 +121,45 @@ func (m UpdateMeRequestModel) Validate() []failure.InvalidField {
 	return nil
 }
 
+// UpdatePrivacyRequestModel sets who can see the personal data on the player profile
+type UpdatePrivacyRequestModel struct {
+	privacy.Settings
+}
+
+func (m UpdatePrivacyRequestModel) Validate() []failure.InvalidField {
+	return m.Settings.Validate("body")
+}
+
 type UpdatePasswordRequestModel struct {
 	OldPassword         string `json:"old_password"`
 	NewPassword         string `json:"new_password"`
 	RepeatedNewPassword string `json:"repeated_new_password"`
 }
 
-// todo:
 func (m UpdatePasswordRequestModel) Validate() []failure.InvalidField {
 	var inv []failure.InvalidField
 
+	if m.NewPassword == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "new_password",
+			Message:  "New password field is required",
+			Location: "body",
+		})
+	} else if m.NewPassword == m.OldPassword {
+		inv = append(inv, failure.InvalidField{
+			Field:    "new_password",
+			Message:  "New password must be different from the old password",
+			Location: "body",
+		})
+	}
+	if m.RepeatedNewPassword != m.NewPassword {
+		inv = append(inv, failure.InvalidField{
+			Field:    "repeated_new_password",
+			Message:  "Repeated password does not match the new password",
+			Location: "body",
+		})
+	}
+
 	if len(inv) > 0 {
 		return inv
 	}
@@ -121,6 +167,264 @@ func (m UpdatePasswordRequestModel) Validate() []failure.InvalidField {
 	return nil
 }
 
+// update password response model. the account refresh tokens
+// get revoked so a new token pair is returned to the caller
 type UpdatePasswordResponseModel struct {
+	Message      string `json:"message"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// request email change body model
+type RequestEmailChangeRequestModel struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+func (m RequestEmailChangeRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.NewEmail == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "new_email",
+			Message:  "New email field is required",
+			Location: "body",
+		})
+	} else if !sec.IsValidEmail(m.NewEmail) {
+		inv = append(inv, failure.InvalidField{
+			Field:    "new_email",
+			Message:  "Invalid email",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+// confirm email change body model
+type ConfirmEmailChangeRequestModel struct {
+	Token string `json:"token"`
+}
+
+func (m ConfirmEmailChangeRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Token == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "token",
+			Message:  "Token field is required",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+type EmailChangeResponseModel struct {
 	Message string `json:"message"`
 }
+
+// account credentials used for verifying the current password
+// and issuing new tokens
+type CredentialsModel struct {
+	Id       string
+	Email    string
+	Password string
+	// the accounts provisioned through oidc have no password of their own
+	PasswordUnusable bool
+	Role             string
+	PlayerId         string
+}
+
+func (cm *CredentialsModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&cm.Id, &cm.Email, &cm.Password, &cm.PasswordUnusable, &cm.Role, &cm.PlayerId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning credentials row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error scanning credentials row", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+type EmailChangeModel struct {
+	Id          string
+	AccountId   string
+	NewEmail    string
+	ExpiresAt   time.Time
+	ConfirmedAt *time.Time
+}
+
+func (ecm *EmailChangeModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&ecm.Id, &ecm.AccountId, &ecm.NewEmail, &ecm.ExpiresAt, &ecm.ConfirmedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning email change row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error scanning email change row", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// two-factor status response model
+type TwoFactorStatusModel struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// two-factor enrollment response model. the provisioning uri is usually
+// rendered as a qr code which the authenticator app scans
+type TwoFactorEnrollmentModel struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// totp code body model, used for enabling two-factor and regenerating the recovery codes
+type TwoFactorCodeRequestModel struct {
+	Code string `json:"code"`
+}
+
+func (m TwoFactorCodeRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Code == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "code",
+			Message:  "Code field is required",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+// disable two-factor body model
+type DisableTwoFactorRequestModel struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (m DisableTwoFactorRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Code == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "code",
+			Message:  "Code field is required",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+// recovery codes are only shown once, right after they are generated
+type RecoveryCodesResponseModel struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TOTPModel struct {
+	AccountId    string
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep *int64
+}
+
+func (tm *TOTPModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&tm.AccountId, &tm.Secret, &tm.EnabledAt, &tm.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning totp row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error scanning totp row", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// ExportModel holds all the personal data of the account, returned as a json archive
+type ExportModel struct {
+	ExportedAt time.Time             `json:"exported_at"`
+	Account    MeModel               `json:"account"`
+	Identities []ExportIdentityModel `json:"identities"`
+	Matches    []ExportMatchModel    `json:"matches"`
+	Standings  []ExportStandingModel `json:"standings"`
+	Sessions   []ExportSessionModel  `json:"sessions"`
+	APIKeys    []ExportAPIKeyModel   `json:"api_keys"`
+}
+
+// identity provider logins linked to the account
+type ExportIdentityModel struct {
+	Issuer      string     `json:"issuer"`
+	Email       *string    `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ExportMatchModel struct {
+	Id            string    `json:"id"`
+	Court         string    `json:"court"`
+	ScheduledAt   time.Time `json:"scheduled_at"`
+	PlayerOneName string    `json:"player_one_name"`
+	PlayerTwoName string    `json:"player_two_name"`
+	Won           *bool     `json:"won"`
+	Score         *string   `json:"score"`
+	Season        string    `json:"season"`
+	League        string    `json:"league"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ExportStandingModel struct {
+	Season        string `json:"season"`
+	League        string `json:"league"`
+	Points        int    `json:"points"`
+	MatchesPlayed int    `json:"matches_played"`
+	MatchesWon    int    `json:"matches_won"`
+	SetsWon       int    `json:"sets_won"`
+	SetsLost      int    `json:"sets_lost"`
+	GamesWon      int    `json:"games_won"`
+	GamesLost     int    `json:"games_lost"`
+}
+
+// the refresh tokens issued to the account, without the tokens themselves
+type ExportSessionModel struct {
+	DeviceId   *string    `json:"device_id"`
+	IpAddress  *string    `json:"ip_address"`
+	UserAgent  *string    `json:"user_agent"`
+	IssuedAt   time.Time  `json:"issued_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	IsRevoked  bool       `json:"is_revoked"`
+}
+
+type ExportAPIKeyModel struct {
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// delete account body model, the password confirms the deletion of the accounts which have one
+type DeleteMeRequestModel struct {
+	Password string `json:"password"`
+}
@@ -0,0 +1,109 @@
+package me
+
+import (
+	"testing"
+
+	"github.com/markovidakovic/gdsi/server/failure"
+)
+
+// invalidFields returns the names of the fields the validation rejected
+func invalidFields(inv []failure.InvalidField) []string {
+	var fields []string
+	for _, f := range inv {
+		fields = append(fields, f.Field)
+	}
+	return fields
+}
+
+func equalFields(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUpdatePasswordRequestModelValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		model    UpdatePasswordRequestModel
+		expected []string
+	}{
+		{
+			name:     "Valid",
+			model:    UpdatePasswordRequestModel{OldPassword: "old-secret", NewPassword: "quiet-harbor-lantern-42", RepeatedNewPassword: "quiet-harbor-lantern-42"},
+			expected: nil,
+		},
+		{
+			// the old password is checked by the service, the oidc accounts don't have one
+			name:     "MissingFields",
+			model:    UpdatePasswordRequestModel{},
+			expected: []string{"new_password"},
+		},
+		{
+			name:     "SameAsOld",
+			model:    UpdatePasswordRequestModel{OldPassword: "quiet-harbor-lantern-42", NewPassword: "quiet-harbor-lantern-42", RepeatedNewPassword: "quiet-harbor-lantern-42"},
+			expected: []string{"new_password"},
+		},
+		{
+			name:     "RepeatedMismatch",
+			model:    UpdatePasswordRequestModel{OldPassword: "old-secret", NewPassword: "quiet-harbor-lantern-42", RepeatedNewPassword: "quiet-harbor-lantern-24"},
+			expected: []string{"repeated_new_password"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := invalidFields(tc.model.Validate())
+			if !equalFields(fields, tc.expected) {
+				t.Errorf("Validate() rejected %v; want %v", fields, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRequestEmailChangeRequestModelValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		model    RequestEmailChangeRequestModel
+		expected []string
+	}{
+		{
+			name:     "Valid",
+			model:    RequestEmailChangeRequestModel{NewEmail: "new@gdsi.test", Password: "secret"},
+			expected: nil,
+		},
+		{
+			name:     "MissingFields",
+			model:    RequestEmailChangeRequestModel{},
+			expected: []string{"new_email"},
+		},
+		{
+			name:     "InvalidEmail",
+			model:    RequestEmailChangeRequestModel{NewEmail: "not-an-email", Password: "secret"},
+			expected: []string{"new_email"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := invalidFields(tc.model.Validate())
+			if !equalFields(fields, tc.expected) {
+				t.Errorf("Validate() rejected %v; want %v", fields, tc.expected)
+			}
+		})
+	}
+}
+
+func TestConfirmEmailChangeRequestModelValidate(t *testing.T) {
+	if inv := (ConfirmEmailChangeRequestModel{}).Validate(); !equalFields(invalidFields(inv), []string{"token"}) {
+		t.Errorf("expected the missing token to be rejected, got %v", inv)
+	}
+	if inv := (ConfirmEmailChangeRequestModel{Token: "abc"}).Validate(); inv != nil {
+		t.Errorf("expected the token to be accepted, got %v", inv)
+	}
+}
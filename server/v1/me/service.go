@@ -1,17 +1,744 @@
 package me
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
 	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/mail"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/sec"
+	"github.com/markovidakovic/gdsi/server/session"
+	"github.com/markovidakovic/gdsi/server/tracing"
+)
+
+const (
+	// how long the email change confirmation token stays valid
+	emailChangeExpiration = 24 * time.Hour
+	// number of recovery codes generated when two-factor gets enabled
+	recoveryCodesCount = 10
+	// issuer shown in the authenticator apps
+	totpIssuer = "gdsi"
 )
 
 type service struct {
-	cfg   *config.Config
-	store *store
+	cfg      *config.Config
+	store    Repository
+	mailer   mail.Sender
+	sessions *session.Cache
 }
 
-func newService(cfg *config.Config, store *store) *service {
+func newService(cfg *config.Config, store Repository, mailer mail.Sender, sessions *session.Cache) *service {
 	return &service{
 		cfg,
 		store,
+		mailer,
+		sessions,
+	}
+}
+
+// checkPassword verifies the current password of the account, a wrong one is reported on the field.
+// The accounts provisioned through oidc never had a password to confirm, their signed in session
+// does instead. The api keys can't reach the me routes at all
+func (s *service) checkPassword(creds *CredentialsModel, pwd, field string) error {
+	if creds.PasswordUnusable {
+		return nil
+	}
+	if pwd == "" {
+		return failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: field, Message: "Password field is required", Location: "body"},
+		})
+	}
+
+	ok, _, err := s.cfg.Passwords.Verify(creds.Password, pwd)
+	if err != nil {
+		return failure.New("unable to verify password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !ok {
+		return failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: field, Message: "Invalid password", Location: "body"},
+		})
+	}
+	return nil
+}
+
+// processUpdatePassword verifies the current password, stores the new one and revokes
+// all of the account access and refresh tokens. a new token pair is issued for the caller
+// so only the other sessions get logged out. amr holds the authentication methods of the current
+// session so the new tokens keep the same two-factor state
+func (s *service) processUpdatePassword(ctx context.Context, accountId string, amr []string, model UpdatePasswordRequestModel) (*UpdatePasswordResponseModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processUpdatePassword")
+	defer span.End()
+
+	creds, err := s.store.findCredentials(ctx, nil, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkPassword(creds, model.OldPassword, "old_password")
+	if err != nil {
+		return nil, err
+	}
+
+	if msg := s.cfg.PasswordPolicy.Check(model.NewPassword, creds.Email); msg != "" {
+		return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: "new_password", Message: msg, Location: "body"},
+		})
+	}
+
+	hashed, err := s.cfg.Passwords.Hash(model.NewPassword)
+	if err != nil {
+		return nil, failure.New("unable to update password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	if len(amr) == 0 {
+		amr = []string{sec.AmrPassword}
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the update password tx", "error", err)
+		}
+	}()
+
+	err = s.store.updatePassword(ctx, tx, creds.Id, hashed)
+	if err != nil {
+		return nil, failure.New("unable to update password", err)
+	}
+
+	err = s.store.revokeAccountRefreshTokens(ctx, tx, creds.Id)
+	if err != nil {
+		return nil, failure.New("unable to update password", err)
+	}
+
+	tokenVersion, err := s.store.bumpTokenVersion(ctx, tx, creds.Id)
+	if err != nil {
+		return nil, failure.New("unable to update password", err)
+	}
+
+	accessTkn, refreshTkn, err := sec.GenerateAuthTokens(s.cfg.JwtKeys, s.cfg.JwtAccessExpiration, s.cfg.JwtRefreshExpiration, creds.Id, creds.Role, creds.PlayerId, tokenVersion, amr)
+	if err != nil {
+		return nil, failure.New("unable to update password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	err = s.store.insertRefreshToken(ctx, tx, creds.Id, sec.HashToken(refreshTkn.Value), refreshTkn.IssuedAt, refreshTkn.ExpiresAt, amr)
+	if err != nil {
+		return nil, failure.New("unable to update password", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	s.sessions.Invalidate(creds.Id)
+
+	return &UpdatePasswordResponseModel{
+		Message:      "password updated, other sessions have been logged out",
+		AccessToken:  accessTkn.Value,
+		RefreshToken: refreshTkn.Value,
+	}, nil
+}
+
+// processRequestEmailChange stores a pending email change and sends the confirmation
+// token to the new address. the account email stays the same until the change is confirmed
+func (s *service) processRequestEmailChange(ctx context.Context, accountId string, model RequestEmailChangeRequestModel) (*EmailChangeResponseModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processRequestEmailChange")
+	defer span.End()
+
+	model.NewEmail = strings.TrimSpace(model.NewEmail)
+
+	creds, err := s.store.findCredentials(ctx, nil, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkPassword(creds, model.Password, "password")
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(creds.Email, model.NewEmail) {
+		return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+			{Field: "new_email", Message: "New email must be different from the current email", Location: "body"},
+		})
+	}
+
+	exists, err := s.store.checkEmailExists(ctx, model.NewEmail)
+	if err != nil {
+		return nil, failure.New("unable to request email change", err)
+	}
+	if exists {
+		return nil, failure.New("email already registered", failure.ErrDuplicate)
+	}
+
+	token, err := sec.RandomToken(32)
+	if err != nil {
+		return nil, failure.New("unable to request email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to request email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the request email change tx", "error", err)
+		}
+	}()
+
+	err = s.store.deletePendingEmailChanges(ctx, tx, creds.Id)
+	if err != nil {
+		return nil, failure.New("unable to request email change", err)
+	}
+
+	err = s.store.insertEmailChange(ctx, tx, creds.Id, model.NewEmail, sec.HashToken(token), time.Now().Add(emailChangeExpiration))
+	if err != nil {
+		return nil, failure.New("unable to request email change", err)
+	}
+
+	// send before committing so a change that can't be confirmed is never stored
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      model.NewEmail,
+		Subject: "Confirm your new gdsi email address",
+		Body:    fmt.Sprintf("Use the following code to confirm your new email address:\n\n%s\n\nThe code expires in %s.\n", token, emailChangeExpiration),
+	})
+	if err != nil {
+		return nil, failure.New("unable to send email change confirmation", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to request email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &EmailChangeResponseModel{
+		Message: "confirmation sent to the new email address",
+	}, nil
+}
+
+// processConfirmEmailChange switches the account email to the confirmed address and
+// notifies the previous address about the change
+func (s *service) processConfirmEmailChange(ctx context.Context, accountId string, model ConfirmEmailChangeRequestModel) (*MeModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processConfirmEmailChange")
+	defer span.End()
+
+	creds, err := s.store.findCredentials(ctx, nil, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to confirm email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the confirm email change tx", "error", err)
+		}
+	}()
+
+	ec, err := s.store.findEmailChange(ctx, tx, creds.Id, sec.HashToken(model.Token))
+	if err != nil {
+		return nil, err
+	}
+
+	if ec.ConfirmedAt != nil {
+		return nil, failure.New("email change already confirmed", failure.ErrCantModify)
+	}
+	if time.Now().After(ec.ExpiresAt) {
+		return nil, failure.New("email change token expired", failure.ErrBadRequest)
+	}
+
+	err = s.store.updateEmail(ctx, tx, creds.Id, ec.NewEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.confirmEmailChange(ctx, tx, ec.Id)
+	if err != nil {
+		return nil, failure.New("unable to confirm email change", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to confirm email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	// the change is already stored, a failed notification is only logged
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      creds.Email,
+		Subject: "Your gdsi email address was changed",
+		Body:    fmt.Sprintf("The email address of your gdsi account was changed to %s.\n\nIf you did not make this change, contact an administrator immediately.\n", ec.NewEmail),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to notify previous email address about the email change", "error", err)
+	}
+
+	return s.store.findMe(ctx, nil, creds.Id)
+}
+
+func (s *service) processGetTwoFactor(ctx context.Context, accountId, role string) (*TwoFactorStatusModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processGetTwoFactor")
+	defer span.End()
+
+	result := &TwoFactorStatusModel{
+		Required: slices.Contains(s.cfg.TwoFactorRoles(), role),
+	}
+
+	totp, err := s.store.findTOTP(ctx, nil, accountId)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return result, nil
+		}
+		return nil, err
+	}
+
+	if totp.EnabledAt == nil {
+		return result, nil
+	}
+
+	remaining, err := s.store.countUnusedRecoveryCodes(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Enabled = true
+	result.EnabledAt = totp.EnabledAt
+	result.RecoveryCodesRemaining = remaining
+
+	return result, nil
+}
+
+// processEnrollTwoFactor generates a new totp secret. The enrollment stays pending
+// until it's confirmed with a code from the authenticator app
+func (s *service) processEnrollTwoFactor(ctx context.Context, accountId string) (*TwoFactorEnrollmentModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processEnrollTwoFactor")
+	defer span.End()
+
+	creds, err := s.store.findCredentials(ctx, nil, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := sec.GenerateTOTPSecret()
+	if err != nil {
+		return nil, failure.New("unable to enroll two-factor authentication", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	err = s.store.upsertPendingTOTP(ctx, nil, creds.Id, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollmentModel{
+		Secret:          secret,
+		ProvisioningURI: sec.TOTPProvisioningURI(totpIssuer, creds.Email, secret),
+	}, nil
+}
+
+// processEnableTwoFactor confirms the pending enrollment and returns the recovery codes
+func (s *service) processEnableTwoFactor(ctx context.Context, accountId string, model TwoFactorCodeRequestModel) (*RecoveryCodesResponseModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processEnableTwoFactor")
+	defer span.End()
+
+	totp, err := s.store.findTOTP(ctx, nil, accountId)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("two-factor authentication not enrolled", failure.ErrBadRequest)
+		}
+		return nil, err
+	}
+	if totp.EnabledAt != nil {
+		return nil, failure.New("two-factor authentication already enabled", failure.ErrCantModify)
+	}
+
+	step, ok := sec.ValidateTOTP(totp.Secret, model.Code, time.Now())
+	if !ok {
+		return nil, invalidCodeFailure()
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, failure.New("unable to enable two-factor authentication", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to enable two-factor authentication", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the enable two-factor tx", "error", err)
+		}
+	}()
+
+	err = s.store.enableTOTP(ctx, tx, accountId, step)
+	if err != nil {
+		return nil, err
 	}
+
+	err = s.store.replaceRecoveryCodes(ctx, tx, accountId, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to enable two-factor authentication", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &RecoveryCodesResponseModel{RecoveryCodes: codes}, nil
+}
+
+// processRegenerateRecoveryCodes replaces all of the recovery codes, the previous ones stop working
+func (s *service) processRegenerateRecoveryCodes(ctx context.Context, accountId string, model TwoFactorCodeRequestModel) (*RecoveryCodesResponseModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processRegenerateRecoveryCodes")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to regenerate recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the regenerate recovery codes tx", "error", err)
+		}
+	}()
+
+	err = s.verifyEnabledTOTP(ctx, tx, accountId, model.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, failure.New("unable to regenerate recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	err = s.store.replaceRecoveryCodes(ctx, tx, accountId, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to regenerate recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &RecoveryCodesResponseModel{RecoveryCodes: codes}, nil
+}
+
+// processDisableTwoFactor removes the totp secret and the recovery codes. Accounts with
+// a role for which two-factor is mandatory can't disable it
+func (s *service) processDisableTwoFactor(ctx context.Context, accountId, role string, model DisableTwoFactorRequestModel) error {
+	ctx, span := tracing.Start(ctx, "me.processDisableTwoFactor")
+	defer span.End()
+
+	if slices.Contains(s.cfg.TwoFactorRoles(), role) {
+		return failure.New("two-factor authentication is mandatory for your role", failure.ErrForbidden)
+	}
+
+	creds, err := s.store.findCredentials(ctx, nil, accountId)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkPassword(creds, model.Password, "password")
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to disable two-factor authentication", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the disable two-factor tx", "error", err)
+		}
+	}()
+
+	err = s.verifyEnabledTOTP(ctx, tx, accountId, model.Code)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.deleteTOTP(ctx, tx, accountId)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to disable two-factor authentication", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// verifyEnabledTOTP checks the code against the enabled totp secret and marks its time step as used
+func (s *service) verifyEnabledTOTP(ctx context.Context, tx db.Tx, accountId, code string) error {
+	totp, err := s.store.findTOTP(ctx, tx, accountId)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return failure.New("two-factor authentication not enabled", failure.ErrBadRequest)
+		}
+		return err
+	}
+	if totp.EnabledAt == nil {
+		return failure.New("two-factor authentication not enabled", failure.ErrBadRequest)
+	}
+
+	step, ok := sec.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return invalidCodeFailure()
+	}
+
+	fresh, err := s.store.useTOTPStep(ctx, tx, accountId, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return invalidCodeFailure()
+	}
+
+	return nil
+}
+
+func invalidCodeFailure() error {
+	return failure.NewValidation("invalid request parameters", []failure.InvalidField{
+		{Field: "code", Message: "Invalid code", Location: "body"},
+	})
+}
+
+// generateRecoveryCodes returns the plain codes for the caller and their hashes for storing
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := sec.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = sec.HashToken(sec.NormalizeRecoveryCode(c))
+	}
+
+	return codes, hashes, nil
+}
+
+// processExportMe collects all the personal data of the account
+func (s *service) processExportMe(ctx context.Context, accountId string) (*ExportModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processExportMe")
+	defer span.End()
+
+	me, err := s.store.findMe(ctx, nil, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &ExportModel{
+		ExportedAt: time.Now().UTC(),
+		Account:    *me,
+	}
+
+	export.Identities, err = s.store.findExportIdentities(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	export.Matches, err = s.store.findExportMatches(ctx, me.Player.Id)
+	if err != nil {
+		return nil, err
+	}
+	export.Standings, err = s.store.findExportStandings(ctx, me.Player.Id)
+	if err != nil {
+		return nil, err
+	}
+	export.Sessions, err = s.store.findExportSessions(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	export.APIKeys, err = s.store.findExportAPIKeys(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// processDeleteMe anonymizes the account instead of deleting it. The player row with its matches
+// and standings stays, so the results of the other players don't change. Everything the account
+// could sign in with is removed and its current access tokens are revoked
+func (s *service) processDeleteMe(ctx context.Context, accountId string, model DeleteMeRequestModel) error {
+	ctx, span := tracing.Start(ctx, "me.processDeleteMe")
+	defer span.End()
+
+	creds, err := s.store.findCredentials(ctx, nil, accountId)
+	if err != nil {
+		return err
+	}
+
+	// somebody has to be left to manage the roles
+	if creds.Role == permission.RoleDeveloper {
+		return failure.New("developer accounts can't be deleted, change the role first", failure.ErrCantModify)
+	}
+
+	err = s.checkPassword(creds, model.Password, "password")
+	if err != nil {
+		return err
+	}
+
+	// nobody knows the new password, the account can't be signed in to anymore
+	pwd, err := sec.RandomToken(32)
+	if err != nil {
+		return failure.New("unable to delete account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	pwd, err = s.cfg.Passwords.Hash(pwd)
+	if err != nil {
+		return failure.New("unable to delete account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to delete account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the delete account tx", "error", err)
+		}
+	}()
+
+	err = s.store.deleteAccountData(ctx, tx, accountId)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.anonymizeAccount(ctx, tx, accountId, pwd)
+	if err != nil {
+		return err
+	}
+
+	// the entry doesn't hold the personal data, it's gone on purpose
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionDelete, ResourceType: audit.Account, ResourceId: accountId})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to delete account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	s.sessions.Invalidate(accountId)
+
+	return nil
+}
+
+// processUpdateMe changes the account name. The If-Match is checked against the locked
+// account, the tag is hashed from the representation of getMe
+func (s *service) processUpdateMe(ctx context.Context, accountId string, ifMatch params.IfMatch, model UpdateMeRequestModel) (*MeModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processUpdateMe")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the update me tx", "error", err)
+		}
+	}()
+
+	before, err := s.store.findMe(ctx, tx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ifMatch.CheckData("account", before); err != nil {
+		return nil, err
+	}
+
+	result, err := s.store.updateMe(ctx, tx, accountId, model)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return result, nil
+}
+
+// processUpdatePrivacy changes who can see the personal data on the player profile
+func (s *service) processUpdatePrivacy(ctx context.Context, accountId string, model UpdatePrivacyRequestModel) (*MeModel, error) {
+	ctx, span := tracing.Start(ctx, "me.processUpdatePrivacy")
+	defer span.End()
+
+	before, err := s.store.findMe(ctx, nil, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update privacy settings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the update privacy tx", "error", err)
+		}
+	}()
+
+	err = s.store.updatePrivacy(ctx, tx, accountId, model.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUpdate, ResourceType: audit.Player, ResourceId: before.Player.Id, Before: before.Player.Privacy, After: model.Settings})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update privacy settings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	before.Player.Privacy = model.Settings
+
+	return before, nil
 }
@@ -3,23 +3,75 @@ package me
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/privacy"
 )
 
+// Repository holds what the signed in account can change about itself: the profile, password,
+// email, second factor and privacy settings, and the data the export and deletion go through
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	findMe(ctx context.Context, tx db.Tx, accountId string) (*MeModel, error)
+	updateMe(ctx context.Context, tx db.Tx, accountId string, model UpdateMeRequestModel) (*MeModel, error)
+	updatePrivacy(ctx context.Context, tx db.Tx, accountId string, settings privacy.Settings) error
+	findCredentials(ctx context.Context, tx db.Tx, accountId string) (*CredentialsModel, error)
+	updatePassword(ctx context.Context, tx db.Tx, accountId, password string) error
+	updateEmail(ctx context.Context, tx db.Tx, accountId, email string) error
+	checkEmailExists(ctx context.Context, email string) (bool, error)
+	bumpTokenVersion(ctx context.Context, tx db.Tx, accountId string) (int, error)
+	revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error
+	insertRefreshToken(ctx context.Context, tx db.Tx, accountId string, token string, issuedAt, expiresAt time.Time, amr []string) error
+	deletePendingEmailChanges(ctx context.Context, tx db.Tx, accountId string) error
+	insertEmailChange(ctx context.Context, tx db.Tx, accountId, newEmail, tokenHash string, expiresAt time.Time) error
+	findEmailChange(ctx context.Context, tx db.Tx, accountId, tokenHash string) (*EmailChangeModel, error)
+	confirmEmailChange(ctx context.Context, tx db.Tx, emailChangeId string) error
+	findTOTP(ctx context.Context, tx db.Tx, accountId string) (*TOTPModel, error)
+	upsertPendingTOTP(ctx context.Context, tx db.Tx, accountId, secret string) error
+	enableTOTP(ctx context.Context, tx db.Tx, accountId string, step int64) error
+	useTOTPStep(ctx context.Context, tx db.Tx, accountId string, step int64) (bool, error)
+	deleteTOTP(ctx context.Context, tx db.Tx, accountId string) error
+	replaceRecoveryCodes(ctx context.Context, tx db.Tx, accountId string, codeHashes []string) error
+	countUnusedRecoveryCodes(ctx context.Context, accountId string) (int, error)
+	findExportIdentities(ctx context.Context, accountId string) ([]ExportIdentityModel, error)
+	findExportMatches(ctx context.Context, playerId string) ([]ExportMatchModel, error)
+	findExportStandings(ctx context.Context, playerId string) ([]ExportStandingModel, error)
+	findExportSessions(ctx context.Context, accountId string) ([]ExportSessionModel, error)
+	findExportAPIKeys(ctx context.Context, accountId string) ([]ExportAPIKeyModel, error)
+	anonymizeAccount(ctx context.Context, tx db.Tx, accountId, password string) error
+	deleteAccountData(ctx context.Context, tx db.Tx, accountId string) error
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
 	return &store{
 		db,
 	}
 }
 
-func (s *store) findMe(ctx context.Context, accountId string) (*MeModel, error) {
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+// findMe locks the account row within the tx, the update checked against the
+// returned state can't interleave with another one
+func (s *store) findMe(ctx context.Context, tx db.Tx, accountId string) (*MeModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
 	sql := `
 		select 
 			account.id as account_id,
@@ -29,6 +81,7 @@ func (s *store) findMe(ctx context.Context, accountId string) (*MeModel, error)
 			account.gender as account_gender,
 			account.phone_number as account_phone_number,
 			account.role as account_role,
+			account.approved_at as account_approved_at,
 			player.id as player_id,
 			player.height as player_height,
 			player.weight as player_weight,
@@ -39,6 +92,10 @@ func (s *store) findMe(ctx context.Context, accountId string) (*MeModel, error)
 			player.matches_won as player_matches_won,
 			player.matches_scheduled as player_matches_scheduled,
 			player.seasons_played as player_seasons_played,
+			player.privacy_phone_number as player_privacy_phone_number,
+			player.privacy_email as player_privacy_email,
+			player.privacy_dob as player_privacy_dob,
+			player.privacy_physique as player_privacy_physique,
 			league.id as league_id,
 			league.title as league_title,
 			player.created_at as player_created_at,
@@ -48,10 +105,13 @@ func (s *store) findMe(ctx context.Context, accountId string) (*MeModel, error)
 		left join league on player.current_league_id = league.id
 		where account.id = $1
 	`
+	if tx != nil {
+		sql += " for update of account"
+	}
 
 	var dest MeModel
 
-	row := s.db.QueryRow(ctx, sql, accountId)
+	row := q.QueryRow(ctx, sql, accountId)
 	err := dest.ScanRow(row)
 	if err != nil {
 		if errors.Is(err, failure.ErrNotFound) {
@@ -63,7 +123,7 @@ func (s *store) findMe(ctx context.Context, accountId string) (*MeModel, error)
 	return &dest, nil
 }
 
-func (s *store) updateMe(ctx context.Context, tx pgx.Tx, accountId string, model UpdateMeRequestModel) (*MeModel, error) {
+func (s *store) updateMe(ctx context.Context, tx db.Tx, accountId string, model UpdateMeRequestModel) (*MeModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -78,7 +138,7 @@ func (s *store) updateMe(ctx context.Context, tx pgx.Tx, accountId string, model
 			update account 
 			set name = $1
 			where id = $2
-			returning id, name, email, dob, gender, phone_number, role, created_at
+			returning id, name, email, dob, gender, phone_number, role, approved_at, created_at
 		)
 		select 
 			ua.id as account_id,
@@ -88,6 +148,7 @@ func (s *store) updateMe(ctx context.Context, tx pgx.Tx, accountId string, model
 			ua.gender as account_gender,
 			ua.phone_number as account_phone_number,
 			ua.role as account_role,
+			ua.approved_at as account_approved_at,
 			player.id as player_id,
 			player.height as player_height,
 			player.weight as player_weight,
@@ -98,6 +159,10 @@ func (s *store) updateMe(ctx context.Context, tx pgx.Tx, accountId string, model
 			player.matches_won as player_matches_won,
 			player.matches_scheduled as player_matches_scheduled,
 			player.seasons_played as player_seasons_played,
+			player.privacy_phone_number as player_privacy_phone_number,
+			player.privacy_email as player_privacy_email,
+			player.privacy_dob as player_privacy_dob,
+			player.privacy_physique as player_privacy_physique,
 			league.id as league_id,
 			league.title as league_title,
 			player.created_at as player_created_at,
@@ -118,3 +183,698 @@ func (s *store) updateMe(ctx context.Context, tx pgx.Tx, accountId string, model
 
 	return &dest, nil
 }
+
+// updatePrivacy replaces the privacy settings of the account player
+func (s *store) updatePrivacy(ctx context.Context, tx db.Tx, accountId string, settings privacy.Settings) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update player
+		set privacy_phone_number = $1, privacy_email = $2, privacy_dob = $3, privacy_physique = $4
+		where account_id = $5
+	`
+
+	tag, err := q.Exec(ctx, sql, settings.PhoneNumber, settings.Email, settings.Dob, settings.Physique, accountId)
+	if err != nil {
+		return failure.New("unable to update privacy settings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if tag.RowsAffected() == 0 {
+		return failure.New("player not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *store) findCredentials(ctx context.Context, tx db.Tx, accountId string) (*CredentialsModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		select
+			account.id as account_id,
+			account.email as account_email,
+			account.password as account_password,
+			account.password_unusable as account_password_unusable,
+			account.role as account_role,
+			player.id as player_id
+		from account
+		join player on account.id = player.account_id
+		where account.id = $1
+	`
+
+	var dest CredentialsModel
+
+	row := q.QueryRow(ctx, sql, accountId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("account not found", err)
+		}
+		return nil, failure.New("unable to find account credentials", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) updatePassword(ctx context.Context, tx db.Tx, accountId, password string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set password = $1, password_unusable = false
+		where id = $2
+	`
+
+	ct, err := q.Exec(ctx, sql, password, accountId)
+	if err != nil {
+		return failure.New("unable to update account password", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("account for updating password not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *store) updateEmail(ctx context.Context, tx db.Tx, accountId, email string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set email = $1
+		where id = $2
+	`
+
+	ct, err := q.Exec(ctx, sql, email, accountId)
+	if err != nil {
+		// unique violation on account.email
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return failure.New("email already registered", fmt.Errorf("%w -> %v", failure.ErrDuplicate, err))
+		}
+		return failure.New("unable to update account email", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("account for updating email not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *store) checkEmailExists(ctx context.Context, email string) (bool, error) {
+	sql := `select exists(select 1 from account where email = $1)`
+
+	var exists bool
+	err := s.db.QueryRow(ctx, sql, email).Scan(&exists)
+	if err != nil {
+		return false, failure.New("unable to check email existance", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return exists, nil
+}
+
+// bumpTokenVersion invalidates all the access tokens issued to the account so far
+// and returns the version the new tokens have to carry
+func (s *store) bumpTokenVersion(ctx context.Context, tx db.Tx, accountId string) (int, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set token_version = token_version + 1
+		where id = $1
+		returning token_version
+	`
+
+	var version int
+	err := q.QueryRow(ctx, sql, accountId).Scan(&version)
+	if err != nil {
+		return 0, failure.New("unable to revoke account tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return version, nil
+}
+
+func (s *store) revokeAccountRefreshTokens(ctx context.Context, tx db.Tx, accountId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update refresh_token
+		set is_revoked = true
+		where account_id = $1 and is_revoked = false
+	`
+
+	_, err := q.Exec(ctx, sql, accountId)
+	if err != nil {
+		return failure.New("failed to revoke account refresh tokens", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) insertRefreshToken(ctx context.Context, tx db.Tx, accountId string, token string, issuedAt, expiresAt time.Time, amr []string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into refresh_token (account_id, token_hash, issued_at, expires_at, amr)
+		values ($1, $2, $3, $4, $5)
+	`
+
+	_, err := q.Exec(ctx, sql, accountId, token, issuedAt, expiresAt, amr)
+	if err != nil {
+		return failure.New("failed to insert refresh token", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// deletePendingEmailChanges removes the unconfirmed email change requests of an account
+// so only the latest requested change can be confirmed
+func (s *store) deletePendingEmailChanges(ctx context.Context, tx db.Tx, accountId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		delete from email_change
+		where account_id = $1 and confirmed_at is null
+	`
+
+	_, err := q.Exec(ctx, sql, accountId)
+	if err != nil {
+		return failure.New("unable to delete pending email changes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) insertEmailChange(ctx context.Context, tx db.Tx, accountId, newEmail, tokenHash string, expiresAt time.Time) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into email_change (account_id, new_email, token_hash, expires_at)
+		values ($1, $2, $3, $4)
+	`
+
+	_, err := q.Exec(ctx, sql, accountId, newEmail, tokenHash, expiresAt)
+	if err != nil {
+		return failure.New("unable to insert email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) findEmailChange(ctx context.Context, tx db.Tx, accountId, tokenHash string) (*EmailChangeModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		select id, account_id, new_email, expires_at, confirmed_at
+		from email_change
+		where account_id = $1 and token_hash = $2
+	`
+
+	var dest EmailChangeModel
+
+	row := q.QueryRow(ctx, sql, accountId, tokenHash)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("email change not found", err)
+		}
+		return nil, failure.New("unable to find email change", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) confirmEmailChange(ctx context.Context, tx db.Tx, emailChangeId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update email_change
+		set confirmed_at = current_timestamp
+		where id = $1
+	`
+
+	_, err := q.Exec(ctx, sql, emailChangeId)
+	if err != nil {
+		return failure.New("unable to confirm email change", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) findTOTP(ctx context.Context, tx db.Tx, accountId string) (*TOTPModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		select account_id, secret, enabled_at, last_used_step
+		from account_totp
+		where account_id = $1
+	`
+
+	var dest TOTPModel
+
+	row := q.QueryRow(ctx, sql, accountId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("two-factor authentication not enrolled", err)
+		}
+		return nil, failure.New("unable to find totp", err)
+	}
+
+	return &dest, nil
+}
+
+// upsertPendingTOTP stores a new secret for the account. Enabled secrets are never
+// replaced, the enrollment has to be disabled first
+func (s *store) upsertPendingTOTP(ctx context.Context, tx db.Tx, accountId, secret string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into account_totp (account_id, secret)
+		values ($1, $2)
+		on conflict (account_id) do update
+		set secret = excluded.secret, last_used_step = null, failed_attempts = 0, last_failed_at = null, created_at = current_timestamp
+		where account_totp.enabled_at is null
+	`
+
+	ct, err := q.Exec(ctx, sql, accountId, secret)
+	if err != nil {
+		return failure.New("unable to store totp secret", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("two-factor authentication already enabled", failure.ErrCantModify)
+	}
+
+	return nil
+}
+
+func (s *store) enableTOTP(ctx context.Context, tx db.Tx, accountId string, step int64) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account_totp
+		set enabled_at = current_timestamp, last_used_step = $1
+		where account_id = $2 and enabled_at is null
+	`
+
+	ct, err := q.Exec(ctx, sql, step, accountId)
+	if err != nil {
+		return failure.New("unable to enable totp", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("two-factor authentication already enabled", failure.ErrCantModify)
+	}
+
+	return nil
+}
+
+// useTOTPStep marks the time step as used. It returns false if the same or a later
+// step was already used, which means the code is being replayed
+func (s *store) useTOTPStep(ctx context.Context, tx db.Tx, accountId string, step int64) (bool, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account_totp
+		set last_used_step = $1
+		where account_id = $2 and (last_used_step is null or last_used_step < $1)
+	`
+
+	ct, err := q.Exec(ctx, sql, step, accountId)
+	if err != nil {
+		return false, failure.New("unable to update totp last used step", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return ct.RowsAffected() > 0, nil
+}
+
+func (s *store) deleteTOTP(ctx context.Context, tx db.Tx, accountId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	_, err := q.Exec(ctx, `delete from totp_recovery_code where account_id = $1`, accountId)
+	if err != nil {
+		return failure.New("unable to delete recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	_, err = q.Exec(ctx, `delete from account_totp where account_id = $1`, accountId)
+	if err != nil {
+		return failure.New("unable to delete totp", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes invalidates all of the previous recovery codes and stores the new hashes
+func (s *store) replaceRecoveryCodes(ctx context.Context, tx db.Tx, accountId string, codeHashes []string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	_, err := q.Exec(ctx, `delete from totp_recovery_code where account_id = $1`, accountId)
+	if err != nil {
+		return failure.New("unable to delete recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	sql := `
+		insert into totp_recovery_code (account_id, code_hash)
+		select $1, unnest($2::text[])
+	`
+
+	_, err = q.Exec(ctx, sql, accountId, codeHashes)
+	if err != nil {
+		return failure.New("unable to insert recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) countUnusedRecoveryCodes(ctx context.Context, accountId string) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, `select count(*) from totp_recovery_code where account_id = $1 and used_at is null`, accountId).Scan(&count)
+	if err != nil {
+		return 0, failure.New("unable to count recovery codes", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *store) findExportIdentities(ctx context.Context, accountId string) ([]ExportIdentityModel, error) {
+	sql := `
+		select issuer, email, last_login_at, created_at
+		from account_identity
+		where account_id = $1
+		order by created_at
+	`
+
+	rows, err := s.db.Query(ctx, sql, accountId)
+	if err != nil {
+		return nil, failure.New("unable to export identities", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	dest := []ExportIdentityModel{}
+	for rows.Next() {
+		var im ExportIdentityModel
+		if err := rows.Scan(&im.Issuer, &im.Email, &im.LastLoginAt, &im.CreatedAt); err != nil {
+			return nil, failure.New("unable to export identities", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		dest = append(dest, im)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to export identities", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) findExportMatches(ctx context.Context, playerId string) ([]ExportMatchModel, error) {
+	sql := `
+		select
+			match.id,
+			court.name as court_name,
+			match.scheduled_at,
+			account1.name as player_one_name,
+			account2.name as player_two_name,
+			case when match.winner_id is null then null else match.winner_id = $1 end as won,
+			match.score,
+			season.title as season_title,
+			league.title as league_title,
+			match.created_at
+		from match
+		join court on match.court_id = court.id
+		join player player1 on match.player_one_id = player1.id
+		join account account1 on player1.account_id = account1.id
+		join player player2 on match.player_two_id = player2.id
+		join account account2 on player2.account_id = account2.id
+		join season on match.season_id = season.id
+		join league on match.league_id = league.id
+		where match.player_one_id = $1 or match.player_two_id = $1
+		order by match.scheduled_at
+	`
+
+	rows, err := s.db.Query(ctx, sql, playerId)
+	if err != nil {
+		return nil, failure.New("unable to export matches", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	dest := []ExportMatchModel{}
+	for rows.Next() {
+		var mm ExportMatchModel
+		if err := rows.Scan(&mm.Id, &mm.Court, &mm.ScheduledAt, &mm.PlayerOneName, &mm.PlayerTwoName, &mm.Won, &mm.Score, &mm.Season, &mm.League, &mm.CreatedAt); err != nil {
+			return nil, failure.New("unable to export matches", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		dest = append(dest, mm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to export matches", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) findExportStandings(ctx context.Context, playerId string) ([]ExportStandingModel, error) {
+	sql := `
+		select
+			season.title as season_title,
+			league.title as league_title,
+			standing.points,
+			standing.matches_played,
+			standing.matches_won,
+			standing.sets_won,
+			standing.sets_lost,
+			standing.games_won,
+			standing.games_lost
+		from standing
+		join season on standing.season_id = season.id
+		join league on standing.league_id = league.id
+		where standing.player_id = $1
+		order by standing.created_at
+	`
+
+	rows, err := s.db.Query(ctx, sql, playerId)
+	if err != nil {
+		return nil, failure.New("unable to export standings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	dest := []ExportStandingModel{}
+	for rows.Next() {
+		var sm ExportStandingModel
+		if err := rows.Scan(&sm.Season, &sm.League, &sm.Points, &sm.MatchesPlayed, &sm.MatchesWon, &sm.SetsWon, &sm.SetsLost, &sm.GamesWon, &sm.GamesLost); err != nil {
+			return nil, failure.New("unable to export standings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		dest = append(dest, sm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to export standings", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) findExportSessions(ctx context.Context, accountId string) ([]ExportSessionModel, error) {
+	sql := `
+		select device_id, ip_address, user_agent, issued_at, expires_at, last_used_at, is_revoked
+		from refresh_token
+		where account_id = $1
+		order by issued_at
+	`
+
+	rows, err := s.db.Query(ctx, sql, accountId)
+	if err != nil {
+		return nil, failure.New("unable to export sessions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	dest := []ExportSessionModel{}
+	for rows.Next() {
+		var sm ExportSessionModel
+		if err := rows.Scan(&sm.DeviceId, &sm.IpAddress, &sm.UserAgent, &sm.IssuedAt, &sm.ExpiresAt, &sm.LastUsedAt, &sm.IsRevoked); err != nil {
+			return nil, failure.New("unable to export sessions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		dest = append(dest, sm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to export sessions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) findExportAPIKeys(ctx context.Context, accountId string) ([]ExportAPIKeyModel, error) {
+	sql := `
+		select name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		from api_key
+		where account_id = $1
+		order by created_at
+	`
+
+	rows, err := s.db.Query(ctx, sql, accountId)
+	if err != nil {
+		return nil, failure.New("unable to export api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	dest := []ExportAPIKeyModel{}
+	for rows.Next() {
+		var km ExportAPIKeyModel
+		if err := rows.Scan(&km.Name, &km.Prefix, &km.Scopes, &km.ExpiresAt, &km.LastUsedAt, &km.RevokedAt, &km.CreatedAt); err != nil {
+			return nil, failure.New("unable to export api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		dest = append(dest, km)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, failure.New("unable to export api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+// anonymizeAccount replaces the personal data of the account and its player. The player row
+// stays with its statistics so the matches and standings of the other players are kept
+func (s *store) anonymizeAccount(ctx context.Context, tx db.Tx, accountId, password string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update account
+		set
+			name = 'Deleted player',
+			email = 'deleted-' || id || '@deleted.invalid',
+			dob = null,
+			gender = null,
+			phone_number = null,
+			password = $2,
+			deactivated_at = coalesce(deactivated_at, current_timestamp),
+			deleted_at = current_timestamp,
+			token_version = token_version + 1
+		where id = $1
+	`
+
+	_, err := q.Exec(ctx, sql, accountId, password)
+	if err != nil {
+		return failure.New("unable to anonymize account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	sql = `
+		update player
+		set height = null, weight = null, handedness = null, racket = null, current_league_id = null
+		where account_id = $1
+	`
+
+	_, err = q.Exec(ctx, sql, accountId)
+	if err != nil {
+		return failure.New("unable to anonymize player", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// deleteAccountData removes everything the account could sign in with or was granted
+func (s *store) deleteAccountData(ctx context.Context, tx db.Tx, accountId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	for _, table := range []string{"refresh_token", "email_change", "account_totp", "totp_recovery_code", "account_identity", "api_key", "scoped_role_grant", "idempotency_key"} {
+		_, err := q.Exec(ctx, fmt.Sprintf("delete from %s where account_id = $1", table), accountId)
+		if err != nil {
+			return failure.New("unable to delete account data", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+	}
+
+	return nil
+}
@@ -7,33 +7,35 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/pagination"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/privacy"
 	"github.com/markovidakovic/gdsi/server/response"
 )
 
 type handler struct {
 	service *service
-	store   *store
+	store   Repository
 }
 
-func newHandler(cfg *config.Config, db *db.Conn) *handler {
+func newHandler(cfg *config.Config, repo Repository) *handler {
 	h := &handler{}
-	h.store = newStore(db)
+	h.store = repo
 	h.service = newService(cfg, h.store)
 	return h
 }
 
 // @Summary Get
-// @Description Get players
+// @Description Get players. The personal data is shown according to the privacy settings of each player
 // @Tags players
 // @Produce json
 // @Param page query int false "page"
 // @Param per_page query int false "per page"
 // @Param order_by query string false "order by"
+// @Param If-None-Match header string false "etag of the list the client has"
 // @Success 200 {array} players.PlayerModel "OK"
+// @Success 304 "Not modified"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 500 {object} failure.Failure "Internal server error"
@@ -42,7 +44,13 @@ func newHandler(cfg *config.Config, db *db.Conn) *handler {
 func (h *handler) getPlayers(w http.ResponseWriter, r *http.Request) {
 	query := params.NewQuery(r.URL.Query())
 
-	players, count, err := h.service.processGetPlayers(r.Context(), query)
+	viewer, err := privacy.ViewerFromRequest(r)
+	if err != nil {
+		response.WriteFailure(w, failure.New("unable to check permissions", err))
+		return
+	}
+
+	players, count, err := h.service.processGetPlayers(r.Context(), viewer, query)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -59,7 +67,7 @@ func (h *handler) getPlayers(w http.ResponseWriter, r *http.Request) {
 
 	result := pagination.NewPaginated(query.Page, query.PerPage, count, players)
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteCached(w, r, result)
 }
 
 // @Summary Get by id
@@ -67,7 +75,8 @@ func (h *handler) getPlayers(w http.ResponseWriter, r *http.Request) {
 // @Tags players
 // @Produce json
 // @Param player_id path string true "player id"
-// @Success 200 {object} matches.MatchModel "OK"
+// @Success 200 {object} players.PlayerModel "OK"
+// @Header 200 {string} ETag "tag of the player representation, it differs by the viewer"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
@@ -75,7 +84,13 @@ func (h *handler) getPlayers(w http.ResponseWriter, r *http.Request) {
 // @Security BearerAuth
 // @Router /v1/players/{player_id} [get]
 func (h *handler) getPlayer(w http.ResponseWriter, r *http.Request) {
-	result, err := h.store.findPlayer(r.Context(), chi.URLParam(r, "player_id"))
+	viewer, err := privacy.ViewerFromRequest(r)
+	if err != nil {
+		response.WriteFailure(w, failure.New("unable to check permissions", err))
+		return
+	}
+
+	result, err := h.service.processGetPlayer(r.Context(), viewer, chi.URLParam(r, "player_id"))
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -90,7 +105,7 @@ func (h *handler) getPlayer(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
 
 // @Summary Update
@@ -99,11 +114,14 @@ func (h *handler) getPlayer(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param player_id path string true "player id"
+// @Param If-Match header string false "etag of the player the update is based on"
 // @Param body body players.UpdatePlayerRequestModel true "Request body"
 // @Success 200 {object} players.PlayerModel "OK"
+// @Header 200 {string} ETag "tag of the updated player representation"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/players/{player_id} [put]
@@ -120,7 +138,13 @@ func (h *handler) updatePlayer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.store.updatePlayer(r.Context(), nil, chi.URLParam(r, "player_id"), model)
+	viewer, err := privacy.ViewerFromRequest(r)
+	if err != nil {
+		response.WriteFailure(w, failure.New("unable to check permissions", err))
+		return
+	}
+
+	result, err := h.service.processUpdatePlayer(r.Context(), viewer, chi.URLParam(r, "player_id"), params.NewIfMatch(r.Header), model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -135,5 +159,5 @@ func (h *handler) updatePlayer(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteBodyTagged(w, http.StatusOK, result)
 }
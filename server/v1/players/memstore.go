@@ -0,0 +1,210 @@
+package players
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/privacy"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortFields = map[string]func(a, b PlayerModel) int{
+	"created_at": func(a, b PlayerModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+// MemRelations is privacy.Relations for the in-memory backend
+func MemRelations(m *memdb.DB, viewer privacy.Viewer, playerIds []string) (map[string]privacy.Relation, error) {
+	result, done := privacy.BaseRelations(viewer, playerIds)
+	if done {
+		return result, nil
+	}
+
+	err := m.Read(nil, func(t *memdb.Tables) error {
+		viewerPl, ok := t.Players[viewer.PlayerId]
+		if !ok {
+			// the join on the viewer finds no rows
+			return nil
+		}
+
+		for _, id := range playerIds {
+			pl, ok := t.Players[id]
+			if !ok {
+				continue
+			}
+
+			rel := result[id]
+			rel.LeagueMate = viewerPl.CurrentLeagueId.Valid && pl.CurrentLeagueId == viewerPl.CurrentLeagueId
+			// a match without a winner is still to be played
+			for _, match := range t.Matches {
+				if match.WinnerId.Valid {
+					continue
+				}
+				if (match.PlayerOneId == id && match.PlayerTwoId == viewer.PlayerId) || (match.PlayerOneId == viewer.PlayerId && match.PlayerTwoId == id) {
+					rel.Opponent = true
+					break
+				}
+			}
+			result[id] = rel
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find player relations", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return result, nil
+}
+
+// MemPlayerModel builds the model of the player row of the in-memory backend, like the select
+// of the player with its account and current league
+func MemPlayerModel(t *memdb.Tables, p db.Player) PlayerModel {
+	account := t.Accounts[p.AccountId]
+
+	pm := PlayerModel{
+		Id:               p.Id,
+		Height:           memdb.Float64Ptr(p.Height),
+		Weight:           memdb.Float64Ptr(p.Weight),
+		Handedness:       memdb.StringPtr(p.Handedness),
+		Racket:           memdb.StringPtr(p.Racket),
+		MatchesExpected:  p.MatchesExpected,
+		MatchesPlayed:    p.MatchesPlayed,
+		MatchesWon:       p.MatchesWon,
+		MatchesScheduled: p.MatchesScheduled,
+		SeasonsPlayed:    p.SeasonsPlayed,
+		Account: AccountModel{
+			Id:          account.Id,
+			Name:        account.Name,
+			Email:       &account.Email,
+			PhoneNumber: memdb.StringPtr(account.PhoneNumber),
+			Dob:         memdb.TimePtr(account.Dob),
+		},
+		Version:   p.Version,
+		CreatedAt: p.CreatedAt,
+		Privacy: privacy.Settings{
+			PhoneNumber: privacy.Visibility(p.PrivacyPhone),
+			Email:       privacy.Visibility(p.PrivacyEmail),
+			Dob:         privacy.Visibility(p.PrivacyDob),
+			Physique:    privacy.Visibility(p.PrivacyPhysique),
+		},
+	}
+	if p.CurrentLeagueId.Valid {
+		pm.CurrentLeague = &CurrentLeagueModel{
+			Id:    p.CurrentLeagueId.String,
+			Title: t.Leagues[p.CurrentLeagueId.String].Title,
+		}
+	}
+
+	return pm
+}
+
+func (s *memStore) findPlayers(ctx context.Context, limit, offset int, sort *params.OrderBy) ([]PlayerModel, error) {
+	dest := []PlayerModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, p := range t.Players {
+			dest = append(dest, MemPlayerModel(t, p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find players", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, sort, memSortFields, func(a, b PlayerModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countPlayers(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		count = len(t.Players)
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count players", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findPlayer(ctx context.Context, playerId string) (*PlayerModel, error) {
+	var dest *PlayerModel
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		if p, ok := t.Players[playerId]; ok {
+			pm := MemPlayerModel(t, p)
+			dest = &pm
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find player", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("player not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) updatePlayer(ctx context.Context, tx db.Tx, playerId string, version int, model UpdatePlayerRequestModel) (*PlayerModel, error) {
+	var dest *PlayerModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		p, ok := t.Players[playerId]
+		if !ok || p.Version != version {
+			return nil
+		}
+		p.Height = memdb.NullFloat64(model.Height)
+		p.Weight = memdb.NullFloat64(model.Weight)
+		p.Handedness = memdb.NullString(model.Handedness)
+		p.Racket = memdb.NullString(model.Racket)
+		p.Version++
+		t.Players[playerId] = p
+		pm := MemPlayerModel(t, p)
+		dest = &pm
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to update player", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("player was changed since it was read", failure.ErrStale)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) checkPlayerOwnership(ctx context.Context, playerId, accountId string) (bool, error) {
+	var exists bool
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		p, ok := t.Players[playerId]
+		exists = ok && p.AccountId == accountId
+		return nil
+	})
+	if err != nil {
+		return false, failure.New("unable to check player ownership", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return exists, nil
+}
+
+func (s *memStore) findRelations(ctx context.Context, viewer privacy.Viewer, playerIds []string) (map[string]privacy.Relation, error) {
+	return MemRelations(s.db, viewer, playerIds)
+}
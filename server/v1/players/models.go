@@ -1,6 +1,7 @@
 package players
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/privacy"
 )
 
 type PlayerModel struct {
@@ -23,12 +25,36 @@ type PlayerModel struct {
 	SeasonsPlayed    int                 `json:"seasons_played"`
 	Account          AccountModel        `json:"account"`
 	CurrentLeague    *CurrentLeagueModel `json:"current_league"`
+	Version          int                 `json:"version"`
 	CreatedAt        time.Time           `json:"created_at"`
+	Privacy          privacy.Settings    `json:"-"`
+}
+
+// auditAccount is the account of the player without its personal data
+type auditAccount struct {
+	Id string `json:"id"`
+}
+
+// AuditState is the player without its physique and the personal data of its account
+func (pm PlayerModel) AuditState() any {
+	return struct {
+		Id               string              `json:"id"`
+		MatchesExpected  int                 `json:"matches_expected"`
+		MatchesPlayed    int                 `json:"matches_played"`
+		MatchesWon       int                 `json:"matches_won"`
+		MatchesScheduled int                 `json:"matches_scheduled"`
+		SeasonsPlayed    int                 `json:"seasons_played"`
+		Account          auditAccount        `json:"account"`
+		CurrentLeague    *CurrentLeagueModel `json:"current_league"`
+		Version          int                 `json:"version"`
+		CreatedAt        time.Time           `json:"created_at"`
+	}{pm.Id, pm.MatchesExpected, pm.MatchesPlayed, pm.MatchesWon, pm.MatchesScheduled, pm.SeasonsPlayed, auditAccount{pm.Account.Id}, pm.CurrentLeague, pm.Version, pm.CreatedAt}
 }
 
 func (pm *PlayerModel) ScanRow(row pgx.Row) error {
 	var leagueId, leagueTitle sql.NullString
-	err := row.Scan(&pm.Id, &pm.Height, &pm.Weight, &pm.Handedness, &pm.Racket, &pm.MatchesExpected, &pm.MatchesPlayed, &pm.MatchesWon, &pm.MatchesScheduled, &pm.SeasonsPlayed, &pm.Account.Id, &pm.Account.Name, &leagueId, &leagueTitle, &pm.CreatedAt)
+	var phone, email, dob, physique string
+	err := row.Scan(&pm.Id, &pm.Height, &pm.Weight, &pm.Handedness, &pm.Racket, &pm.MatchesExpected, &pm.MatchesPlayed, &pm.MatchesWon, &pm.MatchesScheduled, &pm.SeasonsPlayed, &pm.Account.Id, &pm.Account.Name, &pm.Account.Email, &pm.Account.PhoneNumber, &pm.Account.Dob, &phone, &email, &dob, &physique, &leagueId, &leagueTitle, &pm.Version, &pm.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return failure.New("scanning player row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
@@ -36,6 +62,13 @@ func (pm *PlayerModel) ScanRow(row pgx.Row) error {
 		return failure.New("database error scanning player row", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
+	pm.Privacy = privacy.Settings{
+		PhoneNumber: privacy.Visibility(phone),
+		Email:       privacy.Visibility(email),
+		Dob:         privacy.Visibility(dob),
+		Physique:    privacy.Visibility(physique),
+	}
+
 	if !leagueId.Valid {
 		pm.CurrentLeague = nil
 	} else {
@@ -50,11 +83,19 @@ func (pm *PlayerModel) ScanRow(row pgx.Row) error {
 
 func (pm *PlayerModel) ScanRows(rows pgx.Rows) error {
 	var leagueId, leagueTitle sql.NullString
-	err := rows.Scan(&pm.Id, &pm.Height, &pm.Weight, &pm.Handedness, &pm.Racket, &pm.MatchesExpected, &pm.MatchesPlayed, &pm.MatchesWon, &pm.MatchesScheduled, &pm.SeasonsPlayed, &pm.Account.Id, &pm.Account.Name, &leagueId, &leagueTitle, &pm.CreatedAt)
+	var phone, email, dob, physique string
+	err := rows.Scan(&pm.Id, &pm.Height, &pm.Weight, &pm.Handedness, &pm.Racket, &pm.MatchesExpected, &pm.MatchesPlayed, &pm.MatchesWon, &pm.MatchesScheduled, &pm.SeasonsPlayed, &pm.Account.Id, &pm.Account.Name, &pm.Account.Email, &pm.Account.PhoneNumber, &pm.Account.Dob, &phone, &email, &dob, &physique, &leagueId, &leagueTitle, &pm.Version, &pm.CreatedAt)
 	if err != nil {
 		return failure.New("database error scanning player rows", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 
+	pm.Privacy = privacy.Settings{
+		PhoneNumber: privacy.Visibility(phone),
+		Email:       privacy.Visibility(email),
+		Dob:         privacy.Visibility(dob),
+		Physique:    privacy.Visibility(physique),
+	}
+
 	if !leagueId.Valid {
 		pm.CurrentLeague = nil
 	} else {
@@ -66,9 +107,53 @@ func (pm *PlayerModel) ScanRows(rows pgx.Rows) error {
 	return nil
 }
 
+// Redact clears the personal data the viewer with the relation isn't allowed to see
+func (pm *PlayerModel) Redact(rel privacy.Relation) {
+	if !pm.Privacy.PhoneNumber.Allows(rel) {
+		pm.Account.PhoneNumber = nil
+	}
+	if !pm.Privacy.Email.Allows(rel) {
+		pm.Account.Email = nil
+	}
+	if pm.Privacy.Dob.Allows(rel) && pm.Account.Dob != nil {
+		age := privacy.Age(*pm.Account.Dob, time.Now())
+		pm.Account.Age = &age
+	} else {
+		pm.Account.Dob = nil
+		pm.Account.Age = nil
+	}
+	if !pm.Privacy.Physique.Allows(rel) {
+		pm.Height = nil
+		pm.Weight = nil
+	}
+}
+
+// Protect redacts the players for the viewer. Every player model leaving the api has to pass through it
+func Protect(ctx context.Context, relations privacy.RelationFinder, viewer privacy.Viewer, pms ...*PlayerModel) error {
+	ids := make([]string, 0, len(pms))
+	for _, pm := range pms {
+		ids = append(ids, pm.Id)
+	}
+
+	rels, err := relations(ctx, viewer, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, pm := range pms {
+		pm.Redact(rels[pm.Id])
+	}
+
+	return nil
+}
+
 type AccountModel struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
+	Id          string     `json:"id"`
+	Name        string     `json:"name"`
+	Email       *string    `json:"email"`
+	PhoneNumber *string    `json:"phone_number"`
+	Dob         *time.Time `json:"dob"`
+	Age         *int       `json:"age"`
 }
 
 type CurrentLeagueModel struct {
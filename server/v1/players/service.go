@@ -2,25 +2,34 @@ package players
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
 	"github.com/markovidakovic/gdsi/server/config"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/privacy"
+	"github.com/markovidakovic/gdsi/server/tracing"
 )
 
 type service struct {
 	cfg   *config.Config
-	store *store
+	store Repository
 }
 
-func newService(cfg *config.Config, store *store) *service {
+func newService(cfg *config.Config, store Repository) *service {
 	return &service{
 		cfg,
 		store,
 	}
 }
 
-func (s *service) processGetPlayers(ctx context.Context, query *params.Query) ([]PlayerModel, int, error) {
+func (s *service) processGetPlayers(ctx context.Context, viewer privacy.Viewer, query *params.Query) ([]PlayerModel, int, error) {
+	ctx, span := tracing.Start(ctx, "players.processGetPlayers")
+	defer span.End()
+
 	count, err := s.store.countPlayers(ctx)
 	if err != nil {
 		return nil, 0, failure.New("unable to get players", err)
@@ -33,5 +42,84 @@ func (s *service) processGetPlayers(ctx context.Context, query *params.Query) ([
 		return nil, 0, err
 	}
 
+	pms := make([]*PlayerModel, len(result))
+	for i := range result {
+		pms[i] = &result[i]
+	}
+	err = Protect(ctx, s.store.findRelations, viewer, pms...)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	return result, count, nil
 }
+
+func (s *service) processGetPlayer(ctx context.Context, viewer privacy.Viewer, playerId string) (*PlayerModel, error) {
+	ctx, span := tracing.Start(ctx, "players.processGetPlayer")
+	defer span.End()
+
+	pm, err := s.store.findPlayer(ctx, playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Protect(ctx, s.store.findRelations, viewer, pm)
+	if err != nil {
+		return nil, err
+	}
+
+	return pm, nil
+}
+
+func (s *service) processUpdatePlayer(ctx context.Context, viewer privacy.Viewer, playerId string, ifMatch params.IfMatch, model UpdatePlayerRequestModel) (*PlayerModel, error) {
+	ctx, span := tracing.Start(ctx, "players.processUpdatePlayer")
+	defer span.End()
+
+	before, err := s.store.findPlayer(ctx, playerId)
+	if err != nil {
+		return nil, err
+	}
+	// the tag is of the representation the viewer read
+	current := *before
+	err = Protect(ctx, s.store.findRelations, viewer, &current)
+	if err != nil {
+		return nil, err
+	}
+	if err := ifMatch.CheckData("player", current); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update player", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the update player tx", "error", err)
+		}
+	}()
+
+	pm, err := s.store.updatePlayer(ctx, tx, playerId, before.Version, model)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUpdate, ResourceType: audit.Player, ResourceId: playerId, Before: before, After: pm})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update player", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	err = Protect(ctx, s.store.findRelations, viewer, pm)
+	if err != nil {
+		return nil, err
+	}
+
+	return pm, nil
+}
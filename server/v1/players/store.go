@@ -9,18 +9,37 @@ import (
 	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/privacy"
 )
 
+// Repository holds the player profiles with their match statistics, and the relations of the
+// viewers to the players the privacy checks need
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	findPlayers(ctx context.Context, limit, offset int, sort *params.OrderBy) ([]PlayerModel, error)
+	countPlayers(ctx context.Context) (int, error)
+	findPlayer(ctx context.Context, playerId string) (*PlayerModel, error)
+	updatePlayer(ctx context.Context, tx db.Tx, playerId string, version int, model UpdatePlayerRequestModel) (*PlayerModel, error)
+	checkPlayerOwnership(ctx context.Context, playerId, accountId string) (bool, error)
+	findRelations(ctx context.Context, viewer privacy.Viewer, playerIds []string) (map[string]privacy.Relation, error)
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
 	return &store{
 		db,
 	}
 }
 
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
 var allowedSortFields = map[string]string{
 	"created_at": "player.created_at",
 }
@@ -40,8 +59,16 @@ func (s *store) findPlayers(ctx context.Context, limit, offset int, sort *params
 			player.seasons_played,
 			account.id as account_id,
 			account.name as account_name,
+			account.email as account_email,
+			account.phone_number as account_phone_number,
+			account.dob as account_dob,
+			player.privacy_phone_number,
+			player.privacy_email,
+			player.privacy_dob,
+			player.privacy_physique,
 			league.id as league_id,
 			league.title as league_title,
+			player.version,
 			player.created_at
 		from player
 		join account on player.account_id = account.id
@@ -111,8 +138,16 @@ func (s *store) findPlayer(ctx context.Context, playerId string) (*PlayerModel,
 			player.seasons_played,
 			account.id as account_id,
 			account.name as account_name,
+			account.email as account_email,
+			account.phone_number as account_phone_number,
+			account.dob as account_dob,
+			player.privacy_phone_number,
+			player.privacy_email,
+			player.privacy_dob,
+			player.privacy_physique,
 			league.id as league_id,
 			league.title as league_title,
+			player.version,
 			player.created_at
 		from player
 		join account on player.account_id = account.id
@@ -134,7 +169,8 @@ func (s *store) findPlayer(ctx context.Context, playerId string) (*PlayerModel,
 	return &dest, nil
 }
 
-func (s *store) updatePlayer(ctx context.Context, tx pgx.Tx, playerId string, model UpdatePlayerRequestModel) (*PlayerModel, error) {
+// updatePlayer changes the player if it's still at the version, it fails with ErrStale otherwise
+func (s *store) updatePlayer(ctx context.Context, tx db.Tx, playerId string, version int, model UpdatePlayerRequestModel) (*PlayerModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -146,8 +182,8 @@ func (s *store) updatePlayer(ctx context.Context, tx pgx.Tx, playerId string, mo
 		with updated_player as (
 			update player 
 			set height = $1, weight = $2, handedness = $3, racket = $4
-			where id = $5
-			returning id, height, weight, handedness, racket, matches_expected, matches_played, matches_won, matches_scheduled, seasons_played, account_id, current_league_id, created_at
+			where id = $5 and version = $6
+			returning id, height, weight, handedness, racket, matches_expected, matches_played, matches_won, matches_scheduled, seasons_played, account_id, current_league_id, privacy_phone_number, privacy_email, privacy_dob, privacy_physique, version, created_at
 		)
 		select 
 			up.id,
@@ -162,8 +198,16 @@ func (s *store) updatePlayer(ctx context.Context, tx pgx.Tx, playerId string, mo
 			up.seasons_played,
 			account.id as account_id,
 			account.name as account_name,
+			account.email as account_email,
+			account.phone_number as account_phone_number,
+			account.dob as account_dob,
+			up.privacy_phone_number,
+			up.privacy_email,
+			up.privacy_dob,
+			up.privacy_physique,
 			league.id as league_id,
 			league.title as league_title,
+			up.version,
 			up.created_at
 		from updated_player up
 		join account on up.account_id = account.id
@@ -172,11 +216,11 @@ func (s *store) updatePlayer(ctx context.Context, tx pgx.Tx, playerId string, mo
 
 	var dest PlayerModel
 
-	row := q.QueryRow(ctx, sql, model.Height, model.Weight, model.Handedness, model.Racket, playerId)
+	row := q.QueryRow(ctx, sql, model.Height, model.Weight, model.Handedness, model.Racket, playerId, version)
 	err := dest.ScanRow(row)
 	if err != nil {
 		if errors.Is(err, failure.ErrNotFound) {
-			return nil, failure.New("player for update not found", err)
+			return nil, failure.New("player was changed since it was read", fmt.Errorf("%w -> %v", failure.ErrStale, err))
 		}
 		return nil, failure.New("unable to update player", err)
 	}
@@ -200,3 +244,7 @@ func (s *store) checkPlayerOwnership(ctx context.Context, playerId, accountId st
 
 	return exists, nil
 }
+
+func (s *store) findRelations(ctx context.Context, viewer privacy.Viewer, playerIds []string) (map[string]privacy.Relation, error) {
+	return privacy.Relations(ctx, s.db, viewer, playerIds)
+}
@@ -0,0 +1,36 @@
+package roles
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/router"
+)
+
+type api struct {
+	hdl *handler
+}
+
+var _ router.Mounter = (*api)(nil)
+
+func New(cfg *config.Config, repo Repository) *api {
+	return &api{
+		hdl: newHandler(cfg, repo),
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Use(middleware.RequirePermission(permission.ManageRoles))
+
+	r.Get("/", a.hdl.getRoles)
+	r.Post("/", a.hdl.createRole)
+	r.Get("/{role_name}", a.hdl.getRole)
+	r.Put("/{role_name}", a.hdl.updateRole)
+	r.Delete("/{role_name}", a.hdl.deleteRole)
+}
+
+// NewLoader returns the role grants loader backing permission.Has
+func NewLoader(repo Repository) permission.Loader {
+	return repo.findGrants
+}
@@ -0,0 +1,212 @@
+package roles
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/response"
+)
+
+type handler struct {
+	store   Repository
+	service *service
+}
+
+func newHandler(cfg *config.Config, repo Repository) *handler {
+	h := &handler{}
+	h.store = repo
+	h.service = newService(cfg, h.store)
+	return h
+}
+
+// @Summary Get
+// @Description Get the roles with their permissions
+// @Tags admin roles
+// @Produce json
+// @Success 200 {array} roles.RoleModel "OK"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/roles [get]
+func (h *handler) getRoles(w http.ResponseWriter, r *http.Request) {
+	result, err := h.store.findRoles(r.Context())
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Create
+// @Description Create a custom role, the permissions can't exceed the requester role permissions
+// @Tags admin roles
+// @Accept json
+// @Produce json
+// @Param body body roles.CreateRoleRequestModel true "Request body"
+// @Success 201 {object} roles.RoleModel "Created"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/roles [post]
+func (h *handler) createRole(w http.ResponseWriter, r *http.Request) {
+	var model CreateRoleRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	requesterRole := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	result, err := h.service.processCreateRole(r.Context(), requesterRole, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusCreated, result)
+}
+
+// @Summary Get by name
+// @Description Get role by name
+// @Tags admin roles
+// @Produce json
+// @Param role_name path string true "role name"
+// @Success 200 {object} roles.RoleModel "OK"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/roles/{role_name} [get]
+func (h *handler) getRole(w http.ResponseWriter, r *http.Request) {
+	result, err := h.store.findRole(r.Context(), nil, chi.URLParam(r, "role_name"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Update
+// @Description Replace the role description and permissions. The developer and service roles can't be modified
+// @Tags admin roles
+// @Accept json
+// @Produce json
+// @Param role_name path string true "role name"
+// @Param body body roles.UpdateRoleRequestModel true "Request body"
+// @Success 200 {object} roles.RoleModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/roles/{role_name} [put]
+func (h *handler) updateRole(w http.ResponseWriter, r *http.Request) {
+	var model UpdateRoleRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	requesterRole := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+
+	result, err := h.service.processUpdateRole(r.Context(), requesterRole, chi.URLParam(r, "role_name"), model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Delete
+// @Description Delete a custom role which is not assigned to any account
+// @Tags admin roles
+// @Param role_name path string true "role name"
+// @Success 204 "No content"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 409 {object} failure.Failure "Conflict"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/roles/{role_name} [delete]
+func (h *handler) deleteRole(w http.ResponseWriter, r *http.Request) {
+	err := h.service.processDeleteRole(r.Context(), chi.URLParam(r, "role_name"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,191 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toRoleModel(t *memdb.Tables, r db.Role) RoleModel {
+	rm := RoleModel{
+		Name:        r.Name,
+		Description: memdb.StringPtr(r.Description),
+		IsSystem:    r.IsSystem,
+		Permissions: []string{},
+		CreatedAt:   r.CreatedAt,
+	}
+	for _, rp := range t.RolePermissions {
+		if rp.RoleName == r.Name {
+			rm.Permissions = append(rm.Permissions, rp.Permission)
+		}
+	}
+	slices.Sort(rm.Permissions)
+	return rm
+}
+
+func (s *memStore) findRoles(ctx context.Context) ([]RoleModel, error) {
+	dest := []RoleModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, r := range t.Roles {
+			dest = append(dest, toRoleModel(t, r))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find roles", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	// the system roles first, then by name
+	slices.SortFunc(dest, func(a, b RoleModel) int {
+		if a.IsSystem != b.IsSystem {
+			if a.IsSystem {
+				return -1
+			}
+			return 1
+		}
+		return memdb.CompareText(a.Name, b.Name)
+	})
+
+	return dest, nil
+}
+
+func (s *memStore) findRole(ctx context.Context, tx db.Tx, name string) (*RoleModel, error) {
+	var dest *RoleModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		if r, ok := t.Roles[name]; ok {
+			rm := toRoleModel(t, r)
+			dest = &rm
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("role not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) insertRole(ctx context.Context, tx db.Tx, name string, description *string) error {
+	var exists bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, exists = t.Roles[name]; exists {
+			return nil
+		}
+		t.Roles[name] = db.Role{Name: name, Description: memdb.NullString(description), CreatedAt: s.db.Now()}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to insert role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if exists {
+		return failure.New("role already exists", failure.ErrDuplicate)
+	}
+
+	return nil
+}
+
+func (s *memStore) updateRoleDescription(ctx context.Context, tx db.Tx, name string, description *string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if r, ok := t.Roles[name]; ok {
+			r.Description = memdb.NullString(description)
+			t.Roles[name] = r
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to update role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) replaceGrants(ctx context.Context, tx db.Tx, name string, perms []string) error {
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Roles[name]; !ok && len(perms) > 0 {
+			return fmt.Errorf("role %s not found", name)
+		}
+		kept := []string{}
+		t.RolePermissions = slices.DeleteFunc(t.RolePermissions, func(rp db.RolePermission) bool {
+			if rp.RoleName != name {
+				return false
+			}
+			if slices.Contains(perms, rp.Permission) {
+				kept = append(kept, rp.Permission)
+				return false
+			}
+			return true
+		})
+		for _, perm := range perms {
+			if !slices.Contains(kept, perm) {
+				kept = append(kept, perm)
+				t.RolePermissions = append(t.RolePermissions, db.RolePermission{RoleName: name, Permission: perm, CreatedAt: s.db.Now()})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to update role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *memStore) deleteRole(ctx context.Context, tx db.Tx, name string) error {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		var err error
+		found, err = t.DeleteRole(name)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, memdb.ErrForeignKey) {
+			return failure.New("role is still assigned to accounts", fmt.Errorf("%w -> %v", failure.ErrCantModify, err))
+		}
+		return failure.New("unable to delete role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("role not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *memStore) findGrants(ctx context.Context) (map[string][]permission.Permission, error) {
+	grants := make(map[string][]permission.Permission)
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, rp := range t.RolePermissions {
+			grants[rp.RoleName] = append(grants[rp.RoleName], permission.Permission(rp.Permission))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to load role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return grants, nil
+}
@@ -0,0 +1,101 @@
+package roles
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+var roleNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+type RoleModel struct {
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	IsSystem    bool      `json:"is_system"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (rm *RoleModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&rm.Name, &rm.Description, &rm.IsSystem, &rm.Permissions, &rm.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning role row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+func (rm *RoleModel) ScanRows(rows pgx.Rows) error {
+	err := rows.Scan(&rm.Name, &rm.Description, &rm.IsSystem, &rm.Permissions, &rm.CreatedAt)
+	if err != nil {
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+type CreateRoleRequestModel struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (m CreateRoleRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Name == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "name",
+			Message:  "Name field is required",
+			Location: "body",
+		})
+	} else if !roleNameRegex.MatchString(m.Name) {
+		inv = append(inv, failure.InvalidField{
+			Field:    "name",
+			Message:  "Name must be 2-50 lowercase letters, digits or underscores, starting with a letter",
+			Location: "body",
+		})
+	}
+	inv = append(inv, validatePermissions(m.Permissions)...)
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+type UpdateRoleRequestModel struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (m UpdateRoleRequestModel) Validate() []failure.InvalidField {
+	inv := validatePermissions(m.Permissions)
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+func validatePermissions(perms []string) []failure.InvalidField {
+	var inv []failure.InvalidField
+	for _, p := range perms {
+		if !permission.Exists(permission.Permission(p)) {
+			inv = append(inv, failure.InvalidField{
+				Field:    "permissions",
+				Message:  fmt.Sprintf("Unknown permission %q", p),
+				Location: "body",
+			})
+		}
+	}
+	return inv
+}
@@ -0,0 +1,215 @@
+package roles
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/tracing"
+)
+
+type service struct {
+	cfg   *config.Config
+	store Repository
+}
+
+func newService(cfg *config.Config, store Repository) *service {
+	return &service{
+		cfg,
+		store,
+	}
+}
+
+// checkGrantable makes sure the requester only hands out permissions their own role holds
+func checkGrantable(requesterRole string, perms []string) error {
+	for _, p := range perms {
+		if !permission.Has(requesterRole, permission.Permission(p)) {
+			return failure.NewValidation("invalid request parameters", []failure.InvalidField{
+				{Field: "permissions", Message: fmt.Sprintf("Permission %q exceeds your role permissions", p), Location: "body"},
+			})
+		}
+	}
+	return nil
+}
+
+// reloadGrants refreshes the grants permission.Has checks against. The change is already
+// committed, so a failed reload is only logged and picked up with the next periodic reload
+func reloadGrants(ctx context.Context) {
+	if err := permission.Reload(ctx); err != nil {
+		slog.ErrorContext(ctx, "reloading role permissions", "error", err)
+	}
+}
+
+func (s *service) processCreateRole(ctx context.Context, requesterRole string, model CreateRoleRequestModel) (*RoleModel, error) {
+	ctx, span := tracing.Start(ctx, "roles.processCreateRole")
+	defer span.End()
+
+	err := checkGrantable(requesterRole, model.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.Permissions == nil {
+		model.Permissions = []string{}
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		if tx != nil {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
+			}
+		}
+	}()
+
+	err = s.store.insertRole(ctx, tx, model.Name, model.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.replaceGrants(ctx, tx, model.Name, model.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.store.findRole(ctx, tx, model.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.Role, ResourceId: model.Name, After: result})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	reloadGrants(ctx)
+
+	return result, nil
+}
+
+// processUpdateRole replaces the role description and permissions. The developer role always
+// keeps every permission and the service role never gets any, so they can't be changed
+func (s *service) processUpdateRole(ctx context.Context, requesterRole, name string, model UpdateRoleRequestModel) (*RoleModel, error) {
+	ctx, span := tracing.Start(ctx, "roles.processUpdateRole")
+	defer span.End()
+
+	if name == permission.RoleDeveloper || name == permission.RoleService {
+		return nil, failure.New(fmt.Sprintf("the %s role can't be modified", name), failure.ErrCantModify)
+	}
+
+	err := checkGrantable(requesterRole, model.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.Permissions == nil {
+		model.Permissions = []string{}
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		if tx != nil {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
+			}
+		}
+	}()
+
+	before, err := s.store.findRole(ctx, tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.updateRoleDescription(ctx, tx, name, model.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.replaceGrants(ctx, tx, name, model.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.store.findRole(ctx, tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUpdate, ResourceType: audit.Role, ResourceId: name, Before: before, After: result})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	reloadGrants(ctx)
+
+	return result, nil
+}
+
+func (s *service) processDeleteRole(ctx context.Context, name string) error {
+	ctx, span := tracing.Start(ctx, "roles.processDeleteRole")
+	defer span.End()
+
+	role, err := s.store.findRole(ctx, nil, name)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return failure.New("built-in roles can't be deleted", failure.ErrCantModify)
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to delete role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "rolling back tx", "error", err)
+		}
+	}()
+
+	err = s.store.deleteRole(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionDelete, ResourceType: audit.Role, ResourceId: name, Before: role})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to delete role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	reloadGrants(ctx)
+
+	return nil
+}
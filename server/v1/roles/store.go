@@ -0,0 +1,239 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+// Repository holds the roles and the permissions granted to each of them
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	findRoles(ctx context.Context) ([]RoleModel, error)
+	findRole(ctx context.Context, tx db.Tx, name string) (*RoleModel, error)
+	insertRole(ctx context.Context, tx db.Tx, name string, description *string) error
+	updateRoleDescription(ctx context.Context, tx db.Tx, name string, description *string) error
+	replaceGrants(ctx context.Context, tx db.Tx, name string, perms []string) error
+	deleteRole(ctx context.Context, tx db.Tx, name string) error
+	findGrants(ctx context.Context) (map[string][]permission.Permission, error)
+}
+
+type store struct {
+	db *db.Conn
+}
+
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
+		db,
+	}
+}
+
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+const selectRole = `
+	select
+		role.name as role_name,
+		role.description as role_description,
+		role.is_system as role_is_system,
+		coalesce(array_agg(role_permission.permission order by role_permission.permission) filter (where role_permission.permission is not null), '{}') as role_permissions,
+		role.created_at as role_created_at
+	from role
+	left join role_permission on role_permission.role_name = role.name
+`
+
+func (s *store) findRoles(ctx context.Context) ([]RoleModel, error) {
+	sql := selectRole + `
+		group by role.name
+		order by role.is_system desc, role.name
+	`
+
+	rows, err := s.db.Query(ctx, sql)
+	if err != nil {
+		return nil, failure.New("unable to find roles", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	var dest = []RoleModel{}
+	for rows.Next() {
+		var rm RoleModel
+		err := rm.ScanRows(rows)
+		if err != nil {
+			return nil, failure.New("unable to find roles", err)
+		}
+		dest = append(dest, rm)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, failure.New("unable to find roles", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) findRole(ctx context.Context, tx db.Tx, name string) (*RoleModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := selectRole + `
+		where role.name = $1
+		group by role.name
+	`
+
+	var dest RoleModel
+	row := q.QueryRow(ctx, sql, name)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("role not found", err)
+		}
+		return nil, failure.New("unable to find role", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) insertRole(ctx context.Context, tx db.Tx, name string, description *string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into role (name, description)
+		values ($1, $2)
+	`
+
+	_, err := q.Exec(ctx, sql, name, description)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return failure.New("role already exists", fmt.Errorf("%w -> %v", failure.ErrDuplicate, err))
+		}
+		return failure.New("unable to insert role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) updateRoleDescription(ctx context.Context, tx db.Tx, name string, description *string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update role
+		set description = $1
+		where name = $2
+	`
+
+	_, err := q.Exec(ctx, sql, description, name)
+	if err != nil {
+		return failure.New("unable to update role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// replaceGrants sets the role permissions to exactly the provided ones
+func (s *store) replaceGrants(ctx context.Context, tx db.Tx, name string, perms []string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		delete from role_permission
+		where role_name = $1 and permission != all($2)
+	`
+
+	_, err := q.Exec(ctx, sql, name, perms)
+	if err != nil {
+		return failure.New("unable to update role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	sql = `
+		insert into role_permission (role_name, permission)
+		select $1, unnest($2::text[])
+		on conflict do nothing
+	`
+
+	_, err = q.Exec(ctx, sql, name, perms)
+	if err != nil {
+		return failure.New("unable to update role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) deleteRole(ctx context.Context, tx db.Tx, name string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `delete from role where name = $1`
+
+	ct, err := q.Exec(ctx, sql, name)
+	if err != nil {
+		// foreign key violation on account.role
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return failure.New("role is still assigned to accounts", fmt.Errorf("%w -> %v", failure.ErrCantModify, err))
+		}
+		return failure.New("unable to delete role", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("role not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+// findGrants loads the permissions of all the roles for permission.Has
+func (s *store) findGrants(ctx context.Context) (map[string][]permission.Permission, error) {
+	sql := `select role_name, permission from role_permission`
+
+	rows, err := s.db.Query(ctx, sql)
+	if err != nil {
+		return nil, failure.New("unable to load role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	grants := make(map[string][]permission.Permission)
+	for rows.Next() {
+		var role, perm string
+		err := rows.Scan(&role, &perm)
+		if err != nil {
+			return nil, failure.New("unable to load role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		}
+		grants[role] = append(grants[role], permission.Permission(perm))
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, failure.New("unable to load role permissions", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return grants, nil
+}
@@ -3,7 +3,7 @@ package seasons
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/metrics"
 	"github.com/markovidakovic/gdsi/server/middleware"
 	"github.com/markovidakovic/gdsi/server/permission"
 	"github.com/markovidakovic/gdsi/server/router"
@@ -15,9 +15,9 @@ type api struct {
 
 var _ router.Mounter = (*api)(nil)
 
-func New(cfg *config.Config, db *db.Conn) *api {
+func New(cfg *config.Config, repo Repository) *api {
 	return &api{
-		hdl: newHandler(cfg, db),
+		hdl: newHandler(cfg, repo),
 	}
 }
 
@@ -28,3 +28,7 @@ func (a *api) Mount(r chi.Router) {
 	r.With(middleware.URLPathUUIDParams("season_id")).With(middleware.RequirePermission(permission.UpdateSeason)).Put("/{season_id}", a.hdl.updateSeason)
 	r.With(middleware.URLPathUUIDParams("season_id")).With(middleware.RequirePermission(permission.DeleteSeason)).Delete("/{season_id}", a.hdl.deleteSeason)
 }
+
+func NewActiveCounter(repo Repository) metrics.ActiveSeasonsCounter {
+	return repo.countActiveSeasons
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/pagination"
 	"github.com/markovidakovic/gdsi/server/params"
@@ -16,12 +15,12 @@ import (
 
 type handler struct {
 	service *service
-	store   *store
+	store   Repository
 }
 
-func newHandler(cfg *config.Config, db *db.Conn) *handler {
+func newHandler(cfg *config.Config, repo Repository) *handler {
 	h := &handler{}
-	h.store = newStore(db)
+	h.store = repo
 	h.service = newService(cfg, h.store)
 	return h
 }
@@ -66,7 +65,7 @@ func (h *handler) createSeason(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusCreated, result)
+	response.WriteTagged(w, http.StatusCreated, result.Version, result)
 }
 
 // @Summary Get
@@ -76,7 +75,9 @@ func (h *handler) createSeason(w http.ResponseWriter, r *http.Request) {
 // @Param page query int false "page"
 // @Param per_page query int false "per page"
 // @Param order_by query string false "order by"
+// @Param If-None-Match header string false "etag of the list the client has"
 // @Success 200 {array} seasons.SeasonModel "OK"
+// @Success 304 "Not modified"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 500 {object} failure.Failure "Internal server error"
@@ -102,7 +103,7 @@ func (h *handler) getSeasons(w http.ResponseWriter, r *http.Request) {
 
 	result := pagination.NewPaginated(query.Page, query.PerPage, count, seasons)
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteCached(w, r, result)
 }
 
 // @Summary Get by id
@@ -111,6 +112,7 @@ func (h *handler) getSeasons(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param season_id path string true "season id"
 // @Success 200 {object} seasons.SeasonModel "OK"
+// @Header 200 {string} ETag "version of the season"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
@@ -133,7 +135,7 @@ func (h *handler) getSeason(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteTagged(w, http.StatusOK, result.Version, result)
 }
 
 // @Summary Update
@@ -142,11 +144,14 @@ func (h *handler) getSeason(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param season_id path string true "season id"
+// @Param If-Match header string false "etag of the season the update is based on"
 // @Param body body seasons.UpdateSeasonRequestModel true "Request body"
 // @Success 200 {object} seasons.SeasonModel "OK"
+// @Header 200 {string} ETag "version of the updated season"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id} [put]
@@ -162,7 +167,7 @@ func (h *handler) updateSeason(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.store.updateSeason(r.Context(), nil, chi.URLParam(r, "season_id"), model)
+	result, err := h.service.processUpdateSeason(r.Context(), chi.URLParam(r, "season_id"), params.NewIfMatch(r.Header), model)
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -177,7 +182,7 @@ func (h *handler) updateSeason(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response.WriteSuccess(w, http.StatusOK, result)
+	response.WriteTagged(w, http.StatusOK, result.Version, result)
 }
 
 // @Summary Delete
@@ -185,15 +190,17 @@ func (h *handler) updateSeason(w http.ResponseWriter, r *http.Request) {
 // @Tags seasons
 // @Produce json
 // @Param season_id path string true "season id"
+// @Param If-Match header string false "etag of the season the deletion is based on"
 // @Success 204 "No content"
 // @Failure 400 {object} failure.ValidationFailure "Bad request"
 // @Failure 401 {object} failure.Failure "Unauthorized"
 // @Failure 404 {object} failure.Failure "Not found"
+// @Failure 412 {object} failure.Failure "Changed since it was read"
 // @Failure 500 {object} failure.Failure "Internal server error"
 // @Security BearerAuth
 // @Router /v1/seasons/{season_id} [delete]
 func (h *handler) deleteSeason(w http.ResponseWriter, r *http.Request) {
-	err := h.store.deleteSeason(r.Context(), nil, chi.URLParam(r, "season_id"))
+	err := h.service.processDeleteSeason(r.Context(), chi.URLParam(r, "season_id"), params.NewIfMatch(r.Header))
 	if err != nil {
 		switch f := err.(type) {
 		case *failure.ValidationFailure:
@@ -0,0 +1,187 @@
+package seasons
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortFields = map[string]func(a, b SeasonModel) int{
+	"created_at": func(a, b SeasonModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toSeasonModel(t *memdb.Tables, season db.Season) SeasonModel {
+	var sm SeasonModel
+	sm.Id = season.Id
+	sm.Title = season.Title
+	sm.Description = memdb.StringPtr(season.Description)
+	sm.StartDate = season.StartDate
+	sm.EndDate = season.EndDate
+	sm.Creator.Id = season.CreatorId
+	sm.Creator.Name = t.Accounts[season.CreatorId].Name
+	sm.Version = season.Version
+	sm.CreatedAt = season.CreatedAt
+	return sm
+}
+
+func (s *memStore) insertSeason(ctx context.Context, tx db.Tx, model CreateSeasonRequestModel) (SeasonModel, error) {
+	var dest SeasonModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Accounts[model.CreatorId]; !ok {
+			return fmt.Errorf("%w -> creator %s not found", failure.ErrInternal, model.CreatorId)
+		}
+		season := db.Season{
+			Id:          memdb.NewId(),
+			Title:       model.Title,
+			Description: memdb.NullString(model.Description),
+			StartDate:   model.StartDate.Time(),
+			EndDate:     model.EndDate.Time(),
+			CreatorId:   model.CreatorId,
+			Version:     1,
+			CreatedAt:   s.db.Now(),
+		}
+		t.Seasons[season.Id] = season
+		dest = toSeasonModel(t, season)
+		return nil
+	})
+	if err != nil {
+		return dest, failure.New("failed to insert season", err)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) findSeasons(ctx context.Context, limit, offset int, sort *params.OrderBy) ([]SeasonModel, error) {
+	dest := []SeasonModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, season := range t.Seasons {
+			dest = append(dest, toSeasonModel(t, season))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find seasons", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, sort, memSortFields, func(a, b SeasonModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countSeasons(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		count = len(t.Seasons)
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count seasons", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) countActiveSeasons(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, season := range t.Seasons {
+			if !season.StartDate.After(today) && !season.EndDate.Before(today) {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count active seasons", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findSeason(ctx context.Context, seasonId string) (*SeasonModel, error) {
+	var dest *SeasonModel
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		if season, ok := t.Seasons[seasonId]; ok {
+			sm := toSeasonModel(t, season)
+			dest = &sm
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("season not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) updateSeason(ctx context.Context, tx db.Tx, seasonId string, version int, model UpdateSeasonRequestModel) (*SeasonModel, error) {
+	var dest *SeasonModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		season, ok := t.Seasons[seasonId]
+		if !ok || season.Version != version {
+			return nil
+		}
+		season.Title = model.Title
+		season.Description = memdb.NullString(model.Description)
+		season.StartDate = model.StartDate.Time()
+		season.EndDate = model.EndDate.Time()
+		season.Version++
+		t.Seasons[seasonId] = season
+		sm := toSeasonModel(t, season)
+		dest = &sm
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to update season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("season was changed since it was read", failure.ErrStale)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) deleteSeason(ctx context.Context, tx db.Tx, seasonId string, version int) error {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if season, ok := t.Seasons[seasonId]; !ok || season.Version != version {
+			return nil
+		}
+		var err error
+		found, err = t.DeleteSeason(seasonId)
+		return err
+	})
+	if err != nil {
+		return failure.New("unable to delete season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("season was changed since it was read", failure.ErrStale)
+	}
+
+	return nil
+}
@@ -20,11 +20,12 @@ type SeasonModel struct {
 		Id   string `json:"id"`
 		Name string `json:"name"`
 	} `json:"creator"`
+	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 func (sm *SeasonModel) ScanRow(row pgx.Row) error {
-	err := row.Scan(&sm.Id, &sm.Title, &sm.Description, &sm.StartDate, &sm.EndDate, &sm.Creator.Id, &sm.Creator.Name, &sm.CreatedAt)
+	err := row.Scan(&sm.Id, &sm.Title, &sm.Description, &sm.StartDate, &sm.EndDate, &sm.Creator.Id, &sm.Creator.Name, &sm.Version, &sm.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return failure.New("scanning season row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
@@ -36,7 +37,7 @@ func (sm *SeasonModel) ScanRow(row pgx.Row) error {
 }
 
 func (sm *SeasonModel) ScanRows(rows pgx.Rows) error {
-	err := rows.Scan(&sm.Id, &sm.Title, &sm.Description, &sm.StartDate, &sm.EndDate, &sm.Creator.Id, &sm.Creator.Name, &sm.CreatedAt)
+	err := rows.Scan(&sm.Id, &sm.Title, &sm.Description, &sm.StartDate, &sm.EndDate, &sm.Creator.Id, &sm.Creator.Name, &sm.Version, &sm.CreatedAt)
 	if err != nil {
 		return failure.New("database error scanning season rows", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
@@ -2,19 +2,24 @@ package seasons
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
 	"github.com/markovidakovic/gdsi/server/config"
 	"github.com/markovidakovic/gdsi/server/failure"
 	"github.com/markovidakovic/gdsi/server/middleware"
 	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/tracing"
 )
 
 type service struct {
 	cfg   *config.Config
-	store *store
+	store Repository
 }
 
-func newService(cfg *config.Config, store *store) *service {
+func newService(cfg *config.Config, store Repository) *service {
 	return &service{
 		cfg,
 		store,
@@ -22,17 +27,45 @@ func newService(cfg *config.Config, store *store) *service {
 }
 
 func (s *service) processCreateSeason(ctx context.Context, model CreateSeasonRequestModel) (SeasonModel, error) {
+	ctx, span := tracing.Start(ctx, "seasons.processCreateSeason")
+	defer span.End()
+
 	model.CreatorId = ctx.Value(middleware.AccountIdCtxKey).(string)
 
-	sm, err := s.store.insertSeason(ctx, nil, model)
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return SeasonModel{}, failure.New("unable to create season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the create season tx", "error", err)
+		}
+	}()
+
+	sm, err := s.store.insertSeason(ctx, tx, model)
 	if err != nil {
 		return sm, err
 	}
 
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.Season, ResourceId: sm.Id, After: sm})
+	if err != nil {
+		return SeasonModel{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return SeasonModel{}, failure.New("unable to create season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
 	return sm, nil
 }
 
 func (s *service) processGetSeasons(ctx context.Context, query *params.Query) ([]SeasonModel, int, error) {
+	ctx, span := tracing.Start(ctx, "seasons.processGetSeasons")
+	defer span.End()
+
 	count, err := s.store.countSeasons(ctx)
 	if err != nil {
 		return nil, 0, failure.New("unable to get seasons", err)
@@ -47,3 +80,87 @@ func (s *service) processGetSeasons(ctx context.Context, query *params.Query) ([
 
 	return result, count, nil
 }
+
+func (s *service) processUpdateSeason(ctx context.Context, seasonId string, ifMatch params.IfMatch, model UpdateSeasonRequestModel) (*SeasonModel, error) {
+	ctx, span := tracing.Start(ctx, "seasons.processUpdateSeason")
+	defer span.End()
+
+	before, err := s.store.findSeason(ctx, seasonId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ifMatch.Check("season", before.Version); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the update season tx", "error", err)
+		}
+	}()
+
+	sm, err := s.store.updateSeason(ctx, tx, seasonId, before.Version, model)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionUpdate, ResourceType: audit.Season, ResourceId: seasonId, Before: before, After: sm})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to update season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return sm, nil
+}
+
+func (s *service) processDeleteSeason(ctx context.Context, seasonId string, ifMatch params.IfMatch) error {
+	ctx, span := tracing.Start(ctx, "seasons.processDeleteSeason")
+	defer span.End()
+
+	before, err := s.store.findSeason(ctx, seasonId)
+	if err != nil {
+		return err
+	}
+	if err := ifMatch.Check("season", before.Version); err != nil {
+		return err
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to delete season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "failed to rollback the delete season tx", "error", err)
+		}
+	}()
+
+	err = s.store.deleteSeason(ctx, tx, seasonId, before.Version)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionDelete, ResourceType: audit.Season, ResourceId: seasonId, Before: before})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to delete season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
@@ -11,21 +11,39 @@ import (
 	"github.com/markovidakovic/gdsi/server/params"
 )
 
+// Repository holds the seasons and their date ranges
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	insertSeason(ctx context.Context, tx db.Tx, model CreateSeasonRequestModel) (SeasonModel, error)
+	findSeasons(ctx context.Context, limit, offset int, sort *params.OrderBy) ([]SeasonModel, error)
+	countSeasons(ctx context.Context) (int, error)
+	countActiveSeasons(ctx context.Context) (int, error)
+	findSeason(ctx context.Context, seasonId string) (*SeasonModel, error)
+	updateSeason(ctx context.Context, tx db.Tx, seasonId string, version int, model UpdateSeasonRequestModel) (*SeasonModel, error)
+	deleteSeason(ctx context.Context, tx db.Tx, seasonId string, version int) error
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
 	return &store{
 		db,
 	}
 }
 
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
 var allowedSortFields = map[string]string{
 	"created_at": "season.created_at",
 }
 
-func (s *store) insertSeason(ctx context.Context, tx pgx.Tx, model CreateSeasonRequestModel) (SeasonModel, error) {
+func (s *store) insertSeason(ctx context.Context, tx db.Tx, model CreateSeasonRequestModel) (SeasonModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -37,9 +55,9 @@ func (s *store) insertSeason(ctx context.Context, tx pgx.Tx, model CreateSeasonR
 		with inserted_season as (
 			insert into season (title, description, start_date, end_date, creator_id)
 			values ($1, $2, $3, $4, $5)
-			returning id, title, description, start_date, end_date, creator_id, created_at
+			returning id, title, description, start_date, end_date, creator_id, version, created_at
 		)
-		select s.id, s.title, s.description, s.start_date, s.end_date, account.id as creator_id, account.name as creator_name, s.created_at
+		select s.id, s.title, s.description, s.start_date, s.end_date, account.id as creator_id, account.name as creator_name, s.version, s.created_at
 		from inserted_season s
 		join account on s.creator_id = account.id
 	`
@@ -65,6 +83,7 @@ func (s *store) findSeasons(ctx context.Context, limit, offset int, sort *params
 			season.end_date,
 			account.id as creator_id,
 			account.name as creator_name,
+			season.version,
 			season.created_at
 		from season
 		join account on season.creator_id = account.id
@@ -119,6 +138,17 @@ func (s *store) countSeasons(ctx context.Context) (int, error) {
 	return count, nil
 }
 
+// countActiveSeasons counts the seasons running today
+func (s *store) countActiveSeasons(ctx context.Context) (int, error) {
+	var count int
+	sql := `select count(*) from season where start_date <= current_date and end_date >= current_date`
+	err := s.db.QueryRow(ctx, sql).Scan(&count)
+	if err != nil {
+		return 0, failure.New("unable to count active seasons", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
 func (s *store) findSeason(ctx context.Context, seasonId string) (*SeasonModel, error) {
 	sql := `
 		select
@@ -129,6 +159,7 @@ func (s *store) findSeason(ctx context.Context, seasonId string) (*SeasonModel,
 			season.end_date,
 			account.id as creator_id,
 			account.name as creator_name,
+			season.version,
 			season.created_at
 		from season
 		join account on season.creator_id = account.id
@@ -149,7 +180,8 @@ func (s *store) findSeason(ctx context.Context, seasonId string) (*SeasonModel,
 	return &dest, nil
 }
 
-func (s *store) updateSeason(ctx context.Context, tx pgx.Tx, seasonId string, model UpdateSeasonRequestModel) (*SeasonModel, error) {
+// updateSeason changes the season if it's still at the version, it fails with ErrStale otherwise
+func (s *store) updateSeason(ctx context.Context, tx db.Tx, seasonId string, version int, model UpdateSeasonRequestModel) (*SeasonModel, error) {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -161,8 +193,8 @@ func (s *store) updateSeason(ctx context.Context, tx pgx.Tx, seasonId string, mo
 		with updated_season as (
 			update season 
 			set title = $1, description = $2, start_date = $3, end_date = $4
-			where id = $5
-			returning id, title, description, start_date, end_date, creator_id, created_at
+			where id = $5 and version = $6
+			returning id, title, description, start_date, end_date, creator_id, version, created_at
 		)
 		select 
 			us.id as season_id,
@@ -172,6 +204,7 @@ func (s *store) updateSeason(ctx context.Context, tx pgx.Tx, seasonId string, mo
 			us.end_date as season_end_date,
 			account.id as creator_id,
 			account.name as creator_name,
+			us.version as season_version,
 			us.created_at as season_created_at
 		from updated_season us
 		join account on us.creator_id = account.id
@@ -179,11 +212,11 @@ func (s *store) updateSeason(ctx context.Context, tx pgx.Tx, seasonId string, mo
 
 	var dest SeasonModel
 
-	row := q.QueryRow(ctx, sql, model.Title, model.Description, model.StartDate, model.EndDate, seasonId)
+	row := q.QueryRow(ctx, sql, model.Title, model.Description, model.StartDate, model.EndDate, seasonId, version)
 	err := dest.ScanRow(row)
 	if err != nil {
 		if errors.Is(err, failure.ErrNotFound) {
-			return nil, failure.New("season for update not found", err)
+			return nil, failure.New("season was changed since it was read", fmt.Errorf("%w -> %v", failure.ErrStale, err))
 		}
 		return nil, failure.New("unable to update season", err)
 	}
@@ -191,7 +224,8 @@ func (s *store) updateSeason(ctx context.Context, tx pgx.Tx, seasonId string, mo
 	return &dest, nil
 }
 
-func (s *store) deleteSeason(ctx context.Context, tx pgx.Tx, seasonId string) error {
+// deleteSeason removes the season if it's still at the version, it fails with ErrStale otherwise
+func (s *store) deleteSeason(ctx context.Context, tx db.Tx, seasonId string, version int) error {
 	var q db.Querier
 	if tx != nil {
 		q = tx
@@ -200,15 +234,15 @@ func (s *store) deleteSeason(ctx context.Context, tx pgx.Tx, seasonId string) er
 	}
 
 	sql := `
-		delete from season where id = $1
+		delete from season where id = $1 and version = $2
 	`
 
-	ct, err := q.Exec(ctx, sql, seasonId)
+	ct, err := q.Exec(ctx, sql, seasonId, version)
 	if err != nil {
 		return failure.New("unable to delete season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 	}
 	if ct.RowsAffected() == 0 {
-		return failure.New("season for deletion not found", failure.ErrNotFound)
+		return failure.New("season was changed since it was read", failure.ErrStale)
 	}
 
 	return nil
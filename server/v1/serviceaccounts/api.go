@@ -0,0 +1,33 @@
+package serviceaccounts
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/router"
+)
+
+type api struct {
+	hdl *handler
+}
+
+var _ router.Mounter = (*api)(nil)
+
+func New(cfg *config.Config, repo Repository) *api {
+	return &api{
+		hdl: newHandler(cfg, repo),
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Use(middleware.RequirePermission(permission.ManageServiceAccounts))
+
+	r.Post("/", a.hdl.createServiceAccount)
+	r.With(middleware.URLQueryPaginationParams).Get("/", a.hdl.getServiceAccounts)
+	r.With(middleware.URLPathUUIDParams("service_account_id")).Get("/{service_account_id}", a.hdl.getServiceAccount)
+	r.With(middleware.URLPathUUIDParams("service_account_id")).Delete("/{service_account_id}", a.hdl.disableServiceAccount)
+	r.With(middleware.URLPathUUIDParams("service_account_id")).Post("/{service_account_id}/api-keys", a.hdl.createAPIKey)
+	r.With(middleware.URLPathUUIDParams("service_account_id")).Get("/{service_account_id}/api-keys", a.hdl.getAPIKeys)
+	r.With(middleware.URLPathUUIDParams("service_account_id", "api_key_id")).Delete("/{service_account_id}/api-keys/{api_key_id}", a.hdl.revokeAPIKey)
+}
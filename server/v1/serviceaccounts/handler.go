@@ -0,0 +1,287 @@
+package serviceaccounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/pagination"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/response"
+)
+
+type handler struct {
+	store   Repository
+	service *service
+}
+
+func newHandler(cfg *config.Config, repo Repository) *handler {
+	h := &handler{}
+	h.store = repo
+	h.service = newService(cfg, h.store)
+	return h
+}
+
+// @Summary Create
+// @Description Create a new service account
+// @Tags service accounts
+// @Accept json
+// @Produce json
+// @Param body body serviceaccounts.CreateServiceAccountRequestModel true "Request body"
+// @Success 201 {object} serviceaccounts.ServiceAccountModel "Created"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/service-accounts [post]
+func (h *handler) createServiceAccount(w http.ResponseWriter, r *http.Request) {
+	var model CreateServiceAccountRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+
+	result, err := h.service.processCreateServiceAccount(r.Context(), accountId, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusCreated, result)
+}
+
+// @Summary Get
+// @Description Get service accounts
+// @Tags service accounts
+// @Produce json
+// @Param page query int false "page"
+// @Param per_page query int false "per page"
+// @Param order_by query string false "order by"
+// @Success 200 {array} serviceaccounts.ServiceAccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/service-accounts [get]
+func (h *handler) getServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	query := params.NewQuery(r.URL.Query())
+
+	accounts, count, err := h.service.processGetServiceAccounts(r.Context(), query)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	result := pagination.NewPaginated(query.Page, query.PerPage, count, accounts)
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Get by id
+// @Description Get service account by id
+// @Tags service accounts
+// @Produce json
+// @Param service_account_id path string true "service account id"
+// @Success 200 {object} serviceaccounts.ServiceAccountModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/service-accounts/{service_account_id} [get]
+func (h *handler) getServiceAccount(w http.ResponseWriter, r *http.Request) {
+	result, err := h.store.findServiceAccount(r.Context(), nil, chi.URLParam(r, "service_account_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Disable
+// @Description Disable the service account and revoke all of its api keys
+// @Tags service accounts
+// @Param service_account_id path string true "service account id"
+// @Success 204 "No content"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/service-accounts/{service_account_id} [delete]
+func (h *handler) disableServiceAccount(w http.ResponseWriter, r *http.Request) {
+	err := h.service.processDisableServiceAccount(r.Context(), chi.URLParam(r, "service_account_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
+
+// @Summary Create api key
+// @Description Create an api key for the service account. The key is only returned once, the scopes can't exceed the creator role permissions
+// @Tags service accounts
+// @Accept json
+// @Produce json
+// @Param service_account_id path string true "service account id"
+// @Param body body serviceaccounts.CreateAPIKeyRequestModel true "Request body"
+// @Success 201 {object} serviceaccounts.CreatedAPIKeyModel "Created"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/service-accounts/{service_account_id}/api-keys [post]
+func (h *handler) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var model CreateAPIKeyRequestModel
+	err := json.NewDecoder(r.Body).Decode(&model)
+	if err != nil {
+		response.WriteFailure(w, failure.New("invalid request body", fmt.Errorf("%w -> %v", failure.ErrBadRequest, err)))
+		return
+	}
+
+	if valErr := model.Validate(); valErr != nil {
+		response.WriteFailure(w, failure.NewValidation("validation failed", valErr))
+		return
+	}
+
+	accountId := r.Context().Value(middleware.AccountIdCtxKey).(string)
+	role := r.Context().Value(middleware.AccountRoleCtxKey).(string)
+	_, viaAPIKey := r.Context().Value(middleware.APIKeyIdCtxKey).(string)
+
+	result, err := h.service.processCreateAPIKey(r.Context(), chi.URLParam(r, "service_account_id"), accountId, role, viaAPIKey, model)
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusCreated, result)
+}
+
+// @Summary Get api keys
+// @Description Get the api keys of the service account
+// @Tags service accounts
+// @Produce json
+// @Param service_account_id path string true "service account id"
+// @Success 200 {array} serviceaccounts.APIKeyModel "OK"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/service-accounts/{service_account_id}/api-keys [get]
+func (h *handler) getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.processGetAPIKeys(r.Context(), chi.URLParam(r, "service_account_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusOK, result)
+}
+
+// @Summary Revoke api key
+// @Description Revoke an api key of the service account
+// @Tags service accounts
+// @Param service_account_id path string true "service account id"
+// @Param api_key_id path string true "api key id"
+// @Success 204 "No content"
+// @Failure 400 {object} failure.ValidationFailure "Bad request"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 404 {object} failure.Failure "Not found"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/service-accounts/{service_account_id}/api-keys/{api_key_id} [delete]
+func (h *handler) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	err := h.store.revokeAPIKey(r.Context(), nil, chi.URLParam(r, "service_account_id"), chi.URLParam(r, "api_key_id"))
+	if err != nil {
+		switch f := err.(type) {
+		case *failure.ValidationFailure:
+			response.WriteFailure(w, f)
+			return
+		case *failure.Failure:
+			response.WriteFailure(w, f)
+			return
+		default:
+			response.WriteFailure(w, failure.New("internal server error", err))
+			return
+		}
+	}
+
+	response.WriteSuccess(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,245 @@
+package serviceaccounts
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+var memSortingFields = map[string]func(a, b ServiceAccountModel) int{
+	"name":       func(a, b ServiceAccountModel) int { return memdb.CompareText(a.Name, b.Name) },
+	"created_at": func(a, b ServiceAccountModel) int { return memdb.CompareTime(a.CreatedAt, b.CreatedAt) },
+}
+
+func (s *memStore) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+func toServiceAccountModel(t *memdb.Tables, sa db.ServiceAccount) ServiceAccountModel {
+	sam := ServiceAccountModel{
+		Id:          sa.AccountId,
+		Name:        t.Accounts[sa.AccountId].Name,
+		Description: memdb.StringPtr(sa.Description),
+		DisabledAt:  memdb.TimePtr(sa.DisabledAt),
+		CreatedAt:   sa.CreatedAt,
+	}
+	if sa.CreatorId.Valid {
+		if creator, ok := t.Accounts[sa.CreatorId.String]; ok {
+			sam.setCreator(&creator.Id, &creator.Name)
+		}
+	}
+	return sam
+}
+
+func (s *memStore) insertServiceAccount(ctx context.Context, tx db.Tx, creatorId, email, password string, model CreateServiceAccountRequestModel) (string, error) {
+	var accountId string
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		now := s.db.Now()
+		account := db.Account{Id: memdb.NewId(), Name: model.Name, Email: email, Password: password, Role: permission.RoleService, CreatedAt: now}
+		if err := t.InsertAccount(account); err != nil {
+			return err
+		}
+		t.ServiceAccounts[account.Id] = db.ServiceAccount{
+			AccountId:   account.Id,
+			Description: memdb.NullString(model.Description),
+			CreatorId:   sql.NullString{String: creatorId, Valid: true},
+			CreatedAt:   now,
+		}
+		accountId = account.Id
+		return nil
+	})
+	if err != nil {
+		return "", failure.New("failed to insert service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return accountId, nil
+}
+
+func (s *memStore) findServiceAccounts(ctx context.Context, limit, offset int, orderBy *params.OrderBy) ([]ServiceAccountModel, error) {
+	dest := []ServiceAccountModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, sa := range t.ServiceAccounts {
+			dest = append(dest, toServiceAccountModel(t, sa))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find service accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	memdb.Sort(dest, orderBy, memSortingFields, func(a, b ServiceAccountModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return memdb.Page(dest, limit, offset), nil
+}
+
+func (s *memStore) countServiceAccounts(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		count = len(t.ServiceAccounts)
+		return nil
+	})
+	if err != nil {
+		return 0, failure.New("unable to count service accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *memStore) findServiceAccount(ctx context.Context, tx db.Tx, serviceAccountId string) (*ServiceAccountModel, error) {
+	var dest *ServiceAccountModel
+	err := s.db.Read(tx, func(t *memdb.Tables) error {
+		if sa, ok := t.ServiceAccounts[serviceAccountId]; ok {
+			sam := toServiceAccountModel(t, sa)
+			dest = &sam
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if dest == nil {
+		return nil, failure.New("service account not found", failure.ErrNotFound)
+	}
+
+	return dest, nil
+}
+
+func (s *memStore) disableServiceAccount(ctx context.Context, tx db.Tx, serviceAccountId string) error {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		sa, ok := t.ServiceAccounts[serviceAccountId]
+		if !ok {
+			return nil
+		}
+		found = true
+		now := sql.NullTime{Time: s.db.Now(), Valid: true}
+		if !sa.DisabledAt.Valid {
+			sa.DisabledAt = now
+			t.ServiceAccounts[serviceAccountId] = sa
+		}
+		// revoke the keys so re-enabling the account doesn't bring them back
+		for id, k := range t.APIKeys {
+			if k.AccountId == serviceAccountId && !k.RevokedAt.Valid {
+				k.RevokedAt = now
+				t.APIKeys[id] = k
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to disable service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("service account not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
+
+func toAPIKeyModel(k db.APIKey) APIKeyModel {
+	return APIKeyModel{
+		Id:         k.Id,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     slices.Clone(k.Scopes),
+		ExpiresAt:  memdb.TimePtr(k.ExpiresAt),
+		LastUsedAt: memdb.TimePtr(k.LastUsedAt),
+		RevokedAt:  memdb.TimePtr(k.RevokedAt),
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+func (s *memStore) insertAPIKey(ctx context.Context, tx db.Tx, accountId, creatorId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*APIKeyModel, error) {
+	var dest APIKeyModel
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		if _, ok := t.Accounts[accountId]; !ok {
+			return fmt.Errorf("%w -> account %s not found", failure.ErrInternal, accountId)
+		}
+		for _, k := range t.APIKeys {
+			if k.KeyHash == keyHash {
+				return fmt.Errorf("%w -> duplicate api key hash", failure.ErrInternal)
+			}
+		}
+		k := db.APIKey{
+			Id:        memdb.NewId(),
+			AccountId: accountId,
+			Name:      name,
+			Prefix:    prefix,
+			KeyHash:   keyHash,
+			Scopes:    slices.Clone(scopes),
+			ExpiresAt: memdb.NullTime(expiresAt),
+			CreatorId: sql.NullString{String: creatorId, Valid: true},
+			CreatedAt: s.db.Now(),
+		}
+		t.APIKeys[k.Id] = k
+		dest = toAPIKeyModel(k)
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("failed to insert api key", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *memStore) findAPIKeys(ctx context.Context, accountId string) ([]APIKeyModel, error) {
+	dest := []APIKeyModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, k := range t.APIKeys {
+			if k.AccountId == accountId {
+				dest = append(dest, toAPIKeyModel(k))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, failure.New("unable to find api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	slices.SortFunc(dest, func(a, b APIKeyModel) int { return memdb.CompareTime(b.CreatedAt, a.CreatedAt) })
+
+	return dest, nil
+}
+
+func (s *memStore) revokeAPIKey(ctx context.Context, tx db.Tx, accountId, apiKeyId string) error {
+	var found bool
+	err := s.db.Write(tx, func(t *memdb.Tables) error {
+		k, ok := t.APIKeys[apiKeyId]
+		if !ok || k.AccountId != accountId {
+			return nil
+		}
+		if !k.RevokedAt.Valid {
+			k.RevokedAt = sql.NullTime{Time: s.db.Now(), Valid: true}
+		}
+		t.APIKeys[apiKeyId] = k
+		found = true
+		return nil
+	})
+	if err != nil {
+		return failure.New("unable to revoke api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if !found {
+		return failure.New("api key not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
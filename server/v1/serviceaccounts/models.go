@@ -0,0 +1,178 @@
+package serviceaccounts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/permission"
+)
+
+type ServiceAccountModel struct {
+	Id          string                      `json:"id"`
+	Name        string                      `json:"name"`
+	Description *string                     `json:"description"`
+	Creator     *ServiceAccountCreatorModel `json:"creator"`
+	DisabledAt  *time.Time                  `json:"disabled_at"`
+	CreatedAt   time.Time                   `json:"created_at"`
+}
+
+type ServiceAccountCreatorModel struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// auditCreator is the creator of the service account without the name
+type auditCreator struct {
+	Id string `json:"id"`
+}
+
+// AuditState is the service account without the name of its creator
+func (sam ServiceAccountModel) AuditState() any {
+	state := struct {
+		ServiceAccountModel
+		Creator *auditCreator `json:"creator"`
+	}{ServiceAccountModel: sam}
+	if sam.Creator != nil {
+		state.Creator = &auditCreator{sam.Creator.Id}
+	}
+	return state
+}
+
+func (sam *ServiceAccountModel) ScanRow(row pgx.Row) error {
+	var creatorId, creatorName *string
+	err := row.Scan(&sam.Id, &sam.Name, &sam.Description, &creatorId, &creatorName, &sam.DisabledAt, &sam.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning service account row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	sam.setCreator(creatorId, creatorName)
+	return nil
+}
+
+func (sam *ServiceAccountModel) ScanRows(rows pgx.Rows) error {
+	var creatorId, creatorName *string
+	err := rows.Scan(&sam.Id, &sam.Name, &sam.Description, &creatorId, &creatorName, &sam.DisabledAt, &sam.CreatedAt)
+	if err != nil {
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	sam.setCreator(creatorId, creatorName)
+	return nil
+}
+
+// the creator is null once the creating account is deleted
+func (sam *ServiceAccountModel) setCreator(id, name *string) {
+	if id != nil && name != nil {
+		sam.Creator = &ServiceAccountCreatorModel{Id: *id, Name: *name}
+	}
+}
+
+type CreateServiceAccountRequestModel struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (m CreateServiceAccountRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Name == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "name",
+			Message:  "Name field is required",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
+
+type APIKeyModel struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (akm *APIKeyModel) ScanRow(row pgx.Row) error {
+	err := row.Scan(&akm.Id, &akm.Name, &akm.Prefix, &akm.Scopes, &akm.ExpiresAt, &akm.LastUsedAt, &akm.RevokedAt, &akm.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return failure.New("scanning api key row", fmt.Errorf("%w -> %v", failure.ErrNotFound, err))
+		}
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+func (akm *APIKeyModel) ScanRows(rows pgx.Rows) error {
+	err := rows.Scan(&akm.Id, &akm.Name, &akm.Prefix, &akm.Scopes, &akm.ExpiresAt, &akm.LastUsedAt, &akm.RevokedAt, &akm.CreatedAt)
+	if err != nil {
+		return failure.New("database error", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return nil
+}
+
+// the plain key is only returned once, right after it's created
+type CreatedAPIKeyModel struct {
+	APIKeyModel
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyRequestModel struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (m CreateAPIKeyRequestModel) Validate() []failure.InvalidField {
+	var inv []failure.InvalidField
+
+	if m.Name == "" {
+		inv = append(inv, failure.InvalidField{
+			Field:    "name",
+			Message:  "Name field is required",
+			Location: "body",
+		})
+	}
+	if len(m.Scopes) == 0 {
+		inv = append(inv, failure.InvalidField{
+			Field:    "scopes",
+			Message:  "At least one scope is required",
+			Location: "body",
+		})
+	}
+	for _, s := range m.Scopes {
+		if !permission.Exists(permission.Permission(s)) {
+			inv = append(inv, failure.InvalidField{
+				Field:    "scopes",
+				Message:  fmt.Sprintf("Unknown scope %q", s),
+				Location: "body",
+			})
+		}
+	}
+	if m.ExpiresAt != nil && !m.ExpiresAt.After(time.Now()) {
+		inv = append(inv, failure.InvalidField{
+			Field:    "expires_at",
+			Message:  "Expiration must be in the future",
+			Location: "body",
+		})
+	}
+
+	if len(inv) > 0 {
+		return inv
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package serviceaccounts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/params"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/sec"
+	"github.com/markovidakovic/gdsi/server/tracing"
+)
+
+type service struct {
+	cfg   *config.Config
+	store Repository
+}
+
+func newService(cfg *config.Config, store Repository) *service {
+	return &service{
+		cfg,
+		store,
+	}
+}
+
+func (s *service) processCreateServiceAccount(ctx context.Context, creatorId string, model CreateServiceAccountRequestModel) (*ServiceAccountModel, error) {
+	ctx, span := tracing.Start(ctx, "serviceaccounts.processCreateServiceAccount")
+	defer span.End()
+
+	// the service accounts can't log in, the password is random and never returned
+	pwd, err := sec.RandomToken(32)
+	if err != nil {
+		return nil, failure.New("unable to create service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	pwd, err = s.cfg.Passwords.Hash(pwd)
+	if err != nil {
+		return nil, failure.New("unable to create service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	email := fmt.Sprintf("svc-%s@service.invalid", uuid.NewString())
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		if tx != nil {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
+			}
+		}
+	}()
+
+	serviceAccountId, err := s.store.insertServiceAccount(ctx, tx, creatorId, email, pwd, model)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.store.findServiceAccount(ctx, tx, serviceAccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.ServiceAccount, ResourceId: serviceAccountId, After: result})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return result, nil
+}
+
+func (s *service) processGetServiceAccounts(ctx context.Context, query *params.Query) ([]ServiceAccountModel, int, error) {
+	ctx, span := tracing.Start(ctx, "serviceaccounts.processGetServiceAccounts")
+	defer span.End()
+
+	count, err := s.store.countServiceAccounts(ctx)
+	if err != nil {
+		return nil, 0, failure.New("unable to get service accounts", err)
+	}
+
+	limit, offset := query.CalcLimitAndOffset(count)
+
+	result, err := s.store.findServiceAccounts(ctx, limit, offset, query.OrderBy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, count, nil
+}
+
+// processDisableServiceAccount disables the service account and revokes all of its api keys
+func (s *service) processDisableServiceAccount(ctx context.Context, serviceAccountId string) error {
+	ctx, span := tracing.Start(ctx, "serviceaccounts.processDisableServiceAccount")
+	defer span.End()
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return failure.New("unable to disable service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		if tx != nil {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				slog.ErrorContext(ctx, "rolling back tx", "error", err)
+			}
+		}
+	}()
+
+	before, err := s.store.findServiceAccount(ctx, tx, serviceAccountId)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.disableServiceAccount(ctx, tx, serviceAccountId)
+	if err != nil {
+		return err
+	}
+
+	after, err := s.store.findServiceAccount(ctx, tx, serviceAccountId)
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionDeactivate, ResourceType: audit.ServiceAccount, ResourceId: serviceAccountId, Before: before, After: after})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return failure.New("unable to disable service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+// processCreateAPIKey issues a key for the service account. The creator can only hand
+// out scopes which their own role holds
+func (s *service) processCreateAPIKey(ctx context.Context, serviceAccountId, creatorId, creatorRole string, viaAPIKey bool, model CreateAPIKeyRequestModel) (*CreatedAPIKeyModel, error) {
+	ctx, span := tracing.Start(ctx, "serviceaccounts.processCreateAPIKey")
+	defer span.End()
+
+	if viaAPIKey {
+		return nil, failure.New("api keys can't be created with an api key", failure.ErrForbidden)
+	}
+
+	for _, sc := range model.Scopes {
+		if !permission.Has(creatorRole, permission.Permission(sc)) {
+			return nil, failure.NewValidation("invalid request parameters", []failure.InvalidField{
+				{Field: "scopes", Message: fmt.Sprintf("Scope %q exceeds your role permissions", sc), Location: "body"},
+			})
+		}
+	}
+
+	sa, err := s.store.findServiceAccount(ctx, nil, serviceAccountId)
+	if err != nil {
+		return nil, err
+	}
+	if sa.DisabledAt != nil {
+		return nil, failure.New("service account is disabled", failure.ErrCantModify)
+	}
+
+	key, prefix, err := sec.GenerateAPIKey()
+	if err != nil {
+		return nil, failure.New("unable to create api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	tx, err := s.store.begin(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			slog.ErrorContext(ctx, "rolling back tx", "error", err)
+		}
+	}()
+
+	created, err := s.store.insertAPIKey(ctx, tx, serviceAccountId, creatorId, model.Name, prefix, sec.HashToken(key), model.Scopes, model.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// the model never holds the key itself, only its prefix
+	err = audit.Record(ctx, tx, audit.Entry{Action: audit.ActionCreate, ResourceType: audit.APIKey, ResourceId: created.Id, After: created})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, failure.New("unable to create api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return &CreatedAPIKeyModel{
+		APIKeyModel: *created,
+		Key:         key,
+	}, nil
+}
+
+func (s *service) processGetAPIKeys(ctx context.Context, serviceAccountId string) ([]APIKeyModel, error) {
+	ctx, span := tracing.Start(ctx, "serviceaccounts.processGetAPIKeys")
+	defer span.End()
+
+	_, err := s.store.findServiceAccount(ctx, nil, serviceAccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.findAPIKeys(ctx, serviceAccountId)
+}
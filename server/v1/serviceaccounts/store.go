@@ -0,0 +1,286 @@
+package serviceaccounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/params"
+)
+
+// Repository holds the service accounts and the api keys issued to them
+type Repository interface {
+	begin(ctx context.Context) (db.Tx, error)
+	insertServiceAccount(ctx context.Context, tx db.Tx, creatorId, email, password string, model CreateServiceAccountRequestModel) (string, error)
+	findServiceAccounts(ctx context.Context, limit, offset int, orderBy *params.OrderBy) ([]ServiceAccountModel, error)
+	countServiceAccounts(ctx context.Context) (int, error)
+	findServiceAccount(ctx context.Context, tx db.Tx, serviceAccountId string) (*ServiceAccountModel, error)
+	disableServiceAccount(ctx context.Context, tx db.Tx, serviceAccountId string) error
+	insertAPIKey(ctx context.Context, tx db.Tx, accountId, creatorId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*APIKeyModel, error)
+	findAPIKeys(ctx context.Context, accountId string) ([]APIKeyModel, error)
+	revokeAPIKey(ctx context.Context, tx db.Tx, accountId, apiKeyId string) error
+}
+
+type store struct {
+	db *db.Conn
+}
+
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
+		db,
+	}
+}
+
+func (s *store) begin(ctx context.Context) (db.Tx, error) {
+	return s.db.Begin(ctx)
+}
+
+var sortingFields = map[string]string{
+	"name":       "account.name",
+	"created_at": "service_account.created_at",
+}
+
+const selectServiceAccount = `
+	select
+		account.id as service_account_id,
+		account.name as service_account_name,
+		service_account.description as service_account_description,
+		creator.id as creator_id,
+		creator.name as creator_name,
+		service_account.disabled_at as service_account_disabled_at,
+		service_account.created_at as service_account_created_at
+	from service_account
+	join account on service_account.account_id = account.id
+	left join account creator on service_account.creator_id = creator.id
+`
+
+// insertServiceAccount inserts the backing account with the service role and the service account
+// details. The account can't log in, it gets a placeholder email and an unusable password
+func (s *store) insertServiceAccount(ctx context.Context, tx db.Tx, creatorId, email, password string, model CreateServiceAccountRequestModel) (string, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into account (name, email, password, role)
+		values ($1, $2, $3, 'service')
+		returning id
+	`
+
+	var accountId string
+	err := q.QueryRow(ctx, sql, model.Name, email, password).Scan(&accountId)
+	if err != nil {
+		return "", failure.New("failed to insert service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	sql = `
+		insert into service_account (account_id, description, creator_id)
+		values ($1, $2, $3)
+	`
+
+	_, err = q.Exec(ctx, sql, accountId, model.Description, creatorId)
+	if err != nil {
+		return "", failure.New("failed to insert service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return accountId, nil
+}
+
+func (s *store) findServiceAccounts(ctx context.Context, limit, offset int, orderBy *params.OrderBy) ([]ServiceAccountModel, error) {
+	sql := selectServiceAccount
+
+	if orderBy != nil && orderBy.IsValid(sortingFields) {
+		sql += fmt.Sprintf("order by %s %s\n", sortingFields[orderBy.Field], orderBy.Direction)
+	} else {
+		sql += fmt.Sprintln("order by service_account.created_at desc")
+	}
+
+	var err error
+	var rows pgx.Rows
+	if limit >= 0 {
+		sql += "limit $1 offset $2"
+		rows, err = s.db.Query(ctx, sql, limit, offset)
+	} else {
+		rows, err = s.db.Query(ctx, sql)
+	}
+
+	if err != nil {
+		return nil, failure.New("unable to find service accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	var dest = []ServiceAccountModel{}
+	for rows.Next() {
+		var sam ServiceAccountModel
+		err := sam.ScanRows(rows)
+		if err != nil {
+			return nil, failure.New("unable to find service accounts", err)
+		}
+		dest = append(dest, sam)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, failure.New("unable to find service accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) countServiceAccounts(ctx context.Context) (int, error) {
+	var count int
+	sql := `select count(*) from service_account`
+	err := s.db.QueryRow(ctx, sql).Scan(&count)
+	if err != nil {
+		return 0, failure.New("unable to count service accounts", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	return count, nil
+}
+
+func (s *store) findServiceAccount(ctx context.Context, tx db.Tx, serviceAccountId string) (*ServiceAccountModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := selectServiceAccount + "where service_account.account_id = $1"
+
+	var dest ServiceAccountModel
+	row := q.QueryRow(ctx, sql, serviceAccountId)
+	err := dest.ScanRow(row)
+	if err != nil {
+		if errors.Is(err, failure.ErrNotFound) {
+			return nil, failure.New("service account not found", err)
+		}
+		return nil, failure.New("unable to find service account", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) disableServiceAccount(ctx context.Context, tx db.Tx, serviceAccountId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update service_account
+		set disabled_at = coalesce(disabled_at, current_timestamp)
+		where account_id = $1
+	`
+
+	ct, err := q.Exec(ctx, sql, serviceAccountId)
+	if err != nil {
+		return failure.New("unable to disable service account", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("service account not found", failure.ErrNotFound)
+	}
+
+	// revoke the keys so re-enabling the account doesn't bring them back
+	sql = `
+		update api_key
+		set revoked_at = current_timestamp
+		where account_id = $1 and revoked_at is null
+	`
+
+	_, err = q.Exec(ctx, sql, serviceAccountId)
+	if err != nil {
+		return failure.New("unable to revoke service account api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return nil
+}
+
+func (s *store) insertAPIKey(ctx context.Context, tx db.Tx, accountId, creatorId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*APIKeyModel, error) {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		insert into api_key (account_id, creator_id, name, prefix, key_hash, scopes, expires_at)
+		values ($1, $2, $3, $4, $5, $6, $7)
+		returning id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+	`
+
+	var dest APIKeyModel
+	row := q.QueryRow(ctx, sql, accountId, creatorId, name, prefix, keyHash, scopes, expiresAt)
+	err := dest.ScanRow(row)
+	if err != nil {
+		return nil, failure.New("failed to insert api key", err)
+	}
+
+	return &dest, nil
+}
+
+func (s *store) findAPIKeys(ctx context.Context, accountId string) ([]APIKeyModel, error) {
+	sql := `
+		select id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		from api_key
+		where account_id = $1
+		order by created_at desc
+	`
+
+	rows, err := s.db.Query(ctx, sql, accountId)
+	if err != nil {
+		return nil, failure.New("unable to find api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	defer rows.Close()
+
+	var dest = []APIKeyModel{}
+	for rows.Next() {
+		var akm APIKeyModel
+		err := akm.ScanRows(rows)
+		if err != nil {
+			return nil, failure.New("unable to find api keys", err)
+		}
+		dest = append(dest, akm)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, failure.New("unable to find api keys", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+
+	return dest, nil
+}
+
+func (s *store) revokeAPIKey(ctx context.Context, tx db.Tx, accountId, apiKeyId string) error {
+	var q db.Querier
+	if tx != nil {
+		q = tx
+	} else {
+		q = s.db
+	}
+
+	sql := `
+		update api_key
+		set revoked_at = coalesce(revoked_at, current_timestamp)
+		where id = $1 and account_id = $2
+	`
+
+	ct, err := q.Exec(ctx, sql, apiKeyId, accountId)
+	if err != nil {
+		return failure.New("unable to revoke api key", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+	}
+	if ct.RowsAffected() == 0 {
+		return failure.New("api key not found", failure.ErrNotFound)
+	}
+
+	return nil
+}
@@ -3,7 +3,6 @@ package standings
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/markovidakovic/gdsi/server/config"
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/middleware"
 	"github.com/markovidakovic/gdsi/server/router"
 	"github.com/markovidakovic/gdsi/server/validation"
@@ -15,9 +14,9 @@ type api struct {
 
 var _ router.Mounter = (*api)(nil)
 
-func New(cfg *config.Config, db *db.Conn) *api {
+func New(cfg *config.Config, repo Repository, validator *validation.Validator) *api {
 	return &api{
-		hdl: newHandler(cfg, db, validation.NewValidator(db)),
+		hdl: newHandler(cfg, repo, validator),
 	}
 }
 
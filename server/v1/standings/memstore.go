@@ -0,0 +1,76 @@
+package standings
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/memdb"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+func toStandingModel(t *memdb.Tables, st db.Standing) StandingModel {
+	sm := StandingModel{
+		Id:            st.Id,
+		Points:        st.Points,
+		MatchesPlayed: st.MatchesPlayed,
+		MatchesWon:    st.MatchesWon,
+		SetsWon:       st.SetsWon,
+		SetsLost:      st.SetsLost,
+		GamesWon:      st.GamesWon,
+		GamesLost:     st.GamesLost,
+		CreatedAt:     st.CreatedAt,
+	}
+	sm.Season.Id = st.SeasonId
+	sm.Season.Title = t.Seasons[st.SeasonId].Title
+	sm.League.Id = st.LeagueId
+	sm.League.Title = t.Leagues[st.LeagueId].Title
+	sm.Player.Id = st.PlayerId
+	sm.Player.Name = t.Accounts[t.Players[st.PlayerId].AccountId].Name
+	return sm
+}
+
+// compareStandings ranks the standings like the order by of findStandings, the best first
+func compareStandings(a, b StandingModel) int {
+	return cmp.Or(
+		cmp.Compare(b.Points, a.Points),
+		cmp.Compare(b.MatchesWon, a.MatchesWon),
+		cmp.Compare(b.SetsWon, a.SetsWon),
+		cmp.Compare(b.SetsWon-b.SetsLost, a.SetsWon-a.SetsLost),
+		cmp.Compare(b.GamesWon, a.GamesWon),
+		cmp.Compare(b.GamesWon-b.GamesLost, a.GamesWon-a.GamesLost),
+		b.CreatedAt.Compare(a.CreatedAt),
+	)
+}
+
+func (s *memStore) findStandings(ctx context.Context, seasonId, leagueId string) ([]StandingModel, error) {
+	dest := []StandingModel{}
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		for _, st := range t.Standings {
+			if st.SeasonId == seasonId && st.LeagueId == leagueId {
+				dest = append(dest, toStandingModel(t, st))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("quering standing rows: %v", err)
+	}
+
+	slices.SortFunc(dest, compareStandings)
+
+	return dest, nil
+}
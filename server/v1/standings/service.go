@@ -4,16 +4,17 @@ import (
 	"context"
 
 	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/tracing"
 	"github.com/markovidakovic/gdsi/server/validation"
 )
 
 type service struct {
 	cfg       *config.Config
-	store     *store
+	store     Repository
 	validator *validation.Validator
 }
 
-func newService(cfg *config.Config, store *store, validator *validation.Validator) *service {
+func newService(cfg *config.Config, store Repository, validator *validation.Validator) *service {
 	return &service{
 		cfg,
 		store,
@@ -22,6 +23,9 @@ func newService(cfg *config.Config, store *store, validator *validation.Validato
 }
 
 func (s *service) processGetStandings(ctx context.Context, seasonId, leagueId string) ([]StandingModel, error) {
+	ctx, span := tracing.Start(ctx, "standings.processGetStandings")
+	defer span.End()
+
 	err := s.validator.NewValidation(ctx).
 		SeasonExists(seasonId, "path").
 		LeagueExists(leagueId, "path").
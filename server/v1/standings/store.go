@@ -7,11 +7,18 @@ import (
 	"github.com/markovidakovic/gdsi/server/db"
 )
 
+// Repository reads the standings of a league, the matches store keeps them up to date
+type Repository interface {
+	findStandings(ctx context.Context, seasonId, leagueId string) ([]StandingModel, error)
+}
+
 type store struct {
 	db *db.Conn
 }
 
-func newStore(db *db.Conn) *store {
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
 	return &store{
 		db,
 	}
@@ -0,0 +1,29 @@
+package system
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/router"
+)
+
+type api struct {
+	hdl *handler
+}
+
+var _ router.Mounter = (*api)(nil)
+
+// New mounts the system endpoints, stats reports the connection pool of the serving instance
+func New(cfg *config.Config, stats func() db.PoolStats) *api {
+	return &api{
+		hdl: newHandler(cfg, stats),
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Use(middleware.RequirePermission(permission.ViewSystem))
+
+	r.Get("/database", a.hdl.getDatabaseStats)
+}
@@ -0,0 +1,35 @@
+package system
+
+import (
+	"net/http"
+
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/response"
+)
+
+type handler struct {
+	cfg   *config.Config
+	stats func() db.PoolStats
+}
+
+func newHandler(cfg *config.Config, stats func() db.PoolStats) *handler {
+	return &handler{
+		cfg:   cfg,
+		stats: stats,
+	}
+}
+
+// @Summary Database stats
+// @Description Get the statistics of the database connection pool of the serving instance
+// @Tags admin system
+// @Produce json
+// @Success 200 {object} db.PoolStats "OK"
+// @Failure 401 {object} failure.Failure "Unauthorized"
+// @Failure 403 {object} failure.Failure "Forbidden"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/system/database [get]
+func (h *handler) getDatabaseStats(w http.ResponseWriter, r *http.Request) {
+	response.WriteSuccess(w, http.StatusOK, h.stats())
+}
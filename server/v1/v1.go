@@ -1,60 +1,198 @@
 package v1
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
+	"github.com/markovidakovic/gdsi/server/audit"
 	"github.com/markovidakovic/gdsi/server/config"
 	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/idempotency"
+	"github.com/markovidakovic/gdsi/server/mail"
+	"github.com/markovidakovic/gdsi/server/memdb"
 	"github.com/markovidakovic/gdsi/server/middleware"
+	"github.com/markovidakovic/gdsi/server/oidc"
 	"github.com/markovidakovic/gdsi/server/router"
+	"github.com/markovidakovic/gdsi/server/session"
+	"github.com/markovidakovic/gdsi/server/v1/accounts"
+	"github.com/markovidakovic/gdsi/server/v1/apikeys"
+	"github.com/markovidakovic/gdsi/server/v1/auditlog"
 	"github.com/markovidakovic/gdsi/server/v1/auth"
 	"github.com/markovidakovic/gdsi/server/v1/courts"
+	"github.com/markovidakovic/gdsi/server/v1/invites"
 	"github.com/markovidakovic/gdsi/server/v1/leagueplayers"
 	"github.com/markovidakovic/gdsi/server/v1/leagues"
 	"github.com/markovidakovic/gdsi/server/v1/matches"
 	"github.com/markovidakovic/gdsi/server/v1/me"
 	"github.com/markovidakovic/gdsi/server/v1/players"
+	"github.com/markovidakovic/gdsi/server/v1/roles"
 	"github.com/markovidakovic/gdsi/server/v1/seasons"
+	"github.com/markovidakovic/gdsi/server/v1/serviceaccounts"
 	"github.com/markovidakovic/gdsi/server/v1/standings"
+	"github.com/markovidakovic/gdsi/server/v1/system"
 	"github.com/markovidakovic/gdsi/server/validation"
 )
 
+// Repositories are the stores of the modules with the session cache and the stats of the
+// connection pool, all from the same backend
+type Repositories struct {
+	Accounts        accounts.Repository
+	APIKeys         apikeys.Repository
+	AuditLog        auditlog.Repository
+	Auth            auth.Repository
+	Courts          courts.Repository
+	Idempotency     idempotency.Repository
+	Invites         invites.Repository
+	LeaguePlayers   leagueplayers.Repository
+	Leagues         leagues.Repository
+	Matches         matches.Repository
+	Me              me.Repository
+	Players         players.Repository
+	Roles           roles.Repository
+	Seasons         seasons.Repository
+	ServiceAccounts serviceaccounts.Repository
+	Standings       standings.Repository
+	Validation      validation.Repository
+	Sessions        func(ttl time.Duration) *session.Cache
+	PoolStats       func() db.PoolStats
+}
+
+// NewRepositories backs the api with postgres
+func NewRepositories(conn *db.Conn) *Repositories {
+	return &Repositories{
+		Accounts:        accounts.NewStore(conn),
+		APIKeys:         apikeys.NewStore(conn),
+		AuditLog:        auditlog.NewStore(conn),
+		Auth:            auth.NewStore(conn),
+		Courts:          courts.NewStore(conn),
+		Idempotency:     idempotency.NewStore(conn),
+		Invites:         invites.NewStore(conn),
+		LeaguePlayers:   leagueplayers.NewStore(conn),
+		Leagues:         leagues.NewStore(conn),
+		Matches:         matches.NewStore(conn),
+		Me:              me.NewStore(conn),
+		Players:         players.NewStore(conn),
+		Roles:           roles.NewStore(conn),
+		Seasons:         seasons.NewStore(conn),
+		ServiceAccounts: serviceaccounts.NewStore(conn),
+		Standings:       standings.NewStore(conn),
+		Validation:      validation.NewStore(conn),
+		Sessions: func(ttl time.Duration) *session.Cache {
+			return session.NewCache(conn, ttl)
+		},
+		PoolStats: conn.Stats,
+	}
+}
+
+// NewMemRepositories backs the api with the in-memory database, there's no connection pool so
+// its stats are empty
+func NewMemRepositories(m *memdb.DB) *Repositories {
+	return &Repositories{
+		Accounts:        accounts.NewMemStore(m),
+		APIKeys:         apikeys.NewMemStore(m),
+		AuditLog:        auditlog.NewMemStore(m),
+		Auth:            auth.NewMemStore(m),
+		Courts:          courts.NewMemStore(m),
+		Idempotency:     idempotency.NewMemStore(m),
+		Invites:         invites.NewMemStore(m),
+		LeaguePlayers:   leagueplayers.NewMemStore(m),
+		Leagues:         leagues.NewMemStore(m),
+		Matches:         matches.NewMemStore(m),
+		Me:              me.NewMemStore(m),
+		Players:         players.NewMemStore(m),
+		Roles:           roles.NewMemStore(m),
+		Seasons:         seasons.NewMemStore(m),
+		ServiceAccounts: serviceaccounts.NewMemStore(m),
+		Standings:       standings.NewMemStore(m),
+		Validation:      validation.NewMemStore(m),
+		Sessions: func(ttl time.Duration) *session.Cache {
+			return session.New(memSessionState(m), ttl)
+		},
+		PoolStats: func() db.PoolStats {
+			return db.PoolStats{}
+		},
+	}
+}
+
+// memSessionState loads the account state of the sessions from the in-memory database
+func memSessionState(m *memdb.DB) session.Loader {
+	return func(ctx context.Context, accountId string) (session.State, error) {
+		var state session.State
+		err := m.Read(nil, func(t *memdb.Tables) error {
+			if account, ok := t.Accounts[accountId]; ok {
+				state = session.State{Active: !account.DeactivatedAt.Valid, TokenVersion: account.TokenVersion}
+			}
+			return nil
+		})
+		return state, err
+	}
+}
+
 type api struct {
 	cfg       *config.Config
-	db        *db.Conn
+	repos     *Repositories
 	validator *validation.Validator
+	mailer    mail.Sender
+	provider  *oidc.Provider
+	sessions  *session.Cache
 }
 
 var _ router.Mounter = (*api)(nil)
 
-func New(cfg *config.Config, db *db.Conn) *api {
+func New(cfg *config.Config, repos *Repositories) *api {
 	return &api{
 		cfg:       cfg,
-		db:        db,
-		validator: validation.NewValidator(db),
+		repos:     repos,
+		validator: validation.NewValidator(repos.Validation),
+		mailer:    mail.NewSender(cfg),
+		provider:  oidc.NewProvider(cfg),
+		sessions:  repos.Sessions(cfg.AccountCacheTtl),
 	}
 }
 
 func (a *api) Mount(r chi.Router) {
+	// the audit entries are tagged with the request metadata
+	r.Use(audit.Request)
+
 	r.Group(func(r chi.Router) {
-		r.Route("/auth", auth.New(a.cfg, a.db).Mount)
+		r.Route("/auth", auth.New(a.cfg, a.repos.Auth, a.provider).Mount)
 	})
 	r.Group(func(r chi.Router) {
+		// api keys authenticate on their own, the jwt middlewares skip those requests
+		r.Use(middleware.APIKeyVerifier(apikeys.NewLookup(a.repos.APIKeys)))
 		// seek, verify and validate jwt
-		r.Use(jwtauth.Verifier(a.cfg.JwtAuth))
-		r.Use(jwtauth.Authenticator(a.cfg.JwtAuth))
-		r.Use(middleware.AccountInfo)
-
-		r.Route("/me", me.New(a.cfg, a.db).Mount)
-		r.Route("/courts", courts.New(a.cfg, a.db).Mount)
-		r.Route("/players", players.New(a.cfg, a.db).Mount)
-		r.Route("/seasons", seasons.New(a.cfg, a.db).Mount)
-		r.Route("/seasons/{season_id}/leagues", leagues.New(a.cfg, a.db).Mount)
-		r.Route("/seasons/{season_id}/leagues/{league_id}/players", leagueplayers.New(a.cfg, a.db).Mount)
-		r.Route("/seasons/{season_id}/leagues/{league_id}/matches", matches.New(a.cfg, a.db, a.validator).Mount)
-		r.Route("/seasons/{season_id}/leagues/{league_id}/standings", standings.New(a.cfg, a.db).Mount)
+		r.Use(middleware.Verifier(a.cfg.JwtKeys))
+		r.Use(middleware.Authenticator)
+		r.Use(middleware.AccountInfo(a.sessions.Get))
+
+		// me stays reachable without two-factor so the accounts can enroll, the api keys
+		// can't reach it so a leaked key can't take over the account
+		r.With(middleware.RejectAPIKey).Route("/me", me.New(a.cfg, a.repos.Me, a.mailer, a.sessions).Mount)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireTwoFactor(a.cfg.TwoFactorRoles()))
+			// the permission checks also accept the roles granted within the season or league of the url
+			r.Use(middleware.ResolveScope(accounts.NewScopedRoleLookup(a.repos.Accounts)))
+			// the retried posts get the response of the first attempt
+			r.Use(idempotency.Keys(a.repos.Idempotency, a.cfg.IdempotencyKeyTtl, a.cfg.IdempotencyReserveTtl))
+
+			r.Route("/api-keys", apikeys.New(a.cfg, a.repos.APIKeys).Mount)
+			r.Route("/service-accounts", serviceaccounts.New(a.cfg, a.repos.ServiceAccounts).Mount)
+			r.Route("/admin/accounts", accounts.New(a.cfg, a.repos.Accounts, a.sessions).Mount)
+			r.Route("/admin/roles", roles.New(a.cfg, a.repos.Roles).Mount)
+			r.Route("/admin/audit", auditlog.New(a.cfg, a.repos.AuditLog).Mount)
+			r.Route("/admin/invites", invites.New(a.cfg, a.repos.Invites).Mount)
+			r.Route("/admin/system", system.New(a.cfg, a.repos.PoolStats).Mount)
+			r.Route("/courts", courts.New(a.cfg, a.repos.Courts).Mount)
+			r.Route("/players", players.New(a.cfg, a.repos.Players).Mount)
+			r.Route("/seasons", seasons.New(a.cfg, a.repos.Seasons).Mount)
+			r.Route("/seasons/{season_id}/leagues", leagues.New(a.cfg, a.repos.Leagues, a.validator).Mount)
+			r.Route("/seasons/{season_id}/leagues/{league_id}/players", leagueplayers.New(a.cfg, a.repos.LeaguePlayers, a.validator).Mount)
+			r.Route("/seasons/{season_id}/leagues/{league_id}/matches", matches.New(a.cfg, a.repos.Matches, a.validator).Mount)
+			r.Route("/seasons/{season_id}/leagues/{league_id}/standings", standings.New(a.cfg, a.repos.Standings, a.validator).Mount)
+		})
 	})
-	log.Println("v1 endpoints mounted")
+	slog.Info("v1 endpoints mounted")
 }
@@ -0,0 +1,822 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/db"
+	"github.com/markovidakovic/gdsi/server/memdb"
+	"github.com/markovidakovic/gdsi/server/permission"
+	"github.com/markovidakovic/gdsi/server/sec"
+	"github.com/markovidakovic/gdsi/server/v1/roles"
+)
+
+// testClient calls the api served from the in-memory database
+type testClient struct {
+	t   *testing.T
+	srv *httptest.Server
+	db  *memdb.DB
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+	return newTestClientWith(t, nil)
+}
+
+// newTestClientWith is newTestClient with the extra config values
+func newTestClientWith(t *testing.T, extra map[string]string) *testClient {
+	t.Helper()
+
+	overrides := map[string]string{
+		"DB_DRIVER":   "postgres",
+		"DB_HOST":     "localhost",
+		"DB_NAME":     "gdsi",
+		"DB_PORT":     "5432",
+		"DB_USER":     "gdsi",
+		"DB_PASSWORD": "gdsi",
+		"JWT_SECRET":  "test-secret-test-secret-test-secret",
+		// the developer below signs in without a second factor
+		"TWO_FACTOR_REQUIRED_ROLES":   "admin",
+		"REGISTRATION_MODE":           config.RegistrationOpen,
+		"PASSWORD_ARGON2_MEMORY":      "1024",
+		"PASSWORD_ARGON2_ITERATIONS":  "1",
+		"PASSWORD_ARGON2_PARALLELISM": "1",
+	}
+	for k, v := range extra {
+		overrides[k] = v
+	}
+
+	cfg, err := config.LoadOptions(config.Options{Overrides: overrides})
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+
+	m := memdb.New()
+	repos := NewMemRepositories(m)
+	// the permission checks use the roles of the database, like withPermissions of the server
+	if err := permission.Use(context.Background(), roles.NewLoader(repos.Roles), 0); err != nil {
+		t.Fatalf("loading role permissions: %v", err)
+	}
+	r := chi.NewRouter()
+	r.Route("/v1", New(cfg, repos).Mount)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return &testClient{t: t, srv: srv, db: m}
+}
+
+// do sends the request and decodes the response into out, the status has to be the wanted one
+func (c *testClient) do(method, path, token string, body, out any, want int) {
+	c.t.Helper()
+	c.doWith(method, path, token, nil, body, out, want)
+}
+
+// doWith is do with the extra request headers, it returns the headers of the response
+func (c *testClient) doWith(method, path, token string, header http.Header, body, out any, want int) http.Header {
+	c.t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			c.t.Fatalf("encoding the body: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, c.srv.URL+path, &buf)
+	if err != nil {
+		c.t.Fatalf("building the request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.srv.Client().Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		var failure map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		c.t.Fatalf("%s %s: expected status %d, got %d %v", method, path, want, resp.StatusCode, failure)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatalf("%s %s: decoding the response: %v", method, path, err)
+		}
+	}
+	return resp.Header
+}
+
+// signup registers the account and returns its access token
+func (c *testClient) signup(name, email string) string {
+	c.t.Helper()
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	c.do(http.MethodPost, "/v1/auth/signup", "", map[string]string{
+		"name":         name,
+		"email":        email,
+		"dob":          "1990-01-01",
+		"gender":       "male",
+		"phone_number": "+385911234567",
+		"password":     "correct-horse-battery",
+	}, &tokens, http.StatusCreated)
+
+	return tokens.AccessToken
+}
+
+// login signs the account in again, so the token carries the current role
+func (c *testClient) login(email string) string {
+	c.t.Helper()
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	c.do(http.MethodPost, "/v1/auth/tokens/access", "", map[string]string{
+		"email":    email,
+		"password": "correct-horse-battery",
+	}, &tokens, http.StatusOK)
+
+	return tokens.AccessToken
+}
+
+// promote gives the account the role directly in the database
+func (c *testClient) promote(email, role string) {
+	c.t.Helper()
+
+	err := c.db.Write(nil, func(t *memdb.Tables) error {
+		a, _ := t.AccountByEmail(email)
+		a.Role = role
+		t.Accounts[a.Id] = a
+		return nil
+	})
+	if err != nil {
+		c.t.Fatalf("promoting %s: %v", email, err)
+	}
+}
+
+// apiKey stores the key with the scopes directly in the database, past the checks of the api, and returns it
+func (c *testClient) apiKey(email string, scopes ...string) string {
+	c.t.Helper()
+
+	key, prefix, err := sec.GenerateAPIKey()
+	if err != nil {
+		c.t.Fatalf("generating the api key: %v", err)
+	}
+	err = c.db.Write(nil, func(t *memdb.Tables) error {
+		a, _ := t.AccountByEmail(email)
+		id := memdb.NewId()
+		t.APIKeys[id] = db.APIKey{
+			Id:        id,
+			AccountId: a.Id,
+			Name:      "test",
+			Prefix:    prefix,
+			KeyHash:   sec.HashToken(key),
+			Scopes:    scopes,
+			// the key is revoked once the account token version changes
+			TokenVersion: a.TokenVersion,
+			CreatedAt:    c.db.Now(),
+		}
+		return nil
+	})
+	if err != nil {
+		c.t.Fatalf("storing the api key of %s: %v", email, err)
+	}
+	return key
+}
+
+type meModel struct {
+	Id     string `json:"id"`
+	Player struct {
+		Id               string `json:"id"`
+		MatchesPlayed    int    `json:"matches_played"`
+		MatchesWon       int    `json:"matches_won"`
+		MatchesScheduled int    `json:"matches_scheduled"`
+	} `json:"player"`
+}
+
+func (c *testClient) me(token string) meModel {
+	c.t.Helper()
+
+	var dest meModel
+	c.do(http.MethodGet, "/v1/me", token, nil, &dest, http.StatusOK)
+	return dest
+}
+
+type created struct {
+	Id string `json:"id"`
+}
+
+func TestLeagueFlow(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+
+	alice := c.signup("Alice", "alice@gdsi.test")
+	bob := c.signup("Bob", "bob@gdsi.test")
+	aliceId := c.me(alice).Player.Id
+	bobId := c.me(bob).Player.Id
+
+	// the players can't manage the seasons
+	c.do(http.MethodPost, "/v1/courts", alice, map[string]string{"name": "Center"}, nil, http.StatusForbidden)
+
+	var court, season, league created
+	c.do(http.MethodPost, "/v1/courts", dev, map[string]string{"name": "Center"}, &court, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/seasons", dev, map[string]string{
+		"title":      "Spring",
+		"start_date": "2026-03-01",
+		"end_date":   "2026-06-30",
+	}, &season, http.StatusCreated)
+	leagues := "/v1/seasons/" + season.Id + "/leagues"
+	c.do(http.MethodPost, leagues, dev, map[string]string{"title": "First"}, &league, http.StatusCreated)
+
+	leaguePath := leagues + "/" + league.Id
+	for _, id := range []string{aliceId, bobId} {
+		c.do(http.MethodPost, leaguePath+"/players/"+id+"/assign", dev, nil, nil, http.StatusOK)
+	}
+
+	var match created
+	c.do(http.MethodPost, leaguePath+"/matches", alice, map[string]string{
+		"court_id":      court.Id,
+		"scheduled_at":  "2026-04-01T18:00:00Z",
+		"player_two_id": bobId,
+	}, &match, http.StatusCreated)
+
+	// the same pair can't be scheduled twice in the league
+	c.do(http.MethodPost, leaguePath+"/matches", alice, map[string]string{
+		"court_id":      court.Id,
+		"scheduled_at":  "2026-04-02T18:00:00Z",
+		"player_two_id": bobId,
+	}, nil, http.StatusBadRequest)
+
+	c.do(http.MethodPost, leaguePath+"/matches/"+match.Id+"/score", bob, map[string]string{"score": "6-3,6-4"}, nil, http.StatusOK)
+	// the score is submitted once, later changes are corrections
+	c.do(http.MethodPost, leaguePath+"/matches/"+match.Id+"/score", bob, map[string]string{"score": "6-3,6-4"}, nil, http.StatusConflict)
+
+	var standings []struct {
+		Points        int `json:"points"`
+		MatchesPlayed int `json:"matches_played"`
+		MatchesWon    int `json:"matches_won"`
+		SetsWon       int `json:"sets_won"`
+		GamesWon      int `json:"games_won"`
+		Player        struct {
+			Id string `json:"id"`
+		} `json:"player"`
+	}
+	c.do(http.MethodGet, leaguePath+"/standings", alice, nil, &standings, http.StatusOK)
+	if len(standings) != 2 {
+		t.Fatalf("expected the standings of both players, got %+v", standings)
+	}
+	leader := standings[0]
+	if leader.Player.Id != aliceId || leader.MatchesWon != 1 || leader.SetsWon != 2 || leader.GamesWon != 12 {
+		t.Errorf("expected alice to lead after winning, got %+v", standings)
+	}
+	if trailer := standings[1]; trailer.Player.Id != bobId || trailer.MatchesPlayed != 1 || trailer.MatchesWon != 0 {
+		t.Errorf("expected bob to trail after losing, got %+v", trailer)
+	}
+
+	if p := c.me(alice).Player; p.MatchesPlayed != 1 || p.MatchesWon != 1 || p.MatchesScheduled != 1 {
+		t.Errorf("expected the statistics of alice to count the match, got %+v", p)
+	}
+	if p := c.me(bob).Player; p.MatchesPlayed != 1 || p.MatchesWon != 0 {
+		t.Errorf("expected the statistics of bob to count the match, got %+v", p)
+	}
+}
+
+func TestPreconditions(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+
+	alice := c.signup("Alice", "alice@gdsi.test")
+	bob := c.signup("Bob", "bob@gdsi.test")
+	bobId := c.me(bob).Player.Id
+	carol := c.signup("Carol", "carol@gdsi.test")
+	carolId := c.me(carol).Player.Id
+
+	var court, season, league created
+	c.do(http.MethodPost, "/v1/courts", dev, map[string]string{"name": "Center"}, &court, http.StatusCreated)
+	seasonBody := map[string]string{
+		"title":      "Spring",
+		"start_date": "2026-03-01",
+		"end_date":   "2026-06-30",
+	}
+	h := c.doWith(http.MethodPost, "/v1/seasons", dev, nil, seasonBody, &season, http.StatusCreated)
+	if etag := h.Get("ETag"); etag != `"1"` {
+		t.Fatalf("expected the etag of the first version, got %q", etag)
+	}
+
+	// the second update is based on the version the first one replaced
+	seasonPath := "/v1/seasons/" + season.Id
+	stale := http.Header{"If-Match": {`"1"`}}
+	seasonBody["title"] = "Spring cup"
+	c.doWith(http.MethodPut, seasonPath, dev, stale, seasonBody, nil, http.StatusOK)
+	seasonBody["title"] = "Spring open"
+	c.doWith(http.MethodPut, seasonPath, dev, stale, seasonBody, nil, http.StatusPreconditionFailed)
+
+	leaguePath := seasonPath + "/leagues"
+	c.do(http.MethodPost, leaguePath, dev, map[string]string{"title": "First"}, &league, http.StatusCreated)
+	leaguePath += "/" + league.Id
+	for _, id := range []string{c.me(alice).Player.Id, bobId, carolId} {
+		c.do(http.MethodPost, leaguePath+"/players/"+id+"/assign", dev, nil, nil, http.StatusOK)
+	}
+
+	var match created
+	matchBody := map[string]string{
+		"court_id":      court.Id,
+		"scheduled_at":  "2026-04-01T18:00:00Z",
+		"player_two_id": bobId,
+	}
+	c.do(http.MethodPost, leaguePath+"/matches", alice, matchBody, &match, http.StatusCreated)
+	matchPath := leaguePath + "/matches/" + match.Id
+	read := c.doWith(http.MethodGet, matchPath, alice, nil, nil, nil, http.StatusOK)
+
+	// the score alice read the match for can't land on the match rescheduled in the meantime
+	matchBody["player_two_id"] = carolId
+	c.doWith(http.MethodPut, matchPath, dev, http.Header{"If-Match": {read.Get("ETag")}}, matchBody, nil, http.StatusOK)
+	c.doWith(http.MethodPost, matchPath+"/score", alice, http.Header{"If-Match": {read.Get("ETag")}}, map[string]string{"score": "6-3,6-4"}, nil, http.StatusPreconditionFailed)
+
+	// the match embeds the court name, renaming the court changes the tag too
+	read = c.doWith(http.MethodGet, matchPath, alice, nil, nil, nil, http.StatusOK)
+	c.do(http.MethodPut, "/v1/courts/"+court.Id, dev, map[string]string{"name": "Centre"}, nil, http.StatusOK)
+	c.doWith(http.MethodPost, matchPath+"/score", alice, http.Header{"If-Match": {read.Get("ETag")}}, map[string]string{"score": "6-3,6-4"}, nil, http.StatusPreconditionFailed)
+
+	// If-Match compares strongly, the weak form of the current tag doesn't match
+	read = c.doWith(http.MethodGet, matchPath, alice, nil, nil, nil, http.StatusOK)
+	c.doWith(http.MethodPost, matchPath+"/score", alice, http.Header{"If-Match": {"W/" + read.Get("ETag")}}, map[string]string{"score": "6-3,6-4"}, nil, http.StatusPreconditionFailed)
+
+	c.doWith(http.MethodPost, matchPath+"/score", alice, http.Header{"If-Match": {read.Get("ETag")}}, map[string]string{"score": "6-3,6-4"}, nil, http.StatusOK)
+
+	// the standings are cached until the next score changes them
+	h = c.doWith(http.MethodGet, leaguePath+"/standings", alice, nil, nil, nil, http.StatusOK)
+	cached := http.Header{"If-None-Match": {h.Get("ETag")}}
+	c.doWith(http.MethodGet, leaguePath+"/standings", alice, cached, nil, nil, http.StatusNotModified)
+	c.doWith(http.MethodPut, matchPath+"/score", dev, nil, map[string]string{"score": "3-6,4-6"}, nil, http.StatusOK)
+	c.doWith(http.MethodGet, leaguePath+"/standings", alice, cached, nil, nil, http.StatusOK)
+
+	// the own account is guarded the same way
+	h = c.doWith(http.MethodGet, "/v1/me", carol, nil, nil, nil, http.StatusOK)
+	stale = http.Header{"If-Match": {h.Get("ETag")}}
+	c.doWith(http.MethodPut, "/v1/me", carol, stale, map[string]string{"name": "Carol C"}, nil, http.StatusOK)
+	c.doWith(http.MethodPut, "/v1/me", carol, stale, map[string]string{"name": "Carol D"}, nil, http.StatusPreconditionFailed)
+}
+
+func TestSignupDuplicateEmail(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Alice", "alice@gdsi.test")
+	c.do(http.MethodPost, "/v1/auth/signup", "", map[string]string{
+		"name":         "Alice",
+		"email":        "alice@gdsi.test",
+		"dob":          "1990-01-01",
+		"gender":       "female",
+		"phone_number": "+385911234567",
+		"password":     "correct-horse-battery",
+	}, nil, http.StatusConflict)
+}
+
+func TestDeactivatedAccountIsRejected(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+	alice := c.signup("Alice", "alice@gdsi.test")
+	aliceAccountId := c.me(alice).Id
+
+	c.do(http.MethodPost, "/v1/admin/accounts/"+aliceAccountId+"/deactivate", dev, nil, nil, http.StatusOK)
+
+	// the token version was bumped, the issued token is refused right away
+	c.do(http.MethodGet, "/v1/me", alice, nil, nil, http.StatusUnauthorized)
+	c.do(http.MethodPost, "/v1/auth/tokens/access", "", map[string]string{
+		"email":    "alice@gdsi.test",
+		"password": "correct-horse-battery",
+	}, nil, http.StatusForbidden)
+}
+
+func TestAuditEntriesHoldNoPersonalData(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+	alice := c.signup("Alice", "alice@gdsi.test")
+	me := c.me(alice)
+
+	c.do(http.MethodPut, "/v1/players/"+me.Player.Id, alice, map[string]string{"racket": "Wilson"}, nil, http.StatusOK)
+	c.do(http.MethodPost, "/v1/admin/accounts/"+me.Id+"/deactivate", dev, nil, nil, http.StatusOK)
+	c.do(http.MethodPost, "/v1/service-accounts", dev, map[string]string{"name": "importer"}, nil, http.StatusCreated)
+
+	err := c.db.Read(nil, func(tb *memdb.Tables) error {
+		if len(tb.AuditLogs) < 3 {
+			t.Fatalf("expected the player, account and service account changes to be recorded, got %d entries", len(tb.AuditLogs))
+		}
+		// the physique of the player and the name of the service account creator are left out too
+		for _, e := range tb.AuditLogs {
+			for _, state := range [][]byte{e.Before, e.After} {
+				if bytes.Contains(state, []byte("alice")) || bytes.Contains(state, []byte("Alice")) || bytes.Contains(state, []byte("+385")) ||
+					bytes.Contains(state, []byte("Wilson")) || bytes.Contains(state, []byte("Dev")) {
+					t.Errorf("expected the %s entry to hold no personal data, got %s", e.ResourceType, state)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading the audit log: %v", err)
+	}
+}
+
+func TestDeletedAccountIsFinal(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+	alice := c.signup("Alice", "alice@gdsi.test")
+	aliceAccountId := c.me(alice).Id
+
+	c.do(http.MethodDelete, "/v1/me", alice, map[string]string{"password": "correct-horse-battery"}, nil, http.StatusNoContent)
+
+	accountPath := "/v1/admin/accounts/" + aliceAccountId
+	c.do(http.MethodPost, accountPath+"/reactivate", dev, nil, nil, http.StatusConflict)
+	c.do(http.MethodPost, accountPath+"/approve", dev, nil, nil, http.StatusConflict)
+	c.do(http.MethodPut, accountPath+"/role", dev, map[string]string{"role": "admin"}, nil, http.StatusConflict)
+
+	var deleted struct {
+		Items []struct {
+			Id        string  `json:"id"`
+			DeletedAt *string `json:"deleted_at"`
+		} `json:"items"`
+	}
+	c.do(http.MethodGet, "/v1/admin/accounts?status=deleted", dev, nil, &deleted, http.StatusOK)
+	if len(deleted.Items) != 1 || deleted.Items[0].Id != aliceAccountId || deleted.Items[0].DeletedAt == nil {
+		t.Errorf("expected the deleted account to be listed as deleted, got %+v", deleted.Items)
+	}
+}
+
+func TestRoleAssignmentWithinPermissions(t *testing.T) {
+	// no role needs two-factor here, the admin signs in with the password
+	c := newTestClientWith(t, map[string]string{"TWO_FACTOR_REQUIRED_ROLES": ","})
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+	c.signup("Admin", "admin@gdsi.test")
+	c.promote("admin@gdsi.test", "admin")
+	admin := c.login("admin@gdsi.test")
+	alice := c.signup("Alice", "alice@gdsi.test")
+	rolePath := "/v1/admin/accounts/" + c.me(alice).Id + "/role"
+
+	c.do(http.MethodPost, "/v1/admin/roles", dev, map[string]any{"name": "role_manager", "permissions": []string{"manage:role"}}, nil, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/admin/roles", dev, map[string]any{"name": "court_keeper", "permissions": []string{"create:court"}}, nil, http.StatusCreated)
+
+	// the admin can't hand out the permissions it doesn't hold itself
+	c.do(http.MethodPut, rolePath, admin, map[string]string{"role": "role_manager"}, nil, http.StatusForbidden)
+	c.do(http.MethodPut, rolePath, admin, map[string]string{"role": "court_keeper"}, nil, http.StatusOK)
+	c.do(http.MethodPut, rolePath, dev, map[string]string{"role": "role_manager"}, nil, http.StatusOK)
+}
+
+func TestScopedGrants(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+	carol := c.signup("Carol", "carol@gdsi.test")
+	carolAccountId := c.me(carol).Id
+
+	var players []string
+	for _, name := range []string{"alice", "bob", "dave", "erin"} {
+		players = append(players, c.me(c.signup(name, name+"@gdsi.test")).Player.Id)
+	}
+
+	var spring, autumn, first, second, third created
+	c.do(http.MethodPost, "/v1/seasons", dev, map[string]string{
+		"title":      "Spring",
+		"start_date": "2026-03-01",
+		"end_date":   "2026-06-30",
+	}, &spring, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/seasons", dev, map[string]string{
+		"title":      "Autumn",
+		"start_date": "2026-09-01",
+		"end_date":   "2026-11-30",
+	}, &autumn, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/seasons/"+spring.Id+"/leagues", dev, map[string]string{"title": "First"}, &first, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/seasons/"+spring.Id+"/leagues", dev, map[string]string{"title": "Second"}, &second, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/seasons/"+autumn.Id+"/leagues", dev, map[string]string{"title": "Third"}, &third, http.StatusCreated)
+
+	assign := func(season, league created, player string) string {
+		return "/v1/seasons/" + season.Id + "/leagues/" + league.Id + "/players/" + player + "/assign"
+	}
+	grants := "/v1/admin/accounts/" + carolAccountId + "/grants"
+
+	// the coordinator of the first league manages only that league
+	c.do(http.MethodPost, grants, dev, map[string]string{"role": "league_coordinator", "league_id": first.Id}, nil, http.StatusCreated)
+	c.do(http.MethodPost, assign(spring, first, players[0]), carol, nil, nil, http.StatusOK)
+	c.do(http.MethodPost, assign(spring, second, players[1]), carol, nil, nil, http.StatusForbidden)
+	c.do(http.MethodPost, assign(autumn, third, players[1]), carol, nil, nil, http.StatusForbidden)
+
+	// the season grant covers every league of the season, but not the other seasons
+	c.do(http.MethodPost, grants, dev, map[string]string{"role": "league_coordinator", "season_id": spring.Id}, nil, http.StatusCreated)
+	c.do(http.MethodPost, assign(spring, second, players[1]), carol, nil, nil, http.StatusOK)
+	c.do(http.MethodPost, assign(autumn, third, players[2]), carol, nil, nil, http.StatusForbidden)
+
+	// the scopes of the api key narrow the scoped roles too
+	narrow := c.apiKey("carol@gdsi.test", "create:match")
+	c.do(http.MethodPost, assign(spring, first, players[2]), narrow, nil, nil, http.StatusForbidden)
+
+	wide := c.apiKey("carol@gdsi.test", "update:player")
+	c.do(http.MethodPost, assign(spring, first, players[2]), wide, nil, nil, http.StatusOK)
+	c.do(http.MethodPost, assign(autumn, third, players[3]), wide, nil, nil, http.StatusForbidden)
+}
+
+func TestAPIKeys(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+	alice := c.signup("Alice", "alice@gdsi.test")
+
+	var key struct {
+		Id  string `json:"id"`
+		Key string `json:"key"`
+	}
+	c.do(http.MethodPost, "/v1/api-keys", dev, map[string]any{"name": "ci", "scopes": []string{"create:court"}}, &key, http.StatusCreated)
+
+	// only the hash of the key is stored
+	err := c.db.Read(nil, func(t *memdb.Tables) error {
+		stored := t.APIKeys[key.Id]
+		if stored.KeyHash != sec.HashToken(key.Key) || stored.KeyHash == key.Key {
+			c.t.Errorf("expected the key to be stored hashed, got %+v", stored)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading the api key: %v", err)
+	}
+
+	// the key is accepted as the bearer token, the unknown keys are rejected
+	c.do(http.MethodPost, "/v1/courts", key.Key, map[string]string{"name": "Center"}, nil, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/courts", sec.APIKeyPrefix+"unknown", map[string]string{"name": "South"}, nil, http.StatusUnauthorized)
+
+	// the scopes narrow the role, and the keys can't create more keys
+	c.do(http.MethodPost, "/v1/seasons", key.Key, map[string]string{
+		"title":      "Spring",
+		"start_date": "2026-03-01",
+		"end_date":   "2026-06-30",
+	}, nil, http.StatusForbidden)
+	c.do(http.MethodPost, "/v1/api-keys", key.Key, map[string]any{"name": "more", "scopes": []string{}}, nil, http.StatusForbidden)
+	// the scopes can't exceed the role
+	c.do(http.MethodPost, "/v1/api-keys", alice, map[string]any{"name": "ci", "scopes": []string{"create:court"}}, nil, http.StatusBadRequest)
+
+	var expiring struct {
+		Id  string `json:"id"`
+		Key string `json:"key"`
+	}
+	c.do(http.MethodPost, "/v1/api-keys", dev, map[string]any{
+		"name":       "expiring",
+		"scopes":     []string{"create:court"},
+		"expires_at": time.Now().Add(time.Hour),
+	}, &expiring, http.StatusCreated)
+	err = c.db.Write(nil, func(t *memdb.Tables) error {
+		k := t.APIKeys[expiring.Id]
+		k.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+		t.APIKeys[k.Id] = k
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expiring the api key: %v", err)
+	}
+	c.do(http.MethodPost, "/v1/courts", expiring.Key, map[string]string{"name": "South"}, nil, http.StatusUnauthorized)
+
+	c.do(http.MethodDelete, "/v1/api-keys/"+key.Id, dev, nil, nil, http.StatusNoContent)
+	c.do(http.MethodPost, "/v1/courts", key.Key, map[string]string{"name": "South"}, nil, http.StatusUnauthorized)
+}
+
+func TestAPIKeyRevokedWithTokens(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+
+	var key struct {
+		Key string `json:"key"`
+	}
+	c.do(http.MethodPost, "/v1/api-keys", dev, map[string]any{"name": "ci", "scopes": []string{"create:court"}}, &key, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/courts", key.Key, map[string]string{"name": "Center"}, nil, http.StatusCreated)
+
+	// the keys can't reach the account itself
+	c.do(http.MethodGet, "/v1/me", key.Key, nil, nil, http.StatusForbidden)
+
+	// changing the password revokes the keys together with the tokens
+	c.do(http.MethodPut, "/v1/me/password", dev, map[string]string{
+		"old_password":          "correct-horse-battery",
+		"new_password":          "staple-battery-horse",
+		"repeated_new_password": "staple-battery-horse",
+	}, nil, http.StatusOK)
+	c.do(http.MethodPost, "/v1/courts", key.Key, map[string]string{"name": "South"}, nil, http.StatusUnauthorized)
+}
+
+func TestServiceAccountKeys(t *testing.T) {
+	c := newTestClient(t)
+
+	c.signup("Dev", "dev@gdsi.test")
+	c.promote("dev@gdsi.test", "developer")
+	dev := c.login("dev@gdsi.test")
+
+	var account created
+	c.do(http.MethodPost, "/v1/service-accounts", dev, map[string]string{"name": "importer"}, &account, http.StatusCreated)
+	var key struct {
+		Key string `json:"key"`
+	}
+	c.do(http.MethodPost, "/v1/service-accounts/"+account.Id+"/api-keys", dev, map[string]any{"name": "ci", "scopes": []string{"create:court"}}, &key, http.StatusCreated)
+
+	// the service accounts only get the scopes of the key
+	c.do(http.MethodPost, "/v1/courts", key.Key, map[string]string{"name": "Center"}, nil, http.StatusCreated)
+	c.do(http.MethodPost, "/v1/seasons", key.Key, map[string]string{
+		"title":      "Spring",
+		"start_date": "2026-03-01",
+		"end_date":   "2026-06-30",
+	}, nil, http.StatusForbidden)
+
+	// the keys of a disabled service account stop working
+	c.do(http.MethodDelete, "/v1/service-accounts/"+account.Id, dev, nil, nil, http.StatusNoContent)
+	c.do(http.MethodPost, "/v1/courts", key.Key, map[string]string{"name": "North"}, nil, http.StatusUnauthorized)
+}
+
+// oidcIssuer is a minimal identity provider, every authorization signs the same person in
+type oidcIssuer struct {
+	t     *testing.T
+	srv   *httptest.Server
+	key   jwk.Key
+	email string
+
+	mu     sync.Mutex
+	nonces map[string]string
+}
+
+func newOidcIssuer(t *testing.T, email string) *oidcIssuer {
+	t.Helper()
+
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating the rsa key: %v", err)
+	}
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		t.Fatalf("creating the jwk: %v", err)
+	}
+	key.Set(jwk.KeyIDKey, "key-1")
+	key.Set(jwk.AlgorithmKey, jwa.RS256)
+
+	iss := &oidcIssuer{t: t, key: key, email: email, nonces: map[string]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 iss.srv.URL,
+			"authorization_endpoint": iss.srv.URL + "/authorize",
+			"token_endpoint":         iss.srv.URL + "/token",
+			"jwks_uri":               iss.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub, _ := iss.key.PublicKey()
+		set := jwk.NewSet()
+		set.AddKey(pub)
+		json.NewEncoder(w).Encode(set)
+	})
+	mux.HandleFunc("/token", iss.token)
+
+	iss.srv = httptest.NewServer(mux)
+	t.Cleanup(iss.srv.Close)
+
+	return iss
+}
+
+func (iss *oidcIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	iss.mu.Lock()
+	nonce, ok := iss.nonces[r.PostForm.Get("code")]
+	iss.mu.Unlock()
+	if !ok {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	tkn := jwt.New()
+	tkn.Set(jwt.IssuerKey, iss.srv.URL)
+	tkn.Set(jwt.SubjectKey, "subject-"+iss.email)
+	tkn.Set(jwt.AudienceKey, "gdsi-client")
+	tkn.Set(jwt.IssuedAtKey, now)
+	tkn.Set(jwt.ExpirationKey, now.Add(5*time.Minute))
+	tkn.Set("nonce", nonce)
+	tkn.Set("email", iss.email)
+	tkn.Set("email_verified", true)
+
+	signed, err := jwt.Sign(tkn, jwt.WithKey(jwa.RS256, iss.key))
+	if err != nil {
+		iss.t.Fatalf("signing the id token: %v", err)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "provider-token", "token_type": "Bearer", "id_token": string(signed)})
+}
+
+// config points the api to the issuer
+func (iss *oidcIssuer) config() map[string]string {
+	return map[string]string{
+		"OIDC_ISSUER_URL":   iss.srv.URL,
+		"OIDC_CLIENT_ID":    "gdsi-client",
+		"OIDC_REDIRECT_URL": "gdsi://oidc/callback",
+	}
+}
+
+// oidcLogin signs in at the issuer, the first login provisions the account, and returns the access token
+func (c *testClient) oidcLogin(iss *oidcIssuer) string {
+	c.t.Helper()
+
+	var authorization struct {
+		AuthorizationUrl string `json:"authorization_url"`
+		State            string `json:"state"`
+	}
+	c.do(http.MethodGet, "/v1/auth/oidc/authorize", "", nil, &authorization, http.StatusOK)
+
+	u, err := url.Parse(authorization.AuthorizationUrl)
+	if err != nil {
+		c.t.Fatalf("parsing the authorization url: %v", err)
+	}
+	code := "code-" + authorization.State
+	iss.mu.Lock()
+	iss.nonces[code] = u.Query().Get("nonce")
+	iss.mu.Unlock()
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	c.do(http.MethodPost, "/v1/auth/oidc/callback", "", map[string]string{"code": code, "state": authorization.State}, &tokens, http.StatusOK)
+	return tokens.AccessToken
+}
+
+func TestOidcAccountWithoutPassword(t *testing.T) {
+	iss := newOidcIssuer(t, "olivia@club.test")
+	c := newTestClientWith(t, iss.config())
+
+	olivia := c.oidcLogin(iss)
+
+	// the provisioned account never had a password, the signed in session confirms the changes
+	c.do(http.MethodPost, "/v1/me/email", olivia, map[string]string{"new_email": "olivia@gdsi.test"}, nil, http.StatusAccepted)
+	// but the api keys of the account can't make them
+	key := c.apiKey("olivia@club.test")
+	c.do(http.MethodPut, "/v1/me/password", key, map[string]string{
+		"new_password":          "correct-horse-battery",
+		"repeated_new_password": "correct-horse-battery",
+	}, nil, http.StatusForbidden)
+
+	var updated struct {
+		AccessToken string `json:"access_token"`
+	}
+	c.do(http.MethodPut, "/v1/me/password", olivia, map[string]string{
+		"new_password":          "correct-horse-battery",
+		"repeated_new_password": "correct-horse-battery",
+	}, &updated, http.StatusOK)
+	olivia = updated.AccessToken
+
+	// with a password of its own the account has to confirm with it, like any other
+	c.do(http.MethodDelete, "/v1/me", olivia, nil, nil, http.StatusBadRequest)
+	c.do(http.MethodDelete, "/v1/me", olivia, map[string]string{"password": "correct-horse-battery"}, nil, http.StatusNoContent)
+
+	// a second account of the issuer is deleted without a password
+	iss.email = "oscar@club.test"
+	oscar := c.oidcLogin(iss)
+	c.do(http.MethodDelete, "/v1/me", oscar, nil, nil, http.StatusNoContent)
+}
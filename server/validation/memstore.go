@@ -0,0 +1,113 @@
+package validation
+
+import (
+	"context"
+
+	"github.com/markovidakovic/gdsi/server/memdb"
+)
+
+type memStore struct {
+	db *memdb.DB
+}
+
+var _ Repository = (*memStore)(nil)
+
+func NewMemStore(db *memdb.DB) Repository {
+	return &memStore{
+		db,
+	}
+}
+
+func (s *memStore) exists(fn func(t *memdb.Tables) (bool, error)) (bool, error) {
+	var exists bool
+	err := s.db.Read(nil, func(t *memdb.Tables) error {
+		var err error
+		exists, err = fn(t)
+		return err
+	})
+	return exists, err
+}
+
+func (s *memStore) courtExists(ctx context.Context, courtId string) (bool, error) {
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		_, ok := t.Courts[courtId]
+		return ok, nil
+	})
+}
+
+func (s *memStore) seasonExists(ctx context.Context, seasonId string) (bool, error) {
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		_, ok := t.Seasons[seasonId]
+		return ok, nil
+	})
+}
+
+func (s *memStore) leagueExists(ctx context.Context, leagueId string) (bool, error) {
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		_, ok := t.Leagues[leagueId]
+		return ok, nil
+	})
+}
+
+func (s *memStore) leagueInSeason(ctx context.Context, seasonId, leagueId string) (bool, error) {
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		league, ok := t.Leagues[leagueId]
+		return ok && league.SeasonId == seasonId, nil
+	})
+}
+
+func (s *memStore) playerExists(ctx context.Context, playerId string) (bool, error) {
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		_, ok := t.Players[playerId]
+		return ok, nil
+	})
+}
+
+func (s *memStore) playerPending(ctx context.Context, playerId string) (bool, error) {
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		player, ok := t.Players[playerId]
+		if !ok {
+			return false, nil
+		}
+		account, ok := t.Accounts[player.AccountId]
+		return ok && !account.ApprovedAt.Valid, nil
+	})
+}
+
+func (s *memStore) playersInLeague(ctx context.Context, leagueId, playerOneId, playerTwoId string) (bool, error) {
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		// the same player twice is a single row
+		if playerOneId == playerTwoId {
+			return false, nil
+		}
+		for _, id := range []string{playerOneId, playerTwoId} {
+			player, ok := t.Players[id]
+			if !ok || !player.CurrentLeagueId.Valid || player.CurrentLeagueId.String != leagueId {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+func (s *memStore) matchExistsBetweenPlayers(ctx context.Context, seasonId, leagueId, playerOneId, playerTwoId string) (bool, error) {
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		for _, match := range t.Matches {
+			if match.SeasonId == seasonId && match.LeagueId == leagueId && match.PlayerOneId == playerOneId && match.PlayerTwoId == playerTwoId {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+func (s *memStore) matchScheduledInSeason(ctx context.Context, seasonId, scheduledAt string) (bool, error) {
+	at, err := memdb.ParseTime(scheduledAt)
+	if err != nil {
+		return false, err
+	}
+	return s.exists(func(t *memdb.Tables) (bool, error) {
+		season, ok := t.Seasons[seasonId]
+		return ok && !at.Before(season.StartDate) && !at.After(season.EndDate), nil
+	})
+}
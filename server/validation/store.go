@@ -0,0 +1,100 @@
+package validation
+
+import (
+	"context"
+
+	"github.com/markovidakovic/gdsi/server/db"
+)
+
+// Repository answers the existence and membership lookups the request checks run
+type Repository interface {
+	courtExists(ctx context.Context, courtId string) (bool, error)
+	seasonExists(ctx context.Context, seasonId string) (bool, error)
+	leagueExists(ctx context.Context, leagueId string) (bool, error)
+	leagueInSeason(ctx context.Context, seasonId, leagueId string) (bool, error)
+	playerExists(ctx context.Context, playerId string) (bool, error)
+	playerPending(ctx context.Context, playerId string) (bool, error)
+	playersInLeague(ctx context.Context, leagueId, playerOneId, playerTwoId string) (bool, error)
+	matchExistsBetweenPlayers(ctx context.Context, seasonId, leagueId, playerOneId, playerTwoId string) (bool, error)
+	matchScheduledInSeason(ctx context.Context, seasonId, scheduledAt string) (bool, error)
+}
+
+type store struct {
+	db *db.Conn
+}
+
+var _ Repository = (*store)(nil)
+
+func NewStore(db *db.Conn) Repository {
+	return &store{
+		db,
+	}
+}
+
+func (s *store) exists(ctx context.Context, sql string, args ...any) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, sql, args...).Scan(&exists)
+	return exists, err
+}
+
+func (s *store) courtExists(ctx context.Context, courtId string) (bool, error) {
+	return s.exists(ctx, `select exists(select 1 from court where id = $1)`, courtId)
+}
+
+func (s *store) seasonExists(ctx context.Context, seasonId string) (bool, error) {
+	return s.exists(ctx, `select exists(select 1 from season where id = $1)`, seasonId)
+}
+
+func (s *store) leagueExists(ctx context.Context, leagueId string) (bool, error) {
+	return s.exists(ctx, `select exists(select 1 from league where id = $1)`, leagueId)
+}
+
+func (s *store) leagueInSeason(ctx context.Context, seasonId, leagueId string) (bool, error) {
+	return s.exists(ctx, `select exists(select 1 from league where id = $1 and season_id = $2)`, leagueId, seasonId)
+}
+
+func (s *store) playerExists(ctx context.Context, playerId string) (bool, error) {
+	return s.exists(ctx, `select exists(select 1 from player where id = $1)`, playerId)
+}
+
+func (s *store) playerPending(ctx context.Context, playerId string) (bool, error) {
+	sql := `
+		select exists (
+			select 1 from player
+			join account on player.account_id = account.id
+			where player.id = $1 and account.approved_at is null
+		)
+	`
+	return s.exists(ctx, sql, playerId)
+}
+
+func (s *store) playersInLeague(ctx context.Context, leagueId, playerOneId, playerTwoId string) (bool, error) {
+	sql := `
+		select exists (
+			select 1 from player
+			where id in ($1, $2) and current_league_id = $3
+			having count(*) = 2
+		)
+	`
+	return s.exists(ctx, sql, playerOneId, playerTwoId, leagueId)
+}
+
+func (s *store) matchExistsBetweenPlayers(ctx context.Context, seasonId, leagueId, playerOneId, playerTwoId string) (bool, error) {
+	sql := `
+		select exists (
+			select 1 from match
+			where season_id = $1 and league_id = $2 and player_one_id = $3 and player_two_Id = $4
+		)
+	`
+	return s.exists(ctx, sql, seasonId, leagueId, playerOneId, playerTwoId)
+}
+
+func (s *store) matchScheduledInSeason(ctx context.Context, seasonId, scheduledAt string) (bool, error) {
+	sql := `
+		select exists (
+			select 1 from season
+			where id = $1 and $2 between start_date and end_date
+		)
+	`
+	return s.exists(ctx, sql, seasonId, scheduledAt)
+}
@@ -10,16 +10,18 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/markovidakovic/gdsi/server/db"
 	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Validator struct {
-	db *db.Conn
+	repo Repository
 }
 
-func NewValidator(db *db.Conn) *Validator {
-	return &Validator{db: db}
+// NewValidator returns the validator running its checks against the repository
+func NewValidator(repo Repository) *Validator {
+	return &Validator{repo: repo}
 }
 
 // ValidationResult holds invalid fields and a possible internal failure
@@ -50,10 +52,8 @@ func (vr *ValidationResult) result() error {
 func (v *Validator) courtExists(ctx context.Context, courtId string, source string) *ValidationResult {
 	vr := &ValidationResult{}
 
-	sql := `select exists(select 1 from court where id = $1)`
-	var exists bool
-
-	if err := v.db.QueryRow(ctx, sql, courtId).Scan(&exists); err != nil {
+	exists, err := v.repo.courtExists(ctx, courtId)
+	if err != nil {
 		vr.failure = failure.New("checking court existance", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 		return vr
 	}
@@ -68,10 +68,8 @@ func (v *Validator) courtExists(ctx context.Context, courtId string, source stri
 func (v *Validator) seasonExists(ctx context.Context, seasonId string, source string) *ValidationResult {
 	vr := &ValidationResult{}
 
-	sql := `select exists(select 1 from season where id = $1)`
-	var exists bool
-
-	if err := v.db.QueryRow(ctx, sql, seasonId).Scan(&exists); err != nil {
+	exists, err := v.repo.seasonExists(ctx, seasonId)
+	if err != nil {
 		vr.failure = failure.New("checking season existance", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 		return vr
 	}
@@ -86,10 +84,8 @@ func (v *Validator) seasonExists(ctx context.Context, seasonId string, source st
 func (v *Validator) leagueExists(ctx context.Context, leagueId string, source string) *ValidationResult {
 	vr := &ValidationResult{}
 
-	sql := `select exists(select 1 from league where id = $1)`
-	var exists bool
-
-	if err := v.db.QueryRow(ctx, sql, leagueId).Scan(&exists); err != nil {
+	exists, err := v.repo.leagueExists(ctx, leagueId)
+	if err != nil {
 		vr.failure = failure.New("checking league existance", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 		return vr
 	}
@@ -103,10 +99,8 @@ func (v *Validator) leagueExists(ctx context.Context, leagueId string, source st
 func (v *Validator) leagueInSeason(ctx context.Context, seasonId, leagueId string, source string) *ValidationResult {
 	vr := &ValidationResult{}
 
-	sql := `select exists(select 1 from league where id = $1 and season_id = $2)`
-	var exists bool
-
-	if err := v.db.QueryRow(ctx, sql, leagueId, seasonId).Scan(&exists); err != nil {
+	exists, err := v.repo.leagueInSeason(ctx, seasonId, leagueId)
+	if err != nil {
 		vr.failure = failure.New("checking if league part of season", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 		return vr
 	}
@@ -119,10 +113,8 @@ func (v *Validator) leagueInSeason(ctx context.Context, seasonId, leagueId strin
 func (v *Validator) playerExists(ctx context.Context, playerId string, source string) *ValidationResult {
 	vr := &ValidationResult{}
 
-	sql := `select exists(select 1 from player where id = $1)`
-	var exists bool
-
-	if err := v.db.QueryRow(ctx, sql, playerId).Scan(&exists); err != nil {
+	exists, err := v.repo.playerExists(ctx, playerId)
+	if err != nil {
 		vr.failure = failure.New("checking player existance", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 		return vr
 	}
@@ -132,19 +124,28 @@ func (v *Validator) playerExists(ctx context.Context, playerId string, source st
 	return vr
 }
 
+// playerApproved checks that the account of the player passed the signup approval. Players
+// waiting for approval can log in but can't join leagues
+func (v *Validator) playerApproved(ctx context.Context, playerId string, source string) *ValidationResult {
+	vr := &ValidationResult{}
+
+	pending, err := v.repo.playerPending(ctx, playerId)
+	if err != nil {
+		vr.failure = failure.New("checking player approval", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
+		return vr
+	}
+	if pending {
+		vr.addInvalFld("player_id", "player account waiting for approval", source)
+	}
+	return vr
+}
+
 // todo: later should be refactored to support a slice of player ids
 func (v *Validator) playersInLeague(ctx context.Context, leagueId, playerOneId, playerTwoId string, source string) *ValidationResult {
 	vr := &ValidationResult{}
 
-	sql := `
-		select exists (
-			select 1 from player
-			where id in ($1, $2) and current_league_id = $3
-			having count(*) = 2
-		)
-	`
-	var exists bool
-	if err := v.db.QueryRow(ctx, sql, playerOneId, playerTwoId, leagueId).Scan(&exists); err != nil {
+	exists, err := v.repo.playersInLeague(ctx, leagueId, playerOneId, playerTwoId)
+	if err != nil {
 		vr.failure = failure.New("checking if player part of league", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 		return vr
 	}
@@ -160,14 +161,8 @@ func (v *Validator) playersInLeague(ctx context.Context, leagueId, playerOneId,
 func (v *Validator) matchExistsBetweenPlayers(ctx context.Context, seasonId, leagueId, playerOneId, playerTwoId string, source string) *ValidationResult {
 	vr := &ValidationResult{}
 
-	sql := `
-		select exists (
-			select 1 from match
-			where season_id = $1 and league_id = $2 and player_one_id = $3 and player_two_Id = $4
-		)
-	`
-	var exists bool
-	if err := v.db.QueryRow(ctx, sql, seasonId, leagueId, playerOneId, playerTwoId).Scan(&exists); err != nil {
+	exists, err := v.repo.matchExistsBetweenPlayers(ctx, seasonId, leagueId, playerOneId, playerTwoId)
+	if err != nil {
 		vr.failure = failure.New("checking if players already played a match", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 		return vr
 	}
@@ -183,14 +178,8 @@ func (v *Validator) matchExistsBetweenPlayers(ctx context.Context, seasonId, lea
 func (v *Validator) matchScheduledInSeason(ctx context.Context, seasonId, scheduledAt string, source string) *ValidationResult {
 	vr := &ValidationResult{}
 
-	sql := `
-		select exists (
-			select 1 from season
-			where id = $1 and $2 between start_date and end_date
-		)
-	`
-	var exists bool
-	if err := v.db.QueryRow(ctx, sql, seasonId, scheduledAt).Scan(&exists); err != nil {
+	exists, err := v.repo.matchScheduledInSeason(ctx, seasonId, scheduledAt)
+	if err != nil {
 		vr.failure = failure.New("checking if scheduled at in season playing range", fmt.Errorf("%w -> %v", failure.ErrInternal, err))
 		return vr
 	}
@@ -220,7 +209,9 @@ func (vb *ValidationBuilder) CourtExists(courtId, source string) *ValidationBuil
 		return vb
 	}
 
-	vr := vb.validator.courtExists(vb.ctx, courtId, source)
+	vr := vb.trace("CourtExists", func(ctx context.Context) *ValidationResult {
+		return vb.validator.courtExists(ctx, courtId, source)
+	})
 	if vr.failure != nil {
 		vb.result.failure = vr.failure
 		return vb
@@ -235,7 +226,9 @@ func (vb *ValidationBuilder) SeasonExists(seasonId, source string) *ValidationBu
 		return vb
 	}
 
-	vr := vb.validator.seasonExists(vb.ctx, seasonId, source)
+	vr := vb.trace("SeasonExists", func(ctx context.Context) *ValidationResult {
+		return vb.validator.seasonExists(ctx, seasonId, source)
+	})
 	if vr.failure != nil {
 		vb.result.failure = vr.failure
 		return vb
@@ -250,7 +243,9 @@ func (vb *ValidationBuilder) LeagueExists(leagueId, source string) *ValidationBu
 		return vb
 	}
 
-	vr := vb.validator.leagueExists(vb.ctx, leagueId, source)
+	vr := vb.trace("LeagueExists", func(ctx context.Context) *ValidationResult {
+		return vb.validator.leagueExists(ctx, leagueId, source)
+	})
 	if vr.failure != nil {
 		vb.result.failure = vr.failure
 		return vb
@@ -265,7 +260,9 @@ func (vb *ValidationBuilder) LeagueInSeason(seasonId, leagueId, source string) *
 		return vb
 	}
 
-	vr := vb.validator.leagueInSeason(vb.ctx, seasonId, leagueId, source)
+	vr := vb.trace("LeagueInSeason", func(ctx context.Context) *ValidationResult {
+		return vb.validator.leagueInSeason(ctx, seasonId, leagueId, source)
+	})
 	if vr.failure != nil {
 		vb.result.failure = vr.failure
 		return vb
@@ -280,7 +277,25 @@ func (vb *ValidationBuilder) PlayerExists(playerId, source string) *ValidationBu
 		return vb
 	}
 
-	vr := vb.validator.playerExists(vb.ctx, playerId, source)
+	vr := vb.trace("PlayerExists", func(ctx context.Context) *ValidationResult {
+		return vb.validator.playerExists(ctx, playerId, source)
+	})
+	if vr.failure != nil {
+		vb.result.failure = vr.failure
+		return vb
+	}
+	vb.result.invalidFields = append(vb.result.invalidFields, vr.invalidFields...)
+	return vb
+}
+
+func (vb *ValidationBuilder) PlayerApproved(playerId, source string) *ValidationBuilder {
+	if vb.result.failure != nil {
+		return vb
+	}
+
+	vr := vb.trace("PlayerApproved", func(ctx context.Context) *ValidationResult {
+		return vb.validator.playerApproved(ctx, playerId, source)
+	})
 	if vr.failure != nil {
 		vb.result.failure = vr.failure
 		return vb
@@ -294,7 +309,9 @@ func (vb *ValidationBuilder) PlayersInLeague(leagueId, playerOneId, playerTwoId,
 		return vb
 	}
 
-	vr := vb.validator.playersInLeague(vb.ctx, leagueId, playerOneId, playerTwoId, source)
+	vr := vb.trace("PlayersInLeague", func(ctx context.Context) *ValidationResult {
+		return vb.validator.playersInLeague(ctx, leagueId, playerOneId, playerTwoId, source)
+	})
 	if vr.failure != nil {
 		vb.result.failure = vr.failure
 		return vb
@@ -307,7 +324,9 @@ func (vb *ValidationBuilder) MatchExistsBetweenPlayers(seasonId, leagueId, playe
 	if vb.result.failure != nil {
 		return vb
 	}
-	vr := vb.validator.matchExistsBetweenPlayers(vb.ctx, seasonId, leagueId, playerOneId, playerTwoId, source)
+	vr := vb.trace("MatchExistsBetweenPlayers", func(ctx context.Context) *ValidationResult {
+		return vb.validator.matchExistsBetweenPlayers(ctx, seasonId, leagueId, playerOneId, playerTwoId, source)
+	})
 	if vr.failure != nil {
 		vb.result.failure = vr.failure
 		return vb
@@ -320,7 +339,9 @@ func (vb *ValidationBuilder) MatchScheduledInSeason(seasonId, scheduledAt string
 	if vb.result.failure != nil {
 		return vb
 	}
-	vr := vb.validator.matchScheduledInSeason(vb.ctx, seasonId, scheduledAt, source)
+	vr := vb.trace("MatchScheduledInSeason", func(ctx context.Context) *ValidationResult {
+		return vb.validator.matchScheduledInSeason(ctx, seasonId, scheduledAt, source)
+	})
 	if vr.failure != nil {
 		vb.result.failure = vr.failure
 		return vb
@@ -329,6 +350,20 @@ func (vb *ValidationBuilder) MatchScheduledInSeason(seasonId, scheduledAt string
 	return vb
 }
 
+// trace runs the check within its own span, the span records the invalid fields it found
+func (vb *ValidationBuilder) trace(name string, check func(ctx context.Context) *ValidationResult) *ValidationResult {
+	ctx, span := tracing.Start(vb.ctx, "validation."+name)
+	defer span.End()
+
+	vr := check(ctx)
+	if vr.failure != nil {
+		tracing.Fail(span, vr.failure)
+	}
+	span.SetAttributes(attribute.Int("validation.invalid_fields", len(vr.invalidFields)))
+
+	return vr
+}
+
 func (vb *ValidationBuilder) Result() error {
 	return vb.result.result()
 }
@@ -0,0 +1,49 @@
+// Package wellknown serves the /.well-known documents other services use to
+// integrate with the gdsi api.
+package wellknown
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/markovidakovic/gdsi/server/config"
+	"github.com/markovidakovic/gdsi/server/failure"
+	"github.com/markovidakovic/gdsi/server/response"
+	"github.com/markovidakovic/gdsi/server/router"
+)
+
+type api struct {
+	cfg *config.Config
+}
+
+var _ router.Mounter = (*api)(nil)
+
+func New(cfg *config.Config) *api {
+	return &api{
+		cfg: cfg,
+	}
+}
+
+func (a *api) Mount(r chi.Router) {
+	r.Get("/jwks.json", a.jwks)
+}
+
+// @Summary JWKS
+// @Description Get the public keys the gdsi access tokens can be verified with. Keys are identified by the kid token header
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} object "OK"
+// @Failure 500 {object} failure.Failure "Internal server error"
+// @Router /.well-known/jwks.json [get]
+func (a *api) jwks(w http.ResponseWriter, r *http.Request) {
+	set, err := a.cfg.JwtKeys.JWKS()
+	if err != nil {
+		response.WriteFailure(w, failure.New("unable to build jwks", fmt.Errorf("%w -> %v", failure.ErrInternal, err)))
+		return
+	}
+
+	// verifiers refetch the keys when they see an unknown kid, so a short cache is enough
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.WriteSuccess(w, http.StatusOK, set)
+}